@@ -0,0 +1,85 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// Authorizer решает, можно ли выполнить запрос method к таблице; ошибка отдаётся клиенту с кодом 403
+type Authorizer interface {
+	Authorize(r *http.Request, tableName, method string) error
+}
+
+// ACL разрешает методы по ролям: роль → таблица → методы. Таблица и метод "*" подходят для любых,
+// HEAD проверяется как GET, OPTIONS разрешён всегда.
+type ACL struct {
+	Rules map[string]map[string][]string
+	// RoleOf определяет роль запроса; по умолчанию берётся claim role из JWT
+	RoleOf func(r *http.Request) string
+}
+
+func (a ACL) Authorize(r *http.Request, tableName, method string) error {
+	if method == http.MethodOptions {
+		return nil
+	}
+	if method == http.MethodHead {
+		method = http.MethodGet
+	}
+
+	roleOf := a.RoleOf
+	if roleOf == nil {
+		roleOf = requestRole
+	}
+	tables := a.Rules[roleOf(r)]
+
+	methods, ok := tables[tableName]
+	if !ok {
+		methods = tables["*"]
+	}
+	for _, allowed := range methods {
+		if allowed == "*" || strings.EqualFold(allowed, method) {
+			return nil
+		}
+	}
+	return errors.New(method + " on table " + tableName + " is not allowed")
+}
+
+// tableAccess проверяет права на таблицу: claim tables из JWT и настроенный Authorizer
+func (d DbExplorer) tableAccess(r *http.Request, tableName, method string) error {
+	write := method != http.MethodGet && method != http.MethodHead && method != http.MethodOptions
+	if !requestPermissions(r).allowed(tableName, write) {
+		return errors.New("access to table " + tableName + " is denied")
+	}
+
+	if d.authorizer != nil {
+		return d.authorizer.Authorize(r, tableName, method)
+	}
+	return nil
+}
+
+// authorizeTable проверяет права на таблицу из пути запроса.
+// Возвращает false, если уже отправлен ответ 403.
+func (d DbExplorer) authorizeTable(rw http.ResponseWriter, r *http.Request) bool {
+	tableName, err := getTableName(r.URL.Path, d.tableKeys)
+	if err != nil {
+		return true
+	}
+
+	if err := d.tableAccess(r, tableName, r.Method); err != nil {
+		responseResult(rw, err, http.StatusForbidden, nil)
+		return false
+	}
+	return true
+}
+
+// readableTables оставляет таблицы, которые запросу разрешено читать
+func (d DbExplorer) readableTables(r *http.Request) []string {
+	tables := make([]string, 0, len(d.tableKeys))
+	for _, tableName := range d.tableKeys {
+		if d.tableAccess(r, tableName, http.MethodGet) == nil {
+			tables = append(tables, tableName)
+		}
+	}
+	return tables
+}
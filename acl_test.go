@@ -0,0 +1,53 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestACL(t *testing.T) {
+	explorer := DbExplorer{
+		dialect:         MySQL,
+		tableKeys:       []string{"logs", "users"},
+		tableIdNamesMap: map[string][]string{"users": {"id"}},
+	}
+	WithAuthorizer(ACL{
+		Rules: map[string]map[string][]string{
+			"viewer": {"users": {"GET"}},
+			"admin":  {"*": {"*"}},
+		},
+		RoleOf: func(r *http.Request) string { return r.Header.Get("X-Role") },
+	})(&explorer)
+
+	cases := []struct {
+		role   string
+		method string
+		path   string
+		status int
+	}{
+		{"viewer", http.MethodHead, "/", http.StatusOK},
+		{"viewer", http.MethodOptions, "/logs", http.StatusNoContent},
+		{"viewer", http.MethodHead, "/logs", http.StatusForbidden},
+		{"viewer", http.MethodDelete, "/users/1", http.StatusForbidden},
+		{"", http.MethodHead, "/users/schema", http.StatusForbidden},
+		{"admin", http.MethodHead, "/users/schema", http.StatusOK},
+	}
+
+	for _, item := range cases {
+		req := httptest.NewRequest(item.method, item.path, nil)
+		req.Header.Set("X-Role", item.role)
+		rw := httptest.NewRecorder()
+		explorer.ServeHTTP(rw, req)
+
+		if rw.Code != item.status {
+			t.Fatalf("[%s %s %s] expected status %d, got %d", item.role, item.method, item.path, item.status, rw.Code)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-Role", "viewer")
+	if tables := explorer.readableTables(req); len(tables) != 1 || tables[0] != "users" {
+		t.Fatalf("expected only users to be readable, got %v", tables)
+	}
+}
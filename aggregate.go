@@ -0,0 +1,130 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// aggregateFunctions — параметры GET /{table}/aggregate и соответствующие им функции SQL
+var aggregateFunctions = []struct {
+	param, function string
+	numeric         bool
+}{
+	{"count", "COUNT", false},
+	{"sum", "SUM", true},
+	{"avg", "AVG", true},
+	{"min", "MIN", false},
+	{"max", "MAX", false},
+}
+
+var aggregateParams = map[string]bool{"group_by": true, "count": true, "sum": true, "avg": true, "min": true, "max": true}
+
+// aggregateColumn проверяет колонку из параметров агрегации: по ней можно группировать, только если
+// она известна, видна запросу и не маскируется — иначе значение можно было бы подобрать по группам
+func (d DbExplorer) aggregateColumn(tableName, field string) (columnParams, error) {
+	column, ok := d.columnsInTablesMap[tableName][d.columnName(tableName, field)]
+	if !ok {
+		return columnParams{}, errors.New("unknown field " + field)
+	}
+	if _, sensitive := d.sensitiveColumn(tableName, column.name); sensitive {
+		return columnParams{}, errors.New("unknown field " + field)
+	}
+	if column.typeName == "json" || column.typeName == "binary" {
+		return columnParams{}, errors.New("field " + field + " does not support aggregation")
+	}
+	return column, nil
+}
+
+// handlerAggregate выполняет GET /{table}/aggregate?group_by=status&sum=amount&count=*.
+// Каждая функция принимает список колонок через запятую, результат называется <функция>_<колонка>,
+// а count=* — просто count. Остальные параметры работают как фильтры списка.
+func (d DbExplorer) handlerAggregate(rw http.ResponseWriter, r *http.Request, tableName string) {
+	params := r.URL.Query()
+	selects := make([]string, 0)
+	groups := make([]string, 0)
+
+	if rawGroups := params.Get("group_by"); rawGroups != "" {
+		for _, field := range strings.Split(rawGroups, ",") {
+			column, err := d.aggregateColumn(tableName, field)
+			if err != nil {
+				responseResult(rw, err, http.StatusBadRequest, nil)
+				return
+			}
+			groups = append(groups, d.dialect.quote(column.name))
+		}
+		selects = append(selects, groups...)
+	}
+
+	aggregates := 0
+	for _, aggregate := range aggregateFunctions {
+		rawColumns := params.Get(aggregate.param)
+		if rawColumns == "" {
+			continue
+		}
+		for _, field := range strings.Split(rawColumns, ",") {
+			if field == "*" && aggregate.param == "count" {
+				selects = append(selects, "COUNT(*) AS "+d.dialect.quote("count"))
+				aggregates++
+				continue
+			}
+
+			column, err := d.aggregateColumn(tableName, field)
+			if err != nil {
+				responseResult(rw, err, http.StatusBadRequest, nil)
+				return
+			}
+			if aggregate.numeric && column.typeName != "int" && column.typeName != "float" && column.typeName != "decimal" {
+				responseResult(rw, errors.New("field "+field+" does not support "+aggregate.param), http.StatusBadRequest, nil)
+				return
+			}
+			alias := d.dialect.quote(aggregate.param + "_" + field)
+			selects = append(selects, aggregate.function+"("+d.dialect.quote(column.name)+") AS "+alias)
+			aggregates++
+		}
+	}
+	if aggregates == 0 {
+		responseResult(rw, errors.New("at least one of count, sum, avg, min, max is required"), http.StatusBadRequest, nil)
+		return
+	}
+
+	filters := url.Values{}
+	for key, values := range params {
+		if !aggregateParams[key] {
+			filters[key] = values
+		}
+	}
+	args := &queryArgs{dialect: d.dialect}
+	condition, err := d.filterCondition(tableName, filters, args)
+	if err != nil {
+		responseResult(rw, err, http.StatusBadRequest, nil)
+		return
+	}
+	condition = andCondition(condition, d.visibleCondition(tableName))
+
+	query := "SELECT " + strings.Join(selects, ", ") + " FROM " + d.dialect.quote(tableName)
+	if condition != "" {
+		query += " WHERE " + condition
+	}
+	if len(groups) > 0 {
+		query += " GROUP BY " + strings.Join(groups, ", ") + " ORDER BY " + strings.Join(groups, ", ")
+	}
+
+	queryResult, err := d.traced(d.db, tableName).QueryContext(d.requestContext(), query+";", args.values...)
+	if err != nil {
+		responseResult(rw, err, http.StatusInternalServerError, nil)
+		return
+	}
+	records, err := d.parsingSqlQueryResult(queryResult, tableName)
+	if err == ErrRecordNotFound {
+		records, err = []map[string]interface{}{}, nil
+	}
+	if err != nil {
+		responseResult(rw, err, http.StatusInternalServerError, nil)
+		return
+	}
+
+	d.countRows(len(records))
+	responseResult(rw, nil, http.StatusOK, map[string]interface{}{"groups": d.toFieldsList(tableName, records)})
+}
@@ -0,0 +1,92 @@
+package main
+
+import "errors"
+
+// fieldName возвращает имя колонки в JSON: алиас из WithColumnAliases или само имя колонки
+func (d DbExplorer) fieldName(tableName, columnName string) string {
+	if field, ok := d.columnAliases[tableName][columnName]; ok {
+		return field
+	}
+	return columnName
+}
+
+// columnName переводит имя поля из запроса в колонку. Исходное имя колонки с алиасом
+// даёт "", чтобы у одной колонки не было двух имён.
+func (d DbExplorer) columnName(tableName, field string) string {
+	if columnName, ok := d.aliasColumns[tableName][field]; ok {
+		return columnName
+	}
+	if _, ok := d.columnAliases[tableName][field]; ok {
+		return ""
+	}
+	return field
+}
+
+// toFields переименовывает колонки записи из базы в поля ответа
+func (d DbExplorer) toFields(tableName string, record map[string]interface{}) map[string]interface{} {
+	if len(d.columnAliases[tableName]) == 0 || record == nil {
+		return record
+	}
+	result := make(map[string]interface{}, len(record))
+	for columnName, value := range record {
+		result[d.fieldName(tableName, columnName)] = value
+	}
+	return result
+}
+
+func (d DbExplorer) toFieldsList(tableName string, records []map[string]interface{}) []map[string]interface{} {
+	if len(d.columnAliases[tableName]) == 0 {
+		return records
+	}
+	result := make([]map[string]interface{}, 0, len(records))
+	for _, record := range records {
+		result = append(result, d.toFields(tableName, record))
+	}
+	return result
+}
+
+// toColumns переименовывает поля тела запроса в колонки; исходные имена колонок с алиасом
+// отбрасываются, как и любые неизвестные поля, если WithUnknownFields не требует иного
+func (d DbExplorer) toColumns(tableName string, record map[string]interface{}) (map[string]interface{}, error) {
+	if err := d.checkUnknownFields(tableName, record); err != nil {
+		return nil, err
+	}
+	if len(d.columnAliases[tableName]) == 0 || record == nil {
+		return record, nil
+	}
+	result := make(map[string]interface{}, len(record))
+	for field, value := range record {
+		if columnName := d.columnName(tableName, field); columnName != "" {
+			result[columnName] = value
+		}
+	}
+	return result, nil
+}
+
+// checkColumnAliases проверяет, что алиасы относятся к существующим колонкам и у полей таблицы нет повторов
+func (d DbExplorer) checkColumnAliases() error {
+	for tableName, aliases := range d.columnAliases {
+		for columnName, field := range aliases {
+			if _, ok := d.columnsInTablesMap[tableName][columnName]; !ok {
+				return errors.New("column alias " + tableName + "." + columnName + ": column not found")
+			}
+			if !identifierPattern.MatchString(field) {
+				return errors.New("column alias " + tableName + "." + columnName + ": invalid field name " + field)
+			}
+			if _, ok := d.columnsInTablesMap[tableName][field]; ok && field != columnName {
+				// иначе поле значило бы разные колонки в запросе и в ответе
+				return errors.New("column alias " + tableName + "." + columnName + ": field " + field + " is a column name")
+			}
+		}
+
+		fields := make(map[string]bool, len(d.columnKeysMap[tableName]))
+		for _, columnName := range d.columnKeysMap[tableName] {
+			field := d.fieldName(tableName, columnName)
+			if fields[field] {
+				return errors.New("column alias " + tableName + "." + columnName + ": field " + field + " is already used")
+			}
+			fields[field] = true
+		}
+	}
+	return nil
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestColumnAliases(t *testing.T) {
+	db, err := sql.Open("mysql", DSN)
+	err = db.Ping()
+	if err != nil {
+		panic(err)
+	}
+
+	PrepareTestApis(db)
+	defer CleanupTestApis(db)
+
+	invalid := []map[string]string{
+		{"nosuch": "missing"},
+		{"login": "email"},
+		{"login": "user name"},
+		{"login": "email", "email": "login"},
+		{"login": "password"},
+	}
+	for idx, aliases := range invalid {
+		if _, err := NewDbExplorer(db, WithColumnAliases("users", aliases)); err == nil {
+			t.Fatalf("[%d] expected error for aliases %v", idx, aliases)
+		}
+	}
+
+	handler, err := NewDbExplorer(db, WithColumnAliases("users", map[string]string{"user_id": "userId", "login": "userName"}))
+	if err != nil {
+		panic(err)
+	}
+
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	cases := []struct {
+		method      string
+		path        string
+		contentType string
+		body        string
+		status      int
+		result      string
+	}{
+		{method: http.MethodGet, path: "/users/1?fields=userId,userName", status: http.StatusOK,
+			result: `{"response":{"record":{"userId":1,"userName":"rvasily"}}}`},
+		{method: http.MethodGet, path: "/users/1?fields=login", status: http.StatusBadRequest,
+			result: `{"error":"unknown field login"}`},
+		{method: http.MethodGet, path: "/users?login=rvasily", status: http.StatusBadRequest,
+			result: `{"error":"unknown field login"}`},
+		{method: http.MethodPut, path: "/users/", body: `{"userName":"ivan","login":"ignored","password":"x","email":"ivan@example.com","info":""}`, status: http.StatusOK,
+			result: `{"response":{"userId":2}}`},
+		{method: http.MethodPost, path: "/users/2", body: `{"userName":"petr"}`, status: http.StatusOK,
+			result: `{"response":{"updated":1}}`},
+		{method: http.MethodPatch, path: "/users/1", contentType: "application/json-patch+json", body: `[{"op":"replace","path":"/userName","value":"vasily"}]`, status: http.StatusOK,
+			result: `{"response":{"updated":1}}`},
+		{method: http.MethodGet, path: "/users?userName__like=%25i%25&fields=userId,userName&sort=-userName", status: http.StatusOK,
+			result: `{"response":{"records":[{"userId":1,"userName":"vasily"}]}}`},
+		{method: http.MethodGet, path: "/users?fields=userName&sort=-userId", status: http.StatusOK,
+			result: `{"response":{"records":[{"userName":"petr"},{"userName":"vasily"}]}}`},
+		{method: http.MethodGet, path: "/users/aggregate?group_by=userName&count=*", status: http.StatusOK,
+			result: `{"response":{"groups":[{"count":1,"userName":"petr"},{"count":1,"userName":"vasily"}]}}`},
+		{method: http.MethodPost, path: "/graphql", body: `{"query":"mutation { update_users(userId: 2, data: {userName: \"sidor\"}) }"}`, status: http.StatusOK,
+			result: `{"data":{"update_users":1}}`},
+		{method: http.MethodPost, path: "/graphql", body: `{"query":"{ users_by_pk(userId: 2) { userId name: userName } users(sort: \"userName\") { userName } }"}`, status: http.StatusOK,
+			result: `{"data":{"users_by_pk":{"userId":2,"name":"sidor"},"users":[{"userName":"sidor"},{"userName":"vasily"}]}}`},
+		{method: http.MethodPost, path: "/graphql", body: `{"query":"{ users_by_pk(userId: 1) { login } }"}`, status: http.StatusOK,
+			result: `{"errors":[{"message":"Cannot query field login on type Users","path":["users_by_pk"]}],"data":{"users_by_pk":null}}`},
+	}
+
+	for idx, item := range cases {
+		req, _ := http.NewRequest(item.method, ts.URL+item.path, strings.NewReader(item.body))
+		if item.contentType != "" {
+			req.Header.Set("Content-Type", item.contentType)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("[%d] request error: %v", idx, err)
+		}
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if resp.StatusCode != item.status {
+			t.Fatalf("[%d] expected http status %v, got %v: %s", idx, item.status, resp.StatusCode, body)
+		}
+		if string(body) != item.result {
+			t.Fatalf("[%d] results not match\nGot : %s\nWant: %s", idx, body, item.result)
+		}
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("cant listen: %v", err)
+	}
+	server := grpc.NewServer()
+	RegisterGRPCService(server, handler)
+	go server.Serve(listener)
+	defer server.Stop()
+
+	conn, err := grpc.Dial(listener.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("cant connect: %v", err)
+	}
+	defer conn.Close()
+
+	grpcCases := []struct {
+		method   string
+		request  string
+		response string
+		code     codes.Code
+	}{
+		{"Update", `{"table": "users", "id": 2, "record": {"userName": "ivan", "login": "ignored"}}`, `{"updated": 1}`, codes.OK},
+		{"Get", `{"table": "users", "id": 2, "fields": "userId,userName"}`, `{"record": {"userId": 2, "userName": "ivan"}}`, codes.OK},
+		{"List", `{"table": "users", "sort": "userId", "fields": "userName", "filter": {"userId__lte": 2}}`, `{"records": [{"userName": "vasily"}, {"userName": "ivan"}]}`, codes.OK},
+		{"Get", `{"table": "users", "id": 1, "fields": "login"}`, ``, codes.InvalidArgument},
+	}
+	for idx, item := range grpcCases {
+		in := &structpb.Struct{}
+		if err := protojson.Unmarshal([]byte(item.request), in); err != nil {
+			panic(err)
+		}
+		out := &structpb.Struct{}
+		err := conn.Invoke(context.Background(), "/dbexplorer.DbExplorer/"+item.method, in, out)
+		if code := status.Code(err); code != item.code {
+			t.Fatalf("[grpc %d: %s] expected code %v, got %v (%v)", idx, item.method, item.code, code, err)
+		}
+		if item.code != codes.OK {
+			continue
+		}
+		expected := &structpb.Struct{}
+		if err := protojson.Unmarshal([]byte(item.response), expected); err != nil {
+			panic(err)
+		}
+		if got, want := protojson.Format(out), protojson.Format(expected); got != want {
+			t.Fatalf("[grpc %d: %s] results not match\nGot : %s\nWant: %s", idx, item.method, got, want)
+		}
+	}
+
+	schema := handler.tableSchema("users")
+	columns := schema["columns"].([]map[string]interface{})
+	if columns[1]["name"] != "userName" || columns[1]["column"] != "login" || schema["primary_key"].([]string)[0] != "userId" {
+		t.Fatalf("unexpected schema: %v", schema)
+	}
+}
@@ -0,0 +1,331 @@
+package main
+
+import (
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// auditTimeFormat — время фиксированной длины, чтобы строки сортировались по времени
+const auditTimeFormat = "2006-01-02T15:04:05.000000Z"
+
+const (
+	auditDefaultLimit = 100
+	auditWriterKeep   = 1000
+)
+
+// AuditEntry — запись журнала изменений: кто, когда и что поменял
+type AuditEntry struct {
+	Time   time.Time              `json:"time"`
+	Actor  string                 `json:"actor"`
+	Action string                 `json:"action"` // insert, update, delete
+	Table  string                 `json:"table"`
+	ID     map[string]interface{} `json:"id,omitempty"`
+	// Filter — query-строка массового обновления; значения до и после для него не сохраняются
+	Filter string                 `json:"filter,omitempty"`
+	Before map[string]interface{} `json:"before,omitempty"`
+	After  map[string]interface{} `json:"after,omitempty"`
+}
+
+// AuditQuery отбирает записи журнала для GET /admin/audit: новые первыми
+type AuditQuery struct {
+	Table string
+	Limit int
+}
+
+// AuditSink сохраняет журнал изменений и отдаёт его последние записи
+type AuditSink interface {
+	Record(entry AuditEntry) error
+	Entries(query AuditQuery) ([]AuditEntry, error)
+}
+
+// writerAuditSink пишет записи строками JSON и помнит последние auditWriterKeep для запросов
+type writerAuditSink struct {
+	mu     sync.Mutex
+	writer io.Writer
+	recent []AuditEntry
+}
+
+// NewAuditWriter пишет журнал изменений в writer (файл, stdout) по записи JSON на строку
+func NewAuditWriter(writer io.Writer) AuditSink {
+	return &writerAuditSink{writer: writer}
+}
+
+func (s *writerAuditSink) Record(entry AuditEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.recent = append(s.recent, entry)
+	if len(s.recent) > auditWriterKeep {
+		s.recent = s.recent[len(s.recent)-auditWriterKeep:]
+	}
+	_, err = s.writer.Write(append(data, '\n'))
+	return err
+}
+
+func (s *writerAuditSink) Entries(query AuditQuery) ([]AuditEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entries := make([]AuditEntry, 0)
+	for i := len(s.recent) - 1; i >= 0 && len(entries) < query.Limit; i-- {
+		if query.Table == "" || s.recent[i].Table == query.Table {
+			entries = append(entries, s.recent[i])
+		}
+	}
+	return entries, nil
+}
+
+// tableAuditSink хранит журнал в таблице базы; сама таблица explorer'ом не публикуется
+type tableAuditSink struct {
+	db      *sql.DB
+	dialect Dialect
+	table   string
+}
+
+// NewAuditTable пишет журнал изменений в таблицу, которую нужно создать заранее:
+//
+//	CREATE TABLE audit_log (logged_at varchar(32), actor varchar(255), action varchar(16),
+//		table_name varchar(255), record_id text, filter text, before_values text, after_values text)
+func NewAuditTable(db *sql.DB, table string) AuditSink {
+	return &tableAuditSink{db: db, dialect: detectDialect(db), table: table}
+}
+
+var auditColumns = []string{"logged_at", "actor", "action", "table_name", "record_id", "filter", "before_values", "after_values"}
+
+func (s *tableAuditSink) auditTable() string {
+	return s.table
+}
+
+func (s *tableAuditSink) Record(entry AuditEntry) error {
+	args := &queryArgs{dialect: s.dialect}
+	columns := make([]string, 0, len(auditColumns))
+	for _, column := range auditColumns {
+		columns = append(columns, s.dialect.quote(column))
+	}
+
+	var filter interface{}
+	if entry.Filter != "" {
+		filter = entry.Filter
+	}
+	values := []interface{}{entry.Time.UTC().Format(auditTimeFormat), entry.Actor, entry.Action, entry.Table, entry.ID, filter, entry.Before, entry.After}
+
+	placeholders := make([]string, 0, len(values))
+	for _, value := range values {
+		// id и снимки записи храним как JSON-текст, отсутствующие — как NULL
+		if record, ok := value.(map[string]interface{}); ok {
+			value = nil
+			if record != nil {
+				data, err := json.Marshal(record)
+				if err != nil {
+					return err
+				}
+				value = string(data)
+			}
+		}
+		placeholders = append(placeholders, args.add(value))
+	}
+
+	_, err := s.db.Exec(fmt.Sprintf(
+		"INSERT INTO %v (%v) VALUES (%v);",
+		s.dialect.quote(s.table),
+		strings.Join(columns, ", "),
+		strings.Join(placeholders, ", "),
+	), args.values...)
+	return err
+}
+
+func (s *tableAuditSink) Entries(query AuditQuery) ([]AuditEntry, error) {
+	args := &queryArgs{dialect: s.dialect}
+	columns := make([]string, 0, len(auditColumns))
+	for _, column := range auditColumns {
+		columns = append(columns, s.dialect.quote(column))
+	}
+
+	where := ""
+	if query.Table != "" {
+		where = " WHERE " + s.dialect.quote("table_name") + " = " + args.add(query.Table)
+	}
+	limit := args.add(query.Limit)
+	offset := args.add(0)
+	rows, err := s.db.Query(
+		"SELECT "+strings.Join(columns, ", ")+" FROM "+s.dialect.quote(s.table)+where+
+			" ORDER BY "+s.dialect.quote("logged_at")+" DESC "+s.dialect.limitOffset(limit, offset, true)+";",
+		args.values...,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	entries := make([]AuditEntry, 0)
+	for rows.Next() {
+		var loggedAt, actor, action, table string
+		var id, filter, before, after sql.NullString
+		if err := rows.Scan(&loggedAt, &actor, &action, &table, &id, &filter, &before, &after); err != nil {
+			return nil, err
+		}
+
+		entry := AuditEntry{Actor: actor, Action: action, Table: table, Filter: filter.String}
+		entry.Time, _ = time.Parse(auditTimeFormat, loggedAt)
+		for _, field := range []struct {
+			raw    sql.NullString
+			target *map[string]interface{}
+		}{{id, &entry.ID}, {before, &entry.Before}, {after, &entry.After}} {
+			if field.raw.Valid {
+				decoder := json.NewDecoder(strings.NewReader(field.raw.String))
+				decoder.UseNumber()
+				if err := decoder.Decode(field.target); err != nil {
+					return nil, err
+				}
+			}
+		}
+		entries = append(entries, entry)
+	}
+	return entries, rows.Err()
+}
+
+// auditBuffer держит значения записей до и после изменения, пока запрос не дойдёт до emit.
+// Живёт один запрос: запись, откаченная вместе с транзакцией, в журнал просто не попадёт.
+type auditBuffer struct {
+	mu     sync.Mutex
+	before map[string]map[string]interface{}
+	after  map[string]map[string]interface{}
+}
+
+func auditKey(tableName, rawId string) string {
+	return tableName + "/" + rawId
+}
+
+// withAudit возвращает копию explorer'а для запроса r со своим буфером журнала и автором изменений
+func (d DbExplorer) withAudit(r *http.Request) DbExplorer {
+	if d.audit == nil {
+		return d
+	}
+	d.auditBuffer = &auditBuffer{before: map[string]map[string]interface{}{}, after: map[string]map[string]interface{}{}}
+	d.actor = requestActor(r)
+	return d
+}
+
+// requestActor описывает автора запроса: sub из JWT, отпечаток API-ключа или IP клиента
+func requestActor(r *http.Request) string {
+	if r == nil {
+		return "grpc"
+	}
+	if claims := requestClaims(r); claims != nil && claims.Subject != "" {
+		return claims.Subject
+	}
+	if key := requestAPIKey(r); key != "" {
+		sum := sha256.Sum256([]byte(key))
+		return "key:" + hex.EncodeToString(sum[:4])
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// auditSnapshot запоминает запись перед изменением (before) или после него (after)
+func (d DbExplorer) auditSnapshot(db queryExecutor, tableName, rawId string, after bool) {
+	if d.audit == nil || d.auditBuffer == nil {
+		return
+	}
+
+	columns, err := d.visibleColumns(tableName)
+	if err != nil {
+		return
+	}
+	record, err := d.queryRecord(db, tableName, rawId, columns)
+	if err != nil {
+		return
+	}
+
+	d.auditBuffer.mu.Lock()
+	defer d.auditBuffer.mu.Unlock()
+	if after {
+		d.auditBuffer.after[auditKey(tableName, rawId)] = record
+	} else {
+		d.auditBuffer.before[auditKey(tableName, rawId)] = record
+	}
+}
+
+// recordAudit сохраняет событие в журнал вместе со снимками записи из буфера запроса
+func (d DbExplorer) recordAudit(event MutationEvent) {
+	if d.audit == nil {
+		return
+	}
+
+	entry := AuditEntry{
+		Time:   time.Now().UTC(),
+		Actor:  d.actor,
+		Action: event.Type,
+		Table:  event.Table,
+		ID:     event.ID,
+		Filter: event.Filter,
+	}
+	if d.auditBuffer != nil && event.ID != nil {
+		key := auditKey(event.Table, d.rawId(event.Table, event.ID))
+		d.auditBuffer.mu.Lock()
+		entry.Before, entry.After = d.auditBuffer.before[key], d.auditBuffer.after[key]
+		delete(d.auditBuffer.before, key)
+		delete(d.auditBuffer.after, key)
+		d.auditBuffer.mu.Unlock()
+	}
+
+	if err := d.audit.Record(entry); err != nil {
+		d.logError("audit record failed", err)
+	}
+}
+
+// rawId собирает id записи в формате пути (/table/1,2) из значений первичного ключа
+func (d DbExplorer) rawId(tableName string, id map[string]interface{}) string {
+	values := make([]string, 0, len(id))
+	for _, key := range d.tableIdNamesMap[tableName] {
+		values = append(values, fmt.Sprintf("%v", id[key]))
+	}
+	return strings.Join(values, ",")
+}
+
+// handlerAudit отдаёт журнал изменений: GET /admin/audit?table=users&limit=50
+func (d DbExplorer) handlerAudit(rw http.ResponseWriter, r *http.Request) {
+	if d.audit == nil {
+		responseResult(rw, errors.New("audit log is disabled"), http.StatusNotFound, nil)
+		return
+	}
+	if err := d.tableAccess(r, "/admin/audit", http.MethodGet); err != nil {
+		responseResult(rw, err, http.StatusForbidden, nil)
+		return
+	}
+
+	query := AuditQuery{Table: r.URL.Query().Get("table"), Limit: auditDefaultLimit}
+	if rawLimit := r.URL.Query().Get("limit"); rawLimit != "" {
+		limit, err := strconv.Atoi(rawLimit)
+		if err != nil || limit <= 0 {
+			responseResult(rw, errors.New("limit must be a positive number"), http.StatusBadRequest, nil)
+			return
+		}
+		query.Limit = limit
+	}
+
+	entries, err := d.audit.Entries(query)
+	if err != nil {
+		responseResult(rw, err, http.StatusInternalServerError, nil)
+		return
+	}
+	responseResult(rw, nil, http.StatusOK, map[string]interface{}{"entries": entries})
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestAuditWriter(t *testing.T) {
+	out := &bytes.Buffer{}
+	sink := NewAuditWriter(out)
+
+	sink.Record(AuditEntry{Action: "insert", Table: "users", ID: map[string]interface{}{"id": 1}})
+	sink.Record(AuditEntry{Action: "update", Table: "items", Filter: "status=pending"})
+	sink.Record(AuditEntry{Action: "delete", Table: "users", ID: map[string]interface{}{"id": 1}})
+
+	if lines := strings.Count(out.String(), "\n"); lines != 3 {
+		t.Fatalf("expected 3 json lines, got %d: %s", lines, out.String())
+	}
+
+	entries, _ := sink.Entries(AuditQuery{Table: "users", Limit: 10})
+	if len(entries) != 2 || entries[0].Action != "delete" || entries[1].Action != "insert" {
+		t.Fatalf("unexpected entries %+v", entries)
+	}
+	if entries, _ := sink.Entries(AuditQuery{Limit: 1}); len(entries) != 1 || entries[0].Action != "delete" {
+		t.Fatalf("unexpected limited entries %+v", entries)
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+var errUnauthorized = errors.New("unauthorized")
+
+// APIKeyLookup сообщает, действителен ли ключ из X-API-Key или Bearer-токен
+type APIKeyLookup func(key string) bool
+
+// staticAPIKeys сравнивает ключ с каждым из заданных за постоянное время
+func staticAPIKeys(keys []string) APIKeyLookup {
+	return func(key string) bool {
+		valid := false
+		for _, allowed := range keys {
+			if subtle.ConstantTimeCompare([]byte(allowed), []byte(key)) == 1 {
+				valid = true
+			}
+		}
+		return valid
+	}
+}
+
+// requestAPIKey достаёт ключ из X-API-Key, а при его отсутствии — из Authorization: Bearer
+func requestAPIKey(r *http.Request) string {
+	if key := r.Header.Get("X-API-Key"); key != "" {
+		return key
+	}
+
+	authorization := r.Header.Get("Authorization")
+	if len(authorization) > len("Bearer ") && strings.EqualFold(authorization[:len("Bearer ")], "Bearer ") {
+		return strings.TrimSpace(authorization[len("Bearer "):])
+	}
+	return ""
+}
+
+// authenticate проверяет API-ключ или JWT запроса, если аутентификация включена.
+// Права из JWT кладутся в контекст возвращённого запроса; false — уже отправлен ответ 401.
+func (d DbExplorer) authenticate(rw http.ResponseWriter, r *http.Request) (*http.Request, bool) {
+	r, err := d.authenticateRequest(r)
+	if err != nil {
+		rw.Header().Set("WWW-Authenticate", `Bearer realm="dbexplorer"`)
+		responseResult(rw, err, http.StatusUnauthorized, nil)
+		return r, false
+	}
+	return r, true
+}
+
+// authenticateRequest — проверка authenticate без ответа клиенту, её же проходят вызовы gRPC
+func (d DbExplorer) authenticateRequest(r *http.Request) (*http.Request, error) {
+	if d.apiKeys == nil && d.jwtSecret == nil {
+		return r, nil
+	}
+
+	key := requestAPIKey(r)
+	if key != "" && d.apiKeys != nil && d.apiKeys(key) {
+		return r, nil
+	}
+
+	if key != "" && d.jwtSecret != nil {
+		claims, err := parseJWT(key, d.jwtSecret, time.Now())
+		if err != nil {
+			return r, err
+		}
+		return withClaims(r, claims), nil
+	}
+	return r, errUnauthorized
+}
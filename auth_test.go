@@ -0,0 +1,40 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAPIKeyAuth(t *testing.T) {
+	explorer := DbExplorer{dialect: MySQL}
+	WithAPIKeys("secret", "other")(&explorer)
+
+	cases := []struct {
+		header string
+		value  string
+		status int
+	}{
+		{"", "", http.StatusUnauthorized},
+		{"X-API-Key", "wrong", http.StatusUnauthorized},
+		{"X-API-Key", "secret", http.StatusOK},
+		{"Authorization", "Bearer other", http.StatusOK},
+		{"Authorization", "Basic other", http.StatusUnauthorized},
+	}
+
+	for _, item := range cases {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		if item.header != "" {
+			req.Header.Set(item.header, item.value)
+		}
+		rw := httptest.NewRecorder()
+		explorer.ServeHTTP(rw, req)
+
+		if rw.Code != item.status {
+			t.Fatalf("[%s: %s] expected status %d, got %d", item.header, item.value, item.status, rw.Code)
+		}
+		if item.status == http.StatusUnauthorized && rw.Body.String() != `{"error":"unauthorized"}` {
+			t.Fatalf("[%s: %s] unexpected body %s", item.header, item.value, rw.Body.String())
+		}
+	}
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// batchItem — шаг POST /batch: запрос method к /{table} или /{table}/{id} с телом body
+type batchItem struct {
+	Method string                 `json:"method"`
+	Table  string                 `json:"table"`
+	ID     interface{}            `json:"id"`
+	Body   map[string]interface{} `json:"body"`
+}
+
+// handlerBatch выполняет POST /batch: шаги из массива в теле по порядку, как отдельные запросы
+// к REST API, и возвращает статус и ответ каждого. Ошибка шага не останавливает остальные.
+// С ?transaction=true шаги выполняются одной транзакцией как в POST /transaction: PUT — вставка,
+// POST и PATCH — обновление, DELETE — удаление; при ошибке откатываются все.
+func (d DbExplorer) handlerBatch(rw http.ResponseWriter, r *http.Request) {
+	items := make([]batchItem, 0)
+	decoder := json.NewDecoder(r.Body)
+	decoder.UseNumber()
+	if err := decoder.Decode(&items); err != nil {
+		responseResult(rw, err, bodyErrorStatus(err), nil)
+		return
+	}
+	if len(items) == 0 {
+		responseResult(rw, errors.New("items are required"), http.StatusBadRequest, nil)
+		return
+	}
+	if len(items) > transactionMaxOperations {
+		responseResult(rw, errors.New("too many items, max "+strconv.Itoa(transactionMaxOperations)), http.StatusBadRequest, nil)
+		return
+	}
+
+	if r.URL.Query().Get("transaction") == "true" {
+		d.handlerBatchTransaction(rw, r, items)
+		return
+	}
+
+	results := make([]map[string]interface{}, 0, len(items))
+	for _, item := range items {
+		results = append(results, d.serveBatchItem(rw, r, item))
+	}
+	responseResult(rw, nil, http.StatusOK, map[string]interface{}{"results": results})
+}
+
+func (d DbExplorer) handlerBatchTransaction(rw http.ResponseWriter, r *http.Request, items []batchItem) {
+	operations := make([]transactionOperation, 0, len(items))
+	for i, item := range items {
+		operation := transactionOperation{Table: item.Table, ID: item.ID, Record: item.Body}
+		switch strings.ToUpper(item.Method) {
+		case http.MethodPut:
+			operation.Op = "insert"
+		case http.MethodPost, http.MethodPatch:
+			operation.Op = "update"
+		case http.MethodDelete:
+			operation.Op = "delete"
+		default:
+			err := errors.New("method " + item.Method + " is not supported in a transaction")
+			transactionError(rw, operationError{index: i, status: http.StatusBadRequest, err: err})
+			return
+		}
+		operations = append(operations, operation)
+	}
+
+	tx, results, events, err := d.execTransaction(r, operations)
+	if err != nil {
+		transactionError(rw, err)
+		return
+	}
+	for _, result := range results {
+		result["status"] = http.StatusOK
+	}
+	d.commitResult(rw, tx, map[string]interface{}{"results": results}, func() {
+		for _, event := range events {
+			d.emit(event)
+		}
+	})
+}
+
+// serveBatchItem выполняет шаг как запрос с заголовками и контекстом (а значит, и правами) исходного
+// запроса и возвращает его конверт ответа с добавленным status
+func (d DbExplorer) serveBatchItem(rw http.ResponseWriter, r *http.Request, item batchItem) map[string]interface{} {
+	writer := newBatchWriter(rw)
+	handler := map[string]func(http.ResponseWriter, *http.Request){
+		http.MethodGet:    d.handlerGet,
+		http.MethodPut:    d.handlerPut,
+		http.MethodPost:   d.handlerPost,
+		http.MethodPatch:  d.handlerPatch,
+		http.MethodDelete: d.handlerDelete,
+	}[strings.ToUpper(item.Method)]
+
+	id := operationId(item.ID)
+	switch {
+	case handler == nil:
+		responseResult(writer, errMethodNotAllowed, http.StatusMethodNotAllowed, nil)
+	case !containsString(d.tableKeys, item.Table):
+		responseResult(writer, ErrUnknownTable, http.StatusNotFound, nil)
+	case strings.Contains(id, "/"):
+		responseResult(writer, errors.New("invalid record id"), http.StatusBadRequest, nil)
+	default:
+		path := "/" + item.Table
+		if id != "" {
+			path += "/" + id
+		}
+		body := []byte{}
+		if item.Body != nil {
+			body, _ = json.Marshal(item.Body)
+		}
+		sub, err := http.NewRequestWithContext(r.Context(), strings.ToUpper(item.Method), path, bytes.NewReader(body))
+		if err != nil {
+			responseResult(writer, err, http.StatusBadRequest, nil)
+			break
+		}
+		sub.Header = r.Header.Clone()
+		sub.Header.Set("Content-Type", "application/json")
+		for _, name := range []string{"Accept", "If-Match", "If-None-Match", "Idempotency-Key"} {
+			sub.Header.Del(name)
+		}
+		if d.authorizeTable(writer, sub) {
+			handler(writer, sub)
+		}
+	}
+
+	result := map[string]interface{}{}
+	if writer.body.Len() > 0 {
+		decoder := json.NewDecoder(&writer.body)
+		decoder.UseNumber()
+		if err := decoder.Decode(&result); err != nil {
+			result = map[string]interface{}{"error": "invalid response: " + err.Error()}
+		}
+	}
+	if writer.status == 0 {
+		writer.status = http.StatusOK
+	}
+	result["status"] = writer.status
+	return result
+}
+
+// batchWriter собирает ответ шага в память. Unwrap отдаёт ответ исходного запроса, чтобы
+// responseResult нашёл его журнал и настройки, но тип ответа шага всегда JSON.
+type batchWriter struct {
+	parent http.ResponseWriter
+	header http.Header
+	status int
+	body   bytes.Buffer
+}
+
+func newBatchWriter(rw http.ResponseWriter) *batchWriter {
+	return &batchWriter{parent: negotiatedWriter{ResponseWriter: rw, mediaType: "application/json"}, header: http.Header{}}
+}
+
+func (w *batchWriter) Unwrap() http.ResponseWriter {
+	return w.parent
+}
+
+func (w *batchWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *batchWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+}
+
+func (w *batchWriter) Write(data []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	return w.body.Write(data)
+}
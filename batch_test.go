@@ -0,0 +1,108 @@
+package main
+
+import (
+	"database/sql"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestBatch(t *testing.T) {
+	db, err := sql.Open("mysql", DSN)
+	err = db.Ping()
+	if err != nil {
+		panic(err)
+	}
+
+	PrepareTestApis(db)
+	defer CleanupTestApis(db)
+
+	handler, err := NewDbExplorer(db)
+	if err != nil {
+		panic(err)
+	}
+
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	cases := []struct {
+		path   string
+		body   string
+		status int
+		result string
+	}{
+		{
+			path: "/batch",
+			body: `[
+				{"method":"PUT","table":"items","body":{"title":"kafka","description":""}},
+				{"method":"POST","table":"items","id":1,"body":{"title":"net/http"}},
+				{"method":"GET","table":"items","id":"1"},
+				{"method":"DELETE","table":"items","id":100500},
+				{"method":"GET","table":"unknown_table"},
+				{"method":"POST","table":"items","id":2,"body":{"title":42}}
+			]`,
+			status: http.StatusOK,
+			result: `{"response":{"results":[` +
+				`{"response":{"id":3},"status":200},` +
+				`{"response":{"updated":1},"status":200},` +
+				`{"response":{"record":{"description":"Рассказать про базы данных","id":1,"title":"net/http","updated":"rvasily"}},"status":200},` +
+				`{"response":{"deleted":0},"status":200},` +
+				`{"error":"unknown table","status":404},` +
+				`{"error":"field title have invalid type","status":400}]}}`,
+		},
+		{
+			path: "/batch?transaction=true",
+			body: `[
+				{"method":"PUT","table":"items","body":{"title":"grpc","description":""}},
+				{"method":"DELETE","table":"items","id":3}
+			]`,
+			status: http.StatusOK,
+			result: `{"response":{"results":[` +
+				`{"id":{"id":4},"op":"insert","status":200,"table":"items"},` +
+				`{"deleted":1,"op":"delete","status":200,"table":"items"}]}}`,
+		},
+		{
+			path: "/batch?transaction=true",
+			body: `[
+				{"method":"PUT","table":"items","body":{"title":"nats","description":""}},
+				{"method":"GET","table":"items","id":1}
+			]`,
+			status: http.StatusBadRequest,
+			result: `{"error":"operation 1: method GET is not supported in a transaction","response":{"failed":1}}`,
+		},
+		{
+			path:   "/batch",
+			body:   `[]`,
+			status: http.StatusBadRequest,
+			result: `{"error":"items are required"}`,
+		},
+		{
+			path:   "/items/4",
+			status: http.StatusOK,
+			result: `{"response":{"record":{"description":"","id":4,"title":"grpc","updated":null}}}`,
+		},
+	}
+
+	for idx, item := range cases {
+		method := http.MethodPost
+		if item.body == "" {
+			method = http.MethodGet
+		}
+		req, _ := http.NewRequest(method, ts.URL+item.path, strings.NewReader(item.body))
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("[case %d] request error: %v", idx, err)
+		}
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if resp.StatusCode != item.status {
+			t.Fatalf("[case %d: %s] expected http status %v, got %v: %s", idx, item.path, item.status, resp.StatusCode, body)
+		}
+		if string(body) != item.result {
+			t.Fatalf("[case %d: %s] results not match\nGot : %s\nWant: %s", idx, item.path, body, item.result)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+const (
+	breakerDefaultThreshold = 5
+	breakerDefaultCooldown  = 10 * time.Second
+	breakerProbeTimeout     = 2 * time.Second
+)
+
+var errDatabaseUnavailable = errors.New("database is unavailable")
+
+// BreakerConfig — автомат отключения при недоступности БД, см. WithCircuitBreaker
+type BreakerConfig struct {
+	// Threshold — сколько ошибок соединения подряд размыкают цепь; 0 — 5
+	Threshold int
+	// Cooldown — через сколько после размыкания пробовать базу снова; 0 — 10s
+	Cooldown time.Duration
+}
+
+// circuitBreaker считает ошибки соединения подряд. Разомкнутая цепь отвечает 503 без обращения
+// к базе, по истечении Cooldown один запрос проверяет её Ping'ом и замыкает цепь или ждёт дальше.
+type circuitBreaker struct {
+	mu       sync.Mutex
+	config   BreakerConfig
+	failures int
+	openedAt time.Time
+	probing  bool
+}
+
+// outageError — ошибка соединения с базой, а не ошибка самого запроса вроде дубликата ключа.
+// Отмена и таймаут запроса — дело одного клиента, хотя context.DeadlineExceeded и реализует net.Error.
+func outageError(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var netErr net.Error
+	return errors.Is(err, driver.ErrBadConn) || errors.Is(err, mysql.ErrInvalidConn) ||
+		errors.Is(err, syscall.ECONNREFUSED) || errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, io.ErrUnexpectedEOF) || errors.As(err, &netErr)
+}
+
+func (b *circuitBreaker) record(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	switch {
+	case err == nil:
+		b.failures = 0
+	case outageError(err):
+		b.failures++
+		if b.failures >= b.config.Threshold && b.openedAt.IsZero() {
+			b.openedAt = time.Now()
+		}
+	}
+}
+
+// allow решает, можно ли выполнить запрос; probe — запрос должен сначала проверить базу, wait — сколько ещё ждать
+func (b *circuitBreaker) allow(now time.Time) (ok, probe bool, wait time.Duration) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.openedAt.IsZero() {
+		return true, false, 0
+	}
+	if wait := b.openedAt.Add(b.config.Cooldown).Sub(now); wait > 0 || b.probing {
+		if wait <= 0 {
+			wait = time.Second
+		}
+		return false, false, wait
+	}
+	b.probing = true
+	return true, true, 0
+}
+
+// probed замыкает цепь после удачной проверки или откладывает следующую на Cooldown
+func (b *circuitBreaker) probed(err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.probing = false
+	if err != nil {
+		b.openedAt = time.Now()
+		return
+	}
+	b.failures = 0
+	b.openedAt = time.Time{}
+}
+
+// circuitClosed отвечает 503 с Retry-After, пока цепь разомкнута; false — ответ уже отправлен
+func (d DbExplorer) circuitClosed(rw http.ResponseWriter, r *http.Request) bool {
+	if d.breaker == nil {
+		return true
+	}
+
+	ok, probe, wait := d.breaker.allow(time.Now())
+	if probe {
+		ctx, cancel := context.WithTimeout(r.Context(), breakerProbeTimeout)
+		err := d.db.PingContext(ctx)
+		cancel()
+		d.breaker.probed(err)
+		ok, wait = err == nil, d.breaker.config.Cooldown
+	}
+	if ok {
+		return true
+	}
+
+	rw.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	responseResult(rw, errDatabaseUnavailable, http.StatusServiceUnavailable, nil)
+	return false
+}
+
+// breakerExecutor сообщает автомату об исходе каждого запроса
+type breakerExecutor struct {
+	queryExecutor
+	breaker *circuitBreaker
+}
+
+func (e breakerExecutor) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	result, err := e.queryExecutor.ExecContext(ctx, query, args...)
+	e.breaker.record(err)
+	return result, err
+}
+
+func (e breakerExecutor) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	rows, err := e.queryExecutor.QueryContext(ctx, query, args...)
+	e.breaker.record(err)
+	return rows, err
+}
+
+func (e breakerExecutor) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	row := e.queryExecutor.QueryRowContext(ctx, query, args...)
+	e.breaker.record(row.Err())
+	return row
+}
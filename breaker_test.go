@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCircuitBreaker(t *testing.T) {
+	db, err := sql.Open("mysql", DSN)
+	err = db.Ping()
+	if err != nil {
+		panic(err)
+	}
+
+	PrepareTestApis(db)
+	defer CleanupTestApis(db)
+
+	explorer, err := NewDbExplorer(db, WithCircuitBreaker(BreakerConfig{Threshold: 2, Cooldown: 50 * time.Millisecond}))
+	if err != nil {
+		panic(err)
+	}
+	// база «упала»: на этом порту никто не слушает
+	deadDB, _ := sql.Open("mysql", "root:1234@tcp(127.0.0.1:1)/golang?timeout=1s")
+	defer deadDB.Close()
+	explorer.db = deadDB
+
+	get := func() (*http.Response, string) {
+		rw := httptest.NewRecorder()
+		explorer.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/items/1?fields=id", nil))
+		resp := rw.Result()
+		body, _ := ioutil.ReadAll(resp.Body)
+		return resp, string(body)
+	}
+
+	for i := 0; i < 2; i++ {
+		if resp, body := get(); resp.StatusCode == http.StatusServiceUnavailable {
+			t.Fatalf("[request %d] circuit must stay closed until threshold, got %s", i, body)
+		}
+	}
+	resp, body := get()
+	if want := `{"error":"database is unavailable"}`; resp.StatusCode != http.StatusServiceUnavailable || body != want {
+		t.Fatalf("expected 503 with open circuit, got %v: %s", resp.StatusCode, body)
+	}
+	if retryAfter := resp.Header.Get("Retry-After"); retryAfter != "1" {
+		t.Fatalf("expected Retry-After 1, got %q", retryAfter)
+	}
+
+	// после Cooldown проверка базы неудачна — цепь остаётся разомкнутой
+	time.Sleep(60 * time.Millisecond)
+	if resp, body := get(); resp.StatusCode != http.StatusServiceUnavailable {
+		t.Fatalf("failed probe must keep circuit open, got %v: %s", resp.StatusCode, body)
+	}
+
+	explorer.db = db
+	time.Sleep(60 * time.Millisecond)
+	if resp, body := get(); resp.StatusCode != http.StatusOK || body != `{"response":{"record":{"id":1}}}` {
+		t.Fatalf("successful probe must close circuit, got %v: %s", resp.StatusCode, body)
+	}
+}
+
+func TestCircuitBreakerTimeout(t *testing.T) {
+	db, err := sql.Open("mysql", DSN)
+	err = db.Ping()
+	if err != nil {
+		panic(err)
+	}
+
+	PrepareTestApis(db)
+	defer CleanupTestApis(db)
+
+	explorer, err := NewDbExplorer(db, WithCircuitBreaker(BreakerConfig{Threshold: 1, Cooldown: time.Minute}))
+	if err != nil {
+		panic(err)
+	}
+
+	// таймаут запроса клиента — не отказ базы: цепь не должна размыкаться для всех
+	for _, err := range []error{context.DeadlineExceeded, context.Canceled} {
+		if outageError(err) {
+			t.Fatalf("%v must not count as an outage", err)
+		}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Nanosecond)
+	defer cancel()
+	time.Sleep(time.Millisecond)
+	rw := httptest.NewRecorder()
+	explorer.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/items/1?fields=id", nil).WithContext(ctx))
+	if rw.Code == http.StatusOK {
+		t.Fatalf("expected timed out request to fail")
+	}
+
+	rw = httptest.NewRecorder()
+	explorer.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/items/1?fields=id", nil))
+	if rw.Code != http.StatusOK {
+		t.Fatalf("circuit must stay closed after a timeout, got %v: %s", rw.Code, rw.Body)
+	}
+}
@@ -0,0 +1,172 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// handlerBulkInsert вставляет массив записей одной транзакцией: либо все, либо ни одной
+func (d DbExplorer) handlerBulkInsert(rw http.ResponseWriter, tableName string, body io.Reader) {
+	rawRecords := make([]map[string]interface{}, 0)
+	decoder := json.NewDecoder(body)
+	decoder.UseNumber()
+	if err := decoder.Decode(&rawRecords); err != nil {
+		responseResult(rw, err, bodyErrorStatus(err), nil)
+		return
+	}
+
+	if len(rawRecords) == 0 {
+		responseResult(rw, errors.New("no records to insert"), http.StatusBadRequest, nil)
+		return
+	}
+
+	records := make([]map[string]interface{}, 0, len(rawRecords))
+	for i, rawRecord := range rawRecords {
+		record, err := d.toColumns(tableName, rawRecord)
+		if err == nil {
+			record, err = d.validateRecord(tableName, record)
+		}
+		if err != nil {
+			responseResult(rw, errors.New("record "+strconv.Itoa(i)+": "+err.Error()), http.StatusBadRequest, nil)
+			return
+		}
+		records = append(records, record)
+	}
+
+	tx, err := d.db.BeginTx(d.requestContext(), nil)
+	if err != nil {
+		responseResult(rw, err, http.StatusInternalServerError, nil)
+		return
+	}
+
+	ids := make([]map[string]interface{}, 0, len(records))
+	for i, record := range records {
+		id, err := d.insertRecord(tx, record, tableName)
+		if err != nil {
+			tx.Rollback()
+			responseResult(rw, errors.New("record "+strconv.Itoa(i)+": "+err.Error()), http.StatusBadRequest, nil)
+			return
+		}
+		ids = append(ids, id)
+	}
+
+	d.commitResult(rw, tx, map[string]interface{}{"ids": d.toFieldsList(tableName, ids)}, func() {
+		for i, id := range ids {
+			d.emitInsert(tableName, id, records[i])
+		}
+	})
+}
+
+// handlerBulkUpdate выполняет POST /{table}?status=pending: один UPDATE ... WHERE по фильтрам из query.
+// Без фильтров запрос отклоняется, чтобы случайно не обновить всю таблицу. С хуками обновления записи
+// обновляются по одной, см. updateMatching.
+func (d DbExplorer) handlerBulkUpdate(rw http.ResponseWriter, r *http.Request) {
+	tableName, err := getTableName(r.URL.Path, d.tableKeys)
+	if err != nil {
+		responseResult(rw, ErrUnknownTable, http.StatusNotFound, nil)
+		return
+	}
+
+	requestData, err := getDataForSqlQuery(r.Body, d, tableName)
+	if err != nil {
+		responseResult(rw, err, bodyErrorStatus(err), nil)
+		return
+	}
+
+	args := &queryArgs{dialect: d.dialect}
+	data, versionSet, expectedVersion, versionSupplied := d.versionUpdate(tableName, requestData)
+	set, err := d.setClause(tableName, data, args)
+	if err != nil {
+		responseResult(rw, err, http.StatusBadRequest, nil)
+		return
+	}
+	set = joinSet(set, versionSet)
+
+	hooked := len(d.hooks[BeforeUpdate]) > 0 || len(d.hooks[AfterUpdate]) > 0
+	conditionArgs := args
+	if hooked {
+		// условие уйдёт в отдельный SELECT, без аргументов SET
+		conditionArgs = &queryArgs{dialect: d.dialect}
+	}
+	condition, err := d.filterCondition(tableName, r.URL.Query(), conditionArgs)
+	if err != nil {
+		responseResult(rw, err, http.StatusBadRequest, nil)
+		return
+	}
+	if condition == "" {
+		responseResult(rw, errors.New("bulk update requires a filter"), http.StatusBadRequest, nil)
+		return
+	}
+	condition = andCondition(condition, d.notDeletedCondition(tableName))
+	if versionSupplied {
+		// присланная версия при массовом обновлении работает как ещё один фильтр
+		condition = andCondition(condition, d.versionCondition(tableName, expectedVersion, conditionArgs))
+	}
+
+	tx, err := d.db.BeginTx(d.requestContext(), nil)
+	if err != nil {
+		responseResult(rw, err, http.StatusInternalServerError, nil)
+		return
+	}
+
+	var affectedCount int
+	if hooked {
+		affectedCount, err = d.updateMatching(tx, tableName, requestData, condition, conditionArgs)
+	} else {
+		affectedCount, err = d.execUpdate(tx, tableName, set, condition, args)
+	}
+	if err != nil {
+		tx.Rollback()
+		responseResult(rw, err, updateErrorStatus(err), nil)
+		return
+	}
+	d.commitResult(rw, tx, map[string]int{"updated": affectedCount}, func() {
+		if affectedCount > 0 {
+			d.emit(MutationEvent{Type: "update", Table: tableName, Fields: sortedKeys(requestData), Filter: r.URL.RawQuery})
+		}
+	})
+}
+
+// updateMatching обновляет записи под условием по одной через updateRecord, чтобы BeforeUpdate
+// и AfterUpdate получили id и поля каждой записи. Отказ хука на любой записи отменяет всё обновление.
+func (d DbExplorer) updateMatching(db queryExecutor, tableName string, data map[string]interface{}, condition string, args *queryArgs) (int, error) {
+	quoted := make([]string, 0, len(d.tableIdNamesMap[tableName]))
+	for _, key := range d.tableIdNamesMap[tableName] {
+		quoted = append(quoted, d.dialect.quote(key))
+	}
+	query := "SELECT " + strings.Join(quoted, ", ") + " FROM " + d.dialect.quote(tableName) + " WHERE " + condition +
+		" ORDER BY " + strings.Join(quoted, ", ") + ";"
+
+	queryResult, err := d.traced(db, tableName).QueryContext(d.requestContext(), query, args.values...)
+	if err != nil {
+		return 0, err
+	}
+	// строки дочитываются до обновлений: на одном соединении нельзя выполнять запрос при открытом курсоре
+	records, err := d.parsingSqlQueryResult(queryResult, tableName)
+	queryResult.Close()
+	if err == ErrRecordNotFound {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+
+	updated := 0
+	for _, record := range records {
+		// хуки могут менять поля, поэтому у каждой записи своя копия
+		rowData := make(map[string]interface{}, len(data))
+		for key, value := range data {
+			rowData[key] = value
+		}
+		affectedCount, err := d.updateRecord(db, rowData, tableName, d.rawId(tableName, record))
+		if err != nil {
+			return 0, err
+		}
+		updated += affectedCount
+	}
+	return updated, nil
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// cacheMaxBodySize — ответы больше этого размера (например, длинные выгрузки списков) не кэшируются
+const cacheMaxBodySize = 1 << 20
+
+// responseCache хранит ответы GET списков и записей до истечения ttl или до записи в таблицу
+type responseCache struct {
+	mu          sync.Mutex
+	ttl         time.Duration
+	maxEntries  int
+	entries     map[string]*cacheEntry
+	order       []*cacheEntry     // по времени создания, для вытеснения старых ответов
+	generations map[string]uint64 // счётчик изменений таблицы, см. invalidate
+}
+
+// cachedHeaders — заголовки ответа, которые кэш сохраняет и повторяет при попадании вместе с телом
+var cachedHeaders = []string{"Content-Type", "Content-Disposition", "Link"}
+
+type cacheEntry struct {
+	key     string
+	tables  []string // таблицы, записи которых попали в ответ, см. cacheTables
+	expires time.Time
+	header  http.Header // см. cachedHeaders
+	etag    string
+	body    []byte
+}
+
+func newResponseCache(ttl time.Duration, maxEntries int) *responseCache {
+	return &responseCache{
+		ttl:         ttl,
+		maxEntries:  maxEntries,
+		entries:     make(map[string]*cacheEntry),
+		generations: make(map[string]uint64),
+	}
+}
+
+func (c *responseCache) get(key string, now time.Time) (*cacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	entry, ok := c.entries[key]
+	if !ok || now.After(entry.expires) {
+		return nil, false
+	}
+	return entry, true
+}
+
+// generation — сумма счётчиков изменений таблиц: она растёт при изменении любой из них
+func (c *responseCache) generation(tables ...string) uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.generationLocked(tables)
+}
+
+func (c *responseCache) generationLocked(tables []string) uint64 {
+	generation := uint64(0)
+	for _, table := range tables {
+		generation += c.generations[table]
+	}
+	return generation
+}
+
+// put сохраняет ответ, если с начала его чтения таблица не менялась: иначе в кэш попал бы
+// результат, прочитанный до записи, но сохранённый уже после её invalidate
+func (c *responseCache) put(entry *cacheEntry, generation uint64, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.generationLocked(entry.tables) != generation {
+		return
+	}
+	for len(c.order) > 0 && (len(c.order) >= c.maxEntries || now.After(c.order[0].expires)) {
+		if c.entries[c.order[0].key] == c.order[0] {
+			delete(c.entries, c.order[0].key)
+		}
+		c.order = c.order[1:]
+	}
+
+	entry.expires = now.Add(c.ttl)
+	c.entries[entry.key] = entry
+	c.order = append(c.order, entry)
+}
+
+// invalidate сбрасывает ответы, в которые попали записи таблицы, после их изменения
+func (c *responseCache) invalidate(table string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generations[table]++
+	for key, entry := range c.entries {
+		if containsString(entry.tables, table) {
+			delete(c.entries, key)
+		}
+	}
+}
+
+// cacheWriter копирует ответ для кэша, пока он не превысит cacheMaxBodySize
+type cacheWriter struct {
+	http.ResponseWriter
+	status   int
+	body     bytes.Buffer
+	tooLarge bool
+	failed   bool // ответ не дошёл до клиента целиком
+}
+
+func (w *cacheWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *cacheWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (w *cacheWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *cacheWriter) Write(data []byte) (int, error) {
+	if w.status == 0 {
+		w.status = http.StatusOK
+	}
+	if !w.tooLarge && w.body.Len()+len(data) <= cacheMaxBodySize {
+		w.body.Write(data)
+	} else {
+		w.tooLarge = true
+		w.body.Reset()
+	}
+	n, err := w.ResponseWriter.Write(data)
+	if err != nil {
+		w.failed = true
+	}
+	return n, err
+}
+
+// cachePrincipal — часть ключа кэша, от которой зависят права запроса: связи в include и _links
+// собираются только из доступных ему таблиц. false — права решает сторонний Authorizer по неизвестным
+// кэшу признакам запроса, такие ответы не кэшируются.
+func (d DbExplorer) cachePrincipal(r *http.Request) (string, bool) {
+	principal := ""
+	if claims := requestClaims(r); claims != nil {
+		data, _ := json.Marshal([]interface{}{claims.Subject, claims.Role, claims.Tables})
+		principal = string(data)
+	}
+
+	var acl ACL
+	switch authorizer := d.authorizer.(type) {
+	case nil:
+		return principal, true
+	case ACL:
+		acl = authorizer
+	case *ACL:
+		acl = *authorizer
+	default:
+		return "", false
+	}
+	if acl.RoleOf != nil {
+		principal += "\x00" + acl.RoleOf(r)
+	}
+	return principal, true
+}
+
+// cacheTables — таблицы, записи которых попадают в ответ: сама таблица и, с ?include=, таблицы,
+// на которые она ссылается внешними ключами. Изменение любой из них сбрасывает ответ.
+func (d DbExplorer) cacheTables(r *http.Request, tableName string) []string {
+	tables := []string{tableName}
+	if r.URL.Query().Get("include") == "" {
+		return tables
+	}
+	for _, key := range d.foreignKeys {
+		if key.table == tableName && !containsString(tables, key.refTable) {
+			tables = append(tables, key.refTable)
+		}
+	}
+	return tables
+}
+
+// cached отдаёт GET списка или записи table из кэша, а промах выполняет handler и запоминает ответ 200.
+// Ключ учитывает путь с query-строкой, формат ответа, маскирование и права запроса, поэтому разные
+// клиенты не получат чужое представление данных.
+func (d DbExplorer) cached(rw http.ResponseWriter, r *http.Request, tableName string, handler http.HandlerFunc) {
+	principal, cacheable := d.cachePrincipal(r)
+	if d.cache == nil || !cacheable {
+		handler(rw, r)
+		return
+	}
+
+	key := tableName + "\x00" + r.URL.RequestURI() + "\x00" + responseEncoding(rw) + "\x00" + strconv.FormatBool(d.masked) +
+		"\x00" + strconv.FormatBool(jsonAPIResponse(rw)) + "\x00" + principal
+	now := time.Now()
+	if entry, ok := d.cache.get(key, now); ok {
+		rw.Header().Set("X-Cache", "HIT")
+		if entry.etag != "" {
+			rw.Header().Set("ETag", entry.etag)
+			if match := r.Header.Get("If-None-Match"); match != "" && etagMatches(match, entry.etag, true) {
+				rw.WriteHeader(http.StatusNotModified)
+				return
+			}
+		}
+		for name, values := range entry.header {
+			rw.Header()[name] = values
+		}
+		if _, err := rw.Write(entry.body); err != nil {
+			logResponseError(rw, err)
+		}
+		return
+	}
+
+	tables := d.cacheTables(r, tableName)
+	generation := d.cache.generation(tables...)
+	rw.Header().Set("X-Cache", "MISS")
+	capture := &cacheWriter{ResponseWriter: rw}
+	// оборванный посреди потока ответ (см. abortStream) сюда не возвращается и в кэш не попадает
+	handler(capture, r)
+	if capture.status != http.StatusOK || capture.tooLarge || capture.failed {
+		return
+	}
+	header := http.Header{}
+	for _, name := range cachedHeaders {
+		if values := rw.Header().Values(name); len(values) > 0 {
+			header[name] = append([]string{}, values...)
+		}
+	}
+	d.cache.put(&cacheEntry{
+		key:    key,
+		tables: tables,
+		header: header,
+		etag:   rw.Header().Get("ETag"),
+		body:   capture.body.Bytes(),
+	}, generation, now)
+}
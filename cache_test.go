@@ -0,0 +1,108 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestResponseCache(t *testing.T) {
+	now := time.Now()
+	cache := newResponseCache(time.Minute, 2)
+
+	cache.put(&cacheEntry{key: "a", tables: []string{"items"}, body: []byte("a")}, cache.generation("items"), now)
+	if entry, ok := cache.get("a", now); !ok || string(entry.body) != "a" {
+		t.Fatalf("expected cached entry, got %v %v", entry, ok)
+	}
+	if _, ok := cache.get("a", now.Add(2*time.Minute)); ok {
+		t.Fatalf("expected entry to expire")
+	}
+
+	// ответ, прочитанный до записи в таблицу, сохраняться не должен
+	generation := cache.generation("items")
+	cache.invalidate("items")
+	cache.put(&cacheEntry{key: "b", tables: []string{"items"}}, generation, now)
+	if _, ok := cache.get("b", now); ok {
+		t.Fatalf("expected stale entry to be dropped")
+	}
+	if _, ok := cache.get("a", now); ok {
+		t.Fatalf("expected invalidate to drop table entries")
+	}
+
+	cache.put(&cacheEntry{key: "u1", tables: []string{"users"}}, cache.generation("users"), now)
+	cache.put(&cacheEntry{key: "u2", tables: []string{"users"}}, cache.generation("users"), now)
+	cache.put(&cacheEntry{key: "u3", tables: []string{"users"}}, cache.generation("users"), now)
+	if _, ok := cache.get("u1", now); ok {
+		t.Fatalf("expected oldest entry to be evicted")
+	}
+	if _, ok := cache.get("u3", now); !ok {
+		t.Fatalf("expected newest entry to be cached")
+	}
+}
+
+func TestResponseCacheIncluded(t *testing.T) {
+	now := time.Now()
+	cache := newResponseCache(time.Minute, 10)
+	explorer := DbExplorer{
+		cache:       cache,
+		foreignKeys: []foreignKey{{table: "orders", column: "user_id", refTable: "users", refColumn: "id"}},
+	}
+
+	plain := explorer.cacheTables(httptest.NewRequest(http.MethodGet, "/orders", nil), "orders")
+	included := explorer.cacheTables(httptest.NewRequest(http.MethodGet, "/orders?include=user", nil), "orders")
+	if len(plain) != 1 || len(included) != 2 || included[1] != "users" {
+		t.Fatalf("unexpected cache tables %v %v", plain, included)
+	}
+
+	cache.put(&cacheEntry{key: "plain", tables: plain}, cache.generation(plain...), now)
+	cache.put(&cacheEntry{key: "included", tables: included}, cache.generation(included...), now)
+	// запись в users сбрасывает только ответы, в которые попали пользователи
+	cache.invalidate("users")
+	if _, ok := cache.get("included", now); ok {
+		t.Fatalf("expected entry with included users to be dropped")
+	}
+	if _, ok := cache.get("plain", now); !ok {
+		t.Fatalf("expected entry without included users to stay cached")
+	}
+}
+
+type allowAll struct{}
+
+func (allowAll) Authorize(r *http.Request, tableName, method string) error { return nil }
+
+func TestCachePrincipal(t *testing.T) {
+	request := func(claims *jwtClaims) *http.Request {
+		r := httptest.NewRequest(http.MethodGet, "/users", nil)
+		if claims != nil {
+			r = withClaims(r, claims)
+		}
+		return r
+	}
+	reader := &jwtClaims{Role: "reader", Tables: tablePermissions{"users": "ro"}}
+	admin := &jwtClaims{Role: "admin"}
+
+	explorer := DbExplorer{}
+	anonymous, _ := explorer.cachePrincipal(request(nil))
+	first, _ := explorer.cachePrincipal(request(reader))
+	second, _ := explorer.cachePrincipal(request(admin))
+	if anonymous == first || first == second {
+		t.Fatalf("expected distinct principals, got %q %q %q", anonymous, first, second)
+	}
+	if again, _ := explorer.cachePrincipal(request(reader)); again != first {
+		t.Fatalf("expected same principal for same claims, got %q %q", first, again)
+	}
+
+	explorer.authorizer = ACL{RoleOf: func(r *http.Request) string { return r.Header.Get("X-Role") }}
+	r := request(nil)
+	r.Header.Set("X-Role", "admin")
+	if principal, ok := explorer.cachePrincipal(r); !ok || principal == anonymous {
+		t.Fatalf("expected ACL role in principal, got %q %v", principal, ok)
+	}
+
+	// решения стороннего Authorizer'а кэшу неизвестны
+	explorer.authorizer = allowAll{}
+	if _, ok := explorer.cachePrincipal(request(reader)); ok {
+		t.Fatalf("expected custom authorizer to bypass cache")
+	}
+}
@@ -0,0 +1,296 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net"
+	"reflect"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/go-mysql-org/go-mysql/mysql"
+	"github.com/go-mysql-org/go-mysql/replication"
+)
+
+// cdcDedupWindow — сколько помнить изменения explorer'а, чтобы не публиковать их второй раз из binlog
+const cdcDedupWindow = 10 * time.Second
+
+// BinlogConfig — подключение к MySQL в роли реплики для ListenBinlog.
+// Серверу нужны binlog_format=ROW, а пользователю — права REPLICATION SLAVE и REPLICATION CLIENT.
+type BinlogConfig struct {
+	Addr     string // host:port
+	User     string
+	Password string
+	// ServerID должен отличаться от server_id самого сервера и других его реплик
+	ServerID uint32
+}
+
+// recentMutations помнит недавние изменения, прошедшие через explorer, пока работает ListenBinlog
+type recentMutations struct {
+	mu        sync.Mutex
+	listeners int
+	seen      map[string]time.Time
+}
+
+func mutationKey(event MutationEvent, rawId string) string {
+	return event.Type + "/" + event.Table + "/" + rawId
+}
+
+func (m *recentMutations) enable() {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.listeners == 0 {
+		m.seen = make(map[string]time.Time)
+	}
+	m.listeners++
+}
+
+func (m *recentMutations) disable() {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.listeners--
+	if m.listeners == 0 {
+		m.seen = nil
+	}
+}
+
+func (m *recentMutations) remember(key string, now time.Time) {
+	if m == nil {
+		return
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.listeners == 0 {
+		return
+	}
+
+	for seenKey, seenAt := range m.seen {
+		if now.Sub(seenAt) > cdcDedupWindow {
+			delete(m.seen, seenKey)
+		}
+	}
+	m.seen[key] = now
+}
+
+// forget возвращает true и забывает ключ, если такое изменение недавно сделал explorer
+func (m *recentMutations) forget(key string, now time.Time) bool {
+	if m == nil {
+		return false
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	seenAt, ok := m.seen[key]
+	if ok {
+		delete(m.seen, key)
+	}
+	return ok && now.Sub(seenAt) <= cdcDedupWindow
+}
+
+// ListenBinlog читает binlog MySQL начиная с текущей позиции и публикует изменения таблиц explorer'а
+// в тот же поток событий, что и запись через API (с "source": "binlog"), сбрасывая их ответы в кэше.
+// Изменения, которые только что записал сам explorer, повторно не публикуются; массовые UPDATE
+// по фильтру приходят из binlog отдельными событиями по записям. Блокируется до отмены ctx.
+func (d DbExplorer) ListenBinlog(ctx context.Context, config BinlogConfig) error {
+	if d.dialect != MySQL {
+		return errors.New("binlog is supported only for mysql")
+	}
+	ctx, stop, err := d.lifecycle.startWorker(ctx)
+	if err != nil {
+		return err
+	}
+	defer stop()
+
+	host, rawPort, err := net.SplitHostPort(config.Addr)
+	if err != nil {
+		return err
+	}
+	port, err := strconv.ParseUint(rawPort, 10, 16)
+	if err != nil {
+		return err
+	}
+
+	var database string
+	if err := d.db.QueryRowContext(ctx, "SELECT DATABASE();").Scan(&database); err != nil {
+		return err
+	}
+	position, err := binlogPosition(ctx, d.db)
+	if err != nil {
+		return err
+	}
+
+	syncer := replication.NewBinlogSyncer(replication.BinlogSyncerConfig{
+		ServerID: config.ServerID,
+		Flavor:   mysql.MySQLFlavor,
+		Host:     host,
+		Port:     uint16(port),
+		User:     config.User,
+		Password: config.Password,
+	})
+	defer syncer.Close()
+
+	streamer, err := syncer.StartSync(position)
+	if err != nil {
+		return err
+	}
+
+	d.recent.enable()
+	defer d.recent.disable()
+
+	for {
+		event, err := streamer.GetEvent(ctx)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		rows, ok := event.Event.(*replication.RowsEvent)
+		if !ok || rows.Table == nil || string(rows.Table.Schema) != database {
+			continue
+		}
+		// схема могла обновиться после миграции, колонки событий сопоставляются с актуальной
+		d = d.withSchema()
+		for _, mutation := range d.binlogMutations(event.Header.EventType, rows) {
+			d.publishExternal(mutation)
+		}
+	}
+}
+
+// binlogPosition возвращает текущую позицию binlog; в MySQL 8.4 SHOW MASTER STATUS переименован
+func binlogPosition(ctx context.Context, db *sql.DB) (mysql.Position, error) {
+	for _, query := range []string{"SHOW BINARY LOG STATUS;", "SHOW MASTER STATUS;"} {
+		rows, err := db.QueryContext(ctx, query)
+		if err != nil {
+			continue
+		}
+
+		columns, _ := rows.Columns()
+		values := make([]interface{}, len(columns))
+		pointers := make([]interface{}, len(columns))
+		for i := range values {
+			pointers[i] = &values[i]
+		}
+
+		if !rows.Next() {
+			rows.Close()
+			return mysql.Position{}, errors.New("binary logging is disabled")
+		}
+		err = rows.Scan(pointers...)
+		rows.Close()
+		if err != nil {
+			return mysql.Position{}, err
+		}
+
+		pos, err := strconv.ParseUint(fmt.Sprintf("%s", values[1]), 10, 32)
+		if err != nil {
+			return mysql.Position{}, err
+		}
+		return mysql.Position{Name: fmt.Sprintf("%s", values[0]), Pos: uint32(pos)}, nil
+	}
+	return mysql.Position{}, errors.New("cannot read binlog position")
+}
+
+// binlogMutations превращает строки события binlog в события explorer'а. Колонки сопоставляются
+// по порядку из схемы таблицы, поэтому после ALTER TABLE explorer нужно пересоздать.
+func (d DbExplorer) binlogMutations(eventType replication.EventType, rows *replication.RowsEvent) []MutationEvent {
+	tableName := string(rows.Table.Table)
+	if !containsString(d.tableKeys, tableName) {
+		return nil
+	}
+	columns := d.columnKeysMap[tableName]
+
+	mutationType := ""
+	step := 1
+	switch eventType {
+	case replication.WRITE_ROWS_EVENTv0, replication.WRITE_ROWS_EVENTv1, replication.WRITE_ROWS_EVENTv2:
+		mutationType = "insert"
+	case replication.UPDATE_ROWS_EVENTv0, replication.UPDATE_ROWS_EVENTv1, replication.UPDATE_ROWS_EVENTv2:
+		// строки UPDATE идут парами: значения до и после изменения
+		mutationType, step = "update", 2
+	case replication.DELETE_ROWS_EVENTv0, replication.DELETE_ROWS_EVENTv1, replication.DELETE_ROWS_EVENTv2:
+		mutationType = "delete"
+	default:
+		return nil
+	}
+
+	mutations := make([]MutationEvent, 0, len(rows.Rows)/step)
+	for i := 0; i+step <= len(rows.Rows); i += step {
+		row := rows.Rows[i+step-1]
+		if len(row) != len(columns) {
+			d.logError("binlog row does not match table schema", errors.New("table "+tableName))
+			continue
+		}
+
+		event := MutationEvent{Type: mutationType, Table: tableName, ID: map[string]interface{}{}, Source: "binlog"}
+		for _, key := range d.tableIdNamesMap[tableName] {
+			if index := indexOf(columns, key); index >= 0 {
+				event.ID[key] = binlogValue(row[index])
+			}
+		}
+
+		switch mutationType {
+		case "insert":
+			event.Fields = append([]string{}, columns...)
+		case "update":
+			for j, column := range columns {
+				if !reflect.DeepEqual(rows.Rows[i][j], row[j]) {
+					event.Fields = append(event.Fields, column)
+				}
+			}
+		}
+		sort.Strings(event.Fields)
+		if len(event.ID) == 0 {
+			event.ID = nil
+		}
+		mutations = append(mutations, event)
+	}
+	return mutations
+}
+
+// binlogValue приводит целые из binlog (int8…uint64) к int, как в событиях explorer'а
+func binlogValue(value interface{}) interface{} {
+	switch reflect.ValueOf(value).Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return int(reflect.ValueOf(value).Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return int(reflect.ValueOf(value).Uint())
+	}
+	if data, ok := value.([]byte); ok {
+		return string(data)
+	}
+	return value
+}
+
+// publishExternal публикует изменение, сделанное в обход explorer'а
+func (d DbExplorer) publishExternal(event MutationEvent) {
+	if event.ID != nil && d.recent.forget(mutationKey(event, d.rawId(event.Table, event.ID)), time.Now()) {
+		return
+	}
+	if d.cache != nil {
+		d.cache.invalidate(event.Table)
+	}
+	if d.events != nil {
+		d.events.publish(event)
+	}
+}
+
+func indexOf(values []string, value string) int {
+	for i, item := range values {
+		if item == value {
+			return i
+		}
+	}
+	return -1
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/go-mysql-org/go-mysql/replication"
+)
+
+func TestBinlogMutations(t *testing.T) {
+	explorer := DbExplorer{
+		dialect:         MySQL,
+		tableKeys:       []string{"items"},
+		columnKeysMap:   map[string][]string{"items": {"id", "title", "updated"}},
+		tableIdNamesMap: map[string][]string{"items": {"id"}},
+		events:          newMutationBroker(),
+		recent:          &recentMutations{},
+	}
+	table := &replication.TableMapEvent{Schema: []byte("golang"), Table: []byte("items")}
+
+	cases := []struct {
+		eventType replication.EventType
+		rows      [][]interface{}
+		expected  []MutationEvent
+	}{
+		{
+			eventType: replication.WRITE_ROWS_EVENTv2,
+			rows:      [][]interface{}{{int32(3), "new", nil}},
+			expected:  []MutationEvent{{Type: "insert", Table: "items", ID: map[string]interface{}{"id": 3}, Fields: []string{"id", "title", "updated"}, Source: "binlog"}},
+		},
+		{
+			eventType: replication.UPDATE_ROWS_EVENTv2,
+			rows:      [][]interface{}{{int32(1), "old", nil}, {int32(1), "new", nil}, {int32(2), "a", nil}, {int32(2), "a", "x"}},
+			expected: []MutationEvent{
+				{Type: "update", Table: "items", ID: map[string]interface{}{"id": 1}, Fields: []string{"title"}, Source: "binlog"},
+				{Type: "update", Table: "items", ID: map[string]interface{}{"id": 2}, Fields: []string{"updated"}, Source: "binlog"},
+			},
+		},
+		{
+			eventType: replication.DELETE_ROWS_EVENTv1,
+			rows:      [][]interface{}{{int64(2), "a", "x"}},
+			expected:  []MutationEvent{{Type: "delete", Table: "items", ID: map[string]interface{}{"id": 2}, Source: "binlog"}},
+		},
+	}
+
+	for idx, item := range cases {
+		mutations := explorer.binlogMutations(item.eventType, &replication.RowsEvent{Table: table, Rows: item.rows})
+		if !reflect.DeepEqual(mutations, item.expected) {
+			t.Fatalf("[case %d] unexpected mutations\nGot : %#v\nWant: %#v", idx, mutations, item.expected)
+		}
+	}
+
+	unknown := &replication.TableMapEvent{Schema: []byte("golang"), Table: []byte("audit_log")}
+	if mutations := explorer.binlogMutations(replication.WRITE_ROWS_EVENTv2, &replication.RowsEvent{Table: unknown, Rows: [][]interface{}{{1}}}); len(mutations) != 0 {
+		t.Fatalf("expected rows of unknown tables to be skipped, got %v", mutations)
+	}
+
+	// изменение, которое только что записал сам explorer, из binlog повторно не публикуется
+	explorer.recent.enable()
+	events, unsubscribe := explorer.events.subscribe()
+	defer unsubscribe()
+
+	explorer.emitUpdate("items", "1", map[string]interface{}{"title": "new"})
+	explorer.publishExternal(cases[1].expected[0])
+	explorer.publishExternal(cases[1].expected[1])
+
+	for _, want := range []string{"", "binlog"} {
+		select {
+		case frame := <-events:
+			if frame.event.Source != want {
+				t.Fatalf("expected event with source %q, got %#v", want, frame.event)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("timeout waiting for event")
+		}
+	}
+	select {
+	case frame := <-events:
+		t.Fatalf("unexpected duplicate event %#v", frame.event)
+	default:
+	}
+}
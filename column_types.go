@@ -0,0 +1,282 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var decimalPattern = regexp.MustCompile(`^[+-]?[0-9]+(\.[0-9]+)?([eE][+-]?[0-9]+)?$`)
+
+// writable сообщает, умеет ли explorer принимать значения колонки в PUT/POST;
+// вычисляемые базой колонки не пишутся никогда
+func (c columnParams) writable() bool {
+	if c.computed {
+		return false
+	}
+	switch c.typeName {
+	case "string", "int", "float", "decimal", "bool", "enum", "set", "json", "binary":
+		return true
+	}
+	return false
+}
+
+// decodeValue приводит значение из драйвера к виду, в котором оно уйдёт в ответ
+func (d DbExplorer) decodeValue(column columnParams, columnType *sql.ColumnType, value interface{}) interface{} {
+	typeName := column.typeName
+	if typeName == "" {
+		// без метаданных колонки (служебные запросы) бинарные данные в base64 не кодируем
+		typeName = normalizeColumnType(strings.TrimPrefix(columnType.DatabaseTypeName(), "UNSIGNED "))
+		if typeName == "binary" {
+			typeName = "string"
+		}
+	}
+
+	if typeName == "bool" {
+		return decodeBool(value)
+	}
+
+	bytes, ok := value.([]byte)
+	if !ok {
+		if stringValue, ok := value.(string); ok && typeName == "json" && json.Valid([]byte(stringValue)) {
+			return json.RawMessage(stringValue)
+		}
+		if typeName == "decimal" && d.decimalsAsStrings {
+			if floatValue, ok := value.(float64); ok {
+				return strconv.FormatFloat(floatValue, 'f', -1, 64)
+			}
+		}
+		return value
+	}
+
+	stringValue := string(bytes)
+	switch typeName {
+	case "binary":
+		return base64.StdEncoding.EncodeToString(bytes)
+	case "json":
+		// JSON-колонки встраиваем в ответ как есть, а не экранированной строкой
+		if json.Valid(bytes) {
+			return json.RawMessage(stringValue)
+		}
+	case "int":
+		intValue, _ := strconv.Atoi(stringValue)
+		return intValue
+	case "float":
+		if floatValue, err := strconv.ParseFloat(stringValue, 64); err == nil {
+			return floatValue
+		}
+	case "decimal":
+		if d.decimalsAsStrings {
+			return stringValue
+		}
+		// json.Number сериализуется как есть, без округления через float64
+		return json.Number(stringValue)
+	}
+
+	return stringValue
+}
+
+// encodeValue проверяет значение из тела запроса и приводит его к аргументу SQL-запроса
+func (d DbExplorer) encodeValue(column columnParams, data interface{}) (interface{}, error) {
+	invalidType := invalidTypeError(column.name)
+
+	// null явно очищает nullable-колонку любого типа; отсутствующее поле сюда не попадает и не меняется
+	if data == nil {
+		if !column.isNull {
+			return nil, invalidType
+		}
+		return nil, nil
+	}
+
+	switch column.typeName {
+	case "int":
+		number, ok := data.(json.Number)
+		if !ok {
+			return nil, invalidType
+		}
+		val, err := number.Int64()
+		if err != nil {
+			return nil, invalidType
+		}
+		return int(val), nil
+
+	case "float":
+		number, ok := data.(json.Number)
+		if !ok {
+			return nil, invalidType
+		}
+		val, err := number.Float64()
+		if err != nil {
+			return nil, invalidType
+		}
+		return val, nil
+
+	case "decimal":
+		// decimal принимаем и числом, и строкой, в базу передаём строкой без потери точности
+		var val string
+		switch typed := data.(type) {
+		case json.Number:
+			val = typed.String()
+		case string:
+			val = typed
+		default:
+			return nil, invalidType
+		}
+		if !decimalPattern.MatchString(val) {
+			return nil, invalidType
+		}
+		return val, nil
+
+	case "binary":
+		encoded, ok := data.(string)
+		if !ok {
+			return nil, invalidType
+		}
+		val, err := base64.StdEncoding.DecodeString(encoded)
+		if err != nil {
+			return nil, invalidType
+		}
+		if d.maxBlobSize > 0 && len(val) > d.maxBlobSize {
+			return nil, errors.New("field " + column.name + " exceeds " + strconv.Itoa(d.maxBlobSize) + " bytes")
+		}
+		return val, nil
+
+	case "json":
+		val, err := json.Marshal(data)
+		if err != nil {
+			return nil, invalidType
+		}
+		return string(val), nil
+
+	case "bool":
+		val, ok := data.(bool)
+		if !ok {
+			return nil, invalidType
+		}
+		return val, nil
+
+	case "string":
+		val, ok := data.(string)
+		if !ok {
+			return nil, invalidType
+		}
+		return val, nil
+
+	case "enum":
+		val, ok := data.(string)
+		if !ok {
+			return nil, invalidType
+		}
+		if !containsString(column.enumValues, val) {
+			return nil, column.enumError()
+		}
+		return val, nil
+
+	case "set":
+		// SET принимаем строкой "a,b" или массивом строк
+		var values []string
+		switch typed := data.(type) {
+		case string:
+			if typed != "" {
+				values = strings.Split(typed, ",")
+			}
+		case []interface{}:
+			for _, item := range typed {
+				val, ok := item.(string)
+				if !ok {
+					return nil, invalidType
+				}
+				values = append(values, val)
+			}
+		default:
+			return nil, invalidType
+		}
+		for _, val := range values {
+			if !containsString(column.enumValues, val) {
+				return nil, column.enumError()
+			}
+		}
+		return strings.Join(values, ","), nil
+	}
+
+	return nil, invalidType
+}
+
+func (c columnParams) enumError() error {
+	return errors.New("field " + c.name + " must be one of: " + strings.Join(c.enumValues, ", "))
+}
+
+// parseEnumValues достаёт допустимые значения из типа вида enum('a','b”c')
+func parseEnumValues(rawType string) []string {
+	start := strings.Index(rawType, "(")
+	end := strings.LastIndex(rawType, ")")
+	if start == -1 || end <= start {
+		return nil
+	}
+
+	values := make([]string, 0)
+	current := strings.Builder{}
+	inQuotes := false
+	list := rawType[start+1 : end]
+	for i := 0; i < len(list); i++ {
+		switch ch := list[i]; {
+		case ch == '\'' && inQuotes && i+1 < len(list) && list[i+1] == '\'':
+			current.WriteByte('\'')
+			i++
+		case ch == '\'':
+			if inQuotes {
+				values = append(values, current.String())
+				current.Reset()
+			}
+			inQuotes = !inQuotes
+		case inQuotes:
+			current.WriteByte(ch)
+		}
+	}
+
+	return values
+}
+
+func containsString(values []string, value string) bool {
+	for _, item := range values {
+		if item == value {
+			return true
+		}
+	}
+	return false
+}
+
+func removeString(values []string, value string) []string {
+	result := make([]string, 0, len(values))
+	for _, item := range values {
+		if item != value {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+// decodeBool разбирает boolean и tinyint(1): драйверы отдают их как bool, int64 или []byte
+func decodeBool(value interface{}) interface{} {
+	switch typed := value.(type) {
+	case bool:
+		return typed
+	case int64:
+		return typed != 0
+	case []byte:
+		stringValue := string(typed)
+		if boolValue, err := strconv.ParseBool(stringValue); err == nil {
+			return boolValue
+		}
+		intValue, err := strconv.Atoi(stringValue)
+		if err == nil {
+			return intValue != 0
+		}
+		return stringValue
+	}
+	return value
+}
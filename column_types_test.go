@@ -0,0 +1,62 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestParseEnumValues(t *testing.T) {
+	values := parseEnumValues("enum('new','in progress','it''s done')")
+	expected := []string{"new", "in progress", "it's done"}
+	if !reflect.DeepEqual(values, expected) {
+		t.Fatalf("expected %#v, got %#v", expected, values)
+	}
+}
+
+func TestEncodeNull(t *testing.T) {
+	cases := []struct {
+		column columnParams
+		valid  bool
+	}{
+		{columnParams{name: "quantity", typeName: "int", isNull: true}, true},
+		{columnParams{name: "id", typeName: "int", primary: true}, false},
+		{columnParams{name: "title", typeName: "string"}, false},
+		{columnParams{name: "updated", typeName: "string", isNull: true}, true},
+	}
+	for _, item := range cases {
+		val, err := DbExplorer{}.encodeValue(item.column, nil)
+		if item.valid && (err != nil || val != nil) {
+			t.Fatalf("[%s] expected null, got %v, %v", item.column.name, val, err)
+		}
+		if !item.valid && err == nil {
+			t.Fatalf("[%s] expected error for null", item.column.name)
+		}
+	}
+}
+
+func TestComputedColumnsNotWritten(t *testing.T) {
+	explorer := DbExplorer{
+		dialect: MySQL,
+		columnsInTablesMap: map[string]map[string]columnParams{
+			"orders": {
+				"id":         {name: "id", typeName: "int", primary: true},
+				"price":      {name: "price", typeName: "int"},
+				"total":      {name: "total", typeName: "int", computed: true},
+				"changed_at": {name: "changed_at", typeName: "string", computed: true},
+			},
+		},
+		tableIdNamesMap: map[string][]string{"orders": {"id"}},
+	}
+
+	record, err := explorer.validateRecord("orders", map[string]interface{}{"price": json.Number("5"), "total": json.Number("50")})
+	if err != nil || !reflect.DeepEqual(record, map[string]interface{}{"price": 5}) {
+		t.Fatalf("expected computed column to be dropped, got %#v, %v", record, err)
+	}
+
+	args := &queryArgs{dialect: MySQL}
+	set, err := explorer.setClause("orders", map[string]interface{}{"price": 5, "changed_at": "2024-01-01 00:00:00"}, args)
+	if err != nil || set != "`price` = ?" || !reflect.DeepEqual(args.values, []interface{}{5}) {
+		t.Fatalf("unexpected set clause %q %#v, %v", set, args.values, err)
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"compress/flate"
+	"compress/gzip"
+	"io"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const defaultCompressionMinSize = 1024
+
+// compressWriter копит начало ответа и включает сжатие, только если тело набрало minSize байт
+type compressWriter struct {
+	http.ResponseWriter
+	encoding   string
+	minSize    int
+	buffer     []byte
+	status     int
+	decided    bool
+	compressor io.WriteCloser
+}
+
+// compress оборачивает ответ сжатием по Accept-Encoding; возвращённую функцию нужно вызвать по окончании ответа
+func (d DbExplorer) compress(rw http.ResponseWriter, r *http.Request) (http.ResponseWriter, func()) {
+	if d.compressionMinSize < 0 || r.Method == http.MethodHead {
+		return rw, func() {}
+	}
+
+	encoding := acceptedEncoding(r.Header.Get("Accept-Encoding"))
+	if encoding == "" {
+		return rw, func() {}
+	}
+
+	rw.Header().Add("Vary", "Accept-Encoding")
+	writer := &compressWriter{ResponseWriter: rw, encoding: encoding, minSize: d.compressionMinSize}
+	return writer, writer.close
+}
+
+// acceptedEncoding выбирает gzip или deflate с наибольшим q
+func acceptedEncoding(header string) string {
+	best, bestQuality := "", 0.0
+	for _, part := range strings.Split(header, ",") {
+		coding, params, err := mime.ParseMediaType("x/" + strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		coding = strings.TrimPrefix(coding, "x/")
+
+		quality := 1.0
+		if q, ok := params["q"]; ok {
+			if quality, err = strconv.ParseFloat(q, 64); err != nil {
+				continue
+			}
+		}
+		if (coding == "gzip" || coding == "deflate") && quality > bestQuality {
+			best, bestQuality = coding, quality
+		}
+	}
+	return best
+}
+
+func (w *compressWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *compressWriter) WriteHeader(status int) {
+	if w.decided || w.status != 0 {
+		return
+	}
+	w.status = status
+	if status == http.StatusNoContent || status == http.StatusNotModified || status < 200 {
+		w.start(false)
+	}
+}
+
+func (w *compressWriter) Write(data []byte) (int, error) {
+	if !w.decided {
+		w.buffer = append(w.buffer, data...)
+		if len(w.buffer) >= w.minSize {
+			if err := w.start(true); err != nil {
+				return 0, err
+			}
+		}
+		return len(data), nil
+	}
+
+	if w.compressor != nil {
+		return w.compressor.Write(data)
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+// Flush отправляет накопленное сразу: потоковые ответы сжимаются независимо от размера
+func (w *compressWriter) Flush() {
+	if !w.decided {
+		w.start(true)
+	}
+	if flusher, ok := w.compressor.(interface{ Flush() error }); ok {
+		flusher.Flush()
+	}
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (w *compressWriter) start(compress bool) error {
+	w.decided = true
+	header := w.Header()
+	if header.Get("Content-Type") == "" && len(w.buffer) > 0 {
+		header.Set("Content-Type", http.DetectContentType(w.buffer))
+	}
+	// SSE не сжимаем, чтобы события доходили без задержек на границах блоков, а gzip-файл уже сжат
+	contentType := header.Get("Content-Type")
+	if strings.HasPrefix(contentType, "text/event-stream") || contentType == "application/gzip" || header.Get("Content-Encoding") != "" {
+		compress = false
+	}
+
+	if compress {
+		header.Del("Content-Length")
+		header.Set("Content-Encoding", w.encoding)
+		if w.encoding == "gzip" {
+			w.compressor = gzip.NewWriter(w.ResponseWriter)
+		} else {
+			w.compressor, _ = flate.NewWriter(w.ResponseWriter, flate.DefaultCompression)
+		}
+	}
+
+	if w.status != 0 {
+		w.ResponseWriter.WriteHeader(w.status)
+	}
+
+	buffer := w.buffer
+	w.buffer = nil
+	if len(buffer) == 0 {
+		return nil
+	}
+	_, err := w.Write(buffer)
+	return err
+}
+
+func (w *compressWriter) close() {
+	if !w.decided {
+		w.start(false)
+	}
+	if w.compressor != nil {
+		w.compressor.Close()
+	}
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestAcceptedEncoding(t *testing.T) {
+	cases := map[string]string{
+		"":                        "",
+		"gzip":                    "gzip",
+		"deflate, gzip;q=0.5":     "deflate",
+		"br, gzip;q=0":            "",
+		"identity, deflate;q=0.8": "deflate",
+	}
+	for header, expected := range cases {
+		if got := acceptedEncoding(header); got != expected {
+			t.Fatalf("[%q] expected %q, got %q", header, expected, got)
+		}
+	}
+}
+
+func TestCompressThreshold(t *testing.T) {
+	explorer := DbExplorer{compressionMinSize: 64}
+	large := strings.Repeat("a", 100)
+
+	cases := []struct {
+		body     string
+		encoding string
+	}{
+		{"small", ""},
+		{large, "gzip"},
+	}
+
+	for _, item := range cases {
+		rw := httptest.NewRecorder()
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("Accept-Encoding", "gzip")
+
+		writer, finish := explorer.compress(rw, r)
+		writer.Header().Set("Content-Type", "text/plain")
+		io.WriteString(writer, item.body)
+		finish()
+
+		if encoding := rw.Header().Get("Content-Encoding"); encoding != item.encoding {
+			t.Fatalf("[%d bytes] expected Content-Encoding %q, got %q", len(item.body), item.encoding, encoding)
+		}
+
+		body := rw.Body.String()
+		if item.encoding == "gzip" {
+			reader, err := gzip.NewReader(rw.Body)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			raw, _ := io.ReadAll(reader)
+			body = string(raw)
+		}
+		if body != item.body {
+			t.Fatalf("[%d bytes] unexpected body %q", len(item.body), body)
+		}
+	}
+}
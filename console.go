@@ -0,0 +1,362 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	consoleDefaultMaxRows = 1000
+	consoleDefaultTimeout = 5 * time.Second
+)
+
+// consoleForbiddenWords — слова, которых не бывает в чтении: запись, DDL, блокировки, выгрузка в файл
+var consoleForbiddenWords = map[string]bool{
+	"INSERT": true, "UPDATE": true, "DELETE": true, "REPLACE": true, "MERGE": true, "UPSERT": true,
+	"CREATE": true, "ALTER": true, "DROP": true, "TRUNCATE": true, "RENAME": true,
+	"GRANT": true, "REVOKE": true, "LOCK": true, "UNLOCK": true, "SET": true,
+	"INTO": true, "OUTFILE": true, "DUMPFILE": true, "LOAD": true,
+	"CALL": true, "EXEC": true, "EXECUTE": true, "HANDLER": true, "ATTACH": true, "PRAGMA": true,
+}
+
+// consoleForbiddenFunctions — функции, которые читают файлы сервера, выполняют SQL из строки, ждут
+// или берут блокировки. Проверяются и имена в кавычках: PostgreSQL вызовет и "pg_read_file"(...).
+var consoleForbiddenFunctions = map[string]bool{
+	"LOAD_FILE": true, "SLEEP": true, "BENCHMARK": true, "GET_LOCK": true, "WAITFOR": true,
+	"PG_SLEEP": true, "PG_SLEEP_FOR": true, "PG_SLEEP_UNTIL": true, "PG_READ_FILE": true, "PG_READ_BINARY_FILE": true,
+	"PG_LS_DIR": true, "PG_STAT_FILE": true, "LO_IMPORT": true, "LO_EXPORT": true, "LO_GET": true,
+	"DBLINK": true, "SET_CONFIG": true, "QUERY_TO_XML": true, "QUERY_TO_XML_AND_XMLSCHEMA": true,
+	"PG_ADVISORY_LOCK": true, "PG_ADVISORY_XACT_LOCK": true,
+	"OPENROWSET": true, "OPENQUERY": true, "OPENDATASOURCE": true,
+	"LOAD_EXTENSION": true, "READFILE": true, "WRITEFILE": true,
+}
+
+// queryConsole — настройки POST /admin/query, см. WithQueryConsole
+type queryConsole struct {
+	maxRows int
+	timeout time.Duration
+}
+
+// consoleToken — слово запроса в верхнем регистре, имя в кавычках или знак . , ( )
+type consoleToken struct {
+	word   string
+	name   string // слово или имя в кавычках как в запросе
+	punct  byte
+	quoted bool
+}
+
+func (t consoleToken) identifier() bool {
+	return t.name != ""
+}
+
+// validateSelect пропускает только одиночный SELECT (или WITH ... SELECT) и возвращает его без
+// завершающей ";" вместе с таблицами, из которых он читает. Строки, идентификаторы в кавычках
+// и комментарии при проверке слов пропускаются.
+func validateSelect(dialect Dialect, query string) (string, []string, error) {
+	query = strings.TrimSpace(query)
+	query = strings.TrimSpace(strings.TrimSuffix(query, ";"))
+	if query == "" {
+		return "", nil, errors.New("query is required")
+	}
+	if strings.Contains(query, "/*!") {
+		// исполняемые комментарии MySQL выполнились бы как обычный SQL
+		return "", nil, errors.New("executable comments are not allowed")
+	}
+
+	tokens := make([]consoleToken, 0)
+	word := strings.Builder{}
+	flush := func() {
+		if word.Len() > 0 {
+			tokens = append(tokens, consoleToken{word: strings.ToUpper(word.String()), name: word.String()})
+			word.Reset()
+		}
+	}
+
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case c == '\'' || c == '"' || c == '`' || c == '[':
+			flush()
+			closing := c
+			if c == '[' {
+				closing = ']'
+			}
+			end := i + 1
+			for ; end < len(query) && query[end] != closing; end++ {
+				if query[end] == '\\' && c != '[' {
+					end++
+				}
+			}
+			if end >= len(query) {
+				return "", nil, errors.New("unterminated quoted string")
+			}
+			if c != '\'' && end > i+1 {
+				tokens = append(tokens, consoleToken{name: query[i+1 : end], quoted: true})
+			}
+			i = end
+		case c == '-' && i+1 < len(query) && query[i+1] == '-', c == '#' && dialect == MySQL:
+			flush()
+			for i < len(query) && query[i] != '\n' {
+				i++
+			}
+		case c == '/' && i+1 < len(query) && query[i+1] == '*':
+			flush()
+			end := strings.Index(query[i+2:], "*/")
+			if end < 0 {
+				return "", nil, errors.New("unterminated comment")
+			}
+			i += end + 3
+		case c == ';':
+			return "", nil, errors.New("only a single statement is allowed")
+		case c == '_' || c == '$' || c >= '0' && c <= '9' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z':
+			word.WriteByte(c)
+		default:
+			flush()
+			if strings.IndexByte(".,()", c) != -1 {
+				tokens = append(tokens, consoleToken{punct: c})
+			}
+		}
+	}
+	flush()
+
+	if len(tokens) == 0 || (tokens[0].word != "SELECT" && tokens[0].word != "WITH") {
+		return "", nil, errors.New("only SELECT statements are allowed")
+	}
+	for _, token := range tokens {
+		if consoleForbiddenWords[token.word] {
+			return "", nil, errors.New(token.word + " is not allowed in a read-only query")
+		}
+		if name := strings.ToUpper(token.name); consoleForbiddenFunctions[name] {
+			return "", nil, errors.New(name + " is not allowed in a read-only query")
+		}
+	}
+	return query, selectTables(tokens), nil
+}
+
+// consoleClauseEnd — слова, на которых кончается FROM: дальше запятые уже не разделяют таблицы
+var consoleClauseEnd = map[string]bool{
+	"WHERE": true, "GROUP": true, "HAVING": true, "ORDER": true, "LIMIT": true, "OFFSET": true, "FETCH": true,
+	"UNION": true, "EXCEPT": true, "INTERSECT": true, "WINDOW": true, "FOR": true, "SELECT": true,
+}
+
+// selectTables собирает имена, стоящие на месте таблиц: после FROM, JOIN, TABLE и запятых в FROM.
+// Схема через точку остаётся в имени, функция в FROM попадает в список под своим именем — такие имена
+// не пройдут проверку таблиц. Имена CTE из WITH в начале запроса пропускаются.
+func selectTables(tokens []consoleToken) []string {
+	startsSubquery := func(i int) bool {
+		return i < len(tokens) && (tokens[i].word == "SELECT" || tokens[i].word == "WITH" || tokens[i].word == "TABLE")
+	}
+
+	ctes := map[string]bool{}
+	for i, depth := 0, 0; tokens[0].word == "WITH" && i < len(tokens); i++ {
+		switch {
+		case tokens[i].punct == '(':
+			depth++
+		case tokens[i].punct == ')':
+			depth--
+		case depth == 0 && tokens[i].identifier():
+			// t AS (...) или t (x, y) AS (...)
+			next := i + 1
+			if next < len(tokens) && tokens[next].punct == '(' {
+				for next < len(tokens) && tokens[next].punct != ')' {
+					next++
+				}
+				next++
+			}
+			if next+1 < len(tokens) && tokens[next].word == "AS" && tokens[next+1].punct == '(' {
+				ctes[strings.ToUpper(tokens[i].name)] = true
+			}
+		}
+	}
+
+	type level struct {
+		query bool // скобки открывают подзапрос или группу таблиц, а не выражение
+		from  bool // идёт FROM этого уровня
+	}
+	levels := []level{{query: true}}
+	tables := make([]string, 0)
+	tablePosition := false
+	for i := 0; i < len(tokens); i++ {
+		token := tokens[i]
+		current := &levels[len(levels)-1]
+		switch {
+		case token.punct == '(':
+			// FROM (a JOIN b) — группа таблиц, FROM (SELECT ...) — подзапрос, остальное — выражение
+			group := tablePosition && !startsSubquery(i+1)
+			levels = append(levels, level{query: group || startsSubquery(i+1), from: group})
+			tablePosition = group
+			continue
+		case token.punct == ')':
+			if len(levels) > 1 {
+				levels = levels[:len(levels)-1]
+			}
+			tablePosition = false
+			continue
+		case !current.query:
+			continue
+		}
+
+		if tablePosition {
+			tablePosition = false
+			if token.word == "LATERAL" || token.word == "ONLY" {
+				tablePosition = true
+				continue
+			}
+			if token.identifier() {
+				name := token.name
+				for ; i+2 < len(tokens) && tokens[i+1].punct == '.' && tokens[i+2].identifier(); i += 2 {
+					name += "." + tokens[i+2].name
+				}
+				if !ctes[strings.ToUpper(name)] {
+					tables = append(tables, name)
+				}
+				continue
+			}
+		}
+
+		switch {
+		case token.word == "FROM":
+			current.from = true
+			tablePosition = true
+		case token.word == "JOIN" || token.word == "STRAIGHT_JOIN" || token.word == "APPLY" || token.word == "TABLE":
+			tablePosition = true
+		case token.punct == ',' && current.from:
+			tablePosition = true
+		case consoleClauseEnd[token.word]:
+			current.from = false
+		}
+	}
+	return tables
+}
+
+// consoleTable возвращает таблицу API, на которую ссылается имя из запроса консоли
+func (d DbExplorer) consoleTable(name string) (string, bool) {
+	for _, tableName := range d.tableKeys {
+		if strings.EqualFold(tableName, name) {
+			return tableName, true
+		}
+	}
+	if d.dialect == MySQL && strings.EqualFold(name, "dual") {
+		return "", true
+	}
+	return "", false
+}
+
+// handlerQueryConsole выполняет POST /admin/query {"query": "SELECT ...", "limit": 100}: запрос
+// оборачивается в подзапрос с LIMIT и выполняется в read-only транзакции с таймаутом.
+func (d DbExplorer) handlerQueryConsole(rw http.ResponseWriter, r *http.Request) {
+	if d.console == nil {
+		responseResult(rw, errors.New("query console is disabled"), http.StatusNotFound, nil)
+		return
+	}
+	if r.Method != http.MethodPost {
+		rw.Header().Set("Allow", "POST, OPTIONS")
+		responseResult(rw, errMethodNotAllowed, http.StatusMethodNotAllowed, nil)
+		return
+	}
+	if err := d.tableAccess(r, "/admin/query", http.MethodGet); err != nil {
+		responseResult(rw, err, http.StatusForbidden, nil)
+		return
+	}
+	if d.masked {
+		// произвольный SELECT прочитал бы замаскированные колонки как есть
+		responseResult(rw, errors.New("query console is not available for masked requests"), http.StatusForbidden, nil)
+		return
+	}
+
+	request := struct {
+		Query string `json:"query"`
+		Limit int    `json:"limit"`
+	}{}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		responseResult(rw, err, bodyErrorStatus(err), nil)
+		return
+	}
+	query, tables, err := validateSelect(d.dialect, request.Query)
+	if err != nil {
+		responseResult(rw, err, http.StatusBadRequest, nil)
+		return
+	}
+	// консоль читает только таблицы, которые отдаёт API, и с теми же правами
+	for _, name := range tables {
+		tableName, ok := d.consoleTable(name)
+		if !ok {
+			responseResult(rw, errors.New("table "+name+" is not available"), http.StatusForbidden, nil)
+			return
+		}
+		if tableName == "" {
+			continue
+		}
+		if err := d.tableAccess(r, tableName, http.MethodGet); err != nil {
+			responseResult(rw, err, http.StatusForbidden, nil)
+			return
+		}
+	}
+	limit := d.console.maxRows
+	if request.Limit > 0 && request.Limit < limit {
+		limit = request.Limit
+	}
+
+	ctx, cancel := context.WithTimeout(d.requestContext(), d.console.timeout)
+	defer cancel()
+	tx, err := d.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		responseResult(rw, err, http.StatusInternalServerError, nil)
+		return
+	}
+	defer tx.Rollback()
+
+	// строку сверх лимита читаем, чтобы сообщить, что результат обрезан
+	args := &queryArgs{dialect: d.dialect}
+	wrapped := "SELECT * FROM (" + query + ") console_query " + d.dialect.limitOffset(args.add(limit+1), args.add(0), false) + ";"
+	queryResult, err := d.traced(tx, "").QueryContext(ctx, wrapped, args.values...)
+	if err != nil {
+		responseResult(rw, consoleError(ctx, err), consoleErrorStatus(ctx), nil)
+		return
+	}
+
+	columns, err := queryResult.Columns()
+	if err != nil {
+		queryResult.Close()
+		responseResult(rw, err, http.StatusInternalServerError, nil)
+		return
+	}
+	records, err := d.parsingSqlQueryResult(queryResult, "")
+	if err == ErrRecordNotFound {
+		records, err = []map[string]interface{}{}, nil
+	}
+	if err != nil || ctx.Err() != nil {
+		responseResult(rw, consoleError(ctx, err), consoleErrorStatus(ctx), nil)
+		return
+	}
+
+	truncated := len(records) > limit
+	if truncated {
+		records = records[:limit]
+	}
+	d.countRows(len(records))
+	responseResult(rw, nil, http.StatusOK, map[string]interface{}{
+		"columns":   columns,
+		"records":   records,
+		"truncated": truncated,
+	})
+}
+
+func consoleError(ctx context.Context, err error) error {
+	if ctx.Err() == context.DeadlineExceeded {
+		return errors.New("query timed out")
+	}
+	return err
+}
+
+func consoleErrorStatus(ctx context.Context) int {
+	if ctx.Err() == context.DeadlineExceeded {
+		return http.StatusGatewayTimeout
+	}
+	return http.StatusBadRequest
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateSelect(t *testing.T) {
+	cases := []struct {
+		query    string
+		expected string
+		err      string
+	}{
+		{query: "SELECT * FROM items;", expected: "SELECT * FROM items"},
+		{query: "  with t as (select 1 as x) select x from t ", expected: "with t as (select 1 as x) select x from t"},
+		{query: "SELECT 'drop table; update' AS note, `set` FROM items -- delete\n", expected: "SELECT 'drop table; update' AS note, `set` FROM items -- delete"},
+		{query: "SELECT 1 /* INSERT */ + 1", expected: "SELECT 1 /* INSERT */ + 1"},
+		{query: "", err: "query is required"},
+		{query: "DELETE FROM items", err: "only SELECT statements are allowed"},
+		{query: "SELECT 1; DROP TABLE items", err: "only a single statement is allowed"},
+		{query: "SELECT * FROM items FOR UPDATE", err: "UPDATE is not allowed in a read-only query"},
+		{query: "SELECT * INTO OUTFILE '/tmp/x' FROM items", err: "INTO is not allowed in a read-only query"},
+		{query: "SELECT /*! SLEEP(1) */ 1", err: "executable comments are not allowed"},
+		{query: "SELECT 'open", err: "unterminated quoted string"},
+		{query: "SELECT 1 # ; DROP TABLE items", expected: "SELECT 1 # ; DROP TABLE items"},
+		{query: "SELECT SLEEP(10)", err: "SLEEP is not allowed in a read-only query"},
+		{query: "SELECT 1 FROM items WHERE BENCHMARK(1000000, MD5('x'))", err: "BENCHMARK is not allowed in a read-only query"},
+		{query: `SELECT "pg_read_file"('/etc/passwd')`, err: "PG_READ_FILE is not allowed in a read-only query"},
+	}
+
+	for idx, item := range cases {
+		query, _, err := validateSelect(MySQL, item.query)
+		if item.err != "" {
+			if err == nil || err.Error() != item.err {
+				t.Fatalf("[case %d: %q] expected error %q, got %v", idx, item.query, item.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("[case %d: %q] unexpected error: %v", idx, item.query, err)
+		}
+		if query != item.expected {
+			t.Fatalf("[case %d] expected %q, got %q", idx, item.expected, query)
+		}
+	}
+
+	// в PostgreSQL # — оператор, а не комментарий: ";" за ним должна отклоняться
+	if _, _, err := validateSelect(PostgreSQL, "SELECT 1 # 2; DROP TABLE items"); err == nil {
+		t.Fatalf("expected error for multiple statements")
+	}
+}
+
+func TestSelectTables(t *testing.T) {
+	cases := []struct {
+		query  string
+		tables string
+	}{
+		{"SELECT 1", ""},
+		{"SELECT * FROM items", "items"},
+		{"SELECT * FROM items AS i, `users` u WHERE i.id = u.user_id", "items,users"},
+		{"SELECT * FROM items i JOIN users u ON u.user_id = i.id, mysql.user", "items,users,mysql.user"},
+		{"SELECT * FROM (SELECT id FROM items) t, information_schema.tables", "items,information_schema.tables"},
+		{"SELECT * FROM (items JOIN mysql.user ON 1 = 1)", "items,mysql.user"},
+		{"SELECT (SELECT MAX(id) FROM users) FROM items ORDER BY id, title", "users,items"},
+		{"SELECT EXTRACT(YEAR FROM updated), TRIM(LEADING 'x' FROM title) FROM items LIMIT 1, 2", "items"},
+		{"WITH t (x) AS (SELECT id FROM items), u AS (SELECT 1) SELECT * FROM t JOIN u ON 1 = 1", "items"},
+		{"SELECT * FROM items UNION TABLE mysql.user", "items,mysql.user"},
+		{"SELECT * FROM generate_series(1, 3)", "generate_series"},
+	}
+
+	for idx, item := range cases {
+		_, tables, err := validateSelect(MySQL, item.query)
+		if err != nil {
+			t.Fatalf("[case %d: %q] unexpected error: %v", idx, item.query, err)
+		}
+		if joined := strings.Join(tables, ","); joined != item.tables {
+			t.Fatalf("[case %d: %q] expected tables %q, got %q", idx, item.query, item.tables, joined)
+		}
+	}
+}
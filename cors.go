@@ -0,0 +1,76 @@
+package main
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// CORSConfig задаёт правила CORS для браузерных клиентов.
+// Пустой AllowedMethods означает методы, которые поддерживает маршрут.
+type CORSConfig struct {
+	AllowedOrigins   []string // "*" разрешает любой Origin
+	AllowedMethods   []string
+	AllowedHeaders   []string
+	ExposedHeaders   []string
+	AllowCredentials bool
+	MaxAge           time.Duration // время кэширования preflight-ответа
+}
+
+func (c *CORSConfig) originAllowed(origin string) bool {
+	for _, allowed := range c.AllowedOrigins {
+		if allowed == "*" || strings.EqualFold(allowed, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+// handleCORS выставляет CORS-заголовки и отвечает на preflight-запросы.
+// Возвращает true, если ответ уже отправлен.
+func (d DbExplorer) handleCORS(rw http.ResponseWriter, r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if d.cors == nil || origin == "" {
+		return false
+	}
+
+	header := rw.Header()
+	header.Add("Vary", "Origin")
+	if !d.cors.originAllowed(origin) {
+		return false
+	}
+
+	header.Set("Access-Control-Allow-Origin", origin)
+	if d.cors.AllowCredentials {
+		header.Set("Access-Control-Allow-Credentials", "true")
+	}
+
+	requestMethod := r.Header.Get("Access-Control-Request-Method")
+	if r.Method != http.MethodOptions || requestMethod == "" {
+		if len(d.cors.ExposedHeaders) > 0 {
+			header.Set("Access-Control-Expose-Headers", strings.Join(d.cors.ExposedHeaders, ", "))
+		}
+		return false
+	}
+
+	methods := d.cors.AllowedMethods
+	if len(methods) == 0 {
+		methods = d.allowedMethods(r.URL.Path)
+	}
+	header.Add("Vary", "Access-Control-Request-Method")
+	header.Add("Vary", "Access-Control-Request-Headers")
+	header.Set("Access-Control-Allow-Methods", strings.Join(methods, ", "))
+
+	if len(d.cors.AllowedHeaders) > 0 {
+		header.Set("Access-Control-Allow-Headers", strings.Join(d.cors.AllowedHeaders, ", "))
+	} else if requested := r.Header.Get("Access-Control-Request-Headers"); requested != "" {
+		header.Set("Access-Control-Allow-Headers", requested)
+	}
+	if d.cors.MaxAge > 0 {
+		header.Set("Access-Control-Max-Age", strconv.Itoa(int(d.cors.MaxAge/time.Second)))
+	}
+
+	rw.WriteHeader(http.StatusNoContent)
+	return true
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCORS(t *testing.T) {
+	explorer := DbExplorer{
+		dialect:         MySQL,
+		tableKeys:       []string{"items"},
+		tableIdNamesMap: map[string][]string{"items": {"id"}},
+	}
+	WithCORS(CORSConfig{
+		AllowedOrigins: []string{"https://admin.example.com"},
+		AllowedHeaders: []string{"Content-Type"},
+		MaxAge:         10 * time.Minute,
+	})(&explorer)
+
+	req := httptest.NewRequest(http.MethodOptions, "/items/1", nil)
+	req.Header.Set("Origin", "https://admin.example.com")
+	req.Header.Set("Access-Control-Request-Method", http.MethodPatch)
+	rw := httptest.NewRecorder()
+	explorer.ServeHTTP(rw, req)
+
+	expected := map[string]string{
+		"Access-Control-Allow-Origin":  "https://admin.example.com",
+		"Access-Control-Allow-Methods": "GET, HEAD, OPTIONS, POST, DELETE, PATCH",
+		"Access-Control-Allow-Headers": "Content-Type",
+		"Access-Control-Max-Age":       "600",
+	}
+	if rw.Code != http.StatusNoContent {
+		t.Fatalf("expected preflight status 204, got %d", rw.Code)
+	}
+	for header, value := range expected {
+		if got := rw.Header().Get(header); got != value {
+			t.Fatalf("[%s] expected %q, got %q", header, value, got)
+		}
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Origin", "https://evil.example.com")
+	rw = httptest.NewRecorder()
+	explorer.ServeHTTP(rw, req)
+	if rw.Code != http.StatusOK || rw.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Fatalf("unexpected CORS response for foreign origin: %d %v", rw.Code, rw.Header())
+	}
+}
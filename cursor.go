@@ -0,0 +1,82 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"strings"
+)
+
+var errInvalidCursor = errors.New("invalid cursor")
+
+// encodeCursor упаковывает значения первичного ключа последней записи страницы в непрозрачную строку
+func (d DbExplorer) encodeCursor(tableName string, record map[string]interface{}) string {
+	values := make([]interface{}, 0, len(d.tableIdNamesMap[tableName]))
+	for _, key := range d.tableIdNamesMap[tableName] {
+		values = append(values, record[key])
+	}
+
+	data, _ := json.Marshal(values)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// cursorCondition строит условие "ключ больше курсора"; для составного ключа
+// сравнение раскрывается в (a > ?) OR (a = ? AND b > ?), чтобы работать во всех диалектах.
+// Пустой курсор означает первую страницу.
+func (d DbExplorer) cursorCondition(tableName, cursor string, args *queryArgs) (string, error) {
+	primaryKeys := d.tableIdNamesMap[tableName]
+	if len(primaryKeys) == 0 {
+		return "", errors.New("table " + tableName + " has no primary key")
+	}
+	if cursor == "" {
+		return "", nil
+	}
+
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return "", errInvalidCursor
+	}
+
+	values := make([]interface{}, 0)
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&values); err != nil || len(values) != len(primaryKeys) {
+		return "", errInvalidCursor
+	}
+
+	for i, key := range primaryKeys {
+		value, err := d.encodeValue(d.columnsInTablesMap[tableName][key], values[i])
+		if err != nil {
+			return "", errInvalidCursor
+		}
+		values[i] = value
+	}
+
+	alternatives := make([]string, 0, len(primaryKeys))
+	for i := range primaryKeys {
+		parts := make([]string, 0, i+1)
+		for j := 0; j < i; j++ {
+			parts = append(parts, d.dialect.quote(primaryKeys[j])+" = "+args.add(values[j]))
+		}
+		parts = append(parts, d.dialect.quote(primaryKeys[i])+" > "+args.add(values[i]))
+		alternatives = append(alternatives, "("+strings.Join(parts, " AND ")+")")
+	}
+
+	return "(" + strings.Join(alternatives, " OR ") + ")", nil
+}
+
+// selectsPrimaryKey проверяет, что в проекции есть все колонки первичного ключа
+func (d DbExplorer) selectsPrimaryKey(tableName, columns string) bool {
+	if columns == "*" {
+		return true
+	}
+
+	selected := strings.Split(columns, ", ")
+	for _, key := range d.tableIdNamesMap[tableName] {
+		if !containsString(selected, d.dialect.quote(key)) {
+			return false
+		}
+	}
+	return true
+}
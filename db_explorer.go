@@ -1,71 +1,246 @@
 package main
 
 import (
+	"bufio"
+	"context"
 	"database/sql"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
-	"io/ioutil"
+	"io/fs"
+	"log/slog"
 	"net/http"
 	"strconv"
 	"strings"
+
+	"go.opentelemetry.io/otel/trace"
 )
 
+var errReadOnly = errors.New("explorer is in read-only mode")
+
+// defaultMaxBodySize — лимит тела запроса по умолчанию, 10 МиБ
+const defaultMaxBodySize = 10 << 20
+
 type columnParams struct {
 	name         string
 	typeName     string
 	isNull       bool
 	primary      bool
 	defaultValue interface{}
+	enumValues   []string
+	dbType       string
+	dbDefault    interface{}
+	comment      string
+	maxLength    int  // N из varchar(N), см. ValidationRule
+	computed     bool // GENERATED или ON UPDATE CURRENT_TIMESTAMP: значение вычисляет база, см. computedColumn
+}
+
+// queryExecutor — общее у *sql.DB и *sql.Tx, чтобы запись работала и внутри транзакции
+type queryExecutor interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
 }
 
 type DbExplorer struct {
 	db                 *sql.DB
+	dialect            Dialect
 	columnsInTablesMap map[string]map[string]columnParams
 	tableKeys          []string
-	tableIdNameMap     map[string]string
+	columnKeysMap      map[string][]string
+	tableIdNamesMap    map[string][]string
+	decimalsAsStrings  bool
+	tinyintAsBool      bool
+	maxBlobSize        int
+	cors               *CORSConfig
+	events             *mutationBroker
+	compressionMinSize int
+	idempotency        *idempotencyStore
+	apiKeys            APIKeyLookup
+	jwtSecret          []byte
+	authorizer         Authorizer
+	masking            *MaskingConfig
+	masked             bool // маскирование включено для текущего запроса, см. withMasking
+	readOnly           bool
+	limiter            *rateLimiter
+	maxBodySize        int64
+	audit              AuditSink
+	auditBuffer        *auditBuffer // снимки записей текущего запроса, см. withAudit
+	actor              string
+	logger             *slog.Logger
+	requestLog         *requestLog // поля журнала текущего запроса, см. withRequestLog
+	tracer             trace.Tracer
+	ctx                context.Context // контекст текущего запроса со span'ом, см. withTracing
+	cache              *responseCache
+	softDelete         map[string]string // таблица → колонка времени удаления, см. WithSoftDelete
+	includeDeleted     bool
+	versionColumns     map[string]string // таблица → колонка версии записи, см. WithVersionColumn
+	recent             *recentMutations
+	publishers         []Publisher
+	console            *queryConsole
+	foreignKeys        []foreignKey
+	schema             *schemaState // актуальная схема, см. withSchema
+	migrations         *migrator
+	ddl                *ddlState
+	fixtures           fs.FS
+	defaultLimit       int
+	maxLimit           int
+	tableFilter        func(tableName string) bool // см. WithTables и WithExcludedTables
+	middleware         *middlewareChain
+	hooks              map[HookEvent][]Hook
+	validation         map[string]map[string]ValidationRule
+	columnAliases      map[string]map[string]string // таблица → колонка → поле JSON, см. WithColumnAliases
+	aliasColumns       map[string]map[string]string // обратный columnAliases: таблица → поле JSON → колонка
+	structuredErrors   bool
+	unknownFields      UnknownFields
+	jsonAPI            bool
+	postgrestFilters   bool
+	hypermedia         bool
+	linkHeader         bool
+	slowQueries        *slowQueryLog
+	queryStats         *queryStats
+	retry              *RetryConfig
+	breaker            *circuitBreaker
+	lifecycle          *lifecycle
+	basePath           string
+	uuidKeys           UUIDVersion      // 0 — ключи не генерируются, см. WithUUIDKeys
+	warnings           *requestWarnings // предупреждения текущего запроса, см. withWarnings
+}
+
+func NewDbExplorer(db *sql.DB, opts ...Option) (*DbExplorer, error) {
+	explorer := &DbExplorer{db: db, dialect: detectDialect(db), tinyintAsBool: true, events: newMutationBroker(),
+		compressionMinSize: defaultCompressionMinSize, idempotency: newIdempotencyStore(),
+		maxBodySize: defaultMaxBodySize, recent: &recentMutations{}, middleware: &middlewareChain{}, lifecycle: newLifecycle()}
+	for _, opt := range opts {
+		opt(explorer)
+	}
+	for _, publisher := range explorer.publishers {
+		explorer.events.publishers = append(explorer.events.publishers, newPublisherQueue(publisher, explorer.logError))
+	}
+
+	if explorer.migrations != nil {
+		if err := explorer.migrations.validate(); err != nil {
+			return nil, err
+		}
+		if explorer.migrations.onStart {
+			if _, err := explorer.migrate(context.Background()); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	snapshot, err := explorer.loadSchema()
+	if err != nil {
+		return nil, err
+	}
+	explorer.schema = &schemaState{snapshot: snapshot}
+	*explorer = explorer.withSchema()
+	return explorer, nil
 }
 
-func NewDbExplorer(db *sql.DB) (*DbExplorer, error) {
-	tableIdNameMap := make(map[string]string)
+// loadSchema читает таблицы, колонки и связи базы; схема проверяется на копии explorer'а,
+// так что при ошибке (например, при обновлении после миграции) действующая схема не меняется
+func (d DbExplorer) loadSchema() (*schemaSnapshot, error) {
+	tableIdNamesMap := make(map[string][]string)
+	columnKeysMap := make(map[string][]string)
 	columnsInTablesMap := make(map[string]map[string]columnParams)
 	tableKeys := make([]string, 0)
 
-	tables, err := db.Query("SHOW TABLES;")
+	tables, err := d.db.Query(d.dialect.tablesQuery())
 	if err != nil {
 		return nil, err
 	}
 
+	// список таблиц вычитываем целиком до запросов колонок:
+	// при одном соединении в пуле (типично для SQLite) вложенный запрос иначе зависнет
 	for tables.Next() {
 		tableName := ""
 
 		if err := tables.Scan(&tableName); err != nil {
 			continue
 		}
+		if err := checkIdentifier(tableName); err != nil || strings.Contains(tableName, "/") {
+			// такую таблицу нельзя ни безопасно процитировать, ни указать в пути запроса
+			d.logError("table skipped", errors.New("invalid table name "+strconv.Quote(tableName)))
+			continue
+		}
 		tableKeys = append(tableKeys, tableName)
+	}
+	tables.Close()
 
+	// таблицу журнала изменений наружу не отдаём, иначе её можно было бы переписать через API
+	if sink, ok := d.audit.(interface{ auditTable() string }); ok {
+		tableKeys = removeString(tableKeys, sink.auditTable())
+	}
+	if d.migrations != nil {
+		tableKeys = removeString(tableKeys, migrationsTable)
+	}
+	if d.tableFilter != nil {
+		filtered := make([]string, 0, len(tableKeys))
+		for _, tableName := range tableKeys {
+			if d.tableFilter(tableName) {
+				filtered = append(filtered, tableName)
+			}
+		}
+		tableKeys = filtered
+	}
+
+	for _, tableName := range tableKeys {
 		columnsInTablesMap[tableName] = make(map[string]columnParams)
-		queryResult, _ := db.Query("SHOW FULL COLUMNS FROM " + tableName)
-		columns, err := parsingSqlQueryResult(queryResult)
+		columnsQuery, args := d.dialect.columnsQuery(tableName)
+		queryResult, _ := d.db.Query(columnsQuery, args...)
+		columns, err := d.parsingSqlQueryResult(queryResult, "")
 		if err != nil {
 			return nil, err
 		}
 
 		for _, value := range columns {
 			name := fmt.Sprintf("%v", value["Field"])
-			typeName := fmt.Sprintf("%v", value["Type"])
+			if err := checkIdentifier(name); err != nil {
+				d.logError("column skipped", errors.New("table "+tableName+": "+err.Error()))
+				continue
+			}
+			rawType := fmt.Sprintf("%v", value["Type"])
+			typeName := normalizeColumnType(rawType)
+			if d.tinyintAsBool && strings.HasPrefix(strings.ToLower(rawType), "tinyint(1)") {
+				typeName = "bool"
+			}
 			var defaultValue interface{}
 
-			if strings.Contains(typeName, "text") || strings.Contains(typeName, "varchar") {
-				typeName = "string"
+			if typeName == "string" {
 				defaultValue = ""
 			}
 
-			if typeName == "int" {
+			if typeName == "int" || typeName == "float" {
 				defaultValue = 0
 			}
 
+			if typeName == "decimal" {
+				defaultValue = "0"
+			}
+
+			if typeName == "bool" {
+				defaultValue = false
+			}
+
+			if typeName == "json" {
+				defaultValue = "null"
+			}
+
+			if typeName == "binary" {
+				defaultValue = []byte{}
+			}
+
+			var enumValues []string
+			if typeName == "enum" || typeName == "set" {
+				enumValues = parseEnumValues(rawType)
+				defaultValue = ""
+				if typeName == "enum" && len(enumValues) > 0 {
+					defaultValue = enumValues[0]
+				}
+			}
+
 			isNull := false
 			if fmt.Sprintf("%v", value["Null"]) == "YES" {
 				isNull = true
@@ -74,45 +249,221 @@ func NewDbExplorer(db *sql.DB) (*DbExplorer, error) {
 			primary := false
 			if fmt.Sprintf("%v", value["Key"]) == "PRI" {
 				primary = true
-				tableIdNameMap[tableName] = name
+				tableIdNamesMap[tableName] = append(tableIdNamesMap[tableName], name)
 			}
 
+			comment := ""
+			if value["Comment"] != nil {
+				comment = fmt.Sprintf("%v", value["Comment"])
+			}
+
+			maxLength := 0
+			if typeName == "string" {
+				maxLength = columnLength(rawType)
+			}
+
+			computed := false
+			if value["Extra"] != nil {
+				computed = computedColumn(fmt.Sprintf("%v", value["Extra"]))
+			}
+
+			columnKeysMap[tableName] = append(columnKeysMap[tableName], name)
 			columnsInTablesMap[tableName][name] = columnParams{
 				name:         name,
 				typeName:     typeName,
 				isNull:       isNull,
 				primary:      primary,
 				defaultValue: defaultValue,
+				enumValues:   enumValues,
+				dbType:       rawType,
+				dbDefault:    value["Default"],
+				comment:      comment,
+				maxLength:    maxLength,
+				computed:     computed,
+			}
+		}
+
+		// SHOW FULL COLUMNS отдаёт колонки в порядке таблицы, а id в пути идут в порядке ключа: PRIMARY KEY (b, a)
+		if len(tableIdNamesMap[tableName]) > 1 {
+			keys, err := d.primaryKeyOrder(tableName, tableIdNamesMap[tableName])
+			if err != nil {
+				return nil, err
+			}
+			if len(keys) == len(tableIdNamesMap[tableName]) {
+				tableIdNamesMap[tableName] = keys
 			}
 		}
 	}
 
-	return &DbExplorer{
-		db:                 db,
-		columnsInTablesMap: columnsInTablesMap,
-		tableKeys:          tableKeys,
-		tableIdNameMap:     tableIdNameMap,
+	d.columnsInTablesMap = columnsInTablesMap
+	d.tableKeys = tableKeys
+	d.columnKeysMap = columnKeysMap
+	d.tableIdNamesMap = tableIdNamesMap
+	if d.foreignKeys, err = d.loadForeignKeys(); err != nil {
+		return nil, err
+	}
+	if err := d.checkSoftDelete(); err != nil {
+		return nil, err
+	}
+	if err := d.checkVersionColumns(); err != nil {
+		return nil, err
+	}
+	if err := d.checkValidation(); err != nil {
+		return nil, err
+	}
+	if err := d.checkColumnAliases(); err != nil {
+		return nil, err
+	}
+	return &schemaSnapshot{
+		tableKeys:          d.tableKeys,
+		columnsInTablesMap: d.columnsInTablesMap,
+		columnKeysMap:      d.columnKeysMap,
+		tableIdNamesMap:    d.tableIdNamesMap,
+		foreignKeys:        d.foreignKeys,
 	}, nil
 }
 
 func (d DbExplorer) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	if d.basePath != "" {
+		if !hasPathPrefix(r.URL.Path, d.basePath) {
+			responseResult(rw, errors.New("not found"), http.StatusNotFound, nil)
+			return
+		}
+		r = stripPathPrefix(r, d.basePath)
+	}
+	if d.middleware == nil || d.middleware.handler == nil {
+		d.serve(rw, r)
+		return
+	}
+	d.middleware.handler.ServeHTTP(rw, d.withSchema().withRoute(r))
+}
+
+// serve обрабатывает запрос внутри цепочки middleware, см. Use
+func (d DbExplorer) serve(rw http.ResponseWriter, r *http.Request) {
+	if !d.lifecycle.begin() {
+		rw.Header().Set("Connection", "close")
+		responseResult(rw, errShuttingDown, http.StatusServiceUnavailable, nil)
+		return
+	}
+	defer d.lifecycle.end()
+	d = d.withSchema()
+	if d.structuredErrors {
+		rw = structuredErrorsWriter{rw}
+	}
+	rw = d.withJSONAPI(rw, r)
+	rw, d = d.withWarnings(rw)
+	rw, d = d.withRequestLog(rw, r)
+	defer d.requestLog.finish()
+	// запросы к базе отменяются вместе с запросом клиента
+	d.ctx = r.Context()
+	r, d = d.withTracing(r)
+	defer d.endRequestSpan()
+
+	rw, finish := d.compress(rw, r)
+	defer finish()
+
+	if d.handleCORS(rw, r) || !d.rateLimit(rw, r) {
+		return
+	}
+	if isUIPath(r.URL.Path) {
+		d.handlerUI(rw, r)
+		return
+	}
+	if !d.circuitClosed(rw, r) {
+		return
+	}
+	if d.maxBodySize > 0 && r.Body != nil {
+		r.Body = http.MaxBytesReader(rw, r.Body, d.maxBodySize)
+	}
+	r, ok := d.authenticate(rw, r)
+	if !ok || !d.authorizeTable(rw, r) {
+		return
+	}
+	d = d.withMasking(r).withAudit(r)
+	if r.URL.Path == "/graphql" && r.Method != http.MethodOptions {
+		d.handlerGraphQL(rw, r)
+		return
+	}
+	rw = negotiate(rw, r)
+	if r.URL.Path == "/admin/query" && r.Method != http.MethodOptions {
+		d.handlerQueryConsole(rw, r)
+		return
+	}
+
+	switch r.Method {
+	case "GET", "HEAD", "OPTIONS":
+	default:
+		if !d.dialect.writable() {
+			d.handlerMethodNotAllowed(rw, r, errors.New("writes are disabled for "+d.dialect.name()))
+			return
+		}
+		if d.readOnly {
+			rw.Header().Set("Allow", strings.Join(d.allowedMethods(r.URL.Path), ", "))
+			responseResult(rw, errReadOnly, http.StatusForbidden, nil)
+			return
+		}
+	}
+
 	switch r.Method {
 	case "GET":
 		d.handlerGet(rw, r)
+	case "HEAD":
+		d.handlerHead(rw, r)
+	case "OPTIONS":
+		d.handlerOptions(rw, r)
 	case "PUT":
-		d.handlerPut(rw, r)
+		d.idempotent(rw, r, d.handlerPut)
 	case "POST":
 		d.handlerPost(rw, r)
 	case "DELETE":
 		d.handlerDelete(rw, r)
+	case "PATCH":
+		d.handlerPatch(rw, r)
 	default:
-		rw.WriteHeader(http.StatusInternalServerError)
+		d.handlerMethodNotAllowed(rw, r, errMethodNotAllowed)
 	}
 }
 
 func (d DbExplorer) handlerGet(rw http.ResponseWriter, r *http.Request) {
+	d = d.withIncludeDeleted(r)
 	if r.URL.Path == "/" {
-		responseResult(rw, nil, http.StatusOK, map[string]interface{}{"tables": d.tableKeys})
+		result := map[string]interface{}{"tables": d.readableTables(r)}
+		if relations := d.readableRelations(r, ""); len(relations) > 0 {
+			result["relations"] = relations
+		}
+		responseResult(rw, nil, http.StatusOK, result)
+		return
+	}
+	if r.URL.Path == "/openapi.json" {
+		d.handlerOpenAPI(rw, r)
+		return
+	}
+	if r.URL.Path == "/_events" {
+		d.handlerEvents(rw, r)
+		return
+	}
+	if r.URL.Path == "/admin/audit" {
+		d.handlerAudit(rw, r)
+		return
+	}
+	if r.URL.Path == "/admin/slow-queries" {
+		d.handlerSlowQueries(rw, r)
+		return
+	}
+	if r.URL.Path == "/admin/stats" {
+		d.handlerStats(rw, r)
+		return
+	}
+	if r.URL.Path == "/admin/db-stats" {
+		d.handlerDBStats(rw, r)
+		return
+	}
+	if r.URL.Path == "/admin/dump" {
+		if err := d.tableAccess(r, "/admin/dump", http.MethodGet); err != nil {
+			responseResult(rw, err, http.StatusForbidden, nil)
+			return
+		}
+		d.handlerDump(rw, r, d.readableTables(r), "")
 		return
 	}
 
@@ -122,62 +473,48 @@ func (d DbExplorer) handlerGet(rw http.ResponseWriter, r *http.Request) {
 		return
 	}
 	pathParts := strings.Split(r.URL.Path, "/")
-	switch len(pathParts) {
-
-	case 2:
-		limit, err := strconv.Atoi(r.FormValue("limit"))
-		if err != nil {
-			limit = 5
+	if len(pathParts) == 3 && pathParts[2] == "schema" {
+		schema := d.tableSchema(tableName)
+		if relations := d.readableRelations(r, tableName); len(relations) > 0 {
+			schema["relations"] = relations
 		}
+		responseResult(rw, nil, http.StatusOK, schema)
+		return
+	}
+	if len(pathParts) == 3 && pathParts[2] == "aggregate" {
+		d.cached(rw, r, tableName, func(rw http.ResponseWriter, r *http.Request) {
+			d.handlerAggregate(rw, r, tableName)
+		})
+		return
+	}
+	if len(pathParts) == 4 && pathParts[2] == "distinct" {
+		d.cached(rw, r, tableName, func(rw http.ResponseWriter, r *http.Request) {
+			d.handlerDistinct(rw, r, tableName, pathParts[3])
+		})
+		return
+	}
+	if len(pathParts) == 3 && pathParts[2] == "dump" {
+		d.handlerDump(rw, r, []string{tableName}, tableName)
+		return
+	}
+	if len(pathParts) == 3 && pathParts[2] == "trash" {
+		d.cached(rw, r, tableName, func(rw http.ResponseWriter, r *http.Request) {
+			d.handlerTrash(rw, r, tableName)
+		})
+		return
+	}
 
-		offset, err := strconv.Atoi(r.FormValue("offset"))
-		if err != nil {
-			offset = 0
-		}
-
-		query := "SELECT * FROM " + tableName + " LIMIT ?,?;"
-		queryResult, err := d.db.Query(query, offset, limit)
-		if err != nil {
-			responseResult(rw, err, http.StatusNotFound, nil)
-			return
-		}
-
-		records, err := parsingSqlQueryResult(queryResult)
-		if err != nil {
-			responseResult(rw, err, http.StatusNotFound, nil)
-			return
-		}
-
-		responseResult(rw, nil, http.StatusOK, map[string]interface{}{"records": records})
-
+	switch len(pathParts) {
+	case 2:
+		d.cached(rw, r, tableName, func(rw http.ResponseWriter, r *http.Request) {
+			d.handlerList(rw, r, tableName)
+		})
 	case 3:
-		id, err := strconv.Atoi(pathParts[2])
-		if err != nil {
-			responseResult(rw, errors.New("record not found"), http.StatusNotFound, nil)
-			return
-		}
-
-		idColumnName := d.tableIdNameMap[tableName]
-		query := "SELECT * FROM " + tableName + " WHERE " + idColumnName + " = ?;"
-		queryResult, err := d.db.Query(query, id)
-		if err != nil {
-			responseResult(rw, err, http.StatusNotFound, nil)
-			return
-		}
-
-		records, err := parsingSqlQueryResult(queryResult)
-		if err != nil {
-			responseResult(rw, err, http.StatusNotFound, nil)
-			return
-		}
-
-		responseResult(
-			rw,
-			nil,
-			http.StatusOK,
-			map[string]interface{}{"record": records[0]},
-		)
-
+		d.cached(rw, r, tableName, func(rw http.ResponseWriter, r *http.Request) {
+			d.handlerRecord(rw, r, tableName, pathParts[2])
+		})
+	case 4:
+		d.handlerNested(rw, r, tableName, pathParts[2], pathParts[3])
 	default:
 		responseResult(rw, errors.New("not found"), http.StatusNotFound, nil)
 		return
@@ -185,42 +522,96 @@ func (d DbExplorer) handlerGet(rw http.ResponseWriter, r *http.Request) {
 }
 
 func (d DbExplorer) handlerPut(rw http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/_tables" {
+		d.handlerCreateTable(rw, r)
+		return
+	}
+
 	pathParts := strings.Split(r.URL.Path, "/")
-	if len(pathParts) != 3 {
-		responseResult(rw, errors.New("unknown table"), http.StatusNotFound, nil)
+	if len(pathParts) != 2 && len(pathParts) != 3 {
+		responseResult(rw, ErrUnknownTable, http.StatusNotFound, nil)
 		return
 	}
 
 	tableName, err := getTableName(r.URL.Path, d.tableKeys)
 	if err != nil {
-		responseResult(rw, errors.New("unknown table"), http.StatusNotFound, nil)
+		responseResult(rw, ErrUnknownTable, http.StatusNotFound, nil)
+		return
+	}
+
+	if !d.checkPrimaryKey(rw, tableName) {
+		return
+	}
+
+	// массив в теле — пакетная вставка; первый значимый символ смотрим без чтения всего тела
+	body := bufio.NewReader(r.Body)
+	if first, err := firstJSONByte(body); err == nil && first == '[' {
+		d.handlerBulkInsert(rw, tableName, body)
+		return
+	}
+
+	requestDataMap, err := getDataForSqlQuery(body, d, tableName)
+	if err != nil {
+		responseResult(rw, err, bodyErrorStatus(err), nil)
 		return
 	}
 
-	requestDataMap, err := getDataForSqlQuery(r.Body, d, tableName)
+	tx, err := d.db.BeginTx(d.requestContext(), nil)
 	if err != nil {
-		responseResult(rw, err, http.StatusBadRequest, nil)
+		responseResult(rw, err, http.StatusInternalServerError, nil)
 		return
 	}
 
-	idColumnName := d.tableIdNameMap[tableName]
-	lastInsertId, err := d.insertRecord(requestDataMap, tableName)
-	result := map[string]int{idColumnName: lastInsertId}
-	responseResult(rw, err, http.StatusOK, result)
+	result, err := d.insertRecord(tx, requestDataMap, tableName)
+	if err != nil {
+		tx.Rollback()
+		responseResult(rw, err, mutationErrorStatus(err, http.StatusOK), nil)
+		return
+	}
+	d.commitResult(rw, tx, d.toFields(tableName, result), func() {
+		d.emitInsert(tableName, result, requestDataMap)
+	})
 }
 
-func (d DbExplorer) insertRecord(dataMap map[string]interface{}, tableName string) (lastInsertId int, err error) {
+// insertRecord возвращает значения первичного ключа вставленной записи.
+// Одиночный целочисленный первичный ключ считается автоинкрементным и игнорируется в теле запроса,
+// строковый (UUID, varchar) и колонки составного ключа берутся из тела, недостающая заполняется
+// через LastInsertId или RETURNING.
+func (d DbExplorer) insertRecord(db queryExecutor, dataMap map[string]interface{}, tableName string) (map[string]interface{}, error) {
+	if err := d.runHooks(BeforeInsert, tableName, map[string]interface{}{}, dataMap); err != nil {
+		return nil, err
+	}
+	if err := d.generateKey(tableName, dataMap); err != nil {
+		return nil, err
+	}
+	if err := d.checkRequired(tableName, dataMap); err != nil {
+		return nil, err
+	}
+	if err := d.checkKeyValues(tableName, dataMap); err != nil {
+		return nil, err
+	}
+
 	columName := ""
-	columValue := make([]interface{}, 0)
+	args := &queryArgs{dialect: d.dialect}
+	placeholders := make([]string, 0)
+	primaryKeys := d.tableIdNamesMap[tableName]
+	autoKey := ""
 
 	for key, rd := range d.columnsInTablesMap[tableName] {
-		if d.columnsInTablesMap[tableName][key].primary {
+		if rd.computed {
 			continue
 		}
+		if rd.primary {
+			if _, ok := dataMap[key]; (len(primaryKeys) == 1 && rd.typeName == "int") || !ok {
+				autoKey = key
+				continue
+			}
+		}
 
 		val, ok := dataMap[key]
 		if !ok {
-			if rd.isNull {
+			// колонку с DEFAULT заполнит сама база, в том числе выражениями вроде CURRENT_TIMESTAMP
+			if rd.isNull || rd.dbDefault != nil {
 				continue
 			}
 			val = rd.defaultValue
@@ -230,93 +621,216 @@ func (d DbExplorer) insertRecord(dataMap map[string]interface{}, tableName strin
 			columName += ", "
 		}
 
-		columValue = append(columValue, val)
-		columName = fmt.Sprintf("%v`%v`", columName, key)
+		placeholders = append(placeholders, args.add(val))
+		columName += d.dialect.quote(key)
 	}
 
-	query := fmt.Sprintf("INSERT INTO %v (%v) VALUES(?"+strings.Repeat(",?", len(columValue)-1)+");", tableName, columName)
-	queryResult, err := d.db.Exec(query, columValue...)
-	if err != nil {
-		return 0, err
+	returningKey := autoKey
+	if returningKey == "" && len(primaryKeys) > 0 {
+		returningKey = primaryKeys[0]
 	}
 
-	id, err := queryResult.LastInsertId()
-	lastInsertId = int(id)
-	return lastInsertId, err
+	var lastInsertId interface{}
+	query, returning := d.dialect.insertQuery(d.dialect.quote(tableName), columName, strings.Join(placeholders, ", "), returningKey)
+	if !returning && autoKey != "" && d.columnsInTablesMap[tableName][autoKey].typeName != "int" {
+		// LastInsertId бывает только у автоинкремента, строковый ключ без RETURNING узнать неоткуда
+		return nil, validationError{[]fieldError{{Field: d.fieldName(tableName, autoKey), Rule: "required", Message: "is required"}}}
+	}
+	if returning {
+		if err := d.traced(db, tableName).QueryRowContext(d.requestContext(), query, args.values...).Scan(&lastInsertId); err != nil {
+			return nil, err
+		}
+		if raw, ok := lastInsertId.([]byte); ok {
+			lastInsertId = string(raw)
+		}
+	} else {
+		queryResult, err := d.traced(db, tableName).ExecContext(d.requestContext(), query, args.values...)
+		if err != nil {
+			return nil, err
+		}
+
+		id, err := queryResult.LastInsertId()
+		if err != nil {
+			return nil, err
+		}
+		lastInsertId = int(id)
+	}
+
+	result := make(map[string]interface{}, len(primaryKeys))
+	for _, key := range primaryKeys {
+		if val, ok := dataMap[key]; ok && key != autoKey {
+			result[key] = val
+			continue
+		}
+		result[key] = lastInsertId
+	}
+	d.auditSnapshot(db, tableName, d.rawId(tableName, result), true)
+	d.countRows(1)
+	if err := d.runHooks(AfterInsert, tableName, result, dataMap); err != nil {
+		return nil, err
+	}
+	return result, nil
 }
 
 func (d DbExplorer) handlerPost(rw http.ResponseWriter, r *http.Request) {
+	if r.URL.Path == "/transaction" {
+		d.idempotent(rw, r, d.handlerTransaction)
+		return
+	}
+	if r.URL.Path == "/batch" {
+		d.handlerBatch(rw, r)
+		return
+	}
+	if r.URL.Path == "/admin/migrate" {
+		d.handlerMigrate(rw, r)
+		return
+	}
+	if r.URL.Path == "/admin/seed" {
+		d.handlerSeed(rw, r)
+		return
+	}
+
 	pathParts := strings.Split(r.URL.Path, "/")
+	if len(pathParts) == 2 {
+		d.handlerBulkUpdate(rw, r)
+		return
+	}
+	if len(pathParts) == 4 && pathParts[3] == "restore" {
+		tableName, err := getTableName(r.URL.Path, d.tableKeys)
+		if err != nil {
+			responseResult(rw, ErrUnknownTable, http.StatusNotFound, nil)
+			return
+		}
+		if d.checkPrimaryKey(rw, tableName) {
+			d.handlerRestore(rw, r, tableName, pathParts[2])
+		}
+		return
+	}
+
 	if len(pathParts) != 3 {
-		responseResult(rw, errors.New("unknown table"), http.StatusNotFound, nil)
+		responseResult(rw, ErrUnknownTable, http.StatusNotFound, nil)
 		return
 	}
 
 	tableName, err := getTableName(r.URL.Path, d.tableKeys)
 	if err != nil {
-		responseResult(rw, errors.New("unknown table"), http.StatusNotFound, nil)
+		responseResult(rw, ErrUnknownTable, http.StatusNotFound, nil)
+		return
+	}
+	if pathParts[2] == "_columns" {
+		d.handlerAddColumn(rw, r, tableName)
 		return
 	}
 
-	id, err := strconv.Atoi(pathParts[2])
-	if err != nil {
-		responseResult(rw, err, http.StatusBadRequest, nil)
+	if !d.checkPrimaryKey(rw, tableName) {
+		return
+	}
+
+	if pathParts[2] == "import" {
+		d.handlerImport(rw, r, tableName)
 		return
 	}
 
 	requestData, err := getDataForSqlQuery(r.Body, d, tableName)
 	if err != nil {
-		responseResult(rw, err, http.StatusBadRequest, nil)
+		responseResult(rw, err, bodyErrorStatus(err), nil)
 		return
 	}
 
-	affectedCount, err := d.updateRecord(requestData, tableName, id)
+	tx, err := d.db.BeginTx(d.requestContext(), nil)
 	if err != nil {
-		responseResult(rw, err, http.StatusBadRequest, nil)
+		responseResult(rw, err, http.StatusInternalServerError, nil)
+		return
+	}
+	if !d.checkIfMatch(rw, r, tx, tableName, pathParts[2]) {
+		tx.Rollback()
 		return
 	}
 
-	result := map[string]int{"updated": affectedCount}
-	responseResult(rw, nil, http.StatusOK, result)
+	affectedCount, err := d.updateRecord(tx, requestData, tableName, pathParts[2])
+	if err != nil {
+		tx.Rollback()
+		responseResult(rw, err, updateErrorStatus(err), nil)
+		return
+	}
+	d.commitResult(rw, tx, map[string]int{"updated": affectedCount}, func() {
+		if affectedCount > 0 {
+			d.emitUpdate(tableName, pathParts[2], requestData)
+		}
+	})
 }
 
-func (d DbExplorer) updateRecord(data map[string]interface{}, tableName string, id int) (int, error) {
-	idKey := ""
-	for key, val := range d.columnsInTablesMap[tableName] {
-		if val.primary {
-			idKey = key
-			break
-		}
+// updateRecord обновляет запись по id. Если для таблицы задан WithVersionColumn и клиент прислал
+// версию, запись с другой версией не меняется и возвращается errVersionConflict.
+func (d DbExplorer) updateRecord(db queryExecutor, data map[string]interface{}, tableName string, id string) (int, error) {
+	if err := d.runHooks(BeforeUpdate, tableName, d.primaryKeyValues(tableName, id), data); err != nil {
+		return 0, err
+	}
+	args := &queryArgs{dialect: d.dialect}
+	data, versionSet, expectedVersion, versionSupplied := d.versionUpdate(tableName, data)
+	set, err := d.setClause(tableName, data, args)
+	if err != nil {
+		return 0, err
 	}
+	set = joinSet(set, versionSet)
 
-	if _, ok := data[idKey]; ok {
-		return 0, errors.New("field " + idKey + " have invalid type")
+	condition, err := d.primaryKeyCondition(tableName, id, args)
+	if err != nil {
+		return 0, err
+	}
+	condition = andCondition(condition, d.notDeletedCondition(tableName))
+	if versionSupplied {
+		condition = andCondition(condition, d.versionCondition(tableName, expectedVersion, args))
 	}
 
-	query := ""
-	for key, rd := range data {
-		if query != "" {
-			query += ", "
+	d.auditSnapshot(db, tableName, id, false)
+	affectedCount, err := d.execUpdate(db, tableName, set, condition, args)
+	if err == nil && affectedCount > 0 {
+		d.auditSnapshot(db, tableName, id, true)
+		if err := d.runHooks(AfterUpdate, tableName, d.primaryKeyValues(tableName, id), data); err != nil {
+			return 0, err
+		}
+	}
+	if err == nil && affectedCount == 0 && versionSupplied {
+		// запись есть, но версия другая — кто-то успел изменить её раньше
+		if _, err := d.queryRecord(db, tableName, id, d.dialect.quote(d.tableIdNamesMap[tableName][0])); err == nil {
+			return 0, errVersionConflict
 		}
+	}
+	return affectedCount, err
+}
 
-		switch d.columnsInTablesMap[tableName][key].typeName {
-		case "string":
-			if rd == nil {
-				query = fmt.Sprintf("%v`%v`= NULL", query, key)
-				continue
-			}
-			query = fmt.Sprintf("%v`%v`='%v'", query, key, rd)
-		case "int":
-			query = fmt.Sprintf("%v`%v`=%v", query, key, rd)
-		default:
+// setClause строит SET для UPDATE; первичный ключ у существующих записей менять нельзя
+func (d DbExplorer) setClause(tableName string, data map[string]interface{}, args *queryArgs) (string, error) {
+	for _, idKey := range d.tableIdNamesMap[tableName] {
+		if _, ok := data[idKey]; ok {
+			return "", invalidTypeError(idKey)
+		}
+	}
+
+	set := make([]string, 0, len(data))
+	for key, rd := range data {
+		if !d.columnsInTablesMap[tableName][key].writable() {
 			continue
 		}
+		set = append(set, d.dialect.quote(key)+" = "+args.add(rd))
+	}
 
+	if len(set) == 0 {
+		return "", errors.New("no fields to update")
 	}
+	return strings.Join(set, ", "), nil
+}
 
-	query = fmt.Sprintf("UPDATE `%v` SET %v WHERE `%v` = ?;", tableName, query, idKey)
+func (d DbExplorer) execUpdate(db queryExecutor, tableName, set, condition string, args *queryArgs) (int, error) {
+	query := fmt.Sprintf(
+		"UPDATE %v SET %v WHERE %v;",
+		d.dialect.quote(tableName),
+		set,
+		condition,
+	)
 
-	queryResult, err := d.db.Exec(query, id)
+	queryResult, err := d.traced(db, tableName).ExecContext(d.requestContext(), query, args.values...)
 	if err != nil {
 		return 0, err
 	}
@@ -326,59 +840,143 @@ func (d DbExplorer) updateRecord(data map[string]interface{}, tableName string,
 		return 0, err
 	}
 
+	d.countRows(int(affectedCount))
 	return int(affectedCount), nil
 }
 
 func (d DbExplorer) handlerDelete(rw http.ResponseWriter, r *http.Request) {
 	pathParts := strings.Split(r.URL.Path, "/")
 	if len(pathParts) != 3 {
-		responseResult(rw, errors.New("unknown table"), http.StatusNotFound, nil)
+		responseResult(rw, ErrUnknownTable, http.StatusNotFound, nil)
 		return
 	}
 
 	tableName, err := getTableName(r.URL.Path, d.tableKeys)
 	if err != nil {
-		responseResult(rw, errors.New("unknown table"), http.StatusNotFound, nil)
+		responseResult(rw, ErrUnknownTable, http.StatusNotFound, nil)
 		return
 	}
 
-	id, err := strconv.Atoi(pathParts[2])
+	if !d.checkPrimaryKey(rw, tableName) {
+		return
+	}
+
+	tx, err := d.db.BeginTx(d.requestContext(), nil)
 	if err != nil {
-		responseResult(rw, err, http.StatusBadRequest, nil)
+		responseResult(rw, err, http.StatusInternalServerError, nil)
+		return
+	}
+	if !d.checkIfMatch(rw, r, tx, tableName, pathParts[2]) {
+		tx.Rollback()
 		return
 	}
 
-	idColumnName := d.tableIdNameMap[tableName]
-	query := fmt.Sprintf("DELETE FROM `%v` WHERE %v = ?", tableName, idColumnName)
-	queryResult, err := d.db.Exec(query, id)
+	rowsAffected, err := d.deleteRecord(tx, tableName, pathParts[2])
 	if err != nil {
-		responseResult(rw, err, http.StatusBadRequest, nil)
+		tx.Rollback()
+		responseResult(rw, err, mutationErrorStatus(err, http.StatusBadRequest), nil)
 		return
 	}
+	d.commitResult(rw, tx, map[string]int{"deleted": rowsAffected}, func() {
+		if rowsAffected > 0 {
+			d.emitDelete(tableName, pathParts[2])
+		}
+	})
+}
+
+func (d DbExplorer) deleteRecord(db queryExecutor, tableName, id string) (int, error) {
+	args := &queryArgs{dialect: d.dialect}
+	condition, err := d.primaryKeyCondition(tableName, id, args)
+	if err != nil {
+		return 0, err
+	}
+	if err := d.runHooks(BeforeDelete, tableName, d.primaryKeyValues(tableName, id), nil); err != nil {
+		return 0, err
+	}
+
+	d.auditSnapshot(db, tableName, id, false)
+	var count int
+	// при мягком удалении запись остаётся в таблице с отметкой времени удаления
+	if column, ok := d.softDelete[tableName]; ok {
+		set := d.dialect.quote(column) + " = CURRENT_TIMESTAMP"
+		count, err = d.execUpdate(db, tableName, set, andCondition(condition, d.notDeletedCondition(tableName)), args)
+	} else {
+		count, err = d.execDelete(db, tableName, condition, args)
+	}
+	if err != nil || count == 0 {
+		return count, err
+	}
+	if err := d.runHooks(AfterDelete, tableName, d.primaryKeyValues(tableName, id), nil); err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+func (d DbExplorer) execDelete(db queryExecutor, tableName, condition string, args *queryArgs) (int, error) {
+	query := fmt.Sprintf("DELETE FROM %v WHERE %v", d.dialect.quote(tableName), condition)
+	queryResult, err := d.traced(db, tableName).ExecContext(d.requestContext(), query, args.values...)
+	if err != nil {
+		return 0, err
+	}
 
 	count, err := queryResult.RowsAffected()
-	rowsAffected := int(count)
 	if err != nil {
-		responseResult(rw, err, http.StatusBadRequest, nil)
-		return
+		return 0, err
 	}
 
-	result := map[string]int{"deleted": rowsAffected}
-	responseResult(rw, err, http.StatusOK, result)
-	return
+	d.countRows(int(count))
+	return int(count), nil
 }
 
 //ФУНКЦИИ-ХЕЛПЕРЫ
 func getDataForSqlQuery(r io.Reader, d DbExplorer, tableName string) (map[string]interface{}, error) {
-	buffer, err := ioutil.ReadAll(r)
+	// числа декодируем как json.Number, чтобы не терять точность decimal-колонок
+	requestDataMap := make(map[string]interface{})
+	decoder := json.NewDecoder(r)
+	decoder.UseNumber()
+	if err := decoder.Decode(&requestDataMap); err != nil {
+		return nil, err
+	}
+
+	requestDataMap, err := d.toColumns(tableName, requestDataMap)
 	if err != nil {
 		return nil, err
 	}
+	return d.validateRecord(tableName, requestDataMap)
+}
 
-	requestDataMap := make(map[string]interface{})
-	if err := json.Unmarshal(buffer, &requestDataMap); err != nil {
+// firstJSONByte возвращает первый непробельный байт тела, не извлекая его из буфера
+func firstJSONByte(body *bufio.Reader) (byte, error) {
+	for {
+		next, err := body.Peek(1)
+		if err != nil {
+			return 0, err
+		}
+		switch next[0] {
+		case ' ', '\t', '\r', '\n':
+			body.ReadByte()
+		default:
+			return next[0], nil
+		}
+	}
+}
+
+// bodyErrorStatus выбирает код ответа для ошибки разбора тела: 413, если тело больше WithMaxBodySize
+func bodyErrorStatus(err error) int {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return http.StatusRequestEntityTooLarge
+	}
+	return mutationErrorStatus(err, http.StatusBadRequest)
+}
+
+// validateRecord проверяет типы значений записи из тела запроса и приводит их к аргументам SQL;
+// правила WithValidation проверяются по исходным значениям, но ошибка типа важнее
+func (d DbExplorer) validateRecord(tableName string, requestDataMap map[string]interface{}) (map[string]interface{}, error) {
+	if err := d.checkSensitiveWrite(tableName, requestDataMap); err != nil {
 		return nil, err
 	}
+	fields := d.ruleErrors(tableName, requestDataMap)
 
 	for columnName, column := range d.columnsInTablesMap[tableName] {
 		data, ok := requestDataMap[columnName]
@@ -386,51 +984,108 @@ func getDataForSqlQuery(r io.Reader, d DbExplorer, tableName string) (map[string
 			continue
 		}
 
-		switch column.typeName {
-		case "int":
-			val, ok := data.(float64)
-			if !ok {
-				return nil, errors.New("field " + column.name + " have invalid type")
-			}
-			requestDataMap[columnName] = int(val)
+		if !column.writable() {
+			delete(requestDataMap, columnName)
+			continue
+		}
 
-		case "string":
-			if data == nil {
-				if !d.columnsInTablesMap[tableName][columnName].isNull {
-					return nil, errors.New("field " + column.name + " have invalid type")
-				}
-				requestDataMap[columnName] = nil
-				continue
-			}
+		val, err := d.encodeValue(column, data)
+		if err != nil {
+			return nil, err
+		}
+		requestDataMap[columnName] = val
+	}
 
-			val, ok := data.(string)
-			if !ok {
-				return nil, errors.New("field " + column.name + " have invalid type")
-			}
-			requestDataMap[columnName] = val
+	if len(fields) > 0 {
+		return nil, validationError{fields}
+	}
+	return requestDataMap, nil
+}
 
-		default:
-			delete(requestDataMap, columnName)
+// checkPrimaryKey отвечает 405 для операций над отдельными записями в таблицах без первичного ключа:
+// такие таблицы доступны только в режиме списка
+func (d DbExplorer) checkPrimaryKey(rw http.ResponseWriter, tableName string) bool {
+	if len(d.tableIdNamesMap[tableName]) > 0 {
+		return true
+	}
+
+	rw.Header().Set("Allow", "GET, HEAD, OPTIONS")
+	responseResult(rw, errors.New("table "+tableName+" has no primary key"), http.StatusMethodNotAllowed, nil)
+	return false
+}
+
+// primaryKeyOrder упорядочивает колонки составного первичного ключа primaryKeys в порядке объявления ключа;
+// nil, если диалект порядок не сообщает
+func (d DbExplorer) primaryKeyOrder(tableName string, primaryKeys []string) ([]string, error) {
+	query, args := d.dialect.primaryKeyQuery(tableName)
+	if query == "" {
+		return nil, nil
+	}
+
+	rows, err := d.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := make([]string, 0)
+	for rows.Next() {
+		key := ""
+		if err := rows.Scan(&key); err != nil {
+			return nil, err
+		}
+		if containsString(primaryKeys, key) {
+			keys = append(keys, key)
 		}
 	}
+	return keys, rows.Err()
+}
 
-	return requestDataMap, nil
+// splitRecordId раскладывает сегмент пути на значения ключа: обычный ключ берётся целиком, даже с запятыми,
+// а составной делится по запятым, поэтому запятых в его значениях быть не может, см. checkKeyValues
+func (d DbExplorer) splitRecordId(tableName, rawId string) []string {
+	if len(d.tableIdNamesMap[tableName]) == 1 {
+		return []string{rawId}
+	}
+	return strings.Split(rawId, ",")
+}
+
+// primaryKeyCondition строит условие WHERE по первичному ключу из сегмента пути:
+// "42" для обычного ключа или "123,456" для составного (в порядке колонок ключа).
+// Значения приводятся к типу колонки ключа, так что UUID и строковые ключи тоже работают.
+func (d DbExplorer) primaryKeyCondition(tableName, rawId string, args *queryArgs) (string, error) {
+	primaryKeys := d.tableIdNamesMap[tableName]
+	values := d.splitRecordId(tableName, rawId)
+	if len(values) != len(primaryKeys) {
+		return "", errors.New("invalid record id")
+	}
+
+	conditions := make([]string, 0, len(primaryKeys))
+	for i, key := range primaryKeys {
+		id, err := d.filterValue(d.columnsInTablesMap[tableName][key], values[i])
+		if err != nil || id == nil {
+			return "", errors.New("invalid record id")
+		}
+		conditions = append(conditions, d.dialect.quote(key)+" = "+args.add(id))
+	}
+
+	return strings.Join(conditions, " AND "), nil
 }
 
 func getTableName(url string, tableKeys []string) (string, error) {
 	pathParts := strings.Split(url, "/")
 	if len(pathParts) < 2 {
-		return "", errors.New("unknown table")
+		return "", ErrUnknownTable
 	}
 	for _, tableName := range tableKeys {
 		if tableName == pathParts[1] {
 			return tableName, nil
 		}
 	}
-	return "", errors.New("unknown table")
+	return "", ErrUnknownTable
 }
 
-func parsingSqlQueryResult(queryResult *sql.Rows) ([]map[string]interface{}, error) {
+func (d DbExplorer) parsingSqlQueryResult(queryResult *sql.Rows, tableName string) ([]map[string]interface{}, error) {
 	result := make([]map[string]interface{}, 0)
 	columns, err := queryResult.ColumnTypes()
 	if err != nil {
@@ -438,65 +1093,110 @@ func parsingSqlQueryResult(queryResult *sql.Rows) ([]map[string]interface{}, err
 	}
 
 	for queryResult.Next() {
-		values := make([]interface{}, len(columns))
-		valuePointers := make([]interface{}, len(columns))
-		for i := range columns {
-			valuePointers[i] = &values[i]
-		}
-
-		if err := queryResult.Scan(valuePointers...); err != nil {
+		values, err := d.scanRow(queryResult, columns, tableName)
+		if err != nil {
 			continue
 		}
 
 		record := make(map[string]interface{}, len(columns))
 		for i, columnType := range columns {
-			var value interface{}
-
-			expectedValue := values[i]
-			bytes, ok := expectedValue.([]byte)
-			if ok {
-				stringValue := string(bytes)
-				if columnType.DatabaseTypeName() == "INT" {
-					record[columnType.Name()], _ = strconv.Atoi(stringValue)
-					continue
-				}
-				value = stringValue
-			} else {
-				value = expectedValue
-			}
-
-			record[columnType.Name()] = value
+			record[columnType.Name()] = values[i]
 		}
 
 		result = append(result, record)
 	}
 
 	if len(result) == 0 {
-		return nil, errors.New("record not found")
+		return nil, ErrRecordNotFound
 	}
 	return result, nil
 }
 
+// scanRow читает текущую строку и приводит значения к виду ответа в порядке колонок запроса
+func (d DbExplorer) scanRow(queryResult *sql.Rows, columns []*sql.ColumnType, tableName string) ([]interface{}, error) {
+	values := make([]interface{}, len(columns))
+	valuePointers := make([]interface{}, len(columns))
+	for i := range columns {
+		valuePointers[i] = &values[i]
+	}
+
+	if err := queryResult.Scan(valuePointers...); err != nil {
+		return nil, err
+	}
+
+	for i, columnType := range columns {
+		column := d.columnsInTablesMap[tableName][columnType.Name()]
+		values[i] = d.maskValue(tableName, columnType.Name(), d.decodeValue(column, columnType, values[i]))
+	}
+	return values, nil
+}
+
+// commitResult кодирует ответ до фиксации транзакции: если ответ не кодируется или коммит не прошёл,
+// изменения откатываются и клиент получает ошибку. committed вызывается после коммита до отправки ответа.
+func (d DbExplorer) commitResult(rw http.ResponseWriter, tx *sql.Tx, result interface{}, committed func()) {
+	encoder := encoderFor(responseEncoding(rw))
+	responseMap := map[string]interface{}{"response": result}
+	if warnings := responseWarnings(rw); len(warnings) > 0 {
+		responseMap["warnings"] = warnings
+	}
+	response, err := encoder.encode(responseMap)
+	if err != nil {
+		tx.Rollback()
+		responseResult(rw, err, http.StatusInternalServerError, nil)
+		return
+	}
+	if err := tx.Commit(); err != nil {
+		responseResult(rw, err, http.StatusInternalServerError, nil)
+		return
+	}
+	if committed != nil {
+		committed()
+	}
+
+	rw.Header().Set("Content-Type", encoder.contentType)
+	if _, err := rw.Write(response); err != nil {
+		logResponseError(rw, err)
+	}
+}
+
 func responseResult(rw http.ResponseWriter, err error, httpStatusCode int, result interface{}) {
 	type CR map[string]interface{}
 	responseMap := CR{}
 	textErr := ""
 
+	if err != nil && jsonAPIResponse(rw) {
+		responseJSONAPIError(rw, err, errorStatus(err, httpStatusCode))
+		return
+	}
 	if err != nil {
+		httpStatusCode = errorStatus(err, httpStatusCode)
 		textErr = err.Error()
 		responseMap["error"] = textErr
-		rw.WriteHeader(httpStatusCode)
+	}
+	if err != nil && structuredErrors(rw) {
+		responseMap["error"] = errorEnvelope(err, httpStatusCode)
+	} else if result == nil {
+		result = validationDetails(err)
 	}
 	if result != nil {
 		responseMap["response"] = result
 	}
+	if warnings := responseWarnings(rw); len(warnings) > 0 {
+		responseMap["warnings"] = warnings
+	}
 
-	response, err := json.Marshal(responseMap)
-	if err != nil {
-		fmt.Println(err)
+	encoder := encoderFor(responseEncoding(rw))
+	rw.Header().Set("Content-Type", encoder.contentType)
+	response, encodeErr := encoder.encode(responseMap)
+	if encodeErr != nil {
+		logResponseError(rw, encodeErr)
 	}
 
+	if err != nil {
+		requestLogOf(rw).setError(err)
+		rw.WriteHeader(httpStatusCode)
+	}
 	if _, err := rw.Write(response); err != nil {
-		fmt.Println(err)
+		logResponseError(rw, err)
 	}
 }
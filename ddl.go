@@ -0,0 +1,286 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// ddlState включается WithDDL; mu не даёт двум изменениям схемы обновить её вперемешку
+type ddlState struct {
+	mu sync.Mutex
+}
+
+var identifierPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]{0,63}$`)
+
+// columnSpec — описание колонки в PUT /_tables и POST /{table}/_columns.
+// type — один из типов API: int, bigint, float, decimal, string, text, bool, datetime, json.
+type columnSpec struct {
+	Name          string      `json:"name"`
+	Type          string      `json:"type"`
+	Length        int         `json:"length"`    // для string, по умолчанию 255
+	Precision     int         `json:"precision"` // для decimal, по умолчанию 10
+	Scale         int         `json:"scale"`     // для decimal, по умолчанию 2
+	Nullable      bool        `json:"nullable"`
+	Primary       bool        `json:"primary"`
+	AutoIncrement bool        `json:"auto_increment"`
+	Default       interface{} `json:"default"`
+}
+
+type tableSpec struct {
+	Name    string       `json:"name"`
+	Columns []columnSpec `json:"columns"`
+}
+
+// columnType переводит тип API в тип колонки диалекта
+func (d DbExplorer) columnType(column columnSpec) (string, error) {
+	dialect := d.dialect.name()
+	switch column.Type {
+	case "int":
+		if dialect == "sqlite" {
+			// в SQLite автоинкремент есть только у INTEGER PRIMARY KEY
+			return "INTEGER", nil
+		}
+		return "INT", nil
+	case "bigint":
+		return "BIGINT", nil
+	case "float":
+		if dialect == "sqlserver" {
+			return "FLOAT", nil
+		}
+		return "DOUBLE PRECISION", nil
+	case "decimal":
+		precision, scale := column.Precision, column.Scale
+		if precision == 0 {
+			precision, scale = 10, 2
+		}
+		if precision < 1 || precision > 38 || scale < 0 || scale > precision {
+			return "", errors.New("invalid precision or scale of column " + column.Name)
+		}
+		return "DECIMAL(" + strconv.Itoa(precision) + "," + strconv.Itoa(scale) + ")", nil
+	case "string":
+		length := column.Length
+		if length == 0 {
+			length = 255
+		}
+		if length < 1 || length > 65535 {
+			return "", errors.New("invalid length of column " + column.Name)
+		}
+		return "VARCHAR(" + strconv.Itoa(length) + ")", nil
+	case "text", "json":
+		if dialect == "sqlserver" {
+			return "NVARCHAR(MAX)", nil
+		}
+		if column.Type == "json" {
+			return "JSON", nil
+		}
+		return "TEXT", nil
+	case "bool":
+		if dialect == "sqlserver" {
+			return "BIT", nil
+		}
+		return "BOOLEAN", nil
+	case "datetime":
+		if dialect == "postgres" {
+			return "TIMESTAMP", nil
+		}
+		return "DATETIME", nil
+	}
+	return "", errors.New("unsupported type " + column.Type + " of column " + column.Name)
+}
+
+// sqlLiteral записывает значение литералом SQL там, где плейсхолдеры не работают: в DDL и дампе
+func (d DbExplorer) sqlLiteral(value interface{}) (string, error) {
+	switch value := value.(type) {
+	case json.Number:
+		if _, err := value.Float64(); err != nil {
+			return "", err
+		}
+		return value.String(), nil
+	case bool:
+		if d.dialect.name() == "sqlserver" {
+			if value {
+				return "1", nil
+			}
+			return "0", nil
+		}
+		return strconv.FormatBool(value), nil
+	case string:
+		if d.dialect == MySQL {
+			// без NO_BACKSLASH_ESCAPES обратная косая в MySQL экранирует кавычку
+			value = strings.ReplaceAll(value, `\`, `\\`)
+		}
+		return "'" + strings.ReplaceAll(value, "'", "''") + "'", nil
+	}
+	return "", errors.New("value must be a number, string or boolean")
+}
+
+// columnDefinition собирает определение колонки; первичный ключ объявляется отдельно, см. createTableQuery
+func (d DbExplorer) columnDefinition(column columnSpec) (string, error) {
+	if !identifierPattern.MatchString(column.Name) {
+		return "", errors.New("invalid column name " + column.Name)
+	}
+	columnType, err := d.columnType(column)
+	if err != nil {
+		return "", err
+	}
+
+	definition := d.dialect.quote(column.Name) + " " + columnType
+	if column.AutoIncrement {
+		if !column.Primary || column.Type != "int" && column.Type != "bigint" {
+			return "", errors.New("auto_increment requires an integer primary key column " + column.Name)
+		}
+		switch d.dialect.name() {
+		case "mysql":
+			definition += " NOT NULL AUTO_INCREMENT"
+		case "postgres":
+			definition += " GENERATED BY DEFAULT AS IDENTITY"
+		case "sqlserver":
+			definition += " IDENTITY(1,1)"
+		}
+		return definition, nil
+	}
+
+	if column.Nullable {
+		if column.Primary {
+			return "", errors.New("primary key column " + column.Name + " can not be nullable")
+		}
+		definition += " NULL"
+	} else {
+		definition += " NOT NULL"
+	}
+	if column.Default != nil {
+		literal, err := d.sqlLiteral(column.Default)
+		if err != nil {
+			return "", errors.New("column " + column.Name + ": " + err.Error())
+		}
+		definition += " DEFAULT " + literal
+	}
+	return definition, nil
+}
+
+func (d DbExplorer) createTableQuery(spec tableSpec) (string, error) {
+	if !identifierPattern.MatchString(spec.Name) {
+		return "", errors.New("invalid table name " + spec.Name)
+	}
+	if len(spec.Columns) == 0 {
+		return "", errors.New("columns are required")
+	}
+
+	definitions := make([]string, 0, len(spec.Columns)+1)
+	primaryKeys := make([]string, 0)
+	names := make(map[string]bool, len(spec.Columns))
+	for _, column := range spec.Columns {
+		if names[column.Name] {
+			return "", errors.New("column " + column.Name + " is defined twice")
+		}
+		names[column.Name] = true
+
+		definition, err := d.columnDefinition(column)
+		if err != nil {
+			return "", err
+		}
+		definitions = append(definitions, definition)
+		if column.Primary {
+			primaryKeys = append(primaryKeys, d.dialect.quote(column.Name))
+		}
+	}
+	if len(primaryKeys) > 0 {
+		definitions = append(definitions, "PRIMARY KEY ("+strings.Join(primaryKeys, ", ")+")")
+	}
+
+	return "CREATE TABLE " + d.dialect.quote(spec.Name) + " (" + strings.Join(definitions, ", ") + ");", nil
+}
+
+// execDDL выполняет изменение схемы и перечитывает её, чтобы таблица или колонка сразу появились в API
+func (d DbExplorer) execDDL(query string) (DbExplorer, error) {
+	d.ddl.mu.Lock()
+	defer d.ddl.mu.Unlock()
+
+	if _, err := d.db.ExecContext(d.requestContext(), query); err != nil {
+		return d, err
+	}
+	if err := d.refreshSchema(); err != nil {
+		return d, err
+	}
+	return d.withSchema(), nil
+}
+
+// handlerCreateTable выполняет PUT /_tables: создаёт таблицу по описанию колонок
+func (d DbExplorer) handlerCreateTable(rw http.ResponseWriter, r *http.Request) {
+	if d.ddl == nil {
+		responseResult(rw, errors.New("ddl is disabled"), http.StatusNotFound, nil)
+		return
+	}
+	if err := d.tableAccess(r, "/_tables", http.MethodPut); err != nil {
+		responseResult(rw, err, http.StatusForbidden, nil)
+		return
+	}
+
+	spec := tableSpec{}
+	decoder := json.NewDecoder(r.Body)
+	decoder.UseNumber()
+	if err := decoder.Decode(&spec); err != nil {
+		responseResult(rw, err, bodyErrorStatus(err), nil)
+		return
+	}
+	if containsString(d.tableKeys, spec.Name) {
+		responseResult(rw, errors.New("table "+spec.Name+" already exists"), http.StatusConflict, nil)
+		return
+	}
+
+	query, err := d.createTableQuery(spec)
+	if err != nil {
+		responseResult(rw, err, http.StatusBadRequest, nil)
+		return
+	}
+	if d, err = d.execDDL(query); err != nil {
+		responseResult(rw, err, http.StatusBadRequest, nil)
+		return
+	}
+	responseResult(rw, nil, http.StatusOK, d.tableSchema(spec.Name))
+}
+
+// handlerAddColumn выполняет POST /{table}/_columns: добавляет в таблицу одну колонку
+func (d DbExplorer) handlerAddColumn(rw http.ResponseWriter, r *http.Request, tableName string) {
+	if d.ddl == nil {
+		responseResult(rw, errors.New("ddl is disabled"), http.StatusNotFound, nil)
+		return
+	}
+
+	column := columnSpec{}
+	decoder := json.NewDecoder(r.Body)
+	decoder.UseNumber()
+	if err := decoder.Decode(&column); err != nil {
+		responseResult(rw, err, bodyErrorStatus(err), nil)
+		return
+	}
+	if _, ok := d.columnsInTablesMap[tableName][column.Name]; ok {
+		responseResult(rw, errors.New("column "+column.Name+" already exists"), http.StatusConflict, nil)
+		return
+	}
+	if column.Primary {
+		responseResult(rw, errors.New("primary key can not be changed"), http.StatusBadRequest, nil)
+		return
+	}
+
+	definition, err := d.columnDefinition(column)
+	if err != nil {
+		responseResult(rw, err, http.StatusBadRequest, nil)
+		return
+	}
+	// в SQL Server ADD без COLUMN, остальные диалекты принимают ADD COLUMN
+	add := " ADD COLUMN "
+	if d.dialect.name() == "sqlserver" {
+		add = " ADD "
+	}
+	if d, err = d.execDDL("ALTER TABLE " + d.dialect.quote(tableName) + add + definition + ";"); err != nil {
+		responseResult(rw, err, http.StatusBadRequest, nil)
+		return
+	}
+	responseResult(rw, nil, http.StatusOK, d.tableSchema(tableName))
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestCreateTableQuery(t *testing.T) {
+	spec := tableSpec{
+		Name: "orders",
+		Columns: []columnSpec{
+			{Name: "id", Type: "int", Primary: true, AutoIncrement: true},
+			{Name: "title", Type: "string", Length: 100, Default: "it's"},
+			{Name: "paid", Type: "bool", Default: false},
+			{Name: "amount", Type: "decimal", Nullable: true},
+		},
+	}
+
+	cases := []struct {
+		dialect Dialect
+		query   string
+	}{
+		{MySQL, "CREATE TABLE `orders` (`id` INT NOT NULL AUTO_INCREMENT, `title` VARCHAR(100) NOT NULL DEFAULT 'it''s', `paid` BOOLEAN NOT NULL DEFAULT false, `amount` DECIMAL(10,2) NULL, PRIMARY KEY (`id`));"},
+		{PostgreSQL, `CREATE TABLE "orders" ("id" INT GENERATED BY DEFAULT AS IDENTITY, "title" VARCHAR(100) NOT NULL DEFAULT 'it''s', "paid" BOOLEAN NOT NULL DEFAULT false, "amount" DECIMAL(10,2) NULL, PRIMARY KEY ("id"));`},
+		{SQLite, `CREATE TABLE "orders" ("id" INTEGER, "title" VARCHAR(100) NOT NULL DEFAULT 'it''s', "paid" BOOLEAN NOT NULL DEFAULT false, "amount" DECIMAL(10,2) NULL, PRIMARY KEY ("id"));`},
+		{SQLServer, `CREATE TABLE [orders] ([id] INT IDENTITY(1,1), [title] VARCHAR(100) NOT NULL DEFAULT 'it''s', [paid] BIT NOT NULL DEFAULT 0, [amount] DECIMAL(10,2) NULL, PRIMARY KEY ([id]));`},
+	}
+	for _, item := range cases {
+		query, err := DbExplorer{dialect: item.dialect}.createTableQuery(spec)
+		if err != nil {
+			t.Fatalf("[%s] unexpected error: %v", item.dialect.name(), err)
+		}
+		if query != item.query {
+			t.Fatalf("[%s] query not match\nGot : %s\nWant: %s", item.dialect.name(), query, item.query)
+		}
+	}
+
+	invalid := []tableSpec{
+		{Name: "orders; DROP TABLE users", Columns: spec.Columns},
+		{Name: "orders"},
+		{Name: "orders", Columns: []columnSpec{{Name: "id", Type: "uuid"}}},
+		{Name: "orders", Columns: []columnSpec{{Name: "id", Type: "int"}, {Name: "id", Type: "int"}}},
+		{Name: "orders", Columns: []columnSpec{{Name: "title", Type: "string", AutoIncrement: true}}},
+		{Name: "orders", Columns: []columnSpec{{Name: "id", Type: "int", Primary: true, Nullable: true}}},
+	}
+	for idx, item := range invalid {
+		if _, err := (DbExplorer{dialect: MySQL}).createTableQuery(item); err == nil {
+			t.Fatalf("[case %d] expected error for %+v", idx, item)
+		}
+	}
+
+	if literal, _ := (DbExplorer{dialect: MySQL}).sqlLiteral(`a\' OR 1`); literal != `'a\\'' OR 1'` {
+		t.Fatalf("unexpected escaping of default: %s", literal)
+	}
+}
+
+func TestDDL(t *testing.T) {
+	db, err := sql.Open("mysql", DSN)
+	err = db.Ping()
+	if err != nil {
+		panic(err)
+	}
+
+	PrepareTestApis(db)
+	defer CleanupTestApis(db)
+	defer db.Exec(`DROP TABLE IF EXISTS orders;`)
+
+	handler, err := NewDbExplorer(db, WithDDL(true))
+	if err != nil {
+		panic(err)
+	}
+
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	cases := []struct {
+		method string
+		path   string
+		body   interface{}
+		status int
+		result string
+	}{
+		{
+			method: http.MethodPut,
+			path:   "/_tables",
+			body: CR{"name": "orders", "columns": []CR{
+				{"name": "id", "type": "int", "primary": true, "auto_increment": true},
+				{"name": "title", "type": "string"},
+			}},
+			status: http.StatusOK,
+			result: `{"response":{"columns":[{"comment":"","db_type":"int","default":null,"name":"id","nullable":false,"primary":true,"type":"int"},{"comment":"","db_type":"varchar(255)","default":null,"name":"title","nullable":false,"primary":false,"type":"string"}],"primary_key":["id"],"table":"orders"}}`,
+		},
+		{
+			method: http.MethodPut,
+			path:   "/orders",
+			body:   CR{"title": "first"},
+			status: http.StatusOK,
+			result: `{"response":{"id":1}}`,
+		},
+		{
+			method: http.MethodPost,
+			path:   "/orders/_columns",
+			body:   CR{"name": "amount", "type": "int", "default": 5},
+			status: http.StatusOK,
+		},
+		{
+			method: http.MethodGet,
+			path:   "/orders/1",
+			status: http.StatusOK,
+			result: `{"response":{"record":{"amount":5,"id":1,"title":"first"}}}`,
+		},
+		{
+			method: http.MethodPut,
+			path:   "/_tables",
+			body:   CR{"name": "orders", "columns": []CR{{"name": "id", "type": "int"}}},
+			status: http.StatusConflict,
+			result: `{"error":"table orders already exists"}`,
+		},
+		{
+			method: http.MethodPost,
+			path:   "/orders/_columns",
+			body:   CR{"name": "amount", "type": "int"},
+			status: http.StatusConflict,
+			result: `{"error":"column amount already exists"}`,
+		},
+	}
+
+	for idx, item := range cases {
+		var body []byte
+		if item.body != nil {
+			body, _ = json.Marshal(item.body)
+		}
+		req, _ := http.NewRequest(item.method, ts.URL+item.path, bytes.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("[case %d] request error: %v", idx, err)
+		}
+		respBody, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if resp.StatusCode != item.status {
+			t.Fatalf("[case %d: %s %s] expected http status %v, got %v: %s", idx, item.method, item.path, item.status, resp.StatusCode, respBody)
+		}
+		if item.result != "" && string(respBody) != item.result {
+			t.Fatalf("[case %d: %s %s] results not match\nGot : %s\nWant: %s", idx, item.method, item.path, respBody, item.result)
+		}
+	}
+
+	disabled, err := NewDbExplorer(db)
+	if err != nil {
+		panic(err)
+	}
+	rw := httptest.NewRecorder()
+	disabled.ServeHTTP(rw, httptest.NewRequest(http.MethodPut, "/_tables", bytes.NewReader([]byte(`{}`))))
+	if rw.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 with ddl disabled, got %d", rw.Code)
+	}
+}
@@ -0,0 +1,415 @@
+package main
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Dialect описывает отличия SQL конкретной СУБД: интроспекцию схемы,
+// плейсхолдеры, квотирование идентификаторов и получение id вставленной записи.
+type Dialect interface {
+	name() string
+	placeholder(n int) string
+	quote(ident string) string
+	tablesQuery() string
+	columnsQuery(tableName string) (string, []interface{})
+	// колонки первичного ключа в порядке объявления ключа; пустой запрос — порядок колонок таблицы
+	primaryKeyQuery(tableName string) (string, []interface{})
+	foreignKeysQuery() string
+	limitOffset(limit, offset string, ordered bool) string
+	explain(query string) string
+	// имя таблицы и колонки в insertQuery приходят уже в кавычках, idColumn — без
+	insertQuery(tableName, columns, values, idColumn string) (query string, returning bool)
+	writable() bool
+}
+
+var (
+	MySQL      Dialect = mysqlDialect{}
+	PostgreSQL Dialect = postgresDialect{}
+	SQLite     Dialect = sqliteDialect{}
+	SQLServer  Dialect = mssqlDialect{}
+	ClickHouse Dialect = clickhouseDialect{}
+)
+
+// checkIdentifier проверяет имя таблицы или колонки из схемы перед тем, как оно попадёт в SQL:
+// кавычки внутри имени экранирует quote, а пустое имя, NUL и битый UTF-8 процитировать нельзя
+func checkIdentifier(name string) error {
+	if name == "" || strings.ContainsRune(name, 0) || !utf8.ValidString(name) {
+		return errors.New("invalid identifier " + strconv.Quote(name))
+	}
+	return nil
+}
+
+// detectDialect подбирает диалект по типу драйвера, с которым открыт *sql.DB.
+func detectDialect(db *sql.DB) Dialect {
+	driverType := fmt.Sprintf("%T", db.Driver())
+	switch {
+	case strings.HasPrefix(driverType, "*pq."), strings.HasPrefix(driverType, "*stdlib."):
+		return PostgreSQL
+	case strings.HasPrefix(driverType, "*sqlite3."), strings.HasPrefix(driverType, "*sqlite."):
+		return SQLite
+	case strings.HasPrefix(driverType, "*mssql."):
+		return SQLServer
+	case strings.HasPrefix(driverType, "*clickhouse."):
+		return ClickHouse
+	default:
+		return MySQL
+	}
+}
+
+type mysqlDialect struct{}
+
+func (mysqlDialect) name() string { return "mysql" }
+
+func (mysqlDialect) writable() bool { return true }
+
+func (mysqlDialect) placeholder(n int) string { return "?" }
+
+func (mysqlDialect) quote(ident string) string {
+	return "`" + strings.ReplaceAll(ident, "`", "``") + "`"
+}
+
+func (mysqlDialect) tablesQuery() string { return "SHOW TABLES;" }
+
+func (mysqlDialect) columnsQuery(tableName string) (string, []interface{}) {
+	return "SHOW FULL COLUMNS FROM " + mysqlDialect{}.quote(tableName), nil
+}
+
+func (mysqlDialect) primaryKeyQuery(tableName string) (string, []interface{}) {
+	return `SELECT COLUMN_NAME FROM information_schema.KEY_COLUMN_USAGE
+WHERE TABLE_SCHEMA = DATABASE() AND TABLE_NAME = ? AND CONSTRAINT_NAME = 'PRIMARY'
+ORDER BY ORDINAL_POSITION;`, []interface{}{tableName}
+}
+
+// внешние ключи отдаются колонками constraint, table, column, referenced_table, referenced_column
+func (mysqlDialect) foreignKeysQuery() string {
+	return `SELECT CONSTRAINT_NAME, TABLE_NAME, COLUMN_NAME, REFERENCED_TABLE_NAME, REFERENCED_COLUMN_NAME
+FROM information_schema.KEY_COLUMN_USAGE
+WHERE TABLE_SCHEMA = DATABASE() AND REFERENCED_TABLE_NAME IS NOT NULL
+ORDER BY TABLE_NAME, CONSTRAINT_NAME, ORDINAL_POSITION;`
+}
+
+func (mysqlDialect) limitOffset(limit, offset string, ordered bool) string {
+	return "LIMIT " + limit + " OFFSET " + offset
+}
+
+func (mysqlDialect) explain(query string) string {
+	return "EXPLAIN " + query
+}
+
+func (mysqlDialect) insertQuery(tableName, columns, values, idColumn string) (string, bool) {
+	return fmt.Sprintf("INSERT INTO %v (%v) VALUES (%v);", tableName, columns, values), false
+}
+
+type postgresDialect struct{}
+
+func (postgresDialect) name() string { return "postgres" }
+
+func (postgresDialect) writable() bool { return true }
+
+func (postgresDialect) placeholder(n int) string { return fmt.Sprintf("$%d", n) }
+
+func (postgresDialect) quote(ident string) string {
+	return `"` + strings.ReplaceAll(ident, `"`, `""`) + `"`
+}
+
+func (postgresDialect) tablesQuery() string {
+	return `SELECT table_name FROM information_schema.tables
+WHERE table_schema = current_schema() AND table_type = 'BASE TABLE'
+ORDER BY table_name;`
+}
+
+// колонки отдаются под теми же именами, что и в SHOW FULL COLUMNS у MySQL, в Extra — признак
+// GENERATED-колонки в тех же словах
+func (postgresDialect) columnsQuery(tableName string) (string, []interface{}) {
+	return `SELECT c.column_name AS "Field", c.data_type AS "Type", c.is_nullable AS "Null",
+	CASE WHEN pk.column_name IS NULL THEN '' ELSE 'PRI' END AS "Key",
+	c.column_default AS "Default",
+	CASE WHEN c.is_generated = 'ALWAYS' THEN 'STORED GENERATED' ELSE '' END AS "Extra",
+	col_description((quote_ident(c.table_schema) || '.' || quote_ident(c.table_name))::regclass, c.ordinal_position) AS "Comment"
+FROM information_schema.columns c
+LEFT JOIN (
+	SELECT kcu.table_name, kcu.column_name
+	FROM information_schema.table_constraints tc
+	JOIN information_schema.key_column_usage kcu
+		ON kcu.constraint_name = tc.constraint_name AND kcu.table_schema = tc.table_schema
+	WHERE tc.constraint_type = 'PRIMARY KEY' AND tc.table_schema = current_schema()
+) pk ON pk.table_name = c.table_name AND pk.column_name = c.column_name
+WHERE c.table_schema = current_schema() AND c.table_name = $1
+ORDER BY c.ordinal_position;`, []interface{}{tableName}
+}
+
+func (postgresDialect) primaryKeyQuery(tableName string) (string, []interface{}) {
+	return `SELECT kcu.column_name
+FROM information_schema.table_constraints tc
+JOIN information_schema.key_column_usage kcu
+	ON kcu.constraint_name = tc.constraint_name AND kcu.table_schema = tc.table_schema
+WHERE tc.constraint_type = 'PRIMARY KEY' AND tc.table_schema = current_schema() AND tc.table_name = $1
+ORDER BY kcu.ordinal_position;`, []interface{}{tableName}
+}
+
+func (postgresDialect) foreignKeysQuery() string {
+	return `SELECT kcu.constraint_name, kcu.table_name, kcu.column_name, ref.table_name, ref.column_name
+FROM information_schema.referential_constraints rc
+JOIN information_schema.key_column_usage kcu
+	ON kcu.constraint_name = rc.constraint_name AND kcu.constraint_schema = rc.constraint_schema
+JOIN information_schema.key_column_usage ref
+	ON ref.constraint_name = rc.unique_constraint_name AND ref.constraint_schema = rc.unique_constraint_schema
+	AND ref.ordinal_position = kcu.position_in_unique_constraint
+WHERE kcu.table_schema = current_schema()
+ORDER BY kcu.table_name, kcu.constraint_name, kcu.ordinal_position;`
+}
+
+func (postgresDialect) limitOffset(limit, offset string, ordered bool) string {
+	return "LIMIT " + limit + " OFFSET " + offset
+}
+
+func (postgresDialect) explain(query string) string {
+	return "EXPLAIN " + query
+}
+
+func (d postgresDialect) insertQuery(tableName, columns, values, idColumn string) (string, bool) {
+	if columns == "" {
+		return fmt.Sprintf("INSERT INTO %v DEFAULT VALUES RETURNING %v;", tableName, d.quote(idColumn)), true
+	}
+	return fmt.Sprintf("INSERT INTO %v (%v) VALUES (%v) RETURNING %v;", tableName, columns, values, d.quote(idColumn)), true
+}
+
+type sqliteDialect struct{}
+
+func (sqliteDialect) name() string { return "sqlite" }
+
+func (sqliteDialect) writable() bool { return true }
+
+func (sqliteDialect) placeholder(n int) string { return "?" }
+
+func (sqliteDialect) quote(ident string) string {
+	return `"` + strings.ReplaceAll(ident, `"`, `""`) + `"`
+}
+
+func (sqliteDialect) tablesQuery() string {
+	return "SELECT name FROM sqlite_master WHERE type = 'table' AND name NOT LIKE 'sqlite_%' ORDER BY name;"
+}
+
+func (sqliteDialect) columnsQuery(tableName string) (string, []interface{}) {
+	return `SELECT name AS "Field", type AS "Type",
+	CASE "notnull" WHEN 0 THEN 'YES' ELSE 'NO' END AS "Null",
+	CASE WHEN pk > 0 THEN 'PRI' ELSE '' END AS "Key",
+	dflt_value AS "Default",
+	CASE hidden WHEN 2 THEN 'VIRTUAL GENERATED' WHEN 3 THEN 'STORED GENERATED' ELSE '' END AS "Extra",
+	'' AS "Comment"
+FROM pragma_table_xinfo(?)
+WHERE hidden <> 1
+ORDER BY cid;`, []interface{}{tableName}
+}
+
+// pk в pragma_table_info — номер колонки внутри первичного ключа, начиная с 1
+func (sqliteDialect) primaryKeyQuery(tableName string) (string, []interface{}) {
+	return "SELECT name FROM pragma_table_info(?) WHERE pk > 0 ORDER BY pk;", []interface{}{tableName}
+}
+
+// "to" пуст, если ключ ссылается на первичный ключ неявно, см. loadForeignKeys
+func (sqliteDialect) foreignKeysQuery() string {
+	return `SELECT CAST(f.id AS TEXT), m.name, f."from", f."table", f."to"
+FROM sqlite_master m JOIN pragma_foreign_key_list(m.name) f
+WHERE m.type = 'table'
+ORDER BY m.name, f.id, f.seq;`
+}
+
+func (sqliteDialect) limitOffset(limit, offset string, ordered bool) string {
+	return "LIMIT " + limit + " OFFSET " + offset
+}
+
+func (sqliteDialect) explain(query string) string {
+	return "EXPLAIN QUERY PLAN " + query
+}
+
+func (sqliteDialect) insertQuery(tableName, columns, values, idColumn string) (string, bool) {
+	if columns == "" {
+		return fmt.Sprintf("INSERT INTO %v DEFAULT VALUES;", tableName), false
+	}
+	return fmt.Sprintf("INSERT INTO %v (%v) VALUES (%v);", tableName, columns, values), false
+}
+
+type mssqlDialect struct{}
+
+func (mssqlDialect) name() string { return "sqlserver" }
+
+func (mssqlDialect) writable() bool { return true }
+
+func (mssqlDialect) placeholder(n int) string { return fmt.Sprintf("@p%d", n) }
+
+func (mssqlDialect) quote(ident string) string {
+	return "[" + strings.ReplaceAll(ident, "]", "]]") + "]"
+}
+
+func (mssqlDialect) tablesQuery() string {
+	return "SELECT name FROM sys.tables WHERE is_ms_shipped = 0 AND schema_id = SCHEMA_ID() ORDER BY name;"
+}
+
+func (mssqlDialect) columnsQuery(tableName string) (string, []interface{}) {
+	return `SELECT c.name AS [Field], ty.name AS [Type],
+	CASE c.is_nullable WHEN 1 THEN 'YES' ELSE 'NO' END AS [Null],
+	CASE WHEN pk.column_id IS NULL THEN '' ELSE 'PRI' END AS [Key],
+	OBJECT_DEFINITION(c.default_object_id) AS [Default],
+	CASE WHEN c.is_computed = 1 OR ty.name IN ('timestamp', 'rowversion') THEN 'STORED GENERATED' ELSE '' END AS [Extra],
+	CAST(ep.value AS nvarchar(4000)) AS [Comment]
+FROM sys.columns c
+JOIN sys.types ty ON ty.user_type_id = c.user_type_id
+LEFT JOIN sys.extended_properties ep
+	ON ep.major_id = c.object_id AND ep.minor_id = c.column_id AND ep.name = 'MS_Description'
+LEFT JOIN (
+	SELECT ic.object_id, ic.column_id
+	FROM sys.indexes i
+	JOIN sys.index_columns ic ON ic.object_id = i.object_id AND ic.index_id = i.index_id
+	WHERE i.is_primary_key = 1
+) pk ON pk.object_id = c.object_id AND pk.column_id = c.column_id
+WHERE c.object_id = OBJECT_ID(@p1)
+ORDER BY c.column_id;`, []interface{}{tableName}
+}
+
+func (mssqlDialect) primaryKeyQuery(tableName string) (string, []interface{}) {
+	return `SELECT c.name
+FROM sys.indexes i
+JOIN sys.index_columns ic ON ic.object_id = i.object_id AND ic.index_id = i.index_id
+JOIN sys.columns c ON c.object_id = ic.object_id AND c.column_id = ic.column_id
+WHERE i.is_primary_key = 1 AND i.object_id = OBJECT_ID(@p1)
+ORDER BY ic.key_ordinal;`, []interface{}{tableName}
+}
+
+func (mssqlDialect) foreignKeysQuery() string {
+	return `SELECT OBJECT_NAME(fkc.constraint_object_id), OBJECT_NAME(fkc.parent_object_id), pc.name,
+	OBJECT_NAME(fkc.referenced_object_id), rc.name
+FROM sys.foreign_key_columns fkc
+JOIN sys.columns pc ON pc.object_id = fkc.parent_object_id AND pc.column_id = fkc.parent_column_id
+JOIN sys.columns rc ON rc.object_id = fkc.referenced_object_id AND rc.column_id = fkc.referenced_column_id
+WHERE OBJECT_SCHEMA_NAME(fkc.parent_object_id) = SCHEMA_NAME()
+ORDER BY 2, 1, fkc.constraint_column_id;`
+}
+
+// OFFSET-FETCH в SQL Server допустим только после ORDER BY
+func (mssqlDialect) limitOffset(limit, offset string, ordered bool) string {
+	clause := "OFFSET " + offset + " ROWS FETCH NEXT " + limit + " ROWS ONLY"
+	if !ordered {
+		clause = "ORDER BY (SELECT NULL) " + clause
+	}
+	return clause
+}
+
+func (mssqlDialect) explain(query string) string {
+	// план в SQL Server включается отдельной командой SET SHOWPLAN_ALL для всего соединения
+	return ""
+}
+
+func (d mssqlDialect) insertQuery(tableName, columns, values, idColumn string) (string, bool) {
+	if columns == "" {
+		return fmt.Sprintf("INSERT INTO %v OUTPUT INSERTED.%v DEFAULT VALUES;", tableName, d.quote(idColumn)), true
+	}
+	return fmt.Sprintf("INSERT INTO %v (%v) OUTPUT INSERTED.%v VALUES (%v);", tableName, columns, d.quote(idColumn), values), true
+}
+
+// clickhouseDialect только для чтения: таблицы отдаются через GET, запись отключена
+type clickhouseDialect struct{}
+
+func (clickhouseDialect) name() string { return "clickhouse" }
+
+func (clickhouseDialect) writable() bool { return false }
+
+func (clickhouseDialect) placeholder(n int) string { return "?" }
+
+func (clickhouseDialect) quote(ident string) string {
+	return "`" + strings.ReplaceAll(ident, "`", "\\`") + "`"
+}
+
+func (clickhouseDialect) tablesQuery() string {
+	return "SELECT name FROM system.tables WHERE database = currentDatabase() ORDER BY name;"
+}
+
+func (clickhouseDialect) columnsQuery(tableName string) (string, []interface{}) {
+	return `SELECT name AS "Field",
+	replaceRegexpAll(type, '^(LowCardinality\\()?(Nullable\\()?([^)]*)\\)*$', '\\3') AS "Type",
+	if(match(type, 'Nullable\\('), 'YES', 'NO') AS "Null",
+	if(is_in_primary_key, 'PRI', '') AS "Key",
+	default_expression AS "Default",
+	if(default_kind IN ('MATERIALIZED', 'ALIAS'), 'VIRTUAL GENERATED', '') AS "Extra",
+	comment AS "Comment"
+FROM system.columns
+WHERE database = currentDatabase() AND table = ?
+ORDER BY position;`, []interface{}{tableName}
+}
+
+// адресация записей ClickHouse идёт по колонкам ключа сортировки в порядке колонок таблицы
+func (clickhouseDialect) primaryKeyQuery(tableName string) (string, []interface{}) { return "", nil }
+
+// внешних ключей в ClickHouse нет
+func (clickhouseDialect) foreignKeysQuery() string { return "" }
+
+func (clickhouseDialect) limitOffset(limit, offset string, ordered bool) string {
+	return "LIMIT " + limit + " OFFSET " + offset
+}
+
+func (clickhouseDialect) explain(query string) string {
+	return "EXPLAIN " + query
+}
+
+func (clickhouseDialect) insertQuery(tableName, columns, values, idColumn string) (string, bool) {
+	return fmt.Sprintf("INSERT INTO %v (%v) VALUES (%v);", tableName, columns, values), false
+}
+
+// queryArgs собирает аргументы запроса и выдаёт плейсхолдеры с правильной нумерацией
+type queryArgs struct {
+	dialect Dialect
+	values  []interface{}
+}
+
+func (a *queryArgs) add(value interface{}) string {
+	a.values = append(a.values, value)
+	return a.dialect.placeholder(len(a.values))
+}
+
+// computedColumn сообщает по полю Extra интроспекции, что значение колонки вычисляет база:
+// GENERATED (VIRTUAL или STORED) или ON UPDATE CURRENT_TIMESTAMP. DEFAULT_GENERATED у MySQL 8 —
+// обычный DEFAULT с выражением, в такую колонку писать можно.
+func computedColumn(extra string) bool {
+	extra = strings.ToLower(extra)
+	if strings.Contains(extra, "on update") {
+		return true
+	}
+	return strings.Contains(strings.ReplaceAll(extra, "default_generated", ""), "generated")
+}
+
+// normalizeColumnType сводит тип колонки из интроспекции к типам, с которыми работает explorer
+func normalizeColumnType(dbType string) string {
+	typeName := strings.ToLower(strings.TrimSpace(dbType))
+	if i := strings.IndexAny(typeName, "( "); i != -1 {
+		typeName = typeName[:i]
+	}
+
+	switch typeName {
+	case "int", "integer", "bigint", "smallint", "mediumint", "tinyint", "serial", "bigserial",
+		"int2", "int4", "int8", "int16", "int32", "int64", "uint8", "uint16", "uint32", "uint64":
+		return "int"
+	case "float", "double", "real", "float4", "float8", "float32", "float64":
+		return "float"
+	case "decimal", "numeric", "money":
+		return "decimal"
+	case "bool", "boolean":
+		return "bool"
+	case "json", "jsonb":
+		return "json"
+	case "blob", "tinyblob", "mediumblob", "longblob", "binary", "varbinary", "bytea", "image":
+		return "binary"
+	case "uuid", "uniqueidentifier":
+		return "string"
+	}
+
+	if strings.Contains(typeName, "text") || strings.Contains(typeName, "char") {
+		return "string"
+	}
+
+	return typeName
+}
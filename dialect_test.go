@@ -0,0 +1,126 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestNormalizeColumnType(t *testing.T) {
+	cases := map[string]string{
+		"int":               "int",
+		"int(11)":           "int",
+		"int(10) unsigned":  "int",
+		"integer":           "int",
+		"bigint":            "int",
+		"UInt8":             "int",
+		"uint64":            "int",
+		"UInt16":            "int",
+		"varchar(255)":      "string",
+		"text":              "string",
+		"character varying": "string",
+		"TEXT":              "string",
+		"uuid":              "string",
+		"uniqueidentifier":  "string",
+		"datetime":          "datetime",
+	}
+
+	for dbType, expected := range cases {
+		if got := normalizeColumnType(dbType); got != expected {
+			t.Fatalf("[%s] expected %s, got %s", dbType, expected, got)
+		}
+	}
+}
+
+func TestComputedColumn(t *testing.T) {
+	cases := map[string]bool{
+		"":                  false,
+		"auto_increment":    false,
+		"VIRTUAL GENERATED": true,
+		"STORED GENERATED":  true,
+		"DEFAULT_GENERATED": false,
+		"DEFAULT_GENERATED on update CURRENT_TIMESTAMP": true,
+		"on update CURRENT_TIMESTAMP(3)":                true,
+	}
+
+	for extra, expected := range cases {
+		if got := computedColumn(extra); got != expected {
+			t.Fatalf("[%s] expected %v, got %v", extra, expected, got)
+		}
+	}
+}
+
+func TestDialectPlaceholders(t *testing.T) {
+	args := &queryArgs{dialect: PostgreSQL}
+	query := "SELECT * FROM items " + PostgreSQL.limitOffset(args.add(5), args.add(0), false)
+	if query != "SELECT * FROM items LIMIT $1 OFFSET $2" {
+		t.Fatalf("unexpected query: %s", query)
+	}
+	if len(args.values) != 2 || args.values[0] != 5 || args.values[1] != 0 {
+		t.Fatalf("unexpected args: %#v", args.values)
+	}
+
+	query, returning := PostgreSQL.insertQuery("items", `"title"`, "$1", "id")
+	if !returning || query != `INSERT INTO items ("title") VALUES ($1) RETURNING "id";` {
+		t.Fatalf("unexpected insert query: %s", query)
+	}
+
+	query, returning = MySQL.insertQuery("items", "`title`", "?", "id")
+	if returning || query != "INSERT INTO items (`title`) VALUES (?);" {
+		t.Fatalf("unexpected insert query: %s", query)
+	}
+
+	// все колонки получают DEFAULT
+	query, _ = PostgreSQL.insertQuery("items", "", "", "id")
+	if query != `INSERT INTO items DEFAULT VALUES RETURNING "id";` {
+		t.Fatalf("unexpected insert query: %s", query)
+	}
+	query, _ = SQLite.insertQuery("items", "", "", "id")
+	if query != `INSERT INTO items DEFAULT VALUES;` {
+		t.Fatalf("unexpected insert query: %s", query)
+	}
+}
+
+func TestSQLServerDialect(t *testing.T) {
+	args := &queryArgs{dialect: SQLServer}
+	query := "SELECT * FROM items " + SQLServer.limitOffset(args.add(5), args.add(10), false)
+	if query != "SELECT * FROM items ORDER BY (SELECT NULL) OFFSET @p2 ROWS FETCH NEXT @p1 ROWS ONLY" {
+		t.Fatalf("unexpected query: %s", query)
+	}
+
+	query = "SELECT * FROM items ORDER BY [id] ASC " + SQLServer.limitOffset("@p1", "@p2", true)
+	if query != "SELECT * FROM items ORDER BY [id] ASC OFFSET @p2 ROWS FETCH NEXT @p1 ROWS ONLY" {
+		t.Fatalf("unexpected query: %s", query)
+	}
+
+	if quoted := SQLServer.quote("order]s"); quoted != "[order]]s]" {
+		t.Fatalf("unexpected quoting: %s", quoted)
+	}
+
+	query, returning := SQLServer.insertQuery("items", "[title]", "@p1", "id")
+	if !returning || query != "INSERT INTO items ([title]) OUTPUT INSERTED.[id] VALUES (@p1);" {
+		t.Fatalf("unexpected insert query: %s", query)
+	}
+
+	query, _ = SQLServer.insertQuery("items", "", "", "id")
+	if query != "INSERT INTO items OUTPUT INSERTED.[id] DEFAULT VALUES;" {
+		t.Fatalf("unexpected insert query: %s", query)
+	}
+}
+
+func TestClickHouseDialectReadOnly(t *testing.T) {
+	if ClickHouse.writable() {
+		t.Fatalf("clickhouse dialect must be read-only")
+	}
+
+	for _, dialect := range []Dialect{MySQL, PostgreSQL, SQLite, SQLServer} {
+		if !dialect.writable() {
+			t.Fatalf("[%s] dialect must be writable", dialect.name())
+		}
+	}
+
+	if got := normalizeColumnType("UInt64"); got != "int" {
+		t.Fatalf("expected int for UInt64, got %s", got)
+	}
+	if got := normalizeColumnType("String"); got != "string" {
+		t.Fatalf("expected string for String, got %s", got)
+	}
+}
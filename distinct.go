@@ -0,0 +1,86 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+const defaultDistinctLimit = 100
+
+var distinctParams = map[string]bool{"limit": true, "counts": true}
+
+// handlerDistinct выполняет GET /{table}/distinct/{column}?limit=100: уникальные значения колонки
+// по возрастанию, например для выпадающих списков фильтров. С ?counts=true значения приходят вместе
+// с числом записей и сортируются от частых к редким. Остальные параметры работают как фильтры списка.
+func (d DbExplorer) handlerDistinct(rw http.ResponseWriter, r *http.Request, tableName, field string) {
+	column, err := d.aggregateColumn(tableName, field)
+	if err != nil {
+		responseResult(rw, err, http.StatusNotFound, nil)
+		return
+	}
+
+	params := r.URL.Query()
+	limit, err := strconv.Atoi(params.Get("limit"))
+	if err != nil || limit <= 0 {
+		limit = defaultDistinctLimit
+	}
+	if d.maxLimit > 0 && limit > d.maxLimit {
+		responseResult(rw, errors.New("limit must not exceed "+strconv.Itoa(d.maxLimit)), http.StatusBadRequest, nil)
+		return
+	}
+	counts, _ := strconv.ParseBool(params.Get("counts"))
+
+	filters := url.Values{}
+	for key, values := range params {
+		if !distinctParams[key] {
+			filters[key] = values
+		}
+	}
+	args := &queryArgs{dialect: d.dialect}
+	condition, err := d.filterCondition(tableName, filters, args)
+	if err != nil {
+		responseResult(rw, err, http.StatusBadRequest, nil)
+		return
+	}
+	condition = andCondition(condition, d.visibleCondition(tableName))
+
+	quotedColumn := d.dialect.quote(column.name)
+	query := "SELECT " + quotedColumn
+	order := " ORDER BY " + quotedColumn
+	if counts {
+		query += ", COUNT(*) AS " + d.dialect.quote("count")
+		order = " ORDER BY " + d.dialect.quote("count") + " DESC, " + quotedColumn
+	}
+	query += " FROM " + d.dialect.quote(tableName)
+	if condition != "" {
+		query += " WHERE " + condition
+	}
+	query += " GROUP BY " + quotedColumn + order + " " + d.dialect.limitOffset(args.add(limit), args.add(0), true)
+
+	queryResult, err := d.traced(d.db, tableName).QueryContext(d.requestContext(), query+";", args.values...)
+	if err != nil {
+		responseResult(rw, err, http.StatusInternalServerError, nil)
+		return
+	}
+	records, err := d.parsingSqlQueryResult(queryResult, tableName)
+	if err == ErrRecordNotFound {
+		records, err = []map[string]interface{}{}, nil
+	}
+	if err != nil {
+		responseResult(rw, err, http.StatusInternalServerError, nil)
+		return
+	}
+
+	values := make([]interface{}, 0, len(records))
+	for _, record := range records {
+		if counts {
+			values = append(values, map[string]interface{}{"value": record[column.name], "count": record["count"]})
+		} else {
+			values = append(values, record[column.name])
+		}
+	}
+	d.countRows(len(records))
+	responseResult(rw, nil, http.StatusOK, map[string]interface{}{"values": values})
+}
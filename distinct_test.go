@@ -0,0 +1,78 @@
+package main
+
+import (
+	"database/sql"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDistinct(t *testing.T) {
+	db, err := sql.Open("mysql", DSN)
+	err = db.Ping()
+	if err != nil {
+		panic(err)
+	}
+
+	PrepareTestApis(db)
+	defer CleanupTestApis(db)
+
+	db.Exec("INSERT INTO items (id, title, description, updated) VALUES (3, 'grpc', '', 'rvasily'), (4, 'kafka', '', 'admin')")
+
+	handler, err := NewDbExplorer(db)
+	if err != nil {
+		panic(err)
+	}
+
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	cases := []struct {
+		path   string
+		status int
+		result string
+	}{
+		{
+			path:   "/items/distinct/updated",
+			status: http.StatusOK,
+			result: `{"response":{"values":[null,"admin","rvasily"]}}`,
+		},
+		{
+			path:   "/items/distinct/updated?counts=true&limit=2",
+			status: http.StatusOK,
+			result: `{"response":{"values":[{"count":2,"value":"rvasily"},{"count":1,"value":null}]}}`,
+		},
+		{
+			path:   "/items/distinct/updated?id=4",
+			status: http.StatusOK,
+			result: `{"response":{"values":["admin"]}}`,
+		},
+		{
+			path:   "/items/distinct/updated?id=100",
+			status: http.StatusOK,
+			result: `{"response":{"values":[]}}`,
+		},
+		{
+			path:   "/items/distinct/author",
+			status: http.StatusNotFound,
+			result: `{"error":"unknown field author"}`,
+		},
+	}
+
+	for idx, item := range cases {
+		resp, err := client.Get(ts.URL + item.path)
+		if err != nil {
+			t.Fatalf("[case %d] request error: %v", idx, err)
+		}
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if resp.StatusCode != item.status {
+			t.Fatalf("[case %d: %s] expected http status %v, got %v: %s", idx, item.path, item.status, resp.StatusCode, body)
+		}
+		if string(body) != item.result {
+			t.Fatalf("[case %d: %s] results not match\nGot : %s\nWant: %s", idx, item.path, body, item.result)
+		}
+	}
+}
@@ -0,0 +1,236 @@
+package main
+
+import (
+	"bufio"
+	"compress/gzip"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// handlerDump отдаёт GET /{table}/dump и GET /admin/dump: потоковую выгрузку таблиц для резервной копии.
+// ?format=sql (по умолчанию) — INSERT'ы без DDL в порядке внешних ключей, ?format=json — записи
+// в том же виде, что в API (дамп одной таблицы подходит как файл фикстур), ?gzip=true сжимает файл.
+// В выгрузку попадают и мягко удалённые записи. Пустой tableName — дамп всех таблиц tables.
+func (d DbExplorer) handlerDump(rw http.ResponseWriter, r *http.Request, tables []string, tableName string) {
+	params := r.URL.Query()
+	format := params.Get("format")
+	if format == "" {
+		format = "sql"
+	}
+	if format != "sql" && format != "json" {
+		responseResult(rw, errors.New("unknown format "+format), http.StatusBadRequest, nil)
+		return
+	}
+	if d.masked {
+		// в дампе были бы значения, скрытые маскированием
+		responseResult(rw, errors.New("dump is not available with masking"), http.StatusForbidden, nil)
+		return
+	}
+	compressed, _ := strconv.ParseBool(params.Get("gzip"))
+
+	order, err := d.tableOrder(tables)
+	if err != nil {
+		// при циклических ссылках порядок не спасёт: загружать такой дамп нужно с отключенной проверкой ключей
+		order = tables
+	}
+	d.includeDeleted = true
+
+	single := tableName != ""
+	filename := "dump." + format
+	if single {
+		filename = tableName + "." + format
+	}
+	contentType := "application/sql; charset=utf-8"
+	if format == "json" {
+		contentType = "application/json"
+	}
+	var out io.Writer = rw
+	var compressor *gzip.Writer
+	if compressed {
+		filename += ".gz"
+		contentType = "application/gzip"
+		compressor = gzip.NewWriter(rw)
+		out = compressor
+	}
+	rw.Header().Set("Content-Type", contentType)
+	rw.Header().Set("Content-Disposition", `attachment; filename="`+filename+`"`)
+
+	writer := bufio.NewWriter(out)
+	if format == "json" && !single {
+		writer.WriteByte('{')
+	}
+	for i, tableName := range order {
+		if format == "json" && !single {
+			if i > 0 {
+				writer.WriteByte(',')
+			}
+			key, _ := json.Marshal(tableName)
+			writer.Write(key)
+			writer.WriteByte(':')
+		}
+		if err := d.dumpTable(writer, rw, tableName, format); err != nil {
+			// заголовки уже отправлены, сообщить об ошибке можно только оборвав выгрузку: закрытый gzip
+			// с корректным окончанием выглядел бы как целый, но неполный дамп
+			d.abortStream("dump aborted", err)
+		}
+	}
+	if format == "json" && !single {
+		writer.WriteByte('}')
+	}
+	if err := writer.Flush(); err != nil {
+		d.abortStream("dump aborted", err)
+	}
+	if compressor != nil {
+		if err := compressor.Close(); err != nil {
+			d.abortStream("dump aborted", err)
+		}
+	}
+}
+
+func (d DbExplorer) dumpTable(writer *bufio.Writer, rw http.ResponseWriter, tableName, format string) error {
+	query := "SELECT * FROM " + d.dialect.quote(tableName)
+	if condition := d.visibleCondition(tableName); condition != "" {
+		query += " WHERE " + condition
+	}
+	if primaryKeys := d.tableIdNamesMap[tableName]; len(primaryKeys) > 0 {
+		quoted := make([]string, 0, len(primaryKeys))
+		for _, key := range primaryKeys {
+			quoted = append(quoted, d.dialect.quote(key))
+		}
+		query += " ORDER BY " + strings.Join(quoted, ", ")
+	}
+
+	queryResult, err := d.traced(d.db, tableName).QueryContext(d.requestContext(), query+";")
+	if err != nil {
+		return err
+	}
+	defer queryResult.Close()
+
+	columnTypes, err := queryResult.ColumnTypes()
+	if err != nil {
+		return err
+	}
+	columns := make([]string, 0, len(columnTypes))
+	quotedColumns := make([]string, 0, len(columnTypes))
+	for _, column := range columnTypes {
+		columns = append(columns, column.Name())
+		quotedColumns = append(quotedColumns, d.dialect.quote(column.Name()))
+	}
+	insert := "INSERT INTO " + d.dialect.quote(tableName) + " (" + strings.Join(quotedColumns, ", ") + ") VALUES ("
+
+	if format == "json" {
+		writer.WriteByte('[')
+	}
+	flusher, _ := rw.(http.Flusher)
+	for rows := 1; queryResult.Next(); rows++ {
+		if format == "json" {
+			values, err := d.scanRow(queryResult, columnTypes, tableName)
+			if err != nil {
+				return err
+			}
+			record := make(map[string]interface{}, len(columns))
+			for i, column := range columns {
+				record[column] = values[i]
+			}
+			data, err := json.Marshal(record)
+			if err != nil {
+				return err
+			}
+			if rows > 1 {
+				writer.WriteByte(',')
+			}
+			writer.Write(data)
+		} else {
+			statement, err := d.dumpInsert(queryResult, columnTypes, tableName, insert)
+			if err != nil {
+				return err
+			}
+			writer.WriteString(statement)
+		}
+		d.countRows(1)
+
+		if rows%streamFlushRows == 0 {
+			if err := writer.Flush(); err != nil {
+				return err
+			}
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+	}
+	if format == "json" {
+		writer.WriteByte(']')
+	}
+	return queryResult.Err()
+}
+
+// dumpInsert собирает INSERT из значений драйвера как есть, минуя представление для JSON
+func (d DbExplorer) dumpInsert(queryResult *sql.Rows, columnTypes []*sql.ColumnType, tableName, insert string) (string, error) {
+	values := make([]interface{}, len(columnTypes))
+	valuePointers := make([]interface{}, len(columnTypes))
+	for i := range values {
+		valuePointers[i] = &values[i]
+	}
+	if err := queryResult.Scan(valuePointers...); err != nil {
+		return "", err
+	}
+
+	literals := make([]string, len(values))
+	for i, value := range values {
+		column := d.columnsInTablesMap[tableName][columnTypes[i].Name()]
+		literal, err := d.dumpLiteral(column, value)
+		if err != nil {
+			return "", err
+		}
+		literals[i] = literal
+	}
+	return insert + strings.Join(literals, ", ") + ");\n", nil
+}
+
+func (d DbExplorer) dumpLiteral(column columnParams, value interface{}) (string, error) {
+	switch value := value.(type) {
+	case nil:
+		return "NULL", nil
+	case int64:
+		return strconv.FormatInt(value, 10), nil
+	case float64:
+		return strconv.FormatFloat(value, 'g', -1, 64), nil
+	case bool:
+		return d.sqlLiteral(value)
+	case time.Time:
+		return d.sqlLiteral(value.Format("2006-01-02 15:04:05.999999"))
+	case []byte:
+		if column.typeName == "binary" {
+			return d.hexLiteral(value), nil
+		}
+		return d.dumpLiteral(column, string(value))
+	case string:
+		// текстовый протокол MySQL отдаёт числа строками, в дампе они остаются числами
+		switch column.typeName {
+		case "int", "float", "decimal", "bool":
+			if decimalPattern.MatchString(value) {
+				return value, nil
+			}
+		}
+		return d.sqlLiteral(value)
+	}
+	return d.sqlLiteral(fmt.Sprintf("%v", value))
+}
+
+func (d DbExplorer) hexLiteral(value []byte) string {
+	switch d.dialect.name() {
+	case "postgres":
+		return `'\x` + hex.EncodeToString(value) + `'::bytea`
+	case "sqlserver":
+		return "0x" + hex.EncodeToString(value)
+	}
+	return "X'" + hex.EncodeToString(value) + "'"
+}
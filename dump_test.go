@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"database/sql"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestDumpLiteral(t *testing.T) {
+	cases := []struct {
+		dialect Dialect
+		column  columnParams
+		value   interface{}
+		literal string
+	}{
+		{MySQL, columnParams{typeName: "int"}, []byte("42"), "42"},
+		{MySQL, columnParams{typeName: "string"}, []byte("42"), "'42'"},
+		{MySQL, columnParams{typeName: "string"}, "it's \\", `'it''s \\'`},
+		{MySQL, columnParams{typeName: "binary"}, []byte{0xde, 0xad}, "X'dead'"},
+		{PostgreSQL, columnParams{typeName: "binary"}, []byte{0xde, 0xad}, `'\xdead'::bytea`},
+		{SQLServer, columnParams{typeName: "bool"}, true, "1"},
+		{PostgreSQL, columnParams{typeName: "string"}, nil, "NULL"},
+		{PostgreSQL, columnParams{typeName: "float"}, 1.5, "1.5"},
+	}
+	for idx, item := range cases {
+		literal, err := DbExplorer{dialect: item.dialect}.dumpLiteral(item.column, item.value)
+		if err != nil {
+			t.Fatalf("[case %d] unexpected error: %v", idx, err)
+		}
+		if literal != item.literal {
+			t.Fatalf("[case %d] literal not match\nGot : %s\nWant: %s", idx, literal, item.literal)
+		}
+	}
+}
+
+func TestDump(t *testing.T) {
+	db, err := sql.Open("mysql", DSN)
+	err = db.Ping()
+	if err != nil {
+		panic(err)
+	}
+
+	PrepareTestApis(db)
+	defer CleanupTestApis(db)
+
+	handler, err := NewDbExplorer(db)
+	if err != nil {
+		panic(err)
+	}
+
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	itemsSQL := "INSERT INTO `items` (`id`, `title`, `description`, `updated`) VALUES (1, 'database/sql', 'Рассказать про базы данных', 'rvasily');\n" +
+		"INSERT INTO `items` (`id`, `title`, `description`, `updated`) VALUES (2, 'memcache', 'Рассказать про мемкеш с примером использования', NULL);\n"
+	usersSQL := "INSERT INTO `users` (`user_id`, `login`, `password`, `email`, `info`, `updated`) VALUES (1, 'rvasily', 'love', 'rvasily@example.com', 'none', NULL);\n"
+
+	cases := []struct {
+		path        string
+		contentType string
+		filename    string
+		result      string
+	}{
+		{"/items/dump", "application/sql; charset=utf-8", "items.sql", itemsSQL},
+		{"/items/dump?format=json", "application/json", "items.json",
+			`[{"description":"Рассказать про базы данных","id":1,"title":"database/sql","updated":"rvasily"},{"description":"Рассказать про мемкеш с примером использования","id":2,"title":"memcache","updated":null}]`},
+		{"/admin/dump", "application/sql; charset=utf-8", "dump.sql", itemsSQL + usersSQL},
+		{"/admin/dump?format=json", "application/json", "dump.json",
+			`{"items":[{"description":"Рассказать про базы данных","id":1,"title":"database/sql","updated":"rvasily"},{"description":"Рассказать про мемкеш с примером использования","id":2,"title":"memcache","updated":null}],` +
+				`"users":[{"email":"rvasily@example.com","info":"none","login":"rvasily","password":"love","updated":null,"user_id":1}]}`},
+	}
+
+	for idx, item := range cases {
+		resp, err := client.Get(ts.URL + item.path)
+		if err != nil {
+			t.Fatalf("[case %d] request error: %v", idx, err)
+		}
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("[case %d: %s] expected http status 200, got %v: %s", idx, item.path, resp.StatusCode, body)
+		}
+		if contentType := resp.Header.Get("Content-Type"); contentType != item.contentType {
+			t.Fatalf("[case %d: %s] unexpected Content-Type %q", idx, item.path, contentType)
+		}
+		if disposition := resp.Header.Get("Content-Disposition"); disposition != `attachment; filename="`+item.filename+`"` {
+			t.Fatalf("[case %d: %s] unexpected Content-Disposition %q", idx, item.path, disposition)
+		}
+		if string(body) != item.result {
+			t.Fatalf("[case %d: %s] results not match\nGot : %s\nWant: %s", idx, item.path, body, item.result)
+		}
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, ts.URL+"/items/dump?gzip=true", nil)
+	req.Header.Set("Accept-Encoding", "gzip")
+	resp, err := http.DefaultTransport.RoundTrip(req)
+	if err != nil {
+		t.Fatalf("request error: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.Header.Get("Content-Encoding") != "" || resp.Header.Get("Content-Type") != "application/gzip" {
+		t.Fatalf("gzip dump must be sent as a file, got headers %v", resp.Header)
+	}
+	reader, err := gzip.NewReader(resp.Body)
+	if err != nil {
+		t.Fatalf("gzip error: %v", err)
+	}
+	body, _ := ioutil.ReadAll(reader)
+	if !bytes.Equal(body, []byte(itemsSQL)) {
+		t.Fatalf("gzip dump not match\nGot : %s\nWant: %s", body, itemsSQL)
+	}
+}
+
+func TestDumpAborted(t *testing.T) {
+	explorer := brokenRowsExplorer(2 * streamFlushRows)
+	ts := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		explorer.handlerDump(rw, r, []string{"items"}, "items")
+	}))
+	defer ts.Close()
+
+	// и несжатый, и gzip-дамп должны оборваться, а не закончиться корректным окончанием файла
+	checkAborted(t, ts.URL+"/items/dump")
+	checkAborted(t, ts.URL+"/items/dump?format=json")
+	checkAborted(t, ts.URL+"/items/dump?gzip=true")
+}
@@ -0,0 +1,254 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// EncoderFunc рендерит конверт ответа ({"response": ...} или {"error": ...}) в свой формат
+type EncoderFunc func(envelope map[string]interface{}) ([]byte, error)
+
+type encoderEntry struct {
+	mediaType   string
+	contentType string
+	encode      EncoderFunc
+}
+
+var (
+	encodersMu sync.RWMutex
+	encoders   []encoderEntry
+)
+
+func init() {
+	RegisterEncoder("application/json", func(envelope map[string]interface{}) ([]byte, error) {
+		return json.Marshal(envelope)
+	})
+	RegisterEncoder("application/xml; charset=utf-8", encodeXML)
+	RegisterEncoder("text/xml; charset=utf-8", encodeXML)
+	RegisterEncoder("application/msgpack", encodeMsgpack)
+	RegisterEncoder("application/x-msgpack", encodeMsgpack)
+	RegisterEncoder("text/csv; charset=utf-8", encodeCSV)
+	RegisterEncoder("application/x-ndjson", encodeNDJSON)
+}
+
+// RegisterEncoder добавляет формат ответа, который выбирается по заголовку Accept.
+// contentType уходит в заголовок Content-Type как есть, сравнение с Accept идёт без параметров.
+// Повторная регистрация того же типа заменяет кодировщик.
+func RegisterEncoder(contentType string, encode EncoderFunc) {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		panic("dbexplorer: invalid content type " + contentType)
+	}
+
+	encodersMu.Lock()
+	defer encodersMu.Unlock()
+
+	entry := encoderEntry{mediaType: mediaType, contentType: contentType, encode: encode}
+	for i := range encoders {
+		if encoders[i].mediaType == mediaType {
+			encoders[i] = entry
+			return
+		}
+	}
+	encoders = append(encoders, entry)
+}
+
+// encoderFor возвращает кодировщик типа; незнакомый тип отдаётся в JSON
+func encoderFor(mediaType string) encoderEntry {
+	encodersMu.RLock()
+	defer encodersMu.RUnlock()
+
+	for _, entry := range encoders {
+		if entry.mediaType == mediaType {
+			return entry
+		}
+	}
+	return encoders[0]
+}
+
+// negotiateMediaType выбирает зарегистрированный тип по Accept с учётом q и масок type/* и */*
+func negotiateMediaType(accept string) string {
+	type mediaRange struct {
+		mediaType string
+		quality   float64
+	}
+
+	ranges := make([]mediaRange, 0)
+	for _, part := range strings.Split(accept, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+		quality := 1.0
+		if q, ok := params["q"]; ok {
+			if quality, err = strconv.ParseFloat(q, 64); err != nil {
+				continue
+			}
+		}
+		if quality > 0 {
+			ranges = append(ranges, mediaRange{mediaType: mediaType, quality: quality})
+		}
+	}
+	sort.SliceStable(ranges, func(i, j int) bool {
+		return ranges[i].quality > ranges[j].quality
+	})
+
+	encodersMu.RLock()
+	defer encodersMu.RUnlock()
+
+	for _, item := range ranges {
+		for _, entry := range encoders {
+			if item.mediaType == "*/*" || item.mediaType == entry.mediaType ||
+				(strings.HasSuffix(item.mediaType, "/*") && strings.HasPrefix(entry.mediaType, strings.TrimSuffix(item.mediaType, "*"))) {
+				return entry.mediaType
+			}
+		}
+	}
+	return ""
+}
+
+// negotiatedWriter запоминает тип ответа, выбранный по заголовку Accept,
+// чтобы responseResult мог отрендерить тот же результат не только в JSON
+type negotiatedWriter struct {
+	http.ResponseWriter
+	mediaType string
+}
+
+func (w negotiatedWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+// Flush пробрасывается, чтобы потоковые ответы (SSE, выгрузки) работали через обёртку
+func (w negotiatedWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func negotiate(rw http.ResponseWriter, r *http.Request) http.ResponseWriter {
+	accept := r.Header.Get("Accept")
+	if accept == "" {
+		return rw
+	}
+
+	mediaType := negotiateMediaType(accept)
+	if mediaType == "" || mediaType == "application/json" {
+		return rw
+	}
+	return negotiatedWriter{ResponseWriter: rw, mediaType: mediaType}
+}
+
+// responseEncoding находит тип ответа среди обёрток ResponseWriter
+func responseEncoding(rw http.ResponseWriter) string {
+	for {
+		switch typed := rw.(type) {
+		case negotiatedWriter:
+			return typed.mediaType
+		case interface{ Unwrap() http.ResponseWriter }:
+			rw = typed.Unwrap()
+		default:
+			return "application/json"
+		}
+	}
+}
+
+// normalizeEnvelope приводит json.RawMessage, json.Number и структуры к обычным map/[]interface{},
+// чтобы кодировщики работали с теми же значениями, что уходят в JSON
+func normalizeEnvelope(value interface{}) (interface{}, error) {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return nil, err
+	}
+
+	var generic interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	if err := decoder.Decode(&generic); err != nil {
+		return nil, err
+	}
+	return generic, nil
+}
+
+// encodeXML строит XML из того же результата, что уходит в JSON: корень — ключ конверта
+// (response или error), элементы массива названы по имени массива без "s" (records → record)
+func encodeXML(responseMap map[string]interface{}) ([]byte, error) {
+	rootName := "response"
+	if _, ok := responseMap["error"]; ok {
+		rootName = "error"
+	}
+
+	value, err := normalizeEnvelope(responseMap[rootName])
+	if err != nil {
+		return nil, err
+	}
+
+	buffer := bytes.NewBufferString(xml.Header)
+	writeXMLElement(buffer, rootName, value)
+	return buffer.Bytes(), nil
+}
+
+func writeXMLElement(buffer *bytes.Buffer, name string, value interface{}) {
+	name = xmlName(name)
+	if value == nil {
+		buffer.WriteString("<" + name + ` nil="true"/>`)
+		return
+	}
+
+	buffer.WriteString("<" + name + ">")
+	switch typed := value.(type) {
+	case map[string]interface{}:
+		keys := make([]string, 0, len(typed))
+		for key := range typed {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			writeXMLElement(buffer, key, typed[key])
+		}
+	case []interface{}:
+		itemName := "item"
+		if strings.HasSuffix(name, "s") && len(name) > 1 {
+			itemName = strings.TrimSuffix(name, "s")
+		}
+		for _, item := range typed {
+			writeXMLElement(buffer, itemName, item)
+		}
+	case string:
+		xml.EscapeText(buffer, []byte(typed))
+	case json.Number:
+		buffer.WriteString(typed.String())
+	case bool:
+		if typed {
+			buffer.WriteString("true")
+		} else {
+			buffer.WriteString("false")
+		}
+	}
+	buffer.WriteString("</" + name + ">")
+}
+
+// xmlName заменяет символы, недопустимые в имени XML-элемента
+func xmlName(name string) string {
+	if name == "" {
+		return "_"
+	}
+
+	result := []rune(name)
+	for i, r := range result {
+		valid := r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || r > 0x7f
+		if i > 0 {
+			valid = valid || r == '-' || r == '.' || (r >= '0' && r <= '9')
+		}
+		if !valid {
+			result[i] = '_'
+		}
+	}
+	return string(result)
+}
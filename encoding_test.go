@@ -0,0 +1,93 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestEncodeXML(t *testing.T) {
+	result, err := encodeXML(map[string]interface{}{
+		"response": map[string]interface{}{
+			"records": []map[string]interface{}{
+				{"id": 1, "title": "a & b", "updated": nil, "payload": json.RawMessage(`{"tags":["x"]}`)},
+			},
+			"has more": true,
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := `<?xml version="1.0" encoding="UTF-8"?>` + "\n" +
+		`<response><has_more>true</has_more><records><record><id>1</id>` +
+		`<payload><tags><tag>x</tag></tags></payload><title>a &amp; b</title><updated nil="true"/>` +
+		`</record></records></response>`
+	if string(result) != expected {
+		t.Fatalf("results not match\nGot : %s\nWant: %s", result, expected)
+	}
+}
+
+func TestXMLNegotiation(t *testing.T) {
+	explorer := DbExplorer{dialect: MySQL, tableKeys: []string{"items"}}
+
+	req := httptest.NewRequest(http.MethodGet, "/unknown", nil)
+	req.Header.Set("Accept", "application/xml")
+	rw := httptest.NewRecorder()
+	explorer.ServeHTTP(rw, req)
+
+	expected := `<?xml version="1.0" encoding="UTF-8"?>` + "\n" + `<error>unknown table</error>`
+	if rw.Code != http.StatusNotFound || rw.Body.String() != expected {
+		t.Fatalf("unexpected response %d: %s", rw.Code, rw.Body.String())
+	}
+	if contentType := rw.Header().Get("Content-Type"); contentType != "application/xml; charset=utf-8" {
+		t.Fatalf("unexpected content type %q", contentType)
+	}
+}
+
+func TestRegisterEncoder(t *testing.T) {
+	RegisterEncoder("text/plain; charset=utf-8", func(envelope map[string]interface{}) ([]byte, error) {
+		if err, ok := envelope["error"]; ok {
+			return []byte("error: " + err.(string)), nil
+		}
+		return []byte("ok"), nil
+	})
+
+	cases := map[string]string{
+		"text/plain": "text/plain",
+		"text/html, application/xml;q=0.9, */*;q=0.8": "application/xml",
+		"application/msgpack;q=0.5, text/csv":         "text/csv",
+		"text/*":                                      "text/xml",
+		"*/*":                                         "application/json",
+		"image/png":                                   "",
+	}
+	for accept, expected := range cases {
+		if got := negotiateMediaType(accept); got != expected {
+			t.Fatalf("[%s] expected %q, got %q", accept, expected, got)
+		}
+	}
+
+	explorer := DbExplorer{dialect: MySQL, tableKeys: []string{"items"}}
+	req := httptest.NewRequest(http.MethodGet, "/unknown", nil)
+	req.Header.Set("Accept", "text/plain")
+	rw := httptest.NewRecorder()
+	explorer.ServeHTTP(rw, req)
+	if rw.Body.String() != "error: unknown table" || rw.Header().Get("Content-Type") != "text/plain; charset=utf-8" {
+		t.Fatalf("unexpected response %q with %q", rw.Body.String(), rw.Header().Get("Content-Type"))
+	}
+}
+
+func TestEncodeCSVEnvelope(t *testing.T) {
+	result, err := encodeCSV(map[string]interface{}{
+		"response": map[string]interface{}{
+			"record": map[string]interface{}{"id": 1, "tags": []string{"a", "b"}, "note": nil},
+		},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := "id,note,tags\n1,,\"[\"\"a\"\",\"\"b\"\"]\"\n"; string(result) != expected {
+		t.Fatalf("results not match\nGot : %q\nWant: %q", result, expected)
+	}
+}
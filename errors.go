@@ -0,0 +1,173 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// ErrUnknownTable и ErrRecordNotFound можно сравнивать через errors.Is в коде, который встраивает explorer:
+// в хуках, middleware и обработчиках поверх Routes
+var (
+	ErrUnknownTable   = errors.New("unknown table")
+	ErrRecordNotFound = errors.New("record not found")
+)
+
+// Error — ошибка с машиночитаемым кодом. Хук или встраивающий код может вернуть её, чтобы задать
+// код, поле и статус ответа; Status 0 оставляет статус, который выбрал бы сам explorer.
+type Error struct {
+	Status  int
+	Code    string
+	Message string
+	Field   string
+	Details interface{}
+}
+
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// invalidTypeError — значение поля не подходит к типу колонки
+func invalidTypeError(field string) error {
+	return &Error{Code: "invalid_type", Message: "field " + field + " have invalid type", Field: field}
+}
+
+// errorCodes — коды известных ошибок; остальные получают код по статусу ответа, см. statusCodes
+var errorCodes = []struct {
+	err  error
+	code string
+}{
+	{ErrUnknownTable, "unknown_table"},
+	{ErrRecordNotFound, "record_not_found"},
+	{errReadOnly, "read_only"},
+	{errUnauthorized, "unauthorized"},
+	{errInvalidToken, "invalid_token"},
+	{errInvalidCursor, "invalid_cursor"},
+	{errMethodNotAllowed, "method_not_allowed"},
+	{errTooManyRequests, "too_many_requests"},
+	{errVersionConflict, "version_conflict"},
+}
+
+var statusCodes = map[int]string{
+	http.StatusBadRequest:            "bad_request",
+	http.StatusUnauthorized:          "unauthorized",
+	http.StatusForbidden:             "forbidden",
+	http.StatusNotFound:              "not_found",
+	http.StatusMethodNotAllowed:      "method_not_allowed",
+	http.StatusNotAcceptable:         "not_acceptable",
+	http.StatusConflict:              "conflict",
+	http.StatusPreconditionFailed:    "precondition_failed",
+	http.StatusRequestEntityTooLarge: "body_too_large",
+	http.StatusUnsupportedMediaType:  "unsupported_media_type",
+	http.StatusUnprocessableEntity:   "unprocessable_entity",
+	http.StatusTooManyRequests:       "too_many_requests",
+}
+
+// isDuplicateKey — нарушение уникального ключа в MySQL, ошибка 1062
+func isDuplicateKey(err error) bool {
+	var mysqlErr *mysql.MySQLError
+	return errors.As(err, &mysqlErr) && mysqlErr.Number == 1062
+}
+
+// errorStatus приводит статус к общему для всех обработчиков: неизвестная таблица и запись — 404,
+// конфликт версии и уникального ключа — 409; для остальных ошибок остаётся status
+func errorStatus(err error, status int) int {
+	var apiErr *Error
+	switch {
+	case errors.As(err, &apiErr) && apiErr.Status != 0:
+		return apiErr.Status
+	case errors.Is(err, ErrUnknownTable), errors.Is(err, ErrRecordNotFound):
+		return http.StatusNotFound
+	case errors.Is(err, errVersionConflict), isDuplicateKey(err):
+		return http.StatusConflict
+	}
+	return status
+}
+
+// errorCode возвращает машиночитаемый код ошибки для ответа со статусом status
+func errorCode(err error, status int) string {
+	var apiErr *Error
+	var invalid validationError
+	var vetoed hookError
+	var tooLarge *http.MaxBytesError
+	switch {
+	case errors.As(err, &apiErr) && apiErr.Code != "":
+		return apiErr.Code
+	case errors.As(err, &invalid):
+		return "validation_failed"
+	case errors.As(err, &vetoed):
+		return "rejected"
+	case errors.As(err, &tooLarge):
+		return "body_too_large"
+	case isDuplicateKey(err):
+		return "duplicate_key"
+	}
+	for _, known := range errorCodes {
+		if errors.Is(err, known.err) {
+			return known.code
+		}
+	}
+	if code, ok := statusCodes[status]; ok {
+		return code
+	}
+	if status >= http.StatusInternalServerError {
+		return "internal_error"
+	}
+	return "error"
+}
+
+// errorEnvelope — ошибка в режиме WithStructuredErrors: {"code", "message", "field", "details"}
+func errorEnvelope(err error, status int) map[string]interface{} {
+	envelope := map[string]interface{}{
+		"code":    errorCode(err, status),
+		"message": err.Error(),
+	}
+
+	var apiErr *Error
+	var invalid validationError
+	switch {
+	case errors.As(err, &apiErr):
+		if apiErr.Field != "" {
+			envelope["field"] = apiErr.Field
+		}
+		if apiErr.Details != nil {
+			envelope["details"] = apiErr.Details
+		}
+	case errors.As(err, &invalid):
+		if len(invalid.fields) == 1 {
+			envelope["field"] = invalid.fields[0].Field
+		}
+		envelope["details"] = invalid.fields
+	}
+	return envelope
+}
+
+// structuredErrorsWriter включает формат ошибок WithStructuredErrors для responseResult
+type structuredErrorsWriter struct {
+	http.ResponseWriter
+}
+
+func (w structuredErrorsWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w structuredErrorsWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// structuredErrors проверяет, включён ли формат ошибок WithStructuredErrors для ответа
+func structuredErrors(rw http.ResponseWriter) bool {
+	for {
+		switch typed := rw.(type) {
+		case structuredErrorsWriter:
+			return true
+		case interface{ Unwrap() http.ResponseWriter }:
+			rw = typed.Unwrap()
+		default:
+			return false
+		}
+	}
+}
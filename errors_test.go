@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestStructuredErrors(t *testing.T) {
+	db, err := sql.Open("mysql", DSN)
+	err = db.Ping()
+	if err != nil {
+		panic(err)
+	}
+
+	PrepareTestApis(db)
+	defer CleanupTestApis(db)
+
+	handler, err := NewDbExplorer(db,
+		WithStructuredErrors(true),
+		WithValidation("items", map[string]ValidationRule{"title": {MinLength: 3}}),
+		WithHook(BeforeDelete, func(ctx context.Context, table string, id, record map[string]interface{}) error {
+			return &Error{Status: http.StatusForbidden, Code: "protected", Message: "record is protected", Details: id}
+		}),
+	)
+	if err != nil {
+		panic(err)
+	}
+
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	runCases(t, ts, db, []Case{
+		{
+			Path:   "/unknown_table",
+			Status: http.StatusNotFound,
+			Result: CR{
+				"error": CR{"code": "unknown_table", "message": "unknown table"},
+			},
+		},
+		{
+			Path:   "/items/100500",
+			Status: http.StatusNotFound,
+			Result: CR{
+				"error": CR{"code": "record_not_found", "message": "record not found"},
+			},
+		},
+		{
+			Path:   "/items/1",
+			Method: http.MethodPost,
+			Status: http.StatusBadRequest,
+			Body:   CR{"title": 42},
+			Result: CR{
+				"error": CR{"code": "invalid_type", "message": "field title have invalid type", "field": "title"},
+			},
+		},
+		{
+			Path:   "/items/1",
+			Method: http.MethodPost,
+			Status: http.StatusUnprocessableEntity,
+			Body:   CR{"title": "go"},
+			Result: CR{
+				"error": CR{
+					"code":    "validation_failed",
+					"message": "field title must be at least 3 characters",
+					"field":   "title",
+					"details": []CR{
+						{"field": "title", "rule": "min_length", "message": "must be at least 3 characters"},
+					},
+				},
+			},
+		},
+		{
+			Path:   "/items/1",
+			Method: http.MethodDelete,
+			Status: http.StatusForbidden,
+			Result: CR{
+				"error": CR{"code": "protected", "message": "record is protected", "details": CR{"id": 1}},
+			},
+		},
+	})
+}
+
+func TestErrorStatus(t *testing.T) {
+	cases := []struct {
+		err    error
+		status int
+		code   string
+	}{
+		{ErrUnknownTable, http.StatusNotFound, "unknown_table"},
+		{fmt.Errorf("items: %w", ErrRecordNotFound), http.StatusNotFound, "record_not_found"},
+		{errVersionConflict, http.StatusConflict, "version_conflict"},
+		{errReadOnly, http.StatusForbidden, "read_only"},
+		{errors.New("connection refused"), http.StatusInternalServerError, "internal_error"},
+		{errors.New("invalid record id"), http.StatusBadRequest, "bad_request"},
+	}
+
+	for idx, item := range cases {
+		// статус вызывающей стороны меняется только для ошибок, у которых он один на весь API
+		fallback := item.status
+		if errors.Is(item.err, ErrUnknownTable) || errors.Is(item.err, ErrRecordNotFound) || item.err == errVersionConflict {
+			fallback = http.StatusBadRequest
+		}
+		status := errorStatus(item.err, fallback)
+		if status != item.status {
+			t.Fatalf("[%d] expected status %d, got %d", idx, item.status, status)
+		}
+		if code := errorCode(item.err, status); code != item.code {
+			t.Fatalf("[%d] expected code %s, got %s", idx, item.code, code)
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// recordETag — сильный ETag записи: хеш её JSON-представления
+func recordETag(record map[string]interface{}) (string, error) {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return "", err
+	}
+
+	sum := sha1.Sum(data)
+	return `"` + hex.EncodeToString(sum[:]) + `"`, nil
+}
+
+// etagMatches проверяет ETag по списку из If-None-Match/If-Match; "*" совпадает с любой существующей записью.
+// If-None-Match сравнивает слабо (W/ игнорируется), If-Match — только сильные теги
+func etagMatches(header, etag string, weak bool) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if weak {
+			candidate = strings.TrimPrefix(candidate, "W/")
+		}
+		if candidate == "*" || candidate == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// checkIfMatch сверяет If-Match с текущей версией записи и при расхождении отвечает 412.
+// db — транзакция, в которой затем выполняется изменение
+func (d DbExplorer) checkIfMatch(rw http.ResponseWriter, r *http.Request, db queryExecutor, tableName, rawId string) bool {
+	match := r.Header.Get("If-Match")
+	if match == "" {
+		return true
+	}
+
+	columns, err := d.visibleColumns(tableName)
+	if err != nil {
+		responseResult(rw, err, http.StatusForbidden, nil)
+		return false
+	}
+	record, err := d.queryRecord(db, tableName, rawId, columns)
+	if err == ErrRecordNotFound {
+		responseResult(rw, errors.New("precondition failed"), http.StatusPreconditionFailed, nil)
+		return false
+	}
+	if err != nil {
+		responseResult(rw, err, http.StatusInternalServerError, nil)
+		return false
+	}
+
+	etag, err := recordETag(record)
+	if err != nil {
+		responseResult(rw, err, http.StatusInternalServerError, nil)
+		return false
+	}
+	if !etagMatches(match, etag, false) {
+		responseResult(rw, errors.New("record has been modified"), http.StatusPreconditionFailed, nil)
+		return false
+	}
+	return true
+}
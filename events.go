@@ -0,0 +1,190 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// MutationEvent описывает изменение записей таблицы: через explorer или, с ListenBinlog, в обход него
+type MutationEvent struct {
+	Type   string                 `json:"type"` // insert, update, delete
+	Table  string                 `json:"table"`
+	ID     map[string]interface{} `json:"id"`
+	Fields []string               `json:"fields,omitempty"`
+	// Filter — query-строка массового обновления, для которого id записей неизвестны
+	Filter string `json:"filter,omitempty"`
+	// Source — "binlog" для изменений в обход explorer'а, см. ListenBinlog
+	Source string `json:"source,omitempty"`
+}
+
+// mutationBroker раздаёт события подписчикам; медленный подписчик теряет события, но не тормозит запись
+type mutationBroker struct {
+	mu          sync.Mutex
+	sequence    int
+	subscribers map[chan mutationFrame]struct{}
+	publishers  []*publisherQueue // внешние получатели событий, см. WithPublishers
+}
+
+type mutationFrame struct {
+	sequence int
+	event    MutationEvent
+}
+
+const mutationBufferSize = 64
+
+func newMutationBroker() *mutationBroker {
+	return &mutationBroker{subscribers: make(map[chan mutationFrame]struct{})}
+}
+
+func (b *mutationBroker) subscribe() (chan mutationFrame, func()) {
+	ch := make(chan mutationFrame, mutationBufferSize)
+	b.mu.Lock()
+	b.subscribers[ch] = struct{}{}
+	b.mu.Unlock()
+
+	return ch, func() {
+		b.mu.Lock()
+		delete(b.subscribers, ch)
+		b.mu.Unlock()
+	}
+}
+
+func (b *mutationBroker) publish(event MutationEvent) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.sequence++
+	frame := mutationFrame{sequence: b.sequence, event: event}
+	for ch := range b.subscribers {
+		select {
+		case ch <- frame:
+		default:
+		}
+	}
+	for _, publisher := range b.publishers {
+		publisher.enqueue(event)
+	}
+}
+
+// emit публикует событие записи; вызывается после успешного выполнения (и коммита) запроса
+func (d DbExplorer) emit(event MutationEvent) {
+	if event.ID != nil {
+		d.recent.remember(mutationKey(event, d.rawId(event.Table, event.ID)), time.Now())
+	}
+	if d.cache != nil {
+		d.cache.invalidate(event.Table)
+	}
+	if d.events != nil {
+		d.events.publish(event)
+	}
+	d.recordAudit(event)
+}
+
+func (d DbExplorer) emitInsert(tableName string, id map[string]interface{}, data map[string]interface{}) {
+	d.emit(MutationEvent{Type: "insert", Table: tableName, ID: id, Fields: sortedKeys(data)})
+}
+
+func (d DbExplorer) emitUpdate(tableName, rawId string, data map[string]interface{}) {
+	d.emit(MutationEvent{Type: "update", Table: tableName, ID: d.primaryKeyValues(tableName, rawId), Fields: sortedKeys(data)})
+}
+
+func (d DbExplorer) emitDelete(tableName, rawId string) {
+	d.emit(MutationEvent{Type: "delete", Table: tableName, ID: d.primaryKeyValues(tableName, rawId)})
+}
+
+// primaryKeyValues раскладывает id из пути по колонкам первичного ключа
+func (d DbExplorer) primaryKeyValues(tableName, rawId string) map[string]interface{} {
+	values := d.splitRecordId(tableName, rawId)
+	id := make(map[string]interface{}, len(values))
+	for i, key := range d.tableIdNamesMap[tableName] {
+		if i >= len(values) {
+			break
+		}
+		if column, ok := d.columnsInTablesMap[tableName][key]; ok {
+			if value, err := d.filterValue(column, values[i]); err == nil {
+				id[key] = value
+				continue
+			}
+		}
+		if intValue, err := strconv.Atoi(values[i]); err == nil {
+			id[key] = intValue
+		} else {
+			id[key] = values[i]
+		}
+	}
+	return id
+}
+
+func sortedKeys(data map[string]interface{}) []string {
+	keys := make([]string, 0, len(data))
+	for key := range data {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+const eventsHeartbeat = 15 * time.Second
+
+// handlerEvents отдаёт поток изменений как Server-Sent Events; ?table=a,b ограничивает таблицы
+func (d DbExplorer) handlerEvents(rw http.ResponseWriter, r *http.Request) {
+	flusher, ok := rw.(http.Flusher)
+	if !ok || d.events == nil {
+		responseResult(rw, errors.New("streaming is not supported"), http.StatusInternalServerError, nil)
+		return
+	}
+
+	tables := map[string]bool{}
+	if rawTables := r.URL.Query().Get("table"); rawTables != "" {
+		for _, tableName := range strings.Split(rawTables, ",") {
+			if !containsString(d.tableKeys, tableName) {
+				responseResult(rw, ErrUnknownTable, http.StatusNotFound, nil)
+				return
+			}
+			tables[tableName] = true
+		}
+	}
+
+	events, unsubscribe := d.events.subscribe()
+	defer unsubscribe()
+
+	header := rw.Header()
+	header.Set("Content-Type", "text/event-stream")
+	header.Set("Cache-Control", "no-cache")
+	header.Set("Connection", "keep-alive")
+	rw.WriteHeader(http.StatusOK)
+	fmt.Fprint(rw, ": connected\n\n")
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(eventsHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case <-d.lifecycle.stopping():
+			return
+		case <-heartbeat.C:
+			fmt.Fprint(rw, ": ping\n\n")
+		case frame := <-events:
+			if len(tables) > 0 && !tables[frame.event.Table] || d.tableAccess(r, frame.event.Table, http.MethodGet) != nil {
+				continue
+			}
+			data, err := json.Marshal(frame.event)
+			if err != nil {
+				d.logError("event encoding failed", err)
+				continue
+			}
+			fmt.Fprintf(rw, "id: %d\nevent: %s\ndata: %s\n\n", frame.sequence, frame.event.Type, data)
+		}
+		flusher.Flush()
+	}
+}
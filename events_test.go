@@ -0,0 +1,64 @@
+package main
+
+import (
+	"bufio"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEventsStream(t *testing.T) {
+	explorer := DbExplorer{
+		dialect:         MySQL,
+		tableKeys:       []string{"items", "users"},
+		tableIdNamesMap: map[string][]string{"items": {"id"}, "users": {"user_id"}},
+		events:          newMutationBroker(),
+	}
+
+	ts := httptest.NewServer(explorer)
+	defer ts.Close()
+
+	resp, err := http.Get(ts.URL + "/_events?table=items")
+	if err != nil {
+		t.Fatalf("request error: %v", err)
+	}
+	defer resp.Body.Close()
+	if contentType := resp.Header.Get("Content-Type"); contentType != "text/event-stream" {
+		t.Fatalf("unexpected content type %q", contentType)
+	}
+
+	reader := bufio.NewReader(resp.Body)
+	if line, _ := reader.ReadString('\n'); line != ": connected\n" {
+		t.Fatalf("unexpected greeting %q", line)
+	}
+	reader.ReadString('\n')
+
+	explorer.emitUpdate("users", "1", map[string]interface{}{"login": "x"})
+	explorer.emitUpdate("items", "1", map[string]interface{}{"title": "x", "description": "y"})
+
+	lines := make(chan string)
+	go func() {
+		for i := 0; i < 3; i++ {
+			line, _ := reader.ReadString('\n')
+			lines <- line
+		}
+	}()
+
+	expected := []string{
+		"id: 2\n",
+		"event: update\n",
+		`data: {"type":"update","table":"items","id":{"id":1},"fields":["description","title"]}` + "\n",
+	}
+	for _, want := range expected {
+		select {
+		case got := <-lines:
+			if got != want {
+				t.Fatalf("unexpected line\nGot : %q\nWant: %q", got, want)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("timeout waiting for %q", strings.TrimSpace(want))
+		}
+	}
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+)
+
+// handlerExplain отвечает на GET /{table}?explain=true планом запроса, который explorer выполнил бы
+// для этих фильтров, сортировки и страницы. Запрос не выполняется; нужен доступ к /admin/explain.
+func (d DbExplorer) handlerExplain(rw http.ResponseWriter, r *http.Request, list *listQuery) {
+	if err := d.tableAccess(r, "/admin/explain", http.MethodGet); err != nil {
+		responseResult(rw, err, http.StatusForbidden, nil)
+		return
+	}
+
+	query, args := list.selectSQL()
+	explainQuery := d.dialect.explain(query)
+	if explainQuery == "" {
+		responseResult(rw, errors.New("explain is not supported for "+d.dialect.name()), http.StatusNotImplemented, nil)
+		return
+	}
+
+	queryResult, err := d.traced(d.db, list.tableName).QueryContext(d.requestContext(), explainQuery, args...)
+	if err != nil {
+		responseResult(rw, err, http.StatusInternalServerError, nil)
+		return
+	}
+	// колонки плана не относятся к таблице, поэтому значения не приводятся по её схеме
+	plan, err := d.parsingSqlQueryResult(queryResult, "")
+	if err == ErrRecordNotFound {
+		plan, err = []map[string]interface{}{}, nil
+	}
+	if err != nil {
+		responseResult(rw, err, http.StatusInternalServerError, nil)
+		return
+	}
+
+	responseResult(rw, nil, http.StatusOK, map[string]interface{}{"query": query, "args": args, "plan": plan})
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"database/sql"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+// noExplainDialect — MySQL без поддержки EXPLAIN, как SQL Server
+type noExplainDialect struct {
+	mysqlDialect
+}
+
+func (noExplainDialect) explain(query string) string { return "" }
+
+func TestExplain(t *testing.T) {
+	db, err := sql.Open("mysql", DSN)
+	err = db.Ping()
+	if err != nil {
+		panic(err)
+	}
+
+	PrepareTestApis(db)
+	defer CleanupTestApis(db)
+
+	handler, err := NewDbExplorer(db, WithAuthorizer(ACL{
+		Rules: map[string]map[string][]string{
+			"admin":  {"*": {"*"}},
+			"reader": {"items": {"GET"}},
+		},
+		RoleOf: func(r *http.Request) string { return r.Header.Get("X-Role") },
+	}))
+	if err != nil {
+		panic(err)
+	}
+
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	get := func(path, role string) (int, []byte) {
+		req, _ := http.NewRequest(http.MethodGet, ts.URL+path, nil)
+		req.Header.Set("X-Role", role)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("request error: %v", err)
+		}
+		defer resp.Body.Close()
+		body, _ := ioutil.ReadAll(resp.Body)
+		return resp.StatusCode, body
+	}
+
+	status, body := get("/items?explain=true", "reader")
+	if want := `{"error":"GET on table /admin/explain is not allowed"}`; status != http.StatusForbidden || string(body) != want {
+		t.Fatalf("explain must be admin only, got %v: %s", status, body)
+	}
+
+	status, body = get("/items?explain=false&fields=id&limit=1", "reader")
+	if want := `{"response":{"records":[{"id":1}]}}`; status != http.StatusOK || string(body) != want {
+		t.Fatalf("explain=false must return records, got %v: %s", status, body)
+	}
+
+	unsupported, err := NewDbExplorer(db, WithDialect(noExplainDialect{}))
+	if err != nil {
+		panic(err)
+	}
+	resp := httptest.NewRecorder()
+	unsupported.ServeHTTP(resp, httptest.NewRequest(http.MethodGet, "/items?explain=true", nil))
+	if want := `{"error":"explain is not supported for mysql"}`; resp.Code != http.StatusNotImplemented || resp.Body.String() != want {
+		t.Fatalf("expected 501 for dialect without explain, got %v: %s", resp.Code, resp.Body.String())
+	}
+}
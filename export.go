@@ -0,0 +1,379 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+)
+
+// responseFormat выбирает формат списка по ?format= или типу, согласованному по Accept.
+// csv и ndjson отдаются потоком, остальные типы — через responseResult.
+func responseFormat(rw http.ResponseWriter, r *http.Request) (string, error) {
+	if format := r.URL.Query().Get("format"); format != "" {
+		switch format {
+		case "json", "csv", "ndjson":
+			return format, nil
+		}
+		return "", errors.New("unknown format " + format)
+	}
+
+	switch responseEncoding(rw) {
+	case "text/csv":
+		return "csv", nil
+	case "application/x-ndjson":
+		return "ndjson", nil
+	}
+	return "json", nil
+}
+
+// rowWriter пишет выборку построчно по мере чтения из базы
+type rowWriter interface {
+	contentType() string
+	header(columns []string) error
+	row(columns []string, values []interface{}) error
+	flush() error
+}
+
+type csvRowWriter struct {
+	writer *csv.Writer
+}
+
+func (w csvRowWriter) contentType() string { return "text/csv; charset=utf-8" }
+
+func (w csvRowWriter) header(columns []string) error {
+	return w.writer.Write(columns)
+}
+
+func (w csvRowWriter) row(columns []string, values []interface{}) error {
+	record := make([]string, len(values))
+	for i, value := range values {
+		record[i] = csvCell(value)
+	}
+	return w.writer.Write(record)
+}
+
+func (w csvRowWriter) flush() error {
+	w.writer.Flush()
+	return w.writer.Error()
+}
+
+// ndjsonRowWriter пишет по JSON-объекту записи на строку
+type ndjsonRowWriter struct {
+	writer *bufio.Writer
+}
+
+func (w ndjsonRowWriter) contentType() string { return "application/x-ndjson" }
+
+func (w ndjsonRowWriter) header(columns []string) error { return nil }
+
+func (w ndjsonRowWriter) row(columns []string, values []interface{}) error {
+	record := make(map[string]interface{}, len(columns))
+	for i, column := range columns {
+		record[column] = values[i]
+	}
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	w.writer.Write(data)
+	return w.writer.WriteByte('\n')
+}
+
+func (w ndjsonRowWriter) flush() error {
+	return w.writer.Flush()
+}
+
+const streamFlushRows = 100
+
+// handlerListStream отдаёт выборку в CSV или NDJSON, не собирая её в памяти.
+// Без явных limit/offset выгружается вся выборка, а не первая страница, но не больше WithMaxLimit.
+func (d DbExplorer) handlerListStream(rw http.ResponseWriter, r *http.Request, list *listQuery, format string) {
+	params, _, _ := odataParams(r.URL.Query())
+	if params.Get("limit") == "" && params.Get("offset") == "" && !list.cursor {
+		if d.maxLimit > 0 {
+			list.limit = d.maxLimit
+		} else {
+			list.unbounded = true
+		}
+	}
+
+	query, args := list.selectSQL()
+	queryResult, err := d.traced(d.db, list.tableName).QueryContext(d.requestContext(), query, args...)
+	if err != nil {
+		responseResult(rw, err, http.StatusInternalServerError, nil)
+		return
+	}
+	defer queryResult.Close()
+
+	columnTypes, err := queryResult.ColumnTypes()
+	if err != nil {
+		responseResult(rw, err, http.StatusInternalServerError, nil)
+		return
+	}
+	columns := make([]string, 0, len(columnTypes))
+	for _, column := range columnTypes {
+		columns = append(columns, d.fieldName(list.tableName, column.Name()))
+	}
+
+	var writer rowWriter = ndjsonRowWriter{writer: bufio.NewWriter(rw)}
+	if format == "csv" {
+		writer = csvRowWriter{writer: csv.NewWriter(rw)}
+		rw.Header().Set("Content-Disposition", `attachment; filename="`+list.tableName+`.csv"`)
+	}
+	rw.Header().Set("Content-Type", writer.contentType())
+	writer.header(columns)
+
+	flusher, _ := rw.(http.Flusher)
+	for rows := 1; queryResult.Next(); rows++ {
+		values, err := d.scanRow(queryResult, columnTypes, list.tableName)
+		if err == nil {
+			err = writer.row(columns, values)
+		}
+		if err != nil {
+			d.abortStream("export aborted", err)
+		}
+		d.countRows(1)
+
+		if rows%streamFlushRows == 0 {
+			writer.flush()
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+	}
+	if err := queryResult.Err(); err != nil {
+		d.abortStream("export aborted", err)
+	}
+
+	if err := writer.flush(); err != nil {
+		d.abortStream("export aborted", err)
+	}
+}
+
+// abortStream обрывает ответ, заголовки и часть которого уже отправлены: сервер сбросит соединение,
+// и клиент получит ошибку чтения, а не обрезанный, но внешне целый ответ со статусом 200
+func (d DbExplorer) abortStream(message string, err error) {
+	d.logError(message, err)
+	panic(http.ErrAbortHandler)
+}
+
+// handlerListJSON отдаёт страницу списка в том же JSON, что responseResult, но кодирует записи
+// по одной, не собирая выборку в памяти. Метаданные ?count=true дописываются после записей.
+func (d DbExplorer) handlerListJSON(rw http.ResponseWriter, r *http.Request, list *listQuery) {
+	meta := map[string]interface{}{}
+	withCount := list.count
+	total := 0
+	if withCount {
+		// COUNT выполняется до выборки: после первой записи сообщить об ошибке уже нельзя
+		query, args := list.countSQL()
+		if err := d.traced(d.db, list.tableName).QueryRowContext(d.requestContext(), query, args...).Scan(&total); err != nil {
+			responseResult(rw, err, http.StatusInternalServerError, nil)
+			return
+		}
+	}
+
+	query, args := list.selectSQL()
+	queryResult, err := d.traced(d.db, list.tableName).QueryContext(d.requestContext(), query, args...)
+	if err != nil {
+		responseResult(rw, err, http.StatusNotFound, nil)
+		return
+	}
+	defer queryResult.Close()
+
+	columnTypes, err := queryResult.ColumnTypes()
+	if err != nil {
+		responseResult(rw, err, http.StatusNotFound, nil)
+		return
+	}
+
+	// первую строку читаем заранее: пустая выборка без курсора — по-прежнему 404
+	hasRows := queryResult.Next()
+	if !hasRows && queryResult.Err() != nil {
+		responseResult(rw, queryResult.Err(), http.StatusNotFound, nil)
+		return
+	}
+	if !hasRows && !list.cursor {
+		responseResult(rw, ErrRecordNotFound, http.StatusNotFound, nil)
+		return
+	}
+
+	rw.Header().Set("Content-Type", encoderFor("application/json").contentType)
+	writer := bufio.NewWriter(rw)
+	writer.WriteString(`{"response":{"records":[`)
+
+	flusher, _ := rw.(http.Flusher)
+	var last map[string]interface{}
+	rows := 0
+	for ; hasRows; hasRows = queryResult.Next() {
+		values, err := d.scanRow(queryResult, columnTypes, list.tableName)
+		if err != nil {
+			d.abortStream("list aborted", err)
+		}
+
+		record := make(map[string]interface{}, len(columnTypes))
+		for i, columnType := range columnTypes {
+			record[columnType.Name()] = values[i]
+		}
+		data, err := json.Marshal(d.toFields(list.tableName, record))
+		if err != nil {
+			d.abortStream("list aborted", err)
+		}
+
+		if rows > 0 {
+			writer.WriteByte(',')
+		}
+		writer.Write(data)
+		last = record
+		rows++
+		d.countRows(1)
+
+		if rows%streamFlushRows == 0 {
+			writer.Flush()
+			if flusher != nil {
+				flusher.Flush()
+			}
+		}
+	}
+	if err := queryResult.Err(); err != nil {
+		// в том числе отмена запроса: недописанный JSON не должен выглядеть законченным
+		d.abortStream("list aborted", err)
+	}
+	writer.WriteByte(']')
+
+	if list.cursor {
+		var nextCursor interface{}
+		if last != nil && rows == list.limit {
+			nextCursor = d.encodeCursor(list.tableName, last)
+		}
+		meta["next_cursor"] = nextCursor
+	}
+	if withCount {
+		meta["total"] = total
+		meta["limit"] = list.limit
+		meta["offset"] = list.offset
+		meta["has_more"] = list.offset+rows < total
+	}
+
+	keys := make([]string, 0, len(meta))
+	for key := range meta {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		data, err := json.Marshal(meta[key])
+		if err != nil {
+			d.abortStream("list aborted", err)
+		}
+		writer.WriteString(`,"` + key + `":`)
+		writer.Write(data)
+	}
+	writer.WriteString("}}")
+
+	if err := writer.Flush(); err != nil {
+		logResponseError(rw, err)
+	}
+}
+
+func csvCell(value interface{}) string {
+	switch typed := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return typed
+	case []byte:
+		return string(typed)
+	case json.RawMessage:
+		return string(typed)
+	case bool:
+		return strconv.FormatBool(typed)
+	case float64:
+		return strconv.FormatFloat(typed, 'f', -1, 64)
+	case map[string]interface{}, []interface{}:
+		data, _ := json.Marshal(typed)
+		return string(data)
+	}
+	return fmt.Sprintf("%v", value)
+}
+
+// envelopeRows достаёт записи из конверта: список records, одну record или сам результат
+func envelopeRows(envelope map[string]interface{}) ([]interface{}, error) {
+	value, err := normalizeEnvelope(envelope)
+	if err != nil {
+		return nil, err
+	}
+
+	normalized := value.(map[string]interface{})
+	if _, ok := normalized["error"]; ok {
+		return []interface{}{map[string]interface{}{"error": normalized["error"]}}, nil
+	}
+
+	response, ok := normalized["response"].(map[string]interface{})
+	if !ok {
+		return []interface{}{}, nil
+	}
+	if records, ok := response["records"].([]interface{}); ok {
+		return records, nil
+	}
+	if record, ok := response["record"]; ok {
+		return []interface{}{record}, nil
+	}
+	return []interface{}{response}, nil
+}
+
+// encodeCSV — кодировщик для ответов, которые не идут потоком (запись, результат изменения, ошибка)
+func encodeCSV(envelope map[string]interface{}) ([]byte, error) {
+	rows, err := envelopeRows(envelope)
+	if err != nil {
+		return nil, err
+	}
+
+	columns := make([]string, 0)
+	for _, row := range rows {
+		if record, ok := row.(map[string]interface{}); ok {
+			for key := range record {
+				if !containsString(columns, key) {
+					columns = append(columns, key)
+				}
+			}
+		}
+	}
+	sort.Strings(columns)
+
+	buffer := &bytes.Buffer{}
+	writer := csv.NewWriter(buffer)
+	writer.Write(columns)
+	for _, row := range rows {
+		record, _ := row.(map[string]interface{})
+		cells := make([]string, len(columns))
+		for i, column := range columns {
+			cells[i] = csvCell(record[column])
+		}
+		writer.Write(cells)
+	}
+	writer.Flush()
+	return buffer.Bytes(), writer.Error()
+}
+
+func encodeNDJSON(envelope map[string]interface{}) ([]byte, error) {
+	rows, err := envelopeRows(envelope)
+	if err != nil {
+		return nil, err
+	}
+
+	buffer := &bytes.Buffer{}
+	for _, row := range rows {
+		data, err := json.Marshal(row)
+		if err != nil {
+			return nil, err
+		}
+		buffer.Write(data)
+		buffer.WriteByte('\n')
+	}
+	return buffer.Bytes(), nil
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// listParams — служебные параметры списка, которые не являются фильтрами по колонкам
+var listParams = map[string]bool{
+	"limit":           true,
+	"offset":          true,
+	"sort":            true,
+	"fields":          true,
+	"count":           true,
+	"after":           true,
+	"format":          true,
+	"include_deleted": true,
+	"include":         true, // связи JSON:API, см. WithJSONAPI
+	"explain":         true, // план запроса вместо записей, см. handlerExplain
+}
+
+// filterOperators — суффиксы вида ?age__gte=18, которые можно добавлять к имени колонки
+var filterOperators = map[string]string{
+	"eq":   "=",
+	"ne":   "<>",
+	"gt":   ">",
+	"gte":  ">=",
+	"lt":   "<",
+	"lte":  "<=",
+	"like": "LIKE",
+	"in":   "IN",
+}
+
+// filterCondition строит параметризованное условие WHERE из query-параметров вида
+// ?name=Ivan&age__gte=18&name__like=iv%&id__in=1,2,3.
+// Фильтровать можно только по известным колонкам, несколько значений одного параметра дают IN (...).
+func (d DbExplorer) filterCondition(tableName string, params url.Values, args *queryArgs) (string, error) {
+	keys := make([]string, 0, len(params))
+	for key := range params {
+		if listParams[key] {
+			continue
+		}
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	conditions := make([]string, 0, len(keys))
+	for _, key := range keys {
+		field, operator := parseFilterKey(key)
+		column, ok := d.columnsInTablesMap[tableName][d.columnName(tableName, field)]
+		if !ok {
+			// колонка может сама содержать "__", тогда это обычное равенство
+			column, ok = d.columnsInTablesMap[tableName][d.columnName(tableName, key)]
+			if !ok {
+				return "", errors.New("unknown field " + field)
+			}
+			field, operator = key, "eq"
+		}
+		// по чувствительным колонкам не фильтруем, иначе значение можно подобрать перебором
+		if _, ok := d.sensitiveColumn(tableName, column.name); ok {
+			return "", errors.New("unknown field " + field)
+		}
+
+		rawValues := params[key]
+		if operator == "in" {
+			rawValues = strings.Split(strings.Join(rawValues, ","), ",")
+		}
+		if operator == "eq" && len(rawValues) > 1 {
+			operator = "in"
+		}
+
+		if operator == "like" && column.typeName != "string" && column.typeName != "enum" && column.typeName != "set" {
+			return "", errors.New("field " + column.name + " does not support like")
+		}
+
+		placeholders := make([]string, 0, len(rawValues))
+		for _, rawValue := range rawValues {
+			var value interface{} = rawValue
+			if operator != "like" {
+				var err error
+				if value, err = d.filterValue(column, rawValue); err != nil {
+					return "", err
+				}
+			}
+			placeholders = append(placeholders, args.add(value))
+		}
+
+		quotedColumn := d.dialect.quote(column.name)
+		if operator == "in" {
+			conditions = append(conditions, quotedColumn+" IN ("+strings.Join(placeholders, ", ")+")")
+			continue
+		}
+		for _, placeholder := range placeholders {
+			conditions = append(conditions, quotedColumn+" "+filterOperators[operator]+" "+placeholder)
+		}
+	}
+
+	return strings.Join(conditions, " AND "), nil
+}
+
+// parseFilterKey разбирает ключ вида "age__gte" на колонку и оператор
+func parseFilterKey(key string) (string, string) {
+	i := strings.LastIndex(key, "__")
+	if i == -1 {
+		return key, "eq"
+	}
+	if _, ok := filterOperators[key[i+2:]]; !ok {
+		return key, "eq"
+	}
+	return key[:i], key[i+2:]
+}
+
+// filterValue приводит строку из URL к типу колонки через те же правила, что и для тела запроса
+func (d DbExplorer) filterValue(column columnParams, rawValue string) (interface{}, error) {
+	switch column.typeName {
+	case "int", "float", "decimal":
+		return d.encodeValue(column, json.Number(rawValue))
+	case "bool":
+		val, err := strconv.ParseBool(rawValue)
+		if err != nil {
+			return nil, invalidTypeError(column.name)
+		}
+		return val, nil
+	case "json":
+		return nil, errors.New("field " + column.name + " is not filterable")
+	}
+	if !column.writable() {
+		// даты, время и прочие типы, которые explorer не пишет, база сама сравнит со строкой
+		return rawValue, nil
+	}
+
+	return d.encodeValue(column, rawValue)
+}
+
+// orderClause строит ORDER BY из параметра ?sort=col,-col2 ("-" означает сортировку по убыванию).
+// Без параметра записи упорядочиваются по первичному ключу, чтобы страницы были детерминированными.
+func (d DbExplorer) orderClause(tableName, rawSort string) (string, error) {
+	orders := make([]string, 0)
+	if rawSort == "" {
+		for _, key := range d.tableIdNamesMap[tableName] {
+			orders = append(orders, d.dialect.quote(key)+" ASC")
+		}
+	}
+
+	for _, field := range strings.Split(rawSort, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+
+		direction := "ASC"
+		if strings.HasPrefix(field, "-") {
+			direction = "DESC"
+			field = field[1:]
+		} else if strings.HasPrefix(field, "+") {
+			field = field[1:]
+		}
+
+		columnName := d.columnName(tableName, field)
+		_, known := d.columnsInTablesMap[tableName][columnName]
+		if _, sensitive := d.sensitiveColumn(tableName, columnName); !known || sensitive {
+			return "", errors.New("unknown sort field " + field)
+		}
+		orders = append(orders, d.dialect.quote(columnName)+" "+direction)
+	}
+
+	if len(orders) == 0 {
+		return "", nil
+	}
+	return "ORDER BY " + strings.Join(orders, ", "), nil
+}
+
+// selectColumns строит список колонок для SELECT из параметра ?fields=id,name
+func (d DbExplorer) selectColumns(tableName, rawFields string) (string, error) {
+	if strings.TrimSpace(rawFields) == "" {
+		return d.visibleColumns(tableName)
+	}
+
+	columns := make([]string, 0)
+	for _, field := range strings.Split(rawFields, ",") {
+		field = strings.TrimSpace(field)
+		if field == "" {
+			continue
+		}
+		columnName := d.columnName(tableName, field)
+		if _, ok := d.columnsInTablesMap[tableName][columnName]; !ok || d.hiddenColumn(tableName, columnName) {
+			return "", errors.New("unknown field " + field)
+		}
+		columns = append(columns, d.dialect.quote(columnName))
+	}
+
+	if len(columns) == 0 {
+		return d.visibleColumns(tableName)
+	}
+	return strings.Join(columns, ", "), nil
+}
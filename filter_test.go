@@ -0,0 +1,113 @@
+package main
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestFilterCondition(t *testing.T) {
+	d := DbExplorer{
+		dialect: PostgreSQL,
+		columnsInTablesMap: map[string]map[string]columnParams{
+			"users": {
+				"id":   {name: "id", typeName: "int"},
+				"age":  {name: "age", typeName: "int"},
+				"name": {name: "name", typeName: "string"},
+			},
+		},
+	}
+
+	params, _ := url.ParseQuery("age__gte=18&name__like=iv%25&id__in=1,2,3&limit=5")
+	args := &queryArgs{dialect: d.dialect}
+	condition, err := d.filterCondition("users", params, args)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := `"age" >= $1 AND "id" IN ($2, $3, $4) AND "name" LIKE $5`
+	if condition != expected {
+		t.Fatalf("expected %s, got %s", expected, condition)
+	}
+	if !reflect.DeepEqual(args.values, []interface{}{18, 1, 2, 3, "iv%"}) {
+		t.Fatalf("unexpected args: %#v", args.values)
+	}
+
+	errorCases := map[string]string{
+		"age__gt=old":     "field age have invalid type",
+		"age__like=1%25":  "field age does not support like",
+		"email__ne=a@b.c": "unknown field email",
+		"id__in=1,x":      "field id have invalid type",
+	}
+	for query, expectedErr := range errorCases {
+		params, _ := url.ParseQuery(query)
+		_, err := d.filterCondition("users", params, &queryArgs{dialect: d.dialect})
+		if err == nil || err.Error() != expectedErr {
+			t.Fatalf("[%s] expected error %q, got %v", query, expectedErr, err)
+		}
+	}
+}
+
+func TestOrderClause(t *testing.T) {
+	d := DbExplorer{
+		dialect: MySQL,
+		tableIdNamesMap: map[string][]string{
+			"users": {"id"},
+		},
+		columnsInTablesMap: map[string]map[string]columnParams{
+			"users": {
+				"id":   {name: "id", typeName: "int"},
+				"name": {name: "name", typeName: "string"},
+			},
+		},
+	}
+
+	cases := map[string]string{
+		"":         "ORDER BY `id` ASC",
+		"name,-id": "ORDER BY `name` ASC, `id` DESC",
+		"-name":    "ORDER BY `name` DESC",
+	}
+	for rawSort, expected := range cases {
+		order, err := d.orderClause("users", rawSort)
+		if err != nil {
+			t.Fatalf("[%s] unexpected error: %v", rawSort, err)
+		}
+		if order != expected {
+			t.Fatalf("[%s] expected %s, got %s", rawSort, expected, order)
+		}
+	}
+
+	if _, err := d.orderClause("users", "email"); err == nil || err.Error() != "unknown sort field email" {
+		t.Fatalf("expected unknown sort field error, got %v", err)
+	}
+}
+
+func TestCompositeCursorCondition(t *testing.T) {
+	d := DbExplorer{
+		dialect: MySQL,
+		tableIdNamesMap: map[string][]string{
+			"order_items": {"order_id", "item_id"},
+		},
+		columnsInTablesMap: map[string]map[string]columnParams{
+			"order_items": {
+				"order_id": {name: "order_id", typeName: "int", primary: true},
+				"item_id":  {name: "item_id", typeName: "int", primary: true},
+			},
+		},
+	}
+
+	cursor := d.encodeCursor("order_items", map[string]interface{}{"order_id": 1, "item_id": 2})
+	args := &queryArgs{dialect: d.dialect}
+	condition, err := d.cursorCondition("order_items", cursor, args)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := "((`order_id` > ?) OR (`order_id` = ? AND `item_id` > ?))"
+	if condition != expected {
+		t.Fatalf("expected %s, got %s", expected, condition)
+	}
+	if !reflect.DeepEqual(args.values, []interface{}{1, 1, 2}) {
+		t.Fatalf("unexpected args: %#v", args.values)
+	}
+}
@@ -0,0 +1,633 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// handlerGraphQL обслуживает /graphql. Корневые поля строятся по таблицам:
+//
+//	query    { items(limit: 10, sort: "-id", title__like: "db%") { id title } items_by_pk(id: 1) { title } }
+//	mutation { insert_items(data: {title: "x"}) { id } update_items(id: 1, data: {...}) delete_items(id: 1) }
+//
+// Аргументы списка те же, что query-параметры GET /{table}; update и delete возвращают число затронутых записей.
+func (d DbExplorer) handlerGraphQL(rw http.ResponseWriter, r *http.Request) {
+	request := struct {
+		Query         string          `json:"query"`
+		OperationName string          `json:"operationName"`
+		Variables     json.RawMessage `json:"variables"`
+	}{}
+
+	switch r.Method {
+	case http.MethodGet:
+		params := r.URL.Query()
+		request.Query = params.Get("query")
+		request.OperationName = params.Get("operationName")
+		request.Variables = json.RawMessage(params.Get("variables"))
+	case http.MethodPost:
+		if strings.HasPrefix(r.Header.Get("Content-Type"), "application/graphql") {
+			body, err := ioutil.ReadAll(r.Body)
+			if err != nil {
+				graphQLResponse(rw, bodyErrorStatus(err), nil, []gqlError{{Message: err.Error()}})
+				return
+			}
+			request.Query = string(body)
+		} else if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+			if status := bodyErrorStatus(err); status == http.StatusRequestEntityTooLarge {
+				graphQLResponse(rw, status, nil, []gqlError{{Message: err.Error()}})
+				return
+			}
+			graphQLResponse(rw, http.StatusBadRequest, nil, []gqlError{{Message: "invalid request body"}})
+			return
+		}
+	default:
+		rw.Header().Set("Allow", "GET, POST, OPTIONS")
+		graphQLResponse(rw, http.StatusMethodNotAllowed, nil, []gqlError{{Message: errMethodNotAllowed.Error()}})
+		return
+	}
+
+	variables := make(map[string]interface{})
+	if len(bytes.TrimSpace(request.Variables)) > 0 && string(request.Variables) != "null" {
+		decoder := json.NewDecoder(bytes.NewReader(request.Variables))
+		decoder.UseNumber()
+		if err := decoder.Decode(&variables); err != nil {
+			graphQLResponse(rw, http.StatusBadRequest, nil, []gqlError{{Message: "variables must be a JSON object"}})
+			return
+		}
+	}
+
+	document, err := parseGraphQL(request.Query)
+	if err != nil {
+		graphQLResponse(rw, http.StatusBadRequest, nil, []gqlError{{Message: err.Error()}})
+		return
+	}
+
+	operation, err := document.operation(request.OperationName)
+	if err != nil {
+		graphQLResponse(rw, http.StatusBadRequest, nil, []gqlError{{Message: err.Error()}})
+		return
+	}
+	if operation.kind == "mutation" && r.Method == http.MethodGet {
+		rw.Header().Set("Allow", "POST")
+		graphQLResponse(rw, http.StatusMethodNotAllowed, nil, []gqlError{{Message: "mutations require POST"}})
+		return
+	}
+	if operation.kind == "subscription" {
+		graphQLResponse(rw, http.StatusBadRequest, nil, []gqlError{{Message: "subscriptions are not supported"}})
+		return
+	}
+
+	for name, value := range operation.defaults {
+		if _, ok := variables[name]; !ok {
+			variables[name] = value
+		}
+	}
+
+	executor := &gqlExecutor{explorer: d, document: document, variables: variables, errors: []gqlError{},
+		request: r}
+	data := executor.execute(operation)
+	graphQLResponse(rw, http.StatusOK, data, executor.errors)
+}
+
+type gqlError struct {
+	Message string        `json:"message"`
+	Path    []interface{} `json:"path,omitempty"`
+}
+
+// gqlObject сохраняет порядок полей из запроса, как требует спецификация
+type gqlObject []gqlField
+
+type gqlField struct {
+	key   string
+	value interface{}
+}
+
+func (o gqlObject) MarshalJSON() ([]byte, error) {
+	buffer := bytes.NewBufferString("{")
+	for i, field := range o {
+		if i > 0 {
+			buffer.WriteByte(',')
+		}
+		key, _ := json.Marshal(field.key)
+		value, err := json.Marshal(field.value)
+		if err != nil {
+			return nil, err
+		}
+		buffer.Write(key)
+		buffer.WriteByte(':')
+		buffer.Write(value)
+	}
+	buffer.WriteByte('}')
+	return buffer.Bytes(), nil
+}
+
+func graphQLResponse(rw http.ResponseWriter, status int, data interface{}, errs []gqlError) {
+	response := gqlObject{}
+	if len(errs) > 0 {
+		response = append(response, gqlField{"errors", errs})
+	}
+	if data != nil {
+		response = append(response, gqlField{"data", data})
+	}
+
+	body, err := json.Marshal(response)
+	if err != nil {
+		logResponseError(rw, err)
+	}
+
+	rw.Header().Set("Content-Type", "application/json")
+	rw.WriteHeader(status)
+	if _, err := rw.Write(body); err != nil {
+		logResponseError(rw, err)
+	}
+}
+
+func (doc *gqlDocument) operation(name string) (*gqlOperation, error) {
+	if name == "" {
+		if len(doc.operations) > 1 {
+			return nil, errors.New("operationName is required for documents with several operations")
+		}
+		return doc.operations[0], nil
+	}
+
+	for _, operation := range doc.operations {
+		if operation.name == name {
+			return operation, nil
+		}
+	}
+	return nil, errors.New("unknown operation " + name)
+}
+
+type gqlExecutor struct {
+	explorer  DbExplorer
+	document  *gqlDocument
+	variables map[string]interface{}
+	errors    []gqlError
+	// request — исходный запрос, по нему проверяются права на таблицы
+	request *http.Request
+}
+
+func (e *gqlExecutor) execute(operation *gqlOperation) gqlObject {
+	typeName := "Query"
+	if operation.kind == "mutation" {
+		typeName = "Mutation"
+	}
+
+	fields, err := e.collectFields(operation.selections, map[string]bool{})
+	if err != nil {
+		e.errors = append(e.errors, gqlError{Message: err.Error()})
+		return nil
+	}
+
+	// поля мутации выполняются по очереди, запросы тоже: порядок ответа совпадает с порядком полей
+	data := gqlObject{}
+	for _, field := range fields {
+		var value interface{}
+		if field.name == "__typename" {
+			value = typeName
+		} else if operation.kind == "mutation" {
+			value, err = e.resolveMutation(field)
+		} else {
+			value, err = e.resolveQuery(field)
+		}
+		if err != nil {
+			e.errors = append(e.errors, gqlError{Message: err.Error(), Path: []interface{}{field.alias}})
+			value = nil
+		}
+		data = append(data, gqlField{field.alias, value})
+	}
+	return data
+}
+
+// collectFields раскрывает фрагменты и применяет @include/@skip
+func (e *gqlExecutor) collectFields(selections []*gqlSelection, visited map[string]bool) ([]*gqlSelection, error) {
+	fields := make([]*gqlSelection, 0, len(selections))
+	for _, selection := range selections {
+		include, err := e.included(selection.directives)
+		if err != nil {
+			return nil, err
+		}
+		if !include {
+			continue
+		}
+
+		switch {
+		case selection.inline:
+			nested, err := e.collectFields(selection.selections, visited)
+			if err != nil {
+				return nil, err
+			}
+			fields = append(fields, nested...)
+
+		case selection.fragment != "":
+			fragment, ok := e.document.fragments[selection.fragment]
+			if !ok {
+				return nil, errors.New("unknown fragment " + selection.fragment)
+			}
+			if visited[fragment.name] {
+				continue
+			}
+			visited[fragment.name] = true
+			nested, err := e.collectFields(fragment.selections, visited)
+			delete(visited, fragment.name)
+			if err != nil {
+				return nil, err
+			}
+			fields = append(fields, nested...)
+
+		default:
+			fields = append(fields, selection)
+		}
+	}
+	return fields, nil
+}
+
+func (e *gqlExecutor) included(directives []gqlDirective) (bool, error) {
+	for _, directive := range directives {
+		if directive.name != "include" && directive.name != "skip" {
+			continue
+		}
+		value, err := e.resolveValue(directive.args["if"])
+		if err != nil {
+			return false, err
+		}
+		condition, ok := value.(bool)
+		if !ok {
+			return false, errors.New("directive @" + directive.name + " requires boolean argument if")
+		}
+		if condition == (directive.name == "skip") {
+			return false, nil
+		}
+	}
+	return true, nil
+}
+
+func (e *gqlExecutor) resolveValue(value interface{}) (interface{}, error) {
+	switch typed := value.(type) {
+	case gqlVariableRef:
+		return e.variables[string(typed)], nil
+	case []interface{}:
+		list := make([]interface{}, 0, len(typed))
+		for _, item := range typed {
+			resolved, err := e.resolveValue(item)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, resolved)
+		}
+		return list, nil
+	case map[string]interface{}:
+		object := make(map[string]interface{}, len(typed))
+		for key, item := range typed {
+			resolved, err := e.resolveValue(item)
+			if err != nil {
+				return nil, err
+			}
+			object[key] = resolved
+		}
+		return object, nil
+	}
+	return value, nil
+}
+
+func (e *gqlExecutor) resolveArgs(field *gqlSelection) (map[string]interface{}, error) {
+	args := make(map[string]interface{}, len(field.args))
+	for name, value := range field.args {
+		resolved, err := e.resolveValue(value)
+		if err != nil {
+			return nil, err
+		}
+		args[name] = resolved
+	}
+	return args, nil
+}
+
+// rootTable находит таблицу по имени корневого поля с учётом префикса и суффикса
+// checkAccess проверяет права так же, как REST-запрос с методом method к таблице
+func (e *gqlExecutor) checkAccess(tableName, method string) error {
+	return e.explorer.tableAccess(e.request, tableName, method)
+}
+
+func (e *gqlExecutor) rootTable(fieldName, prefix, suffix string) (string, bool) {
+	if !strings.HasPrefix(fieldName, prefix) || !strings.HasSuffix(fieldName, suffix) {
+		return "", false
+	}
+	tableName := strings.TrimSuffix(strings.TrimPrefix(fieldName, prefix), suffix)
+	return tableName, containsString(e.explorer.tableKeys, tableName)
+}
+
+func (e *gqlExecutor) resolveQuery(field *gqlSelection) (interface{}, error) {
+	args, err := e.resolveArgs(field)
+	if err != nil {
+		return nil, err
+	}
+
+	if tableName, ok := e.rootTable(field.name, "", ""); ok {
+		if err := e.checkAccess(tableName, http.MethodGet); err != nil {
+			return nil, err
+		}
+		return e.resolveList(tableName, field, args)
+	}
+	if tableName, ok := e.rootTable(field.name, "", "_by_pk"); ok {
+		if err := e.checkAccess(tableName, http.MethodGet); err != nil {
+			return nil, err
+		}
+		rawId, err := e.primaryKeyArgs(tableName, args)
+		if err != nil {
+			return nil, err
+		}
+		return e.resolveRecord(tableName, field, rawId)
+	}
+
+	return nil, errors.New("Cannot query field " + field.name + " on type Query")
+}
+
+func (e *gqlExecutor) resolveList(tableName string, field *gqlSelection, args map[string]interface{}) (interface{}, error) {
+	d := e.explorer
+	columns, err := e.selectedColumns(tableName, field)
+	if err != nil {
+		return nil, err
+	}
+
+	params := url.Values{}
+	for name, value := range args {
+		if name == "fields" || name == "count" || name == "after" {
+			return nil, errors.New("unknown argument " + name + " on field " + field.name)
+		}
+		values, err := graphQLParam(name, value)
+		if err != nil {
+			return nil, err
+		}
+		params[name] = values
+	}
+	params.Set("fields", strings.Join(columns, ","))
+
+	list, err := d.parseListQuery(tableName, params)
+	if err != nil {
+		return nil, err
+	}
+
+	records, err := d.queryList(list)
+	if err == ErrRecordNotFound {
+		records, err = []map[string]interface{}{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	result := make([]interface{}, 0, len(records))
+	for _, record := range records {
+		result = append(result, e.recordObject(tableName, field, record))
+	}
+	return result, nil
+}
+
+func (e *gqlExecutor) resolveRecord(tableName string, field *gqlSelection, rawId string) (interface{}, error) {
+	d := e.explorer
+	columns, err := e.selectedColumns(tableName, field)
+	if err != nil {
+		return nil, err
+	}
+
+	selected, err := d.selectColumns(tableName, strings.Join(columns, ","))
+	if err != nil {
+		return nil, err
+	}
+
+	record, err := d.queryRecord(d.db, tableName, rawId, selected)
+	if err == ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return e.recordObject(tableName, field, record), nil
+}
+
+func (e *gqlExecutor) resolveMutation(field *gqlSelection) (interface{}, error) {
+	d := e.explorer
+	if !d.dialect.writable() {
+		return nil, errors.New("writes are disabled for " + d.dialect.name())
+	}
+	if d.readOnly {
+		return nil, errReadOnly
+	}
+
+	args, err := e.resolveArgs(field)
+	if err != nil {
+		return nil, err
+	}
+
+	if tableName, ok := e.rootTable(field.name, "insert_", ""); ok {
+		if err := e.checkAccess(tableName, http.MethodPut); err != nil {
+			return nil, err
+		}
+		data, err := e.recordData(tableName, args)
+		if err != nil {
+			return nil, err
+		}
+		tx, err := d.db.BeginTx(d.requestContext(), nil)
+		if err != nil {
+			return nil, err
+		}
+		ids, err := d.insertRecord(tx, data, tableName)
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		if err := tx.Commit(); err != nil {
+			return nil, err
+		}
+		d.emitInsert(tableName, ids, data)
+
+		values := make([]string, 0, len(ids))
+		for _, key := range d.tableIdNamesMap[tableName] {
+			values = append(values, fmt.Sprintf("%v", ids[key]))
+		}
+		return e.resolveRecord(tableName, field, strings.Join(values, ","))
+	}
+
+	if tableName, ok := e.rootTable(field.name, "update_", ""); ok {
+		if len(field.selections) > 0 {
+			return nil, errors.New("field " + field.name + " of type Int must not have a selection")
+		}
+		if err := e.checkAccess(tableName, http.MethodPost); err != nil {
+			return nil, err
+		}
+		rawId, err := e.primaryKeyArgs(tableName, args)
+		if err != nil {
+			return nil, err
+		}
+		data, err := e.recordData(tableName, args)
+		if err != nil {
+			return nil, err
+		}
+		tx, err := d.db.BeginTx(d.requestContext(), nil)
+		if err != nil {
+			return nil, err
+		}
+		updated, err := d.updateRecord(tx, data, tableName, rawId)
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		if err := tx.Commit(); err != nil {
+			return nil, err
+		}
+		if updated > 0 {
+			d.emitUpdate(tableName, rawId, data)
+		}
+		return updated, nil
+	}
+
+	if tableName, ok := e.rootTable(field.name, "delete_", ""); ok {
+		if len(field.selections) > 0 {
+			return nil, errors.New("field " + field.name + " of type Int must not have a selection")
+		}
+		if err := e.checkAccess(tableName, http.MethodDelete); err != nil {
+			return nil, err
+		}
+		rawId, err := e.primaryKeyArgs(tableName, args)
+		if err != nil {
+			return nil, err
+		}
+		tx, err := d.db.BeginTx(d.requestContext(), nil)
+		if err != nil {
+			return nil, err
+		}
+		deleted, err := d.deleteRecord(tx, tableName, rawId)
+		if err != nil {
+			tx.Rollback()
+			return nil, err
+		}
+		if err := tx.Commit(); err != nil {
+			return nil, err
+		}
+		if deleted > 0 {
+			d.emitDelete(tableName, rawId)
+		}
+		return deleted, nil
+	}
+
+	return nil, errors.New("Cannot query field " + field.name + " on type Mutation")
+}
+
+// primaryKeyArgs собирает id записи в формате пути (/table/1,2) из аргументов по колонкам первичного ключа
+func (e *gqlExecutor) primaryKeyArgs(tableName string, args map[string]interface{}) (string, error) {
+	primaryKeys := e.explorer.tableIdNamesMap[tableName]
+	if len(primaryKeys) == 0 {
+		return "", errors.New("table " + tableName + " has no primary key")
+	}
+
+	values := make([]string, 0, len(primaryKeys))
+	for _, key := range primaryKeys {
+		field := e.explorer.fieldName(tableName, key)
+		value, ok := args[field]
+		if !ok || value == nil {
+			return "", errors.New("argument " + field + " is required")
+		}
+		params, err := graphQLParam(field, value)
+		if err != nil || len(params) != 1 {
+			return "", errors.New("argument " + field + " have invalid type")
+		}
+		values = append(values, params[0])
+	}
+	return strings.Join(values, ","), nil
+}
+
+func (e *gqlExecutor) recordData(tableName string, args map[string]interface{}) (map[string]interface{}, error) {
+	if len(e.explorer.tableIdNamesMap[tableName]) == 0 {
+		return nil, errors.New("table " + tableName + " has no primary key")
+	}
+
+	data, ok := args["data"].(map[string]interface{})
+	if !ok {
+		return nil, errors.New("argument data must be an object")
+	}
+	data, err := e.explorer.toColumns(tableName, data)
+	if err != nil {
+		return nil, err
+	}
+	return e.explorer.validateRecord(tableName, data)
+}
+
+// selectedColumns возвращает поля, запрошенные в selection set записи: имена колонок или их алиасы,
+// см. WithColumnAliases
+func (e *gqlExecutor) selectedColumns(tableName string, field *gqlSelection) ([]string, error) {
+	if len(field.selections) == 0 {
+		return nil, errors.New("field " + field.name + " must have a selection of subfields")
+	}
+
+	fields, err := e.collectFields(field.selections, map[string]bool{})
+	if err != nil {
+		return nil, err
+	}
+
+	columns := make([]string, 0, len(fields))
+	for _, selection := range fields {
+		if selection.name == "__typename" {
+			continue
+		}
+		columnName := e.explorer.columnName(tableName, selection.name)
+		if _, ok := e.explorer.columnsInTablesMap[tableName][columnName]; !ok || e.explorer.hiddenColumn(tableName, columnName) {
+			return nil, errors.New("Cannot query field " + selection.name + " on type " + openAPIName(tableName))
+		}
+		if len(selection.selections) > 0 {
+			return nil, errors.New("field " + selection.name + " must not have a selection")
+		}
+		if !containsString(columns, selection.name) {
+			columns = append(columns, selection.name)
+		}
+	}
+
+	if len(columns) == 0 {
+		// только __typename: выбираем первичный ключ, чтобы запрос оставался валидным
+		columns = append(columns, e.explorer.fieldName(tableName, e.explorer.columnKeysMap[tableName][0]))
+	}
+	return columns, nil
+}
+
+func (e *gqlExecutor) recordObject(tableName string, field *gqlSelection, record map[string]interface{}) gqlObject {
+	// ошибки разбора уже проверены в selectedColumns
+	fields, _ := e.collectFields(field.selections, map[string]bool{})
+
+	object := make(gqlObject, 0, len(fields))
+	for _, selection := range fields {
+		if selection.name == "__typename" {
+			object = append(object, gqlField{selection.alias, openAPIName(tableName)})
+			continue
+		}
+		object = append(object, gqlField{selection.alias, record[e.explorer.columnName(tableName, selection.name)]})
+	}
+	return object
+}
+
+// graphQLParam переводит значение аргумента в query-параметры, которые понимает parseListQuery
+func graphQLParam(name string, value interface{}) ([]string, error) {
+	switch typed := value.(type) {
+	case string:
+		return []string{typed}, nil
+	case json.Number:
+		return []string{typed.String()}, nil
+	case bool:
+		return []string{strconv.FormatBool(typed)}, nil
+	case []interface{}:
+		values := make([]string, 0, len(typed))
+		for _, item := range typed {
+			params, err := graphQLParam(name, item)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, params...)
+		}
+		return values, nil
+	}
+	return nil, errors.New("argument " + name + " have invalid type")
+}
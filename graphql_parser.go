@@ -0,0 +1,505 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Разбор подмножества GraphQL, которого хватает для /graphql: операции query и mutation,
+// переменные, аргументы, алиасы, фрагменты и директивы @include/@skip.
+
+type gqlDocument struct {
+	operations []*gqlOperation
+	fragments  map[string]*gqlFragment
+}
+
+type gqlOperation struct {
+	kind       string
+	name       string
+	defaults   map[string]interface{}
+	selections []*gqlSelection
+}
+
+type gqlFragment struct {
+	name       string
+	selections []*gqlSelection
+}
+
+type gqlSelection struct {
+	alias      string
+	name       string
+	args       map[string]interface{}
+	directives []gqlDirective
+	selections []*gqlSelection
+	// fragment задан у спреда ...Name, inline — у ... on Type { }
+	fragment string
+	inline   bool
+}
+
+type gqlDirective struct {
+	name string
+	args map[string]interface{}
+}
+
+// gqlVariableRef — ссылка $name в значении аргумента, подставляется при выполнении
+type gqlVariableRef string
+
+type gqlToken struct {
+	kind  byte // 'p' знак препинания, 'n' имя, 'i' целое, 'f' дробное, 's' строка, 0 конец
+	value string
+	pos   int
+}
+
+func gqlTokenize(source string) ([]gqlToken, error) {
+	tokens := make([]gqlToken, 0)
+	source = strings.TrimPrefix(source, "\uFEFF")
+	for i := 0; i < len(source); {
+		ch := source[i]
+		switch {
+		case ch == ' ' || ch == '\t' || ch == '\n' || ch == '\r' || ch == ',':
+			i++
+		case ch == '#':
+			for i < len(source) && source[i] != '\n' && source[i] != '\r' {
+				i++
+			}
+		case strings.HasPrefix(source[i:], "..."):
+			tokens = append(tokens, gqlToken{kind: 'p', value: "...", pos: i})
+			i += 3
+		case strings.IndexByte("!$()[]{}:=@|&", ch) != -1:
+			tokens = append(tokens, gqlToken{kind: 'p', value: string(ch), pos: i})
+			i++
+		case ch == '_' || (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z'):
+			start := i
+			for i < len(source) && (source[i] == '_' || (source[i] >= 'a' && source[i] <= 'z') ||
+				(source[i] >= 'A' && source[i] <= 'Z') || (source[i] >= '0' && source[i] <= '9')) {
+				i++
+			}
+			tokens = append(tokens, gqlToken{kind: 'n', value: source[start:i], pos: start})
+		case ch == '-' || (ch >= '0' && ch <= '9'):
+			start := i
+			kind := byte('i')
+			i++
+			for i < len(source) && strings.IndexByte("0123456789.eE+-", source[i]) != -1 {
+				if strings.IndexByte(".eE", source[i]) != -1 {
+					kind = 'f'
+				}
+				i++
+			}
+			number := source[start:i]
+			if _, err := strconv.ParseFloat(number, 64); err != nil {
+				return nil, errors.New("syntax error: invalid number " + number)
+			}
+			tokens = append(tokens, gqlToken{kind: kind, value: number, pos: start})
+		case strings.HasPrefix(source[i:], `"""`):
+			start := i
+			end := -1
+			for j := i + 3; j+3 <= len(source); j++ {
+				if source[j] == '\\' && strings.HasPrefix(source[j+1:], `"""`) {
+					j += 3
+					continue
+				}
+				if strings.HasPrefix(source[j:], `"""`) {
+					end = j - i - 3
+					break
+				}
+			}
+			if end == -1 {
+				return nil, errors.New("syntax error: unterminated string")
+			}
+			value := strings.ReplaceAll(source[i+3:i+3+end], `\"""`, `"""`)
+			tokens = append(tokens, gqlToken{kind: 's', value: strings.TrimSpace(value), pos: start})
+			i += 3 + end + 3
+		case ch == '"':
+			start := i
+			i++
+			for i < len(source) && source[i] != '"' {
+				if source[i] == '\\' {
+					i++
+				}
+				if i < len(source) && (source[i] == '\n' || source[i] == '\r') {
+					return nil, errors.New("syntax error: unterminated string")
+				}
+				i++
+			}
+			if i >= len(source) {
+				return nil, errors.New("syntax error: unterminated string")
+			}
+			i++
+			// экранирование в GraphQL совпадает с JSON
+			var value string
+			if err := json.Unmarshal([]byte(source[start:i]), &value); err != nil {
+				return nil, errors.New("syntax error: invalid string " + source[start:i])
+			}
+			tokens = append(tokens, gqlToken{kind: 's', value: value, pos: start})
+		default:
+			r, _ := utf8.DecodeRuneInString(source[i:])
+			return nil, errors.New("syntax error: unexpected character " + strconv.QuoteRune(r))
+		}
+	}
+
+	return append(tokens, gqlToken{pos: len(source)}), nil
+}
+
+type gqlParser struct {
+	tokens []gqlToken
+	pos    int
+}
+
+func parseGraphQL(source string) (*gqlDocument, error) {
+	tokens, err := gqlTokenize(source)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &gqlParser{tokens: tokens}
+	document := &gqlDocument{fragments: make(map[string]*gqlFragment)}
+	for p.peek().kind != 0 {
+		if p.peekName("fragment") {
+			fragment, err := p.parseFragment()
+			if err != nil {
+				return nil, err
+			}
+			document.fragments[fragment.name] = fragment
+			continue
+		}
+
+		operation, err := p.parseOperation()
+		if err != nil {
+			return nil, err
+		}
+		document.operations = append(document.operations, operation)
+	}
+
+	if len(document.operations) == 0 {
+		return nil, errors.New("document has no operations")
+	}
+	return document, nil
+}
+
+func (p *gqlParser) peek() gqlToken {
+	return p.tokens[p.pos]
+}
+
+func (p *gqlParser) next() gqlToken {
+	token := p.tokens[p.pos]
+	if token.kind != 0 {
+		p.pos++
+	}
+	return token
+}
+
+func (p *gqlParser) peekPunct(value string) bool {
+	token := p.peek()
+	return token.kind == 'p' && token.value == value
+}
+
+func (p *gqlParser) peekName(value string) bool {
+	token := p.peek()
+	return token.kind == 'n' && token.value == value
+}
+
+func (p *gqlParser) unexpected() error {
+	token := p.peek()
+	if token.kind == 0 {
+		return errors.New("syntax error: unexpected end of document")
+	}
+	return errors.New("syntax error: unexpected " + strconv.Quote(token.value) + " at position " + strconv.Itoa(token.pos))
+}
+
+func (p *gqlParser) expectPunct(value string) error {
+	if !p.peekPunct(value) {
+		return p.unexpected()
+	}
+	p.next()
+	return nil
+}
+
+func (p *gqlParser) expectName() (string, error) {
+	if p.peek().kind != 'n' {
+		return "", p.unexpected()
+	}
+	return p.next().value, nil
+}
+
+func (p *gqlParser) parseOperation() (*gqlOperation, error) {
+	operation := &gqlOperation{kind: "query", defaults: make(map[string]interface{})}
+	if p.peekPunct("{") {
+		selections, err := p.parseSelectionSet()
+		operation.selections = selections
+		return operation, err
+	}
+
+	kind, err := p.expectName()
+	if err != nil {
+		return nil, err
+	}
+	if kind != "query" && kind != "mutation" && kind != "subscription" {
+		return nil, errors.New("syntax error: unknown operation " + kind)
+	}
+	operation.kind = kind
+
+	if p.peek().kind == 'n' {
+		operation.name = p.next().value
+	}
+
+	if p.peekPunct("(") {
+		p.next()
+		for !p.peekPunct(")") {
+			if err := p.expectPunct("$"); err != nil {
+				return nil, err
+			}
+			name, err := p.expectName()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expectPunct(":"); err != nil {
+				return nil, err
+			}
+			if err := p.skipType(); err != nil {
+				return nil, err
+			}
+			if p.peekPunct("=") {
+				p.next()
+				value, err := p.parseValue(true)
+				if err != nil {
+					return nil, err
+				}
+				operation.defaults[name] = value
+			}
+			if _, err := p.parseDirectives(); err != nil {
+				return nil, err
+			}
+		}
+		p.next()
+	}
+
+	if _, err := p.parseDirectives(); err != nil {
+		return nil, err
+	}
+
+	operation.selections, err = p.parseSelectionSet()
+	return operation, err
+}
+
+// skipType пропускает тип переменной: типы проверяются при выполнении по колонкам
+func (p *gqlParser) skipType() error {
+	if p.peekPunct("[") {
+		p.next()
+		if err := p.skipType(); err != nil {
+			return err
+		}
+		if err := p.expectPunct("]"); err != nil {
+			return err
+		}
+	} else if _, err := p.expectName(); err != nil {
+		return err
+	}
+
+	if p.peekPunct("!") {
+		p.next()
+	}
+	return nil
+}
+
+func (p *gqlParser) parseFragment() (*gqlFragment, error) {
+	p.next()
+	name, err := p.expectName()
+	if err != nil {
+		return nil, err
+	}
+	if !p.peekName("on") {
+		return nil, p.unexpected()
+	}
+	p.next()
+	if _, err := p.expectName(); err != nil {
+		return nil, err
+	}
+	if _, err := p.parseDirectives(); err != nil {
+		return nil, err
+	}
+
+	selections, err := p.parseSelectionSet()
+	if err != nil {
+		return nil, err
+	}
+	return &gqlFragment{name: name, selections: selections}, nil
+}
+
+func (p *gqlParser) parseSelectionSet() ([]*gqlSelection, error) {
+	if err := p.expectPunct("{"); err != nil {
+		return nil, err
+	}
+
+	selections := make([]*gqlSelection, 0)
+	for !p.peekPunct("}") {
+		selection, err := p.parseSelection()
+		if err != nil {
+			return nil, err
+		}
+		selections = append(selections, selection)
+	}
+	p.next()
+
+	if len(selections) == 0 {
+		return nil, errors.New("syntax error: empty selection set")
+	}
+	return selections, nil
+}
+
+func (p *gqlParser) parseSelection() (*gqlSelection, error) {
+	var err error
+	selection := &gqlSelection{}
+
+	if p.peekPunct("...") {
+		p.next()
+		if p.peek().kind == 'n' && !p.peekName("on") {
+			selection.fragment = p.next().value
+			selection.directives, err = p.parseDirectives()
+			return selection, err
+		}
+
+		selection.inline = true
+		if p.peekName("on") {
+			p.next()
+			if _, err := p.expectName(); err != nil {
+				return nil, err
+			}
+		}
+		if selection.directives, err = p.parseDirectives(); err != nil {
+			return nil, err
+		}
+		selection.selections, err = p.parseSelectionSet()
+		return selection, err
+	}
+
+	if selection.name, err = p.expectName(); err != nil {
+		return nil, err
+	}
+	selection.alias = selection.name
+	if p.peekPunct(":") {
+		p.next()
+		if selection.name, err = p.expectName(); err != nil {
+			return nil, err
+		}
+	}
+
+	if selection.args, err = p.parseArguments(); err != nil {
+		return nil, err
+	}
+	if selection.directives, err = p.parseDirectives(); err != nil {
+		return nil, err
+	}
+	if p.peekPunct("{") {
+		selection.selections, err = p.parseSelectionSet()
+	}
+	return selection, err
+}
+
+func (p *gqlParser) parseArguments() (map[string]interface{}, error) {
+	args := make(map[string]interface{})
+	if !p.peekPunct("(") {
+		return args, nil
+	}
+	p.next()
+
+	for !p.peekPunct(")") {
+		name, err := p.expectName()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expectPunct(":"); err != nil {
+			return nil, err
+		}
+		if args[name], err = p.parseValue(false); err != nil {
+			return nil, err
+		}
+	}
+	p.next()
+	return args, nil
+}
+
+func (p *gqlParser) parseDirectives() ([]gqlDirective, error) {
+	directives := make([]gqlDirective, 0)
+	for p.peekPunct("@") {
+		p.next()
+		name, err := p.expectName()
+		if err != nil {
+			return nil, err
+		}
+		args, err := p.parseArguments()
+		if err != nil {
+			return nil, err
+		}
+		directives = append(directives, gqlDirective{name: name, args: args})
+	}
+	return directives, nil
+}
+
+// parseValue разбирает литерал в те же типы, что даёт json.Decoder с UseNumber;
+// constant запрещает переменные (значения по умолчанию)
+func (p *gqlParser) parseValue(constant bool) (interface{}, error) {
+	token := p.peek()
+	switch token.kind {
+	case 'i', 'f':
+		p.next()
+		return json.Number(token.value), nil
+	case 's':
+		p.next()
+		return token.value, nil
+	case 'n':
+		p.next()
+		switch token.value {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		case "null":
+			return nil, nil
+		}
+		// значения enum передаются строкой
+		return token.value, nil
+	}
+
+	switch {
+	case p.peekPunct("$") && !constant:
+		p.next()
+		name, err := p.expectName()
+		return gqlVariableRef(name), err
+
+	case p.peekPunct("["):
+		p.next()
+		list := make([]interface{}, 0)
+		for !p.peekPunct("]") {
+			value, err := p.parseValue(constant)
+			if err != nil {
+				return nil, err
+			}
+			list = append(list, value)
+		}
+		p.next()
+		return list, nil
+
+	case p.peekPunct("{"):
+		p.next()
+		object := make(map[string]interface{})
+		for !p.peekPunct("}") {
+			name, err := p.expectName()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expectPunct(":"); err != nil {
+				return nil, err
+			}
+			if object[name], err = p.parseValue(constant); err != nil {
+				return nil, err
+			}
+		}
+		p.next()
+		return object, nil
+	}
+
+	return nil, p.unexpected()
+}
@@ -0,0 +1,62 @@
+package main
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestParseGraphQL(t *testing.T) {
+	document, err := parseGraphQL(`
+# комментарий
+query List($limit: Int = 10, $ids: [Int!]) {
+  first: items(limit: $limit, id__in: $ids, title: "a \"b\"") @include(if: true) {
+    id
+    ... on Items { title }
+    ...rest
+  }
+}
+
+fragment rest on Items { description }
+`)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	operation, err := document.operation("List")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if operation.kind != "query" || !reflect.DeepEqual(operation.defaults, map[string]interface{}{"limit": json.Number("10")}) {
+		t.Fatalf("unexpected operation: %#v", operation)
+	}
+
+	field := operation.selections[0]
+	expectedArgs := map[string]interface{}{
+		"limit":  gqlVariableRef("limit"),
+		"id__in": gqlVariableRef("ids"),
+		"title":  `a "b"`,
+	}
+	if field.alias != "first" || field.name != "items" || !reflect.DeepEqual(field.args, expectedArgs) {
+		t.Fatalf("unexpected field: %#v", field)
+	}
+
+	executor := &gqlExecutor{document: document, variables: map[string]interface{}{}}
+	fields, err := executor.collectFields(field.selections, map[string]bool{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	names := make([]string, 0, len(fields))
+	for _, selection := range fields {
+		names = append(names, selection.name)
+	}
+	if !reflect.DeepEqual(names, []string{"id", "title", "description"}) {
+		t.Fatalf("unexpected fields: %v", names)
+	}
+
+	for _, query := range []string{`{ items { id }`, `{ items(id: ) { id } }`, `{ items { id } } %`, `fragment a on B { id }`} {
+		if _, err := parseGraphQL(query); err == nil {
+			t.Fatalf("[%s] expected syntax error", query)
+		}
+	}
+}
@@ -0,0 +1,406 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+const grpcServiceName = "dbexplorer.DbExplorer"
+
+// grpcExplorerServer — сервис dbexplorer.DbExplorer из dbexplorer.proto
+type grpcExplorerServer interface {
+	List(context.Context, *structpb.Struct) (*structpb.Struct, error)
+	Get(context.Context, *structpb.Struct) (*structpb.Struct, error)
+	Insert(context.Context, *structpb.Struct) (*structpb.Struct, error)
+	Update(context.Context, *structpb.Struct) (*structpb.Struct, error)
+	Delete(context.Context, *structpb.Struct) (*structpb.Struct, error)
+}
+
+// сообщения — google.protobuf.Struct, поэтому описание сервиса собрано вручную без protoc
+var grpcServiceDesc = grpc.ServiceDesc{
+	ServiceName: grpcServiceName,
+	HandlerType: (*grpcExplorerServer)(nil),
+	Methods: []grpc.MethodDesc{
+		grpcMethod("List", grpcExplorerServer.List),
+		grpcMethod("Get", grpcExplorerServer.Get),
+		grpcMethod("Insert", grpcExplorerServer.Insert),
+		grpcMethod("Update", grpcExplorerServer.Update),
+		grpcMethod("Delete", grpcExplorerServer.Delete),
+	},
+	Streams:  []grpc.StreamDesc{},
+	Metadata: "dbexplorer.proto",
+}
+
+// RegisterGRPCService регистрирует gRPC-фасад explorer'а на сервере рядом с HTTP-обработчиком.
+// Вызовы проходят те же проверки, что и REST: лимит запросов, API-ключ или JWT из метаданных
+// x-api-key и authorization и права на таблицу.
+func RegisterGRPCService(server grpc.ServiceRegistrar, explorer *DbExplorer) {
+	server.RegisterService(&grpcServiceDesc, grpcExplorer{explorer: *explorer})
+}
+
+func grpcMethod(name string, call func(grpcExplorerServer, context.Context, *structpb.Struct) (*structpb.Struct, error)) grpc.MethodDesc {
+	return grpc.MethodDesc{
+		MethodName: name,
+		Handler: func(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+			in := new(structpb.Struct)
+			if err := dec(in); err != nil {
+				return nil, err
+			}
+			if explorer, ok := srv.(grpcExplorer); ok {
+				if !explorer.explorer.lifecycle.begin() {
+					return nil, status.Error(codes.Unavailable, errShuttingDown.Error())
+				}
+				defer explorer.explorer.lifecycle.end()
+			}
+			info := &grpc.UnaryServerInfo{Server: srv, FullMethod: "/" + grpcServiceName + "/" + name}
+			handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+				return call(srv.(grpcExplorerServer), ctx, req.(*structpb.Struct))
+			}
+			if explorer, ok := srv.(grpcExplorer); ok {
+				// проверки explorer'а идут до перехватчиков сервера, поэтому их нельзя забыть подключить
+				next := handler
+				handler = func(ctx context.Context, req interface{}) (interface{}, error) {
+					return explorer.authenticateCall(ctx, req, info, next)
+				}
+			}
+			if interceptor == nil {
+				return handler(ctx, in)
+			}
+			return interceptor(ctx, in, info, handler)
+		},
+	}
+}
+
+type grpcExplorer struct {
+	explorer DbExplorer
+	call     *http.Request // запрос текущего вызова, см. begin
+}
+
+// grpcCallKey — ключ контекста, под которым authenticateCall оставляет запрос вызова
+type grpcCallKey struct{}
+
+// grpcHTTPRequest представляет вызов gRPC запросом HTTP: метаданные становятся заголовками, адрес
+// клиента — RemoteAddr. Так аутентификация, лимиты, Authorizer и маскирование работают как для REST.
+func grpcHTTPRequest(ctx context.Context, fullMethod string) *http.Request {
+	r, _ := http.NewRequestWithContext(ctx, http.MethodPost, fullMethod, nil)
+	if md, ok := metadata.FromIncomingContext(ctx); ok {
+		for key, values := range md {
+			for _, value := range values {
+				r.Header.Add(key, value)
+			}
+		}
+	}
+	if client, ok := peer.FromContext(ctx); ok && client.Addr != nil {
+		r.RemoteAddr = client.Addr.String()
+	}
+	return r
+}
+
+// authenticateCall — перехватчик каждого вызова: лимит запросов и API-ключ или JWT из метаданных
+// x-api-key и authorization, как у HTTP-запросов
+func (g grpcExplorer) authenticateCall(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+	r := grpcHTTPRequest(ctx, info.FullMethod)
+	if g.explorer.limiter != nil {
+		if ok, wait := g.explorer.limiter.allow(r, time.Now()); !ok {
+			grpc.SetHeader(ctx, metadata.Pairs("retry-after", strconv.Itoa(int(math.Ceil(wait.Seconds())))))
+			return nil, status.Error(codes.ResourceExhausted, errTooManyRequests.Error())
+		}
+	}
+	r, err := g.explorer.authenticateRequest(r)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+	return handler(context.WithValue(ctx, grpcCallKey{}, r), req)
+}
+
+// begin готовит копию explorer'а для одного вызова, как serve для HTTP-запроса: контекст вызова,
+// маскирование и журнал аудита по запросу, который проверил authenticateCall
+func (g grpcExplorer) begin(ctx context.Context) (grpcExplorer, error) {
+	r, ok := ctx.Value(grpcCallKey{}).(*http.Request)
+	if !ok {
+		return g, status.Error(codes.Unauthenticated, errUnauthorized.Error())
+	}
+	g.call = r
+	g.explorer = g.explorer.withSchema().withMasking(r).withAudit(r)
+	g.explorer.ctx = ctx
+	return g, nil
+}
+
+// grpcRequest — поля запроса, значения разобраны как из JSON-тела HTTP-запроса
+type grpcRequest struct {
+	Table  string                 `json:"table"`
+	ID     interface{}            `json:"id"`
+	Limit  json.Number            `json:"limit"`
+	Offset json.Number            `json:"offset"`
+	Sort   string                 `json:"sort"`
+	Fields string                 `json:"fields"`
+	Filter map[string]interface{} `json:"filter"`
+	Record map[string]interface{} `json:"record"`
+}
+
+// request разбирает вызов и проверяет права на таблицу так же, как REST-запрос method к ней
+func (g grpcExplorer) request(in *structpb.Struct, method string, needID bool) (*grpcRequest, error) {
+	body, err := protojson.Marshal(in)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	request := &grpcRequest{}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	if err := decoder.Decode(request); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	if !containsString(g.explorer.tableKeys, request.Table) {
+		return nil, status.Error(codes.NotFound, "unknown table")
+	}
+	if err := g.explorer.tableAccess(g.call, request.Table, method); err != nil {
+		return nil, status.Error(codes.PermissionDenied, err.Error())
+	}
+	if needID && len(g.explorer.tableIdNamesMap[request.Table]) == 0 {
+		return nil, status.Error(codes.FailedPrecondition, "table "+request.Table+" has no primary key")
+	}
+	if needID && request.ID == nil {
+		return nil, status.Error(codes.InvalidArgument, "id is required")
+	}
+	return request, nil
+}
+
+func (r *grpcRequest) rawId() string {
+	return fmt.Sprintf("%v", r.ID)
+}
+
+func (g grpcExplorer) checkWritable() error {
+	if !g.explorer.dialect.writable() {
+		return status.Error(codes.FailedPrecondition, "writes are disabled for "+g.explorer.dialect.name())
+	}
+	if g.explorer.readOnly {
+		return status.Error(codes.PermissionDenied, errReadOnly.Error())
+	}
+	return nil
+}
+
+// grpcResponse переводит ответ в Struct через JSON, чтобы json.Number и json.RawMessage сериализовались как в HTTP
+func grpcResponse(result interface{}) (*structpb.Struct, error) {
+	body, err := json.Marshal(result)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+
+	out := &structpb.Struct{}
+	if err := protojson.Unmarshal(body, out); err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return out, nil
+}
+
+func (g grpcExplorer) List(ctx context.Context, in *structpb.Struct) (*structpb.Struct, error) {
+	// g — копия, так что контекст вызова не виден другим вызовам
+	g, err := g.begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	request, err := g.request(in, http.MethodGet, false)
+	if err != nil {
+		return nil, err
+	}
+
+	params := url.Values{}
+	for key, value := range request.Filter {
+		if listParams[key] {
+			return nil, status.Error(codes.InvalidArgument, "unknown field "+key)
+		}
+		values, err := graphQLParam(key, value)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		params[key] = values
+	}
+	for key, value := range map[string]string{
+		"limit":  request.Limit.String(),
+		"offset": request.Offset.String(),
+		"sort":   request.Sort,
+		"fields": request.Fields,
+	} {
+		if value != "" {
+			params.Set(key, value)
+		}
+	}
+
+	list, err := g.explorer.parseListQuery(request.Table, params)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	records, err := g.explorer.queryList(list)
+	if err == ErrRecordNotFound {
+		records, err = []map[string]interface{}{}, nil
+	}
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return grpcResponse(map[string]interface{}{"records": g.explorer.toFieldsList(request.Table, records)})
+}
+
+func (g grpcExplorer) Get(ctx context.Context, in *structpb.Struct) (*structpb.Struct, error) {
+	g, err := g.begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	request, err := g.request(in, http.MethodGet, true)
+	if err != nil {
+		return nil, err
+	}
+
+	columns, err := g.explorer.selectColumns(request.Table, request.Fields)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	record, err := g.explorer.queryRecord(g.explorer.db, request.Table, request.rawId(), columns)
+	if err == ErrRecordNotFound {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	return grpcResponse(map[string]interface{}{"record": g.explorer.toFields(request.Table, record)})
+}
+
+func (g grpcExplorer) Insert(ctx context.Context, in *structpb.Struct) (*structpb.Struct, error) {
+	g, err := g.begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := g.checkWritable(); err != nil {
+		return nil, err
+	}
+	request, err := g.request(in, http.MethodPut, false)
+	if err != nil {
+		return nil, err
+	}
+	if len(g.explorer.tableIdNamesMap[request.Table]) == 0 {
+		return nil, status.Error(codes.FailedPrecondition, "table "+request.Table+" has no primary key")
+	}
+
+	data, err := g.record(request)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := g.explorer.db.BeginTx(g.explorer.requestContext(), nil)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	id, err := g.explorer.insertRecord(tx, data, request.Table)
+	if err != nil {
+		tx.Rollback()
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	g.explorer.emitInsert(request.Table, id, data)
+	return grpcResponse(map[string]interface{}{"id": g.explorer.toFields(request.Table, id)})
+}
+
+func (g grpcExplorer) Update(ctx context.Context, in *structpb.Struct) (*structpb.Struct, error) {
+	g, err := g.begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := g.checkWritable(); err != nil {
+		return nil, err
+	}
+	request, err := g.request(in, http.MethodPost, true)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := g.record(request)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := g.explorer.db.BeginTx(g.explorer.requestContext(), nil)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	updated, err := g.explorer.updateRecord(tx, data, request.Table, request.rawId())
+	if err != nil {
+		tx.Rollback()
+		if err == errVersionConflict {
+			return nil, status.Error(codes.Aborted, err.Error())
+		}
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	if updated > 0 {
+		g.explorer.emitUpdate(request.Table, request.rawId(), data)
+	}
+	return grpcResponse(map[string]interface{}{"updated": updated})
+}
+
+func (g grpcExplorer) Delete(ctx context.Context, in *structpb.Struct) (*structpb.Struct, error) {
+	g, err := g.begin(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if err := g.checkWritable(); err != nil {
+		return nil, err
+	}
+	request, err := g.request(in, http.MethodDelete, true)
+	if err != nil {
+		return nil, err
+	}
+
+	tx, err := g.explorer.db.BeginTx(g.explorer.requestContext(), nil)
+	if err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	deleted, err := g.explorer.deleteRecord(tx, request.Table, request.rawId())
+	if err != nil {
+		tx.Rollback()
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, status.Error(codes.Internal, err.Error())
+	}
+	if deleted > 0 {
+		g.explorer.emitDelete(request.Table, request.rawId())
+	}
+	return grpcResponse(map[string]interface{}{"deleted": deleted})
+}
+
+func (g grpcExplorer) record(request *grpcRequest) (map[string]interface{}, error) {
+	if request.Record == nil {
+		return nil, status.Error(codes.InvalidArgument, "record is required")
+	}
+
+	data, err := g.explorer.toColumns(request.Table, request.Record)
+	if err == nil {
+		data, err = g.explorer.validateRecord(request.Table, data)
+	}
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	return data, nil
+}
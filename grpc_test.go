@@ -0,0 +1,235 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestGRPCService(t *testing.T) {
+	db, err := sql.Open("mysql", DSN)
+	err = db.Ping()
+	if err != nil {
+		panic(err)
+	}
+
+	PrepareTestApis(db)
+	defer CleanupTestApis(db)
+
+	explorer, err := NewDbExplorer(db)
+	if err != nil {
+		panic(err)
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("cant listen: %v", err)
+	}
+	server := grpc.NewServer()
+	RegisterGRPCService(server, explorer)
+	go server.Serve(listener)
+	defer server.Stop()
+
+	conn, err := grpc.Dial(listener.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("cant connect: %v", err)
+	}
+	defer conn.Close()
+
+	cases := []struct {
+		method   string
+		request  string
+		response string
+		code     codes.Code
+	}{
+		{"Get", `{"table": "items", "id": 1, "fields": "id,title"}`, `{"record": {"id": 1, "title": "database/sql"}}`, codes.OK},
+		{"List", `{"table": "items", "sort": "-id", "fields": "id", "filter": {"id__lte": 2}}`, `{"records": [{"id": 2}, {"id": 1}]}`, codes.OK},
+		{"Insert", `{"table": "items", "record": {"title": "grpc", "description": "facade"}}`, `{"id": {"id": 3}}`, codes.OK},
+		{"Update", `{"table": "items", "id": 3, "record": {"updated": "admin"}}`, `{"updated": 1}`, codes.OK},
+		{"Update", `{"table": "items", "id": 3, "record": {"title": 42}}`, ``, codes.InvalidArgument},
+		{"Delete", `{"table": "items", "id": 3}`, `{"deleted": 1}`, codes.OK},
+		{"Get", `{"table": "items", "id": 3}`, ``, codes.NotFound},
+		{"Get", `{"table": "unknown", "id": 1}`, ``, codes.NotFound},
+	}
+
+	for idx, item := range cases {
+		in := &structpb.Struct{}
+		if err := protojson.Unmarshal([]byte(item.request), in); err != nil {
+			panic(err)
+		}
+
+		out := &structpb.Struct{}
+		err := conn.Invoke(context.Background(), "/dbexplorer.DbExplorer/"+item.method, in, out)
+		if code := status.Code(err); code != item.code {
+			t.Fatalf("[case %d: %s] expected code %v, got %v (%v)", idx, item.method, item.code, code, err)
+		}
+		if item.code != codes.OK {
+			continue
+		}
+
+		expected := &structpb.Struct{}
+		if err := protojson.Unmarshal([]byte(item.response), expected); err != nil {
+			panic(err)
+		}
+		if got, want := protojson.Format(out), protojson.Format(expected); got != want {
+			t.Fatalf("[case %d: %s] results not match\nGot : %s\nWant: %s", idx, item.method, got, want)
+		}
+	}
+}
+
+func TestGRPCAccess(t *testing.T) {
+	db, err := sql.Open("mysql", DSN)
+	err = db.Ping()
+	if err != nil {
+		panic(err)
+	}
+
+	PrepareTestApis(db)
+	defer CleanupTestApis(db)
+
+	secret := []byte("secret")
+	explorer, err := NewDbExplorer(db, WithJWT(secret), WithRateLimit(RateLimitConfig{PerClient: RateLimit{Rate: 0.001, Burst: 5}}))
+	if err != nil {
+		panic(err)
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("cant listen: %v", err)
+	}
+	server := grpc.NewServer()
+	RegisterGRPCService(server, explorer)
+	go server.Serve(listener)
+	defer server.Stop()
+
+	conn, err := grpc.Dial(listener.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("cant connect: %v", err)
+	}
+	defer conn.Close()
+
+	reader := signJWT(secret, `{"alg":"HS256"}`, `{"tables":{"items":"ro"}}`)
+	cases := []struct {
+		method  string
+		request string
+		token   string
+		code    codes.Code
+	}{
+		{"Get", `{"table": "items", "id": 1}`, "", codes.Unauthenticated},
+		{"Get", `{"table": "items", "id": 1}`, reader, codes.OK},
+		{"Insert", `{"table": "items", "record": {"title": "grpc"}}`, reader, codes.PermissionDenied},
+		{"List", `{"table": "users"}`, reader, codes.PermissionDenied},
+		{"Get", `{"table": "items", "id": 2}`, reader, codes.OK},
+		// лимит общий с REST: запас в 5 вызовов исчерпан
+		{"Get", `{"table": "items", "id": 1}`, reader, codes.ResourceExhausted},
+	}
+
+	for idx, item := range cases {
+		in := &structpb.Struct{}
+		if err := protojson.Unmarshal([]byte(item.request), in); err != nil {
+			panic(err)
+		}
+		ctx := context.Background()
+		if item.token != "" {
+			ctx = metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+item.token)
+		}
+
+		err := conn.Invoke(ctx, "/dbexplorer.DbExplorer/"+item.method, in, &structpb.Struct{})
+		if code := status.Code(err); code != item.code {
+			t.Fatalf("[case %d: %s] expected code %v, got %v (%v)", idx, item.method, item.code, code, err)
+		}
+	}
+}
+
+type memoryAudit struct {
+	entries []AuditEntry
+}
+
+func (a *memoryAudit) Record(entry AuditEntry) error {
+	a.entries = append(a.entries, entry)
+	return nil
+}
+
+func (a *memoryAudit) Entries(query AuditQuery) ([]AuditEntry, error) {
+	return a.entries, nil
+}
+
+func TestGRPCPerCall(t *testing.T) {
+	db, err := sql.Open("mysql", DSN)
+	err = db.Ping()
+	if err != nil {
+		panic(err)
+	}
+
+	PrepareTestApis(db)
+	defer CleanupTestApis(db)
+
+	audit := &memoryAudit{}
+	explorer, err := NewDbExplorer(db, WithAudit(audit), WithMasking(MaskingConfig{
+		Columns:    map[string]map[string]string{"items": {"description": "***"}},
+		Privileged: func(r *http.Request) bool { return r.Header.Get("X-Admin") == "yes" },
+	}))
+	if err != nil {
+		panic(err)
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("cant listen: %v", err)
+	}
+	server := grpc.NewServer()
+	RegisterGRPCService(server, explorer)
+	go server.Serve(listener)
+	defer server.Stop()
+
+	conn, err := grpc.Dial(listener.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("cant connect: %v", err)
+	}
+	defer conn.Close()
+
+	invoke := func(ctx context.Context, method, request string) string {
+		in := &structpb.Struct{}
+		if err := protojson.Unmarshal([]byte(request), in); err != nil {
+			panic(err)
+		}
+		out := &structpb.Struct{}
+		if err := conn.Invoke(ctx, "/dbexplorer.DbExplorer/"+method, in, out); err != nil {
+			t.Fatalf("[%s %s] unexpected error: %v", method, request, err)
+		}
+		return protojson.Format(out)
+	}
+
+	// маскирование решается для каждого вызова по его метаданным
+	admin := metadata.AppendToOutgoingContext(context.Background(), "x-admin", "yes")
+	get := `{"table": "items", "id": 1, "fields": "id,description"}`
+	if got := invoke(context.Background(), "Get", get); !strings.Contains(got, `"***"`) {
+		t.Fatalf("expected masked description, got %s", got)
+	}
+	if got := invoke(admin, "Get", get); strings.Contains(got, `"***"`) {
+		t.Fatalf("expected raw description for privileged call, got %s", got)
+	}
+
+	// у каждого вызова свой буфер аудита: значения до и после не смешиваются между вызовами
+	invoke(admin, "Update", `{"table": "items", "id": 1, "record": {"updated": "first"}}`)
+	invoke(admin, "Update", `{"table": "items", "id": 2, "record": {"updated": "second"}}`)
+	if len(audit.entries) != 2 {
+		t.Fatalf("expected 2 audit entries, got %v", audit.entries)
+	}
+	for i, updated := range []string{"first", "second"} {
+		if entry := audit.entries[i]; entry.After["updated"] != updated || entry.Actor == "grpc" {
+			t.Fatalf("[entry %d] unexpected audit entry %+v", i, entry)
+		}
+	}
+}
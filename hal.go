@@ -0,0 +1,143 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// linkBase — префикс, под которым смонтирован explorer: ссылки должны вести туда же, куда пришёл запрос
+func linkBase(r *http.Request) string {
+	requestURL, err := url.ParseRequestURI(r.RequestURI)
+	if err != nil || !strings.HasSuffix(requestURL.Path, r.URL.Path) {
+		return ""
+	}
+	return strings.TrimSuffix(requestURL.Path, r.URL.Path)
+}
+
+// pageURL возвращает адрес текущего запроса с заменёнными параметрами; пустое значение убирает параметр
+func pageURL(r *http.Request, changes map[string]string) string {
+	params := r.URL.Query()
+	for key, value := range changes {
+		if value == "" {
+			params.Del(key)
+		} else {
+			params.Set(key, value)
+		}
+	}
+	link := linkBase(r) + r.URL.Path
+	if query := params.Encode(); query != "" {
+		link += "?" + query
+	}
+	return link
+}
+
+// pageLinks возвращает адреса соседних страниц списка: next, если страница заполнена целиком,
+// и prev, если она не первая. При keyset-пагинации назад перейти нельзя, next ведёт по курсору.
+func (d DbExplorer) pageLinks(r *http.Request, list *listQuery, records []map[string]interface{}) map[string]string {
+	links := map[string]string{}
+	full := len(records) > 0 && len(records) == list.limit
+	if list.cursor {
+		if full {
+			links["next"] = pageURL(r, map[string]string{"after": d.encodeCursor(list.tableName, records[len(records)-1])})
+		}
+		return links
+	}
+
+	// $top/$skip OData заменяются обычными limit и offset, чтобы ссылка не задала оба варианта
+	odata := map[string]string{"$top": "", "$skip": "", "limit": strconv.Itoa(list.limit)}
+	if full {
+		next := map[string]string{"offset": strconv.Itoa(list.offset + list.limit)}
+		for key, value := range odata {
+			next[key] = value
+		}
+		links["next"] = pageURL(r, next)
+	}
+	if list.offset > 0 {
+		prevOffset := list.offset - list.limit
+		if prevOffset < 0 {
+			prevOffset = 0
+		}
+		prev := map[string]string{"offset": strconv.Itoa(prevOffset)}
+		for key, value := range odata {
+			prev[key] = value
+		}
+		links["prev"] = pageURL(r, prev)
+	}
+	return links
+}
+
+// pageLinkHeader собирает заголовок Link из соседних страниц, см. WithLinkHeader:
+// </items?limit=5&offset=10>; rel="next", </items?limit=5&offset=0>; rel="prev"
+func (d DbExplorer) pageLinkHeader(r *http.Request, list *listQuery, records []map[string]interface{}) string {
+	links := d.pageLinks(r, list, records)
+	parts := make([]string, 0, len(links))
+	for _, rel := range []string{"next", "prev"} {
+		if href, ok := links[rel]; ok {
+			parts = append(parts, "<"+href+`>; rel="`+rel+`"`)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// halLink — ссылка в формате HAL: {"href": "..."}
+func halLink(href string) map[string]interface{} {
+	return map[string]interface{}{"href": href}
+}
+
+// recordLinks строит _links записи, см. WithHypermedia: self, записи, на которые она ссылается внешними
+// ключами, и дочерние таблицы через вложенный маршрут. Связи с недоступными запросу таблицами пропускаются.
+func (d DbExplorer) recordLinks(r *http.Request, tableName string, record map[string]interface{}) map[string]interface{} {
+	base := linkBase(r)
+	links := map[string]interface{}{}
+	id := ""
+	if d.selectsKeys(tableName, record) {
+		id = d.rawId(tableName, record)
+		links["self"] = halLink(base + "/" + tableName + "/" + id)
+	}
+
+	for _, key := range d.foreignKeys {
+		switch {
+		case key.table == tableName:
+			value, ok := record[key.column]
+			if !ok || value == nil || d.tableAccess(r, key.refTable, http.MethodGet) != nil {
+				continue
+			}
+			// ссылка на запись возможна, только если внешний ключ указывает на её первичный ключ
+			if primaryKeys := d.tableIdNamesMap[key.refTable]; len(primaryKeys) != 1 || primaryKeys[0] != key.refColumn {
+				continue
+			}
+			links[jsonAPIRelationName(d.fieldName(tableName, key.column))] = halLink(base + "/" + key.refTable + "/" + fmt.Sprintf("%v", value))
+		case key.refTable == tableName && id != "":
+			if d.tableAccess(r, key.table, http.MethodGet) != nil {
+				continue
+			}
+			links[key.table] = halLink(base + "/" + tableName + "/" + id + "/" + key.table)
+		}
+	}
+	return links
+}
+
+// withRecordLinks добавляет _links к записи ответа; поля записи уже переименованы по алиасам
+func (d DbExplorer) withRecordLinks(r *http.Request, tableName string, record map[string]interface{}) map[string]interface{} {
+	result := d.toFields(tableName, record)
+	if len(d.columnAliases[tableName]) == 0 {
+		result = make(map[string]interface{}, len(record)+1)
+		for key, value := range record {
+			result[key] = value
+		}
+	}
+	result["_links"] = d.recordLinks(r, tableName, record)
+	return result
+}
+
+// listLinks строит _links страницы списка: self, next и prev
+func (d DbExplorer) listLinks(r *http.Request, list *listQuery, records []map[string]interface{}) map[string]interface{} {
+	links := map[string]interface{}{"self": halLink(linkBase(r) + r.URL.RequestURI())}
+	for rel, href := range d.pageLinks(r, list, records) {
+		links[rel] = halLink(href)
+	}
+	return links
+}
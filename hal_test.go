@@ -0,0 +1,165 @@
+package main
+
+import (
+	"database/sql"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestHypermedia(t *testing.T) {
+	db, err := sql.Open("mysql", DSN)
+	err = db.Ping()
+	if err != nil {
+		panic(err)
+	}
+
+	PrepareTestApis(db)
+	defer CleanupTestApis(db)
+
+	qs := []string{
+		`CREATE TABLE orders (
+  id int(11) NOT NULL AUTO_INCREMENT,
+  user_id int(11) NOT NULL,
+  amount int(11) NOT NULL,
+  PRIMARY KEY (id),
+  CONSTRAINT orders_user FOREIGN KEY (user_id) REFERENCES users (user_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;`,
+		`INSERT INTO orders (id, user_id, amount) VALUES (1, 1, 100), (2, 1, 250), (3, 1, 50);`,
+	}
+	for _, q := range qs {
+		if _, err := db.Exec(q); err != nil {
+			panic(err)
+		}
+	}
+	defer db.Exec(`DROP TABLE IF EXISTS orders;`)
+
+	handler, err := NewDbExplorer(db, WithHypermedia(true))
+	if err != nil {
+		panic(err)
+	}
+
+	mux := http.NewServeMux()
+	handler.Mount(mux, "/api")
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	cases := []struct {
+		path   string
+		result string
+	}{
+		{
+			path: "/api/orders/1",
+			result: `{"response":{"record":{"_links":{"self":{"href":"/api/orders/1"},"user":{"href":"/api/users/1"}},` +
+				`"amount":100,"id":1,"user_id":1}}}`,
+		},
+		{
+			path: "/api/users/1?fields=user_id,login",
+			result: `{"response":{"record":{"_links":{"orders":{"href":"/api/users/1/orders"},"self":{"href":"/api/users/1"}},` +
+				`"login":"rvasily","user_id":1}}}`,
+		},
+		{
+			path: "/api/orders?fields=id&limit=1&offset=1",
+			result: `{"response":{"_links":{"next":{"href":"/api/orders?fields=id\u0026limit=1\u0026offset=2"},` +
+				`"prev":{"href":"/api/orders?fields=id\u0026limit=1\u0026offset=0"},"self":{"href":"/api/orders?fields=id\u0026limit=1\u0026offset=1"}},` +
+				`"records":[{"_links":{"self":{"href":"/api/orders/2"}},"id":2}]}}`,
+		},
+		{
+			path: "/api/orders?fields=id&offset=2",
+			result: `{"response":{"_links":{"prev":{"href":"/api/orders?fields=id\u0026limit=5\u0026offset=0"},"self":{"href":"/api/orders?fields=id\u0026offset=2"}},` +
+				`"records":[{"_links":{"self":{"href":"/api/orders/3"}},"id":3}]}}`,
+		},
+	}
+
+	for idx, item := range cases {
+		resp, err := client.Get(ts.URL + item.path)
+		if err != nil {
+			t.Fatalf("[case %d] request error: %v", idx, err)
+		}
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("[case %d: %s] expected http status 200, got %v: %s", idx, item.path, resp.StatusCode, body)
+		}
+		if string(body) != item.result {
+			t.Fatalf("[case %d: %s] results not match\nGot : %s\nWant: %s", idx, item.path, body, item.result)
+		}
+	}
+}
+
+func TestLinkHeader(t *testing.T) {
+	db, err := sql.Open("mysql", DSN)
+	err = db.Ping()
+	if err != nil {
+		panic(err)
+	}
+
+	PrepareTestApis(db)
+	defer CleanupTestApis(db)
+
+	cases := []struct {
+		path   string
+		link   string
+		result string
+	}{
+		{
+			path:   "/items?fields=id&limit=1",
+			link:   `</items?fields=id&limit=1&offset=1>; rel="next"`,
+			result: `{"response":{"records":[{"id":1}]}}`,
+		},
+		{
+			path:   "/items?fields=id&limit=1&offset=1",
+			link:   `</items?fields=id&limit=1&offset=2>; rel="next", </items?fields=id&limit=1&offset=0>; rel="prev"`,
+			result: `{"response":{"records":[{"id":2}]}}`,
+		},
+		{
+			path:   "/items?fields=id",
+			link:   ``,
+			result: `{"response":{"records":[{"id":1},{"id":2}]}}`,
+		},
+	}
+
+	check := func(ts *httptest.Server, idx int, path, link, result, cache string) {
+		resp, err := client.Get(ts.URL + path)
+		if err != nil {
+			t.Fatalf("[case %d] request error: %v", idx, err)
+		}
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if got := resp.Header.Get("Link"); got != link {
+			t.Fatalf("[case %d: %s %s] bad Link header\nGot : %s\nWant: %s", idx, path, cache, got, link)
+		}
+		if string(body) != result {
+			t.Fatalf("[case %d: %s %s] results not match\nGot : %s\nWant: %s", idx, path, cache, body, result)
+		}
+		if got := resp.Header.Get("X-Cache"); got != cache {
+			t.Fatalf("[case %d: %s] expected X-Cache %q, got %q", idx, path, cache, got)
+		}
+	}
+
+	handler, err := NewDbExplorer(db, WithLinkHeader(true))
+	if err != nil {
+		panic(err)
+	}
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+	for idx, item := range cases {
+		check(ts, idx, item.path, item.link, item.result, "")
+	}
+
+	// из кэша Link отдаётся тот же, что и при промахе
+	handler, err = NewDbExplorer(db, WithLinkHeader(true), WithCache(time.Minute, 100))
+	if err != nil {
+		panic(err)
+	}
+	cachedServer := httptest.NewServer(handler)
+	defer cachedServer.Close()
+	for idx, item := range cases {
+		check(cachedServer, idx, item.path, item.link, item.result, "MISS")
+		check(cachedServer, idx, item.path, item.link, item.result, "HIT")
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"net/http"
+)
+
+// HookEvent — момент изменения записи, в который вызывается хук, см. WithHook
+type HookEvent string
+
+const (
+	BeforeInsert HookEvent = "before_insert"
+	AfterInsert  HookEvent = "after_insert"
+	BeforeUpdate HookEvent = "before_update"
+	AfterUpdate  HookEvent = "after_update"
+	BeforeDelete HookEvent = "before_delete"
+	AfterDelete  HookEvent = "after_delete"
+)
+
+// Hook получает таблицу, первичный ключ записи (у BeforeInsert пустой) и её поля (у удаления nil).
+// Before-хуки могут менять поля записи: значения уходят в SQL как есть, без проверки типов,
+// а поля, которых нет в таблице, пропускаются, как и в теле запроса.
+// Ошибка хука отменяет изменение; After-хуки выполняются в транзакции запроса, если она есть.
+type Hook func(ctx context.Context, table string, id, record map[string]interface{}) error
+
+// hookError отличает отказ хука от ошибок базы: клиент получает 422
+type hookError struct {
+	err error
+}
+
+func (e hookError) Error() string {
+	return e.err.Error()
+}
+
+func (e hookError) Unwrap() error {
+	return e.err
+}
+
+// runHooks вызывает хуки события по порядку регистрации до первой ошибки
+func (d DbExplorer) runHooks(event HookEvent, tableName string, id, record map[string]interface{}) error {
+	for _, hook := range d.hooks[event] {
+		if err := hook(d.requestContext(), tableName, id, record); err != nil {
+			return hookError{err}
+		}
+	}
+	return nil
+}
+
+// mutationErrorStatus возвращает 422 для отказа хука и ошибок проверки, status — для остальных ошибок
+func mutationErrorStatus(err error, status int) int {
+	var vetoed hookError
+	var invalid validationError
+	if errors.As(err, &vetoed) || errors.As(err, &invalid) {
+		return http.StatusUnprocessableEntity
+	}
+	return status
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/structpb"
+)
+
+func TestHooks(t *testing.T) {
+	db, err := sql.Open("mysql", DSN)
+	err = db.Ping()
+	if err != nil {
+		panic(err)
+	}
+
+	PrepareTestApis(db)
+	defer CleanupTestApis(db)
+
+	calls := make([]string, 0)
+	record := func(event HookEvent) Hook {
+		return func(ctx context.Context, table string, id, record map[string]interface{}) error {
+			calls = append(calls, fmt.Sprintf("%s %s %v %v", event, table, id, record))
+			return nil
+		}
+	}
+
+	handler, err := NewDbExplorer(db,
+		WithHook(BeforeInsert, func(ctx context.Context, table string, id, record map[string]interface{}) error {
+			if record["title"] == "forbidden" {
+				return errors.New("title is forbidden")
+			}
+			record["description"] = "from hook"
+			return nil
+		}),
+		WithHook(BeforeInsert, record(BeforeInsert)),
+		WithHook(AfterInsert, record(AfterInsert)),
+		WithHook(AfterUpdate, record(AfterUpdate)),
+		WithHook(BeforeDelete, func(ctx context.Context, table string, id, record map[string]interface{}) error {
+			if id["id"] == 1 {
+				return errors.New("record 1 is protected")
+			}
+			return nil
+		}),
+		WithHook(AfterDelete, record(AfterDelete)),
+		WithHook(BeforeUpdate, func(ctx context.Context, table string, id, record map[string]interface{}) error {
+			record["updated"] = "hook"
+			return nil
+		}),
+		WithHook(BeforeUpdate, func(ctx context.Context, table string, id, record map[string]interface{}) error {
+			if record["title"] == "forbidden" && id["id"] == 3 {
+				return errors.New("record 3 is protected")
+			}
+			return nil
+		}),
+	)
+	if err != nil {
+		panic(err)
+	}
+
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	cases := []struct {
+		method string
+		path   string
+		body   string
+		status int
+		result string
+		calls  string
+	}{
+		{http.MethodPut, "/items/", `{"title":"forbidden"}`, http.StatusUnprocessableEntity,
+			`{"error":"title is forbidden"}`, ""},
+		{http.MethodPut, "/items/", `{"title":"hooks"}`, http.StatusOK,
+			`{"response":{"id":3}}`,
+			"before_insert items map[] map[description:from hook title:hooks]|after_insert items map[id:3] map[description:from hook title:hooks]"},
+		{http.MethodGet, "/items/3", ``, http.StatusOK,
+			`{"response":{"record":{"description":"from hook","id":3,"title":"hooks","updated":null}}}`, ""},
+		{http.MethodPost, "/items/3", `{"title":"renamed"}`, http.StatusOK,
+			`{"response":{"updated":1}}`,
+			"after_update items map[id:3] map[title:renamed updated:hook]"},
+		{http.MethodGet, "/items/3", ``, http.StatusOK,
+			`{"response":{"record":{"description":"from hook","id":3,"title":"renamed","updated":"hook"}}}`, ""},
+		// массовое обновление проходит хуки для каждой записи, отказ на одной отменяет всё
+		{http.MethodPost, "/items?id__gte=2", `{"title":"bulk"}`, http.StatusOK,
+			`{"response":{"updated":2}}`,
+			"after_update items map[id:2] map[title:bulk updated:hook]|after_update items map[id:3] map[title:bulk updated:hook]"},
+		{http.MethodPost, "/items?id__gte=2", `{"title":"forbidden"}`, http.StatusUnprocessableEntity,
+			`{"error":"record 3 is protected"}`,
+			"after_update items map[id:2] map[title:forbidden updated:hook]"},
+		{http.MethodGet, "/items/2?fields=id,title", ``, http.StatusOK,
+			`{"response":{"record":{"id":2,"title":"bulk"}}}`, ""},
+		{http.MethodDelete, "/items/1", ``, http.StatusUnprocessableEntity,
+			`{"error":"record 1 is protected"}`, ""},
+		{http.MethodDelete, "/items/3", ``, http.StatusOK,
+			`{"response":{"deleted":1}}`,
+			"after_delete items map[id:3] map[]"},
+		{http.MethodDelete, "/items/3", ``, http.StatusOK,
+			`{"response":{"deleted":0}}`, ""},
+	}
+
+	for idx, item := range cases {
+		calls = calls[:0]
+		req, _ := http.NewRequest(item.method, ts.URL+item.path, strings.NewReader(item.body))
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("[%d] request error: %v", idx, err)
+		}
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if resp.StatusCode != item.status {
+			t.Fatalf("[%d] expected http status %v, got %v: %s", idx, item.status, resp.StatusCode, body)
+		}
+		if string(body) != item.result {
+			t.Fatalf("[%d] results not match\nGot : %s\nWant: %s", idx, body, item.result)
+		}
+		if strings.Join(calls, "|") != item.calls {
+			t.Fatalf("[%d] hook calls not match\nGot : %s\nWant: %s", idx, strings.Join(calls, "|"), item.calls)
+		}
+	}
+}
+
+func TestHooksRollback(t *testing.T) {
+	db, err := sql.Open("mysql", DSN)
+	err = db.Ping()
+	if err != nil {
+		panic(err)
+	}
+
+	PrepareTestApis(db)
+	defer CleanupTestApis(db)
+
+	// ошибка After-хука приходит после записи в базу и должна отменить её в GraphQL и gRPC так же, как в REST
+	handler, err := NewDbExplorer(db,
+		WithHook(AfterInsert, func(ctx context.Context, table string, id, record map[string]interface{}) error {
+			if record["title"] == "rollback" {
+				return errors.New("insert rejected")
+			}
+			return nil
+		}),
+		WithHook(AfterUpdate, func(ctx context.Context, table string, id, record map[string]interface{}) error {
+			if record["updated"] == "rollback" {
+				return errors.New("update rejected")
+			}
+			return nil
+		}),
+		WithHook(AfterDelete, func(ctx context.Context, table string, id, record map[string]interface{}) error {
+			if id["id"] == 2 {
+				return errors.New("delete rejected")
+			}
+			return nil
+		}),
+	)
+	if err != nil {
+		panic(err)
+	}
+
+	checkUnchanged := func(step string) {
+		var inserted, remaining int
+		var updated string
+		if err := db.QueryRow("SELECT COUNT(*) FROM items WHERE title = 'rollback'").Scan(&inserted); err != nil {
+			panic(err)
+		}
+		if err := db.QueryRow("SELECT updated FROM items WHERE id = 1").Scan(&updated); err != nil {
+			panic(err)
+		}
+		if err := db.QueryRow("SELECT COUNT(*) FROM items WHERE id = 2").Scan(&remaining); err != nil {
+			panic(err)
+		}
+		if inserted != 0 || updated != "rvasily" || remaining != 1 {
+			t.Fatalf("[%s] write was not rolled back: inserted %d, updated %q, remaining %d", step, inserted, updated, remaining)
+		}
+	}
+
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	mutations := []string{
+		`mutation { insert_items(data: {title: \"rollback\", description: \"\"}) { id } }`,
+		`mutation { update_items(id: 1, data: {updated: \"rollback\"}) }`,
+		`mutation { delete_items(id: 2) }`,
+	}
+	for idx, mutation := range mutations {
+		resp, err := client.Post(ts.URL+"/graphql", "application/json", strings.NewReader(`{"query":"`+mutation+`"}`))
+		if err != nil {
+			t.Fatalf("[graphql %d] request error: %v", idx, err)
+		}
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		if !strings.Contains(string(body), "rejected") {
+			t.Fatalf("[graphql %d] expected hook error, got %s", idx, body)
+		}
+		checkUnchanged(fmt.Sprintf("graphql %d", idx))
+	}
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("cant listen: %v", err)
+	}
+	server := grpc.NewServer()
+	RegisterGRPCService(server, handler)
+	go server.Serve(listener)
+	defer server.Stop()
+
+	conn, err := grpc.Dial(listener.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatalf("cant connect: %v", err)
+	}
+	defer conn.Close()
+
+	calls := []struct {
+		method  string
+		request string
+	}{
+		{"Insert", `{"table": "items", "record": {"title": "rollback", "description": ""}}`},
+		{"Update", `{"table": "items", "id": 1, "record": {"updated": "rollback"}}`},
+		{"Delete", `{"table": "items", "id": 2}`},
+	}
+	for idx, item := range calls {
+		in := &structpb.Struct{}
+		if err := protojson.Unmarshal([]byte(item.request), in); err != nil {
+			panic(err)
+		}
+		err := conn.Invoke(context.Background(), "/dbexplorer.DbExplorer/"+item.method, in, &structpb.Struct{})
+		if code := status.Code(err); code != codes.InvalidArgument {
+			t.Fatalf("[grpc %d: %s] expected code %v, got %v (%v)", idx, item.method, codes.InvalidArgument, code, err)
+		}
+		checkUnchanged(fmt.Sprintf("grpc %d", idx))
+	}
+}
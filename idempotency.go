@@ -0,0 +1,147 @@
+package main
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	idempotencyTTL   = 24 * time.Hour
+	idempotencyLimit = 1000
+)
+
+// idempotencyStore помнит ответы на недавние запросы с Idempotency-Key
+type idempotencyStore struct {
+	mu      sync.Mutex
+	entries map[string]*idempotencyEntry
+	order   []*idempotencyEntry // по времени создания, для вытеснения старых ключей
+}
+
+type idempotencyEntry struct {
+	key         string
+	fingerprint [sha256.Size]byte
+	created     time.Time
+	done        bool
+	status      int
+	contentType string
+	body        []byte
+}
+
+func newIdempotencyStore() *idempotencyStore {
+	return &idempotencyStore{entries: make(map[string]*idempotencyEntry)}
+}
+
+// begin резервирует ключ; если он уже есть, возвращает прежнюю запись и false
+func (s *idempotencyStore) begin(key string, fingerprint [sha256.Size]byte, now time.Time) (*idempotencyEntry, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for len(s.order) > 0 && (len(s.order) >= idempotencyLimit || now.Sub(s.order[0].created) > idempotencyTTL) {
+		if s.entries[s.order[0].key] == s.order[0] {
+			delete(s.entries, s.order[0].key)
+		}
+		s.order = s.order[1:]
+	}
+
+	if entry, ok := s.entries[key]; ok {
+		return entry, false
+	}
+
+	entry := &idempotencyEntry{key: key, fingerprint: fingerprint, created: now}
+	s.entries[key] = entry
+	s.order = append(s.order, entry)
+	return entry, true
+}
+
+// finish сохраняет ответ; ответы 5xx не запоминаются, чтобы повтор выполнил запрос заново
+func (s *idempotencyStore) finish(entry *idempotencyEntry, capture *captureWriter) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if capture.status >= http.StatusInternalServerError {
+		delete(s.entries, entry.key)
+		return
+	}
+	entry.done = true
+	entry.status = capture.status
+	entry.contentType = capture.contentType
+	entry.body = capture.body.Bytes()
+}
+
+// captureWriter пропускает ответ насквозь и сохраняет его копию
+type captureWriter struct {
+	http.ResponseWriter
+	status      int
+	contentType string
+	body        bytes.Buffer
+}
+
+func (w *captureWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *captureWriter) WriteHeader(status int) {
+	if w.status == 0 {
+		w.status = status
+		w.contentType = w.Header().Get("Content-Type")
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *captureWriter) Write(data []byte) (int, error) {
+	if w.status == 0 {
+		w.WriteHeader(http.StatusOK)
+	}
+	w.body.Write(data)
+	return w.ResponseWriter.Write(data)
+}
+
+// idempotent выполняет handler один раз на Idempotency-Key, а на повторы отдаёт сохранённый ответ.
+// Ключ с другим телом или путём отклоняется, чтобы не выдать чужой результат.
+func (d DbExplorer) idempotent(rw http.ResponseWriter, r *http.Request, handler http.HandlerFunc) {
+	key := r.Header.Get("Idempotency-Key")
+	if key == "" || d.idempotency == nil {
+		handler(rw, r)
+		return
+	}
+
+	body, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		responseResult(rw, err, bodyErrorStatus(err), nil)
+		return
+	}
+	r.Body = ioutil.NopCloser(bytes.NewReader(body))
+	fingerprint := sha256.Sum256(append([]byte(r.URL.Path+"\n"), body...))
+
+	entry, created := d.idempotency.begin(key, fingerprint, time.Now())
+	if !created {
+		d.idempotency.mu.Lock()
+		done, status, contentType, saved := entry.done, entry.status, entry.contentType, entry.body
+		d.idempotency.mu.Unlock()
+
+		switch {
+		case entry.fingerprint != fingerprint:
+			responseResult(rw, errors.New("Idempotency-Key was used with a different request"), http.StatusUnprocessableEntity, nil)
+		case !done:
+			responseResult(rw, errors.New("request with this Idempotency-Key is in progress"), http.StatusConflict, nil)
+		default:
+			rw.Header().Set("Content-Type", contentType)
+			rw.Header().Set("Idempotent-Replayed", "true")
+			rw.WriteHeader(status)
+			rw.Write(saved)
+		}
+		return
+	}
+
+	capture := &captureWriter{ResponseWriter: rw}
+	handler(capture, r)
+	if capture.status == 0 {
+		capture.status = http.StatusOK
+	}
+	d.idempotency.finish(entry, capture)
+}
@@ -0,0 +1,204 @@
+package main
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const importBatchSize = 100
+
+// importRow — строка CSV, прошедшая проверку; line — номер строки в файле (заголовок — строка 1)
+type importRow struct {
+	line   int
+	record map[string]interface{}
+}
+
+type importError struct {
+	Row   int    `json:"row"`
+	Error string `json:"error"`
+}
+
+// handlerImport выполняет POST /{table}/import: CSV с заголовком из имён колонок
+// (телом запроса или полем file в multipart/form-data). Строки с ошибками пропускаются
+// и попадают в отчёт, остальные вставляются транзакциями по importBatchSize строк.
+func (d DbExplorer) handlerImport(rw http.ResponseWriter, r *http.Request, tableName string) {
+	var body io.Reader = r.Body
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "multipart/form-data") {
+		file, _, err := r.FormFile("file")
+		if status := bodyErrorStatus(err); status == http.StatusRequestEntityTooLarge {
+			responseResult(rw, err, status, nil)
+			return
+		}
+		if err != nil {
+			responseResult(rw, errors.New("csv file is required"), http.StatusBadRequest, nil)
+			return
+		}
+		defer file.Close()
+		body = file
+	}
+
+	reader := csv.NewReader(body)
+	reader.FieldsPerRecord = -1
+	header, err := reader.Read()
+	if err != nil {
+		responseResult(rw, errors.New("csv header is required"), http.StatusBadRequest, nil)
+		return
+	}
+
+	columns := make([]columnParams, 0, len(header))
+	for _, name := range header {
+		column, ok := d.columnsInTablesMap[tableName][d.columnName(tableName, strings.TrimSpace(name))]
+		if !ok {
+			responseResult(rw, errors.New("unknown field "+name), http.StatusBadRequest, nil)
+			return
+		}
+		if _, sensitive := d.sensitiveColumn(tableName, column.name); sensitive {
+			responseResult(rw, errors.New("field "+column.name+" is not writable"), http.StatusBadRequest, nil)
+			return
+		}
+		columns = append(columns, column)
+	}
+
+	rows := make([]importRow, 0)
+	report := make([]importError, 0)
+	for line := 2; ; line++ {
+		cells, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if _, malformed := err.(*csv.ParseError); err != nil && !malformed {
+			// ошибка чтения тела, а не формата строки: дальше читать нечего
+			responseResult(rw, err, bodyErrorStatus(err), nil)
+			return
+		}
+		if err != nil {
+			report = append(report, importError{Row: line, Error: err.Error()})
+			continue
+		}
+
+		record, err := d.importRecord(tableName, columns, cells)
+		if err != nil {
+			report = append(report, importError{Row: line, Error: err.Error()})
+			continue
+		}
+		rows = append(rows, importRow{line: line, record: record})
+	}
+
+	ids := make([]map[string]interface{}, 0, len(rows))
+	for start := 0; start < len(rows); start += importBatchSize {
+		end := start + importBatchSize
+		if end > len(rows) {
+			end = len(rows)
+		}
+
+		batchIds, batchErrors, err := d.importBatch(tableName, rows[start:end])
+		if err != nil {
+			responseResult(rw, err, http.StatusInternalServerError, nil)
+			return
+		}
+		ids = append(ids, batchIds...)
+		report = append(report, batchErrors...)
+	}
+
+	responseResult(rw, nil, http.StatusOK, map[string]interface{}{
+		"inserted": len(ids),
+		"ids":      ids,
+		"errors":   report,
+	})
+}
+
+// importBatch вставляет строки одной транзакцией. Если вставка строки падает, транзакция
+// откатывается, строка попадает в отчёт, а пачка повторяется без неё.
+func (d DbExplorer) importBatch(tableName string, rows []importRow) ([]map[string]interface{}, []importError, error) {
+	report := make([]importError, 0)
+	for len(rows) > 0 {
+		tx, err := d.db.BeginTx(d.requestContext(), nil)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		failed := -1
+		ids := make([]map[string]interface{}, 0, len(rows))
+		for i, row := range rows {
+			id, err := d.insertRecord(tx, row.record, tableName)
+			if err != nil {
+				report = append(report, importError{Row: row.line, Error: err.Error()})
+				failed = i
+				break
+			}
+			ids = append(ids, id)
+		}
+
+		if failed != -1 {
+			tx.Rollback()
+			rows = append(append([]importRow{}, rows[:failed]...), rows[failed+1:]...)
+			continue
+		}
+
+		if err := tx.Commit(); err != nil {
+			return nil, nil, err
+		}
+		for i, id := range ids {
+			d.emitInsert(tableName, id, rows[i].record)
+		}
+		return ids, report, nil
+	}
+
+	return nil, report, nil
+}
+
+// importRecord приводит ячейки строки к значениям, как если бы они пришли в JSON-теле PUT
+func (d DbExplorer) importRecord(tableName string, columns []columnParams, cells []string) (map[string]interface{}, error) {
+	if len(cells) != len(columns) {
+		return nil, errors.New("expected " + strconv.Itoa(len(columns)) + " fields, got " + strconv.Itoa(len(cells)))
+	}
+
+	record := make(map[string]interface{}, len(columns))
+	for i, column := range columns {
+		// пустая ячейка — NULL, а для NOT NULL колонок не строкового типа — значение по умолчанию
+		if cells[i] == "" && !column.isNull && column.typeName != "string" {
+			continue
+		}
+
+		value, err := csvValue(column, cells[i])
+		if err != nil {
+			return nil, err
+		}
+		record[column.name] = value
+	}
+
+	return d.validateRecord(tableName, record)
+}
+
+func csvValue(column columnParams, cell string) (interface{}, error) {
+	if cell == "" && column.isNull {
+		return nil, nil
+	}
+
+	switch column.typeName {
+	case "int", "float", "decimal":
+		return json.Number(strings.TrimSpace(cell)), nil
+	case "bool":
+		val, err := strconv.ParseBool(strings.TrimSpace(cell))
+		if err != nil {
+			return nil, invalidTypeError(column.name)
+		}
+		return val, nil
+	case "json":
+		var val interface{}
+		decoder := json.NewDecoder(bytes.NewReader([]byte(cell)))
+		decoder.UseNumber()
+		if err := decoder.Decode(&val); err != nil {
+			return nil, invalidTypeError(column.name)
+		}
+		return val, nil
+	}
+
+	return cell, nil
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type jsonPatchOperation struct {
+	Op    string      `json:"op"`
+	Path  string      `json:"path"`
+	Value interface{} `json:"value"`
+}
+
+// jsonPatchData переводит операции RFC 6902 (add/replace/remove) в набор колонок для одного UPDATE.
+// Путь "/column" задаёт колонку целиком, более глубокий путь допустим только внутри JSON-колонки.
+func (d DbExplorer) jsonPatchData(tableName, rawId string, body io.Reader) (map[string]interface{}, error) {
+	operations := make([]jsonPatchOperation, 0)
+	decoder := json.NewDecoder(body)
+	decoder.UseNumber()
+	if err := decoder.Decode(&operations); err != nil {
+		if bodyErrorStatus(err) == http.StatusRequestEntityTooLarge {
+			return nil, err
+		}
+		return nil, errors.New("json patch must be an array of operations")
+	}
+
+	data := make(map[string]interface{})
+	nested := make(map[string]bool)
+	for _, operation := range operations {
+		pointer, err := parseJSONPointer(operation.Path)
+		if err != nil {
+			return nil, err
+		}
+		column, ok := d.columnsInTablesMap[tableName][d.columnName(tableName, pointer[0])]
+		if !ok {
+			return nil, errors.New("unknown field " + pointer[0])
+		}
+		if len(pointer) > 1 {
+			if column.typeName != "json" {
+				return nil, errors.New("field " + column.name + " is not a JSON column")
+			}
+			nested[column.name] = true
+		}
+	}
+
+	if len(nested) > 0 {
+		columns := make([]string, 0, len(nested))
+		for column := range nested {
+			columns = append(columns, column)
+		}
+		sort.Strings(columns)
+
+		args := &queryArgs{dialect: d.dialect}
+		condition, err := d.primaryKeyCondition(tableName, rawId, args)
+		if err != nil {
+			return nil, ErrRecordNotFound
+		}
+
+		current, err := d.currentJSONValues(tableName, condition, args, columns)
+		if err != nil {
+			return nil, err
+		}
+		for column, value := range current {
+			data[column] = value
+		}
+	}
+
+	for _, operation := range operations {
+		pointer, _ := parseJSONPointer(operation.Path)
+		column := d.columnName(tableName, pointer[0])
+
+		if len(pointer) == 1 {
+			switch operation.Op {
+			case "add", "replace":
+				data[column] = operation.Value
+			case "remove":
+				data[column] = nil
+			default:
+				return nil, errors.New("unsupported json patch operation " + operation.Op)
+			}
+			continue
+		}
+
+		document, err := applyJSONPointer(data[column], pointer[1:], operation)
+		if err != nil {
+			return nil, err
+		}
+		data[column] = document
+	}
+
+	return data, nil
+}
+
+// parseJSONPointer разбирает JSON Pointer (RFC 6901) на сегменты
+func parseJSONPointer(path string) ([]string, error) {
+	if !strings.HasPrefix(path, "/") || len(path) < 2 {
+		return nil, errors.New("invalid json patch path " + path)
+	}
+
+	pointer := strings.Split(path[1:], "/")
+	for i, segment := range pointer {
+		pointer[i] = strings.ReplaceAll(strings.ReplaceAll(segment, "~1", "/"), "~0", "~")
+	}
+	return pointer, nil
+}
+
+// applyJSONPointer применяет операцию к документу по пути внутри него и возвращает изменённый документ
+func applyJSONPointer(document interface{}, pointer []string, operation jsonPatchOperation) (interface{}, error) {
+	notFound := errors.New("json patch path " + operation.Path + " not found")
+	key := pointer[0]
+	last := len(pointer) == 1
+
+	switch typed := document.(type) {
+	case map[string]interface{}:
+		if !last {
+			child, ok := typed[key]
+			if !ok {
+				return nil, notFound
+			}
+			value, err := applyJSONPointer(child, pointer[1:], operation)
+			if err != nil {
+				return nil, err
+			}
+			typed[key] = value
+			return typed, nil
+		}
+
+		_, exists := typed[key]
+		switch operation.Op {
+		case "add":
+			typed[key] = operation.Value
+		case "replace":
+			if !exists {
+				return nil, notFound
+			}
+			typed[key] = operation.Value
+		case "remove":
+			if !exists {
+				return nil, notFound
+			}
+			delete(typed, key)
+		default:
+			return nil, errors.New("unsupported json patch operation " + operation.Op)
+		}
+		return typed, nil
+
+	case []interface{}:
+		if last && operation.Op == "add" && key == "-" {
+			return append(typed, operation.Value), nil
+		}
+
+		index, err := strconv.Atoi(key)
+		if err != nil || index < 0 || index > len(typed) || (index == len(typed) && (!last || operation.Op != "add")) {
+			return nil, notFound
+		}
+
+		if !last {
+			value, err := applyJSONPointer(typed[index], pointer[1:], operation)
+			if err != nil {
+				return nil, err
+			}
+			typed[index] = value
+			return typed, nil
+		}
+
+		switch operation.Op {
+		case "add":
+			typed = append(typed, nil)
+			copy(typed[index+1:], typed[index:])
+			typed[index] = operation.Value
+		case "replace":
+			typed[index] = operation.Value
+		case "remove":
+			typed = append(typed[:index], typed[index+1:]...)
+		default:
+			return nil, errors.New("unsupported json patch operation " + operation.Op)
+		}
+		return typed, nil
+	}
+
+	return nil, notFound
+}
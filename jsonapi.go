@@ -0,0 +1,250 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"mime"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+const jsonAPIMediaType = "application/vnd.api+json"
+
+// jsonAPIWriter помечает запрос, на который отвечаем документом JSON:API, см. WithJSONAPI
+type jsonAPIWriter struct {
+	http.ResponseWriter
+}
+
+func (w jsonAPIWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w jsonAPIWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// withJSONAPI включает JSON:API для запросов с Accept: application/vnd.api+json
+func (d DbExplorer) withJSONAPI(rw http.ResponseWriter, r *http.Request) http.ResponseWriter {
+	if !d.jsonAPI {
+		return rw
+	}
+	for _, part := range strings.Split(r.Header.Get("Accept"), ",") {
+		if mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(part)); err == nil && mediaType == jsonAPIMediaType {
+			return jsonAPIWriter{rw}
+		}
+	}
+	return rw
+}
+
+// jsonAPIResponse проверяет, отвечаем ли на запрос документом JSON:API
+func jsonAPIResponse(rw http.ResponseWriter) bool {
+	for {
+		switch typed := rw.(type) {
+		case jsonAPIWriter:
+			return true
+		case interface{ Unwrap() http.ResponseWriter }:
+			rw = typed.Unwrap()
+		default:
+			return false
+		}
+	}
+}
+
+// jsonAPIResource переводит запись в объект ресурса: внешние ключи уходят из attributes в relationships,
+// дочерние таблицы попадают туда же ссылкой на вложенный маршрут
+func (d DbExplorer) jsonAPIResource(tableName string, record map[string]interface{}) map[string]interface{} {
+	id := ""
+	if d.selectsKeys(tableName, record) {
+		id = d.rawId(tableName, record)
+	}
+	attributes := make(map[string]interface{}, len(record))
+	for columnName, value := range record {
+		if !containsString(d.tableIdNamesMap[tableName], columnName) {
+			attributes[d.fieldName(tableName, columnName)] = value
+		}
+	}
+
+	relationships := make(map[string]interface{})
+	for _, key := range d.foreignKeys {
+		switch {
+		case key.table == tableName:
+			value, ok := record[key.column]
+			if !ok {
+				continue
+			}
+			delete(attributes, d.fieldName(tableName, key.column))
+			var data interface{}
+			if value != nil {
+				data = map[string]interface{}{"type": key.refTable, "id": fmt.Sprintf("%v", value)}
+			}
+			relationships[jsonAPIRelationName(d.fieldName(tableName, key.column))] = map[string]interface{}{"data": data}
+		case key.refTable == tableName && id != "":
+			relationships[key.table] = map[string]interface{}{
+				"links": map[string]interface{}{"related": "/" + tableName + "/" + id + "/" + key.table},
+			}
+		}
+	}
+
+	resource := map[string]interface{}{"type": tableName, "id": id, "attributes": attributes}
+	if len(relationships) > 0 {
+		resource["relationships"] = relationships
+	}
+	if id != "" {
+		resource["links"] = map[string]interface{}{"self": "/" + tableName + "/" + id}
+	}
+	return resource
+}
+
+// selectsKeys проверяет, что в записи есть все колонки первичного ключа
+func (d DbExplorer) selectsKeys(tableName string, record map[string]interface{}) bool {
+	for _, key := range d.tableIdNamesMap[tableName] {
+		if _, ok := record[key]; !ok {
+			return false
+		}
+	}
+	return len(d.tableIdNamesMap[tableName]) > 0
+}
+
+// jsonAPIRelationName называет связь по колонке внешнего ключа без суффикса _id: item_id → item
+func jsonAPIRelationName(column string) string {
+	if name := strings.TrimSuffix(column, "_id"); name != "" {
+		return name
+	}
+	return column
+}
+
+// jsonAPIIncluded загружает записи связей ?include=item,user, на которые ссылаются записи таблицы;
+// поддерживаются только связи через внешние ключи самой таблицы
+func (d DbExplorer) jsonAPIIncluded(r *http.Request, tableName string, records []map[string]interface{}) ([]interface{}, error) {
+	included := make([]interface{}, 0)
+	rawInclude := r.URL.Query().Get("include")
+	if rawInclude == "" {
+		return included, nil
+	}
+
+	seen := make(map[string]bool)
+	for _, name := range strings.Split(rawInclude, ",") {
+		name = strings.TrimSpace(name)
+		var relation *foreignKey
+		for i, key := range d.foreignKeys {
+			if key.table == tableName && jsonAPIRelationName(d.fieldName(tableName, key.column)) == name {
+				relation = &d.foreignKeys[i]
+				break
+			}
+		}
+		if relation == nil || len(d.tableIdNamesMap[relation.refTable]) != 1 || d.tableIdNamesMap[relation.refTable][0] != relation.refColumn {
+			return nil, errors.New("unknown relationship " + name)
+		}
+		if err := d.tableAccess(r, relation.refTable, http.MethodGet); err != nil {
+			return nil, err
+		}
+
+		columns, err := d.selectColumns(relation.refTable, "")
+		if err != nil {
+			return nil, err
+		}
+		for _, record := range records {
+			value, ok := record[relation.column]
+			if !ok || value == nil {
+				continue
+			}
+			rawId := fmt.Sprintf("%v", value)
+			if seen[relation.refTable+"/"+rawId] {
+				continue
+			}
+			seen[relation.refTable+"/"+rawId] = true
+
+			related, err := d.queryRecord(d.db, relation.refTable, rawId, columns)
+			if err == ErrRecordNotFound {
+				continue
+			}
+			if err != nil {
+				return nil, err
+			}
+			included = append(included, d.jsonAPIResource(relation.refTable, related))
+		}
+	}
+	return included, nil
+}
+
+// responseJSONAPI отдаёт запись или страницу записей таблицы документом JSON:API; meta — служебные
+// поля ответа вроде total и next_cursor
+func (d DbExplorer) responseJSONAPI(rw http.ResponseWriter, r *http.Request, tableName string, data interface{}, meta map[string]interface{}) {
+	var records []map[string]interface{}
+	document := map[string]interface{}{"links": map[string]interface{}{"self": r.URL.RequestURI()}}
+	switch data := data.(type) {
+	case map[string]interface{}:
+		records = []map[string]interface{}{data}
+		document["data"] = d.jsonAPIResource(tableName, data)
+	case []map[string]interface{}:
+		records = data
+		resources := make([]interface{}, 0, len(data))
+		for _, record := range data {
+			resources = append(resources, d.jsonAPIResource(tableName, record))
+		}
+		document["data"] = resources
+	}
+	if len(meta) > 0 {
+		document["meta"] = meta
+	}
+
+	included, err := d.jsonAPIIncluded(r, tableName, records)
+	if err != nil {
+		responseResult(rw, err, http.StatusBadRequest, nil)
+		return
+	}
+	if len(included) > 0 {
+		document["included"] = included
+	}
+
+	// ссылки с query-параметрами отдаются как есть, без экранирования &
+	response := bytes.Buffer{}
+	encoder := json.NewEncoder(&response)
+	encoder.SetEscapeHTML(false)
+	if err := encoder.Encode(document); err != nil {
+		responseResult(rw, err, http.StatusInternalServerError, nil)
+		return
+	}
+	rw.Header().Set("Content-Type", jsonAPIMediaType)
+	if _, err := rw.Write(bytes.TrimSuffix(response.Bytes(), []byte("\n"))); err != nil {
+		logResponseError(rw, err)
+	}
+}
+
+// responseJSONAPIError отдаёт ошибку объектами errors из JSON:API: по одному на нарушенное правило поля
+func responseJSONAPIError(rw http.ResponseWriter, err error, status int) {
+	errorObject := func(code, title, field string) map[string]interface{} {
+		object := map[string]interface{}{"status": strconv.Itoa(status), "code": code, "title": title}
+		if field != "" {
+			object["source"] = map[string]interface{}{"pointer": "/data/attributes/" + field}
+		}
+		return object
+	}
+
+	objects := make([]interface{}, 0, 1)
+	var invalid validationError
+	var apiErr *Error
+	switch {
+	case errors.As(err, &invalid):
+		for _, field := range invalid.fields {
+			objects = append(objects, errorObject(field.Rule, field.Message, field.Field))
+		}
+	case errors.As(err, &apiErr):
+		objects = append(objects, errorObject(errorCode(err, status), err.Error(), apiErr.Field))
+	default:
+		objects = append(objects, errorObject(errorCode(err, status), err.Error(), ""))
+	}
+
+	requestLogOf(rw).setError(err)
+	response, _ := json.Marshal(map[string]interface{}{"errors": objects})
+	rw.Header().Set("Content-Type", jsonAPIMediaType)
+	rw.WriteHeader(status)
+	if _, err := rw.Write(response); err != nil {
+		logResponseError(rw, err)
+	}
+}
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"database/sql"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestJSONAPI(t *testing.T) {
+	db, err := sql.Open("mysql", DSN)
+	err = db.Ping()
+	if err != nil {
+		panic(err)
+	}
+
+	PrepareTestApis(db)
+	defer CleanupTestApis(db)
+
+	qs := []string{
+		`CREATE TABLE orders (
+  id int(11) NOT NULL AUTO_INCREMENT,
+  user_id int(11) NOT NULL,
+  amount int(11) NOT NULL,
+  PRIMARY KEY (id),
+  CONSTRAINT orders_user FOREIGN KEY (user_id) REFERENCES users (user_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;`,
+		`INSERT INTO orders (id, user_id, amount) VALUES (1, 1, 100), (2, 1, 250);`,
+	}
+	for _, q := range qs {
+		if _, err := db.Exec(q); err != nil {
+			panic(err)
+		}
+	}
+	defer db.Exec(`DROP TABLE IF EXISTS orders;`)
+
+	handler, err := NewDbExplorer(db, WithJSONAPI(true))
+	if err != nil {
+		panic(err)
+	}
+
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	user := `{"attributes":{"email":"rvasily@example.com","info":"none","login":"rvasily","password":"love","updated":null},` +
+		`"id":"1","links":{"self":"/users/1"},"relationships":{"orders":{"links":{"related":"/users/1/orders"}}},"type":"users"}`
+
+	cases := []struct {
+		path        string
+		accept      string
+		status      int
+		contentType string
+		result      string
+	}{
+		{
+			path:        "/orders/1?include=user",
+			accept:      jsonAPIMediaType,
+			status:      http.StatusOK,
+			contentType: jsonAPIMediaType,
+			result: `{"data":{"attributes":{"amount":100},"id":"1","links":{"self":"/orders/1"},` +
+				`"relationships":{"user":{"data":{"id":"1","type":"users"}}},"type":"orders"},` +
+				`"included":[` + user + `],"links":{"self":"/orders/1?include=user"}}`,
+		},
+		{
+			path:        "/orders?sort=-amount&limit=1&count=true",
+			accept:      jsonAPIMediaType,
+			status:      http.StatusOK,
+			contentType: jsonAPIMediaType,
+			result: `{"data":[{"attributes":{"amount":250},"id":"2","links":{"self":"/orders/2"},` +
+				`"relationships":{"user":{"data":{"id":"1","type":"users"}}},"type":"orders"}],` +
+				`"links":{"self":"/orders?sort=-amount&limit=1&count=true"},` +
+				`"meta":{"has_more":true,"limit":1,"offset":0,"total":2}}`,
+		},
+		{
+			path:        "/orders?amount__gt=1000",
+			accept:      jsonAPIMediaType,
+			status:      http.StatusOK,
+			contentType: jsonAPIMediaType,
+			result:      `{"data":[],"links":{"self":"/orders?amount__gt=1000"}}`,
+		},
+		{
+			path:        "/users/1",
+			accept:      jsonAPIMediaType,
+			status:      http.StatusOK,
+			contentType: jsonAPIMediaType,
+			result:      `{"data":` + user + `,"links":{"self":"/users/1"}}`,
+		},
+		{
+			path:        "/orders/1?include=author",
+			accept:      jsonAPIMediaType,
+			status:      http.StatusBadRequest,
+			contentType: jsonAPIMediaType,
+			result:      `{"errors":[{"code":"bad_request","status":"400","title":"unknown relationship author"}]}`,
+		},
+		{
+			path:        "/unknown_table",
+			accept:      jsonAPIMediaType,
+			status:      http.StatusNotFound,
+			contentType: jsonAPIMediaType,
+			result:      `{"errors":[{"code":"unknown_table","status":"404","title":"unknown table"}]}`,
+		},
+		{
+			path:        "/orders/1",
+			status:      http.StatusOK,
+			contentType: "application/json",
+			result:      `{"response":{"record":{"amount":100,"id":1,"user_id":1}}}`,
+		},
+	}
+
+	for idx, item := range cases {
+		req, _ := http.NewRequest(http.MethodGet, ts.URL+item.path, nil)
+		if item.accept != "" {
+			req.Header.Set("Accept", item.accept)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("[case %d] request error: %v", idx, err)
+		}
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if resp.StatusCode != item.status {
+			t.Fatalf("[case %d: %s] expected http status %v, got %v: %s", idx, item.path, item.status, resp.StatusCode, body)
+		}
+		if contentType := resp.Header.Get("Content-Type"); contentType != item.contentType {
+			t.Fatalf("[case %d: %s] expected content type %s, got %s", idx, item.path, item.contentType, contentType)
+		}
+		if string(body) != item.result {
+			t.Fatalf("[case %d: %s] results not match\nGot : %s\nWant: %s", idx, item.path, body, item.result)
+		}
+	}
+}
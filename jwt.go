@@ -0,0 +1,119 @@
+package main
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+)
+
+var errInvalidToken = errors.New("invalid token")
+
+// tablePermissions — права из claim tables: "ro" даёт чтение, "rw" — чтение и запись.
+// Ключ "*" задаёт права для таблиц, не перечисленных явно.
+type tablePermissions map[string]string
+
+// allowed проверяет доступ к таблице; nil означает запрос без ограничений (например, по API-ключу)
+func (p tablePermissions) allowed(tableName string, write bool) bool {
+	if p == nil {
+		return true
+	}
+
+	level, ok := p[tableName]
+	if !ok {
+		level = p["*"]
+	}
+	return level == "rw" || (level == "ro" && !write)
+}
+
+type jwtClaims struct {
+	Tables    tablePermissions `json:"tables"`
+	Role      string           `json:"role"`
+	Subject   string           `json:"sub"`
+	ExpiresAt *float64         `json:"exp"`
+	NotBefore *float64         `json:"nbf"`
+}
+
+// parseJWT проверяет подпись HS256 и сроки действия токена
+func parseJWT(token string, secret []byte, now time.Time) (*jwtClaims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, errInvalidToken
+	}
+
+	header := struct {
+		Alg string `json:"alg"`
+	}{}
+	if err := decodeJWTPart(parts[0], &header); err != nil || header.Alg != "HS256" {
+		return nil, errInvalidToken
+	}
+
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return nil, errInvalidToken
+	}
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(parts[0] + "." + parts[1]))
+	if !hmac.Equal(signature, mac.Sum(nil)) {
+		return nil, errInvalidToken
+	}
+
+	claims := &jwtClaims{}
+	if err := decodeJWTPart(parts[1], claims); err != nil {
+		return nil, errInvalidToken
+	}
+
+	unix := float64(now.Unix())
+	if claims.ExpiresAt != nil && unix >= *claims.ExpiresAt {
+		return nil, errors.New("token is expired")
+	}
+	if claims.NotBefore != nil && unix < *claims.NotBefore {
+		return nil, errors.New("token is not valid yet")
+	}
+
+	if claims.Tables == nil {
+		claims.Tables = tablePermissions{}
+	}
+	return claims, nil
+}
+
+func decodeJWTPart(part string, target interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(part)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, target)
+}
+
+type claimsKey struct{}
+
+// requestClaims возвращает claims токена запроса; nil — запрос без JWT
+func requestClaims(r *http.Request) *jwtClaims {
+	claims, _ := r.Context().Value(claimsKey{}).(*jwtClaims)
+	return claims
+}
+
+// requestPermissions возвращает права из токена запроса; nil — ограничений нет
+func requestPermissions(r *http.Request) tablePermissions {
+	if claims := requestClaims(r); claims != nil {
+		return claims.Tables
+	}
+	return nil
+}
+
+// requestRole возвращает claim role токена запроса
+func requestRole(r *http.Request) string {
+	if claims := requestClaims(r); claims != nil {
+		return claims.Role
+	}
+	return ""
+}
+
+func withClaims(r *http.Request, claims *jwtClaims) *http.Request {
+	return r.WithContext(context.WithValue(r.Context(), claimsKey{}, claims))
+}
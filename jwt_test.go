@@ -0,0 +1,106 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func signJWT(secret []byte, header, payload string) string {
+	unsigned := base64.RawURLEncoding.EncodeToString([]byte(header)) + "." +
+		base64.RawURLEncoding.EncodeToString([]byte(payload))
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(unsigned))
+	return unsigned + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+func TestParseJWT(t *testing.T) {
+	secret := []byte("secret")
+	now := time.Unix(1000, 0)
+	header := `{"alg":"HS256","typ":"JWT"}`
+
+	cases := []struct {
+		token string
+		err   string
+	}{
+		{signJWT(secret, header, `{"tables":{"users":"ro"},"exp":2000}`), ""},
+		{signJWT(secret, header, `{"exp":1000}`), "token is expired"},
+		{signJWT(secret, header, `{"nbf":1500}`), "token is not valid yet"},
+		{signJWT([]byte("other"), header, `{}`), "invalid token"},
+		{signJWT(secret, `{"alg":"none"}`, `{}`), "invalid token"},
+		{"not.a.token", "invalid token"},
+	}
+
+	for idx, item := range cases {
+		_, err := parseJWT(item.token, secret, now)
+		errText := ""
+		if err != nil {
+			errText = err.Error()
+		}
+		if errText != item.err {
+			t.Fatalf("[case %d] expected error %q, got %q", idx, item.err, errText)
+		}
+	}
+}
+
+func TestJWTPermissions(t *testing.T) {
+	secret := []byte("secret")
+	explorer := DbExplorer{
+		dialect:         MySQL,
+		tableKeys:       []string{"logs", "orders", "users"},
+		tableIdNamesMap: map[string][]string{"orders": {"id"}, "users": {"id"}},
+	}
+	WithJWT(secret)(&explorer)
+	token := signJWT(secret, `{"alg":"HS256"}`, `{"tables":{"users":"ro","orders":"rw"}}`)
+
+	cases := []struct {
+		method string
+		path   string
+		status int
+		body   string
+	}{
+		{http.MethodGet, "/", http.StatusOK, `{"response":{"tables":["orders","users"]}}`},
+		{http.MethodGet, "/logs", http.StatusForbidden, `{"error":"access to table logs is denied"}`},
+		{http.MethodPost, "/users/1", http.StatusForbidden, `{"error":"access to table users is denied"}`},
+		{http.MethodOptions, "/users/1", http.StatusNoContent, ""},
+		{http.MethodOptions, "/orders", http.StatusNoContent, ""},
+	}
+
+	for _, item := range cases {
+		req := httptest.NewRequest(item.method, item.path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		rw := httptest.NewRecorder()
+		explorer.ServeHTTP(rw, req)
+
+		if rw.Code != item.status {
+			t.Fatalf("[%s %s] expected status %d, got %d", item.method, item.path, item.status, rw.Code)
+		}
+		if body := strings.TrimSpace(rw.Body.String()); body != item.body {
+			t.Fatalf("[%s %s] unexpected body %s", item.method, item.path, body)
+		}
+	}
+
+	rw := httptest.NewRecorder()
+	explorer.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/", nil))
+	if rw.Code != http.StatusUnauthorized {
+		t.Fatalf("expected status %d without token, got %d", http.StatusUnauthorized, rw.Code)
+	}
+}
+
+func TestTablePermissions(t *testing.T) {
+	permissions := tablePermissions{"users": "ro", "*": "rw"}
+	if !permissions.allowed("users", false) || permissions.allowed("users", true) {
+		t.Fatalf("expected read-only access to users")
+	}
+	if !permissions.allowed("orders", true) {
+		t.Fatalf("expected wildcard write access to orders")
+	}
+	if !tablePermissions(nil).allowed("users", true) {
+		t.Fatalf("expected unrestricted access without permissions")
+	}
+}
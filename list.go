@@ -0,0 +1,309 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"strconv"
+)
+
+// listQuery — разобранные параметры выборки списка: фильтры, сортировка, проекция и пагинация
+type listQuery struct {
+	tableName string
+	columns   string
+	condition string
+	order     string
+	limit     int
+	offset    int
+	cursor    bool
+	count     bool // ?count=true или $count=true: добавить в ответ total и метаданные пагинации
+	// unbounded снимает LIMIT/OFFSET, например для выгрузки всей выборки в CSV
+	unbounded bool
+	args      *queryArgs
+}
+
+const defaultListLimit = 5
+
+// listLimit — размер страницы без ?limit=, см. WithDefaultLimit; не больше WithMaxLimit
+func (d DbExplorer) listLimit() int {
+	limit := defaultListLimit
+	if d.defaultLimit > 0 {
+		limit = d.defaultLimit
+	}
+	if d.maxLimit > 0 && limit > d.maxLimit {
+		return d.maxLimit
+	}
+	return limit
+}
+
+func (d DbExplorer) parseListQuery(tableName string, params url.Values) (*listQuery, error) {
+	params, odataFilter, err := odataParams(params)
+	if err != nil {
+		return nil, err
+	}
+
+	limit, err := strconv.Atoi(params.Get("limit"))
+	if err != nil {
+		limit = d.listLimit()
+	}
+	if d.maxLimit > 0 && limit > d.maxLimit {
+		return nil, errors.New("limit must not exceed " + strconv.Itoa(d.maxLimit))
+	}
+
+	offset, err := strconv.Atoi(params.Get("offset"))
+	if err != nil {
+		offset = 0
+	}
+	if limit < 0 || offset < 0 {
+		return nil, errors.New("limit and offset must not be negative")
+	}
+
+	args := &queryArgs{dialect: d.dialect}
+	postgrestFilter := ""
+	if d.postgrestFilters {
+		if params, postgrestFilter, err = d.postgrestParams(tableName, params, args); err != nil {
+			return nil, err
+		}
+	}
+	condition, err := d.filterCondition(tableName, params, args)
+	if err != nil {
+		return nil, err
+	}
+	condition = andCondition(condition, postgrestFilter)
+	if odataFilter != "" {
+		filter, err := d.odataCondition(tableName, odataFilter, args)
+		if err != nil {
+			return nil, err
+		}
+		condition = andCondition(condition, filter)
+	}
+
+	order, err := d.orderClause(tableName, params.Get("sort"))
+	if err != nil {
+		return nil, err
+	}
+
+	columns, err := d.selectColumns(tableName, params.Get("fields"))
+	if err != nil {
+		return nil, err
+	}
+
+	// ?after= включает keyset-пагинацию по первичному ключу вместо OFFSET
+	_, cursor := params["after"]
+	if cursor {
+		if params.Get("sort") != "" {
+			return nil, errors.New("sort is not supported with cursor pagination")
+		}
+		if !d.selectsPrimaryKey(tableName, columns) {
+			return nil, errors.New("fields must include primary key for cursor pagination")
+		}
+
+		cursorCondition, err := d.cursorCondition(tableName, params.Get("after"), args)
+		if err != nil {
+			return nil, err
+		}
+		condition = andCondition(condition, cursorCondition)
+		offset = 0
+	}
+	condition = andCondition(condition, d.visibleCondition(tableName))
+	count, _ := strconv.ParseBool(params.Get("count"))
+
+	return &listQuery{
+		tableName: tableName,
+		columns:   columns,
+		condition: condition,
+		order:     order,
+		limit:     limit,
+		offset:    offset,
+		cursor:    cursor,
+		count:     count,
+		args:      args,
+	}, nil
+}
+
+func (q *listQuery) where() string {
+	if q.condition == "" {
+		return ""
+	}
+	return " WHERE " + q.condition
+}
+
+// selectSQL возвращает запрос страницы; аргументы фильтров копируются, чтобы запрос можно было строить повторно
+func (q *listQuery) selectSQL() (string, []interface{}) {
+	args := &queryArgs{dialect: q.args.dialect, values: append([]interface{}{}, q.args.values...)}
+
+	query := "SELECT " + q.columns + " FROM " + args.dialect.quote(q.tableName) + q.where()
+	if q.order != "" {
+		query += " " + q.order
+	}
+	if !q.unbounded {
+		query += " " + args.dialect.limitOffset(args.add(q.limit), args.add(q.offset), q.order != "")
+	}
+	return query + ";", args.values
+}
+
+func (q *listQuery) countSQL() (string, []interface{}) {
+	return "SELECT COUNT(*) FROM " + q.args.dialect.quote(q.tableName) + q.where() + ";", q.args.values
+}
+
+func (d DbExplorer) handlerList(rw http.ResponseWriter, r *http.Request, tableName string) {
+	list, err := d.parseListQuery(tableName, r.URL.Query())
+	if err != nil {
+		responseResult(rw, err, http.StatusBadRequest, nil)
+		return
+	}
+	if explain, _ := strconv.ParseBool(r.URL.Query().Get("explain")); explain {
+		d.handlerExplain(rw, r, list)
+		return
+	}
+
+	format, err := responseFormat(rw, r)
+	if err != nil {
+		responseResult(rw, err, http.StatusBadRequest, nil)
+		return
+	}
+	if format != "json" {
+		d.handlerListStream(rw, r, list, format)
+		return
+	}
+	if responseEncoding(rw) == "application/json" && !jsonAPIResponse(rw) && !d.hypermedia && !d.linkHeader {
+		d.handlerListJSON(rw, r, list)
+		return
+	}
+
+	records, err := d.queryList(list)
+	// в JSON:API пустая коллекция — это пустой data, а не 404
+	if err == ErrRecordNotFound && (list.cursor || jsonAPIResponse(rw)) {
+		records, err = []map[string]interface{}{}, nil
+	}
+	if err != nil {
+		responseResult(rw, err, http.StatusNotFound, nil)
+		return
+	}
+
+	if d.linkHeader {
+		if header := d.pageLinkHeader(r, list, records); header != "" {
+			rw.Header().Set("Link", header)
+		}
+	}
+
+	result := map[string]interface{}{"records": d.toFieldsList(tableName, records)}
+	if d.hypermedia {
+		linked := make([]map[string]interface{}, 0, len(records))
+		for _, record := range records {
+			linked = append(linked, d.withRecordLinks(r, tableName, record))
+		}
+		result["records"] = linked
+		result["_links"] = d.listLinks(r, list, records)
+	}
+
+	if list.cursor {
+		var nextCursor interface{}
+		if len(records) > 0 && len(records) == list.limit {
+			nextCursor = d.encodeCursor(tableName, records[len(records)-1])
+		}
+		result["next_cursor"] = nextCursor
+	}
+
+	// ?count=true добавляет метаданные пагинации
+	if list.count {
+		total := 0
+		query, args := list.countSQL()
+		if err := d.traced(d.db, tableName).QueryRowContext(d.requestContext(), query, args...).Scan(&total); err != nil {
+			responseResult(rw, err, http.StatusInternalServerError, nil)
+			return
+		}
+
+		result["total"] = total
+		result["limit"] = list.limit
+		result["offset"] = list.offset
+		result["has_more"] = list.offset+len(records) < total
+	}
+
+	if jsonAPIResponse(rw) {
+		delete(result, "records")
+		d.responseJSONAPI(rw, r, tableName, records, result)
+		return
+	}
+	responseResult(rw, nil, http.StatusOK, result)
+}
+
+func (d DbExplorer) handlerRecord(rw http.ResponseWriter, r *http.Request, tableName, rawId string) {
+	if !d.checkPrimaryKey(rw, tableName) {
+		return
+	}
+
+	columns, err := d.selectColumns(tableName, r.URL.Query().Get("fields"))
+	if err != nil {
+		responseResult(rw, err, http.StatusBadRequest, nil)
+		return
+	}
+
+	record, err := d.queryRecord(d.db, tableName, rawId, columns)
+	if err != nil {
+		responseResult(rw, err, http.StatusNotFound, nil)
+		return
+	}
+
+	d.countRows(1)
+	etag, err := recordETag(record)
+	if err != nil {
+		responseResult(rw, err, http.StatusInternalServerError, nil)
+		return
+	}
+	rw.Header().Set("ETag", etag)
+	if match := r.Header.Get("If-None-Match"); match != "" && etagMatches(match, etag, true) {
+		rw.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	if jsonAPIResponse(rw) {
+		d.responseJSONAPI(rw, r, tableName, record, nil)
+		return
+	}
+	if d.hypermedia {
+		responseResult(rw, nil, http.StatusOK, map[string]interface{}{"record": d.withRecordLinks(r, tableName, record)})
+		return
+	}
+	responseResult(
+		rw,
+		nil,
+		http.StatusOK,
+		map[string]interface{}{"record": d.toFields(tableName, record)},
+	)
+}
+
+// queryList выполняет выборку страницы; пустой результат — ErrRecordNotFound
+func (d DbExplorer) queryList(list *listQuery) ([]map[string]interface{}, error) {
+	query, args := list.selectSQL()
+	queryResult, err := d.traced(d.db, list.tableName).QueryContext(d.requestContext(), query, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	records, err := d.parsingSqlQueryResult(queryResult, list.tableName)
+	d.countRows(len(records))
+	return records, err
+}
+
+// queryRecord читает одну запись по id из пути; columns — результат selectColumns
+func (d DbExplorer) queryRecord(db queryExecutor, tableName, rawId, columns string) (map[string]interface{}, error) {
+	args := &queryArgs{dialect: d.dialect}
+	condition, err := d.primaryKeyCondition(tableName, rawId, args)
+	if err != nil {
+		return nil, ErrRecordNotFound
+	}
+	condition = andCondition(condition, d.visibleCondition(tableName))
+
+	query := "SELECT " + columns + " FROM " + d.dialect.quote(tableName) + " WHERE " + condition + ";"
+	queryResult, err := d.traced(db, tableName).QueryContext(d.requestContext(), query, args.values...)
+	if err != nil {
+		return nil, err
+	}
+
+	records, err := d.parsingSqlQueryResult(queryResult, tableName)
+	if err != nil {
+		return nil, err
+	}
+	return records[0], nil
+}
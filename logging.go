@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// requestLog собирает поля строки журнала одного запроса
+type requestLog struct {
+	mu     sync.Mutex
+	logger *slog.Logger
+	start  time.Time
+	method string
+	path   string
+	table  string
+	status int
+	rows   int
+	err    error
+}
+
+// withRequestLog включает журнал запроса, если задан WithLogger: ответ оборачивается, чтобы узнать статус
+func (d DbExplorer) withRequestLog(rw http.ResponseWriter, r *http.Request) (http.ResponseWriter, DbExplorer) {
+	if d.logger == nil && d.tracer == nil {
+		return rw, d
+	}
+
+	d.requestLog = &requestLog{logger: d.logger, start: time.Now(), method: r.Method, path: r.URL.Path}
+	if tableName, err := getTableName(r.URL.Path, d.tableKeys); err == nil {
+		d.requestLog.table = tableName
+	}
+	return &loggingWriter{ResponseWriter: rw, log: d.requestLog}, d
+}
+
+func (l *requestLog) addRows(count int) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	l.rows += count
+	l.mu.Unlock()
+}
+
+func (l *requestLog) setError(err error) {
+	if l == nil || err == nil {
+		return
+	}
+	l.mu.Lock()
+	l.err = err
+	l.mu.Unlock()
+}
+
+// finish пишет строку журнала: 5xx — Error, 4xx — Warn, остальное — Info
+func (l *requestLog) finish() {
+	if l == nil || l.logger == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	status := l.status
+	if status == 0 {
+		status = http.StatusOK
+	}
+	level := slog.LevelInfo
+	if status >= http.StatusInternalServerError {
+		level = slog.LevelError
+	} else if status >= http.StatusBadRequest {
+		level = slog.LevelWarn
+	}
+
+	attrs := []slog.Attr{
+		slog.String("method", l.method),
+		slog.String("path", l.path),
+		slog.Int("status", status),
+		slog.Duration("duration", time.Since(l.start)),
+		slog.Int("rows", l.rows),
+	}
+	if l.table != "" {
+		attrs = append(attrs, slog.String("table", l.table))
+	}
+	if l.err != nil {
+		attrs = append(attrs, slog.String("error", l.err.Error()))
+	}
+	l.logger.LogAttrs(context.Background(), level, "request", attrs...)
+}
+
+// countRows учитывает прочитанные или изменённые строки в журнале запроса
+func (d DbExplorer) countRows(count int) {
+	d.requestLog.addRows(count)
+}
+
+// logError пишет внутреннюю ошибку, которую уже нельзя вернуть клиенту
+func (d DbExplorer) logError(message string, err error) {
+	logger := d.logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	if d.requestLog != nil {
+		logger = logger.With("method", d.requestLog.method, "path", d.requestLog.path)
+	}
+	logger.Error(message, "error", err)
+}
+
+// loggingWriter запоминает статус ответа для журнала запроса
+type loggingWriter struct {
+	http.ResponseWriter
+	log *requestLog
+}
+
+func (w *loggingWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w *loggingWriter) WriteHeader(status int) {
+	w.log.mu.Lock()
+	if w.log.status == 0 {
+		w.log.status = status
+	}
+	w.log.mu.Unlock()
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *loggingWriter) Write(data []byte) (int, error) {
+	w.log.mu.Lock()
+	if w.log.status == 0 {
+		w.log.status = http.StatusOK
+	}
+	w.log.mu.Unlock()
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *loggingWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+// requestLogOf находит журнал запроса среди обёрток ответа
+func requestLogOf(rw http.ResponseWriter) *requestLog {
+	for {
+		switch typed := rw.(type) {
+		case *loggingWriter:
+			return typed.log
+		case interface{ Unwrap() http.ResponseWriter }:
+			rw = typed.Unwrap()
+		default:
+			return nil
+		}
+	}
+}
+
+// logResponseError пишет ошибку отправки ответа туда же, куда журнал запроса, а без него — в slog.Default
+func logResponseError(rw http.ResponseWriter, err error) {
+	logger := slog.Default()
+	if log := requestLogOf(rw); log != nil && log.logger != nil {
+		logger = log.logger.With("method", log.method, "path", log.path)
+	}
+	logger.Error("response failed", "error", err)
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestRequestLog(t *testing.T) {
+	out := &bytes.Buffer{}
+	explorer := DbExplorer{dialect: MySQL, tableKeys: []string{"logs"}}
+	WithLogger(slog.New(slog.NewJSONHandler(out, nil)))(&explorer)
+
+	cases := []struct {
+		method string
+		path   string
+		level  string
+		status int
+		table  string
+		err    string
+	}{
+		{http.MethodGet, "/", "INFO", http.StatusOK, "", ""},
+		{http.MethodDelete, "/logs/1", "WARN", http.StatusMethodNotAllowed, "logs", "table logs has no primary key"},
+	}
+
+	for _, item := range cases {
+		out.Reset()
+		explorer.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(item.method, item.path, nil))
+
+		line := map[string]interface{}{}
+		if err := json.Unmarshal(out.Bytes(), &line); err != nil {
+			t.Fatalf("[%s] unexpected log %q: %v", item.path, out.String(), err)
+		}
+		if line["level"] != item.level || line["status"] != float64(item.status) || line["msg"] != "request" {
+			t.Fatalf("[%s] unexpected log %v", item.path, line)
+		}
+		if table, _ := line["table"].(string); table != item.table {
+			t.Fatalf("[%s] expected table %q, got %q", item.path, item.table, table)
+		}
+		if errText, _ := line["error"].(string); errText != item.err {
+			t.Fatalf("[%s] expected error %q, got %q", item.path, item.err, errText)
+		}
+		if _, ok := line["duration"]; !ok {
+			t.Fatalf("[%s] expected duration in log", item.path)
+		}
+	}
+}
@@ -2,10 +2,12 @@ package main
 
 import (
 	"database/sql"
+	"flag"
 	"fmt"
-	"net/http"
+	"net"
 
 	_ "github.com/go-sql-driver/mysql"
+	"google.golang.org/grpc"
 )
 
 var (
@@ -14,6 +16,10 @@ var (
 )
 
 func main() {
+	// gRPC-фасад для вызовов между сервисами запускается только по явному адресу
+	grpcAddr := flag.String("grpc", "", "адрес gRPC-фасада, например :8083")
+	flag.Parse()
+
 	db, err := sql.Open("mysql", DSN)
 	err = db.Ping() // вот тут будет первое подключение к базе
 	if err != nil {
@@ -25,6 +31,19 @@ func main() {
 		panic(err)
 	}
 
+	if *grpcAddr != "" {
+		grpcServer := grpc.NewServer()
+		RegisterGRPCService(grpcServer, handler)
+		listener, err := net.Listen("tcp", *grpcAddr)
+		if err != nil {
+			panic(err)
+		}
+		go grpcServer.Serve(listener)
+		fmt.Println("grpc at " + *grpcAddr)
+	}
+
 	fmt.Println("starting server at :8082")
-	http.ListenAndServe(":8082", handler)
+	if err := handler.Serve(":8082"); err != nil {
+		panic(err)
+	}
 }
@@ -1,19 +1,29 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"database/sql/driver"
+	"errors"
 	"fmt"
 	"reflect"
 	"testing"
 
 	"bytes"
 	"encoding/json"
+	"io"
 	"io/ioutil"
+	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"strings"
 	"time"
 
 	_ "github.com/go-sql-driver/mysql"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	_ "modernc.org/sqlite"
 )
 
 // CaseResponse
@@ -495,6 +505,276 @@ func TestApis(t *testing.T) {
 				},
 			},
 		},
+
+		// фильтрация по колонкам
+		Case{
+			Path:  "/users",
+			Query: "login=qwerty'",
+			Result: CR{
+				"response": CR{
+					"records": []CR{
+						CR{
+							"user_id":  2,
+							"login":    "qwerty'",
+							"password": "love\"",
+							"email":    "",
+							"info":     "",
+							"updated":  nil,
+						},
+					},
+				},
+			},
+		},
+		Case{
+			Path:  "/users",
+			Query: "user_id=1&user_id=3&limit=10",
+			Result: CR{
+				"response": CR{
+					"records": []CR{
+						CR{
+							"user_id":  1,
+							"login":    "rvasily",
+							"password": "love",
+							"email":    "rvasily@example.com",
+							"info":     "try update",
+							"updated":  "now",
+						},
+					},
+				},
+			},
+		},
+		Case{
+			Path:   "/users",
+			Query:  "user_id=1%20OR%201=1",
+			Status: http.StatusBadRequest,
+			Result: CR{
+				"error": "field user_id have invalid type",
+			},
+		},
+		Case{
+			Path:  "/users",
+			Query: "sort=-user_id&limit=1",
+			Result: CR{
+				"response": CR{
+					"records": []CR{
+						CR{
+							"user_id":  2,
+							"login":    "qwerty'",
+							"password": "love\"",
+							"email":    "",
+							"info":     "",
+							"updated":  nil,
+						},
+					},
+				},
+			},
+		},
+		Case{
+			Path:  "/users",
+			Query: "fields=user_id,login&limit=1",
+			Result: CR{
+				"response": CR{
+					"records": []CR{
+						CR{
+							"user_id": 1,
+							"login":   "rvasily",
+						},
+					},
+				},
+			},
+		},
+		Case{
+			Path:  "/users/2",
+			Query: "fields=login",
+			Result: CR{
+				"response": CR{
+					"record": CR{
+						"login": "qwerty'",
+					},
+				},
+			},
+		},
+		Case{
+			Path:  "/users",
+			Query: "fields=user_id&limit=1&count=true",
+			Result: CR{
+				"response": CR{
+					"records": []CR{
+						CR{
+							"user_id": 1,
+						},
+					},
+					"total":    2,
+					"limit":    1,
+					"offset":   0,
+					"has_more": true,
+				},
+			},
+		},
+		Case{
+			Path:  "/users",
+			Query: "fields=user_id&login=rvasily&count=true",
+			Result: CR{
+				"response": CR{
+					"records": []CR{
+						CR{
+							"user_id": 1,
+						},
+					},
+					"total":    1,
+					"limit":    5,
+					"offset":   0,
+					"has_more": false,
+				},
+			},
+		},
+		Case{
+			Path:  "/users",
+			Query: "fields=user_id&limit=1&after=",
+			Result: CR{
+				"response": CR{
+					"records": []CR{
+						CR{
+							"user_id": 1,
+						},
+					},
+					"next_cursor": "WzFd",
+				},
+			},
+		},
+		Case{
+			Path:  "/users",
+			Query: "fields=user_id&limit=1&after=WzFd",
+			Result: CR{
+				"response": CR{
+					"records": []CR{
+						CR{
+							"user_id": 2,
+						},
+					},
+					"next_cursor": "WzJd",
+				},
+			},
+		},
+		Case{
+			Path:  "/users",
+			Query: "fields=user_id&limit=1&after=WzJd",
+			Result: CR{
+				"response": CR{
+					"records":     []CR{},
+					"next_cursor": nil,
+				},
+			},
+		},
+		Case{
+			Path:   "/users",
+			Query:  "after=garbage",
+			Status: http.StatusBadRequest,
+			Result: CR{
+				"error": "invalid cursor",
+			},
+		},
+		// пакетная вставка
+		Case{
+			Path:   "/items",
+			Method: http.MethodPut,
+			Body: []CR{
+				CR{"title": "bulk 1", "description": ""},
+				CR{"title": "bulk 2", "description": "", "updated": "now"},
+			},
+			Result: CR{
+				"response": CR{
+					"ids": []CR{
+						CR{"id": 4},
+						CR{"id": 5},
+					},
+				},
+			},
+		},
+		Case{
+			Path:   "/items",
+			Method: http.MethodPut,
+			Status: http.StatusBadRequest,
+			Body: []CR{
+				CR{"title": "bulk 3", "description": ""},
+				CR{"title": 42},
+			},
+			Result: CR{
+				"error": "record 1: field title have invalid type",
+			},
+		},
+		Case{
+			Path:  "/items",
+			Query: "fields=id,title&id__gte=4&limit=10",
+			Result: CR{
+				"response": CR{
+					"records": []CR{
+						CR{"id": 4, "title": "bulk 1"},
+						CR{"id": 5, "title": "bulk 2"},
+					},
+				},
+			},
+		},
+		// массовое обновление по фильтру
+		Case{
+			Path:   "/items?id__gte=4",
+			Method: http.MethodPost,
+			Body: CR{
+				"updated": "bulk",
+			},
+			Result: CR{
+				"response": CR{
+					"updated": 2,
+				},
+			},
+		},
+		Case{
+			Path:   "/items",
+			Method: http.MethodPost,
+			Status: http.StatusBadRequest,
+			Body: CR{
+				"updated": "everything",
+			},
+			Result: CR{
+				"error": "bulk update requires a filter",
+			},
+		},
+		Case{
+			Path:  "/items",
+			Query: "fields=id,updated&updated=bulk&limit=10",
+			Result: CR{
+				"response": CR{
+					"records": []CR{
+						CR{"id": 4, "updated": "bulk"},
+						CR{"id": 5, "updated": "bulk"},
+					},
+				},
+			},
+		},
+		Case{
+			Path:   "/users/2",
+			Query:  "fields=login,name",
+			Status: http.StatusBadRequest,
+			Result: CR{
+				"error": "unknown field name",
+			},
+		},
+		Case{
+			Path:   "/users",
+			Query:  "sort=name",
+			Status: http.StatusBadRequest,
+			Result: CR{
+				"error": "unknown sort field name",
+			},
+		},
+		Case{
+			Path:   "/users",
+			Query:  "name=Ivan",
+			Status: http.StatusBadRequest,
+			Result: CR{
+				"error": "unknown field name",
+			},
+		},
 	}
 
 	runCases(t, ts, db, cases)
@@ -567,3 +847,2826 @@ func runCases(t *testing.T, ts *httptest.Server, db *sql.DB, cases []Case) {
 	}
 
 }
+
+func TestCompositePrimaryKey(t *testing.T) {
+	db, err := sql.Open("mysql", DSN)
+	err = db.Ping()
+	if err != nil {
+		panic(err)
+	}
+
+	qs := []string{
+		`DROP TABLE IF EXISTS order_items;`,
+		`CREATE TABLE order_items (
+  order_id int(11) NOT NULL,
+  item_id int(11) NOT NULL,
+  comment varchar(255) DEFAULT NULL,
+  PRIMARY KEY (order_id, item_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;`,
+		`INSERT INTO order_items (order_id, item_id, comment) VALUES (1, 1, 'first'), (1, 2, NULL);`,
+	}
+	for _, q := range qs {
+		if _, err := db.Exec(q); err != nil {
+			panic(err)
+		}
+	}
+	defer db.Exec(`DROP TABLE IF EXISTS order_items;`)
+
+	handler, err := NewDbExplorer(db)
+	if err != nil {
+		panic(err)
+	}
+
+	ts := httptest.NewServer(handler)
+
+	cases := []Case{
+		Case{
+			Path: "/order_items/1,2",
+			Result: CR{
+				"response": CR{
+					"record": CR{
+						"order_id": 1,
+						"item_id":  2,
+						"comment":  nil,
+					},
+				},
+			},
+		},
+		Case{
+			Path:   "/order_items/1",
+			Status: http.StatusNotFound,
+			Result: CR{
+				"error": "record not found",
+			},
+		},
+		Case{
+			Path:   "/order_items/",
+			Method: http.MethodPut,
+			Body: CR{
+				"order_id": 2,
+				"item_id":  5,
+				"comment":  "new",
+			},
+			Result: CR{
+				"response": CR{
+					"order_id": 2,
+					"item_id":  5,
+				},
+			},
+		},
+		Case{
+			Path:   "/order_items/2,5",
+			Method: http.MethodPost,
+			Body: CR{
+				"comment": "updated",
+			},
+			Result: CR{
+				"response": CR{
+					"updated": 1,
+				},
+			},
+		},
+		Case{
+			Path:   "/order_items/2,5",
+			Method: http.MethodPost,
+			Status: http.StatusBadRequest,
+			Body: CR{
+				"item_id": 6,
+			},
+			Result: CR{
+				"error": "field item_id have invalid type",
+			},
+		},
+		Case{
+			Path: "/order_items/2,5",
+			Result: CR{
+				"response": CR{
+					"record": CR{
+						"order_id": 2,
+						"item_id":  5,
+						"comment":  "updated",
+					},
+				},
+			},
+		},
+		Case{
+			Path:   "/order_items/2,5",
+			Method: http.MethodDelete,
+			Result: CR{
+				"response": CR{
+					"deleted": 1,
+				},
+			},
+		},
+	}
+
+	runCases(t, ts, db, cases)
+}
+
+func TestCompositePrimaryKeyOrder(t *testing.T) {
+	db, err := sql.Open("mysql", DSN)
+	err = db.Ping()
+	if err != nil {
+		panic(err)
+	}
+
+	qs := []string{
+		`DROP TABLE IF EXISTS stock;`,
+		`CREATE TABLE stock (
+  warehouse_id int(11) NOT NULL,
+  sku int(11) NOT NULL,
+  qty int(11) NOT NULL,
+  PRIMARY KEY (sku, warehouse_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;`,
+		`INSERT INTO stock (warehouse_id, sku, qty) VALUES (1, 7, 10);`,
+	}
+	for _, q := range qs {
+		if _, err := db.Exec(q); err != nil {
+			panic(err)
+		}
+	}
+	defer db.Exec(`DROP TABLE IF EXISTS stock;`)
+
+	handler, err := NewDbExplorer(db)
+	if err != nil {
+		panic(err)
+	}
+
+	ts := httptest.NewServer(handler)
+
+	// id в пути идут в порядке PRIMARY KEY (sku, warehouse_id), а не в порядке колонок таблицы
+	cases := []Case{
+		Case{
+			Path: "/stock/7,1",
+			Result: CR{
+				"response": CR{
+					"record": CR{"warehouse_id": 1, "sku": 7, "qty": 10},
+				},
+			},
+		},
+		Case{
+			Path:   "/stock/1,7",
+			Status: http.StatusNotFound,
+			Result: CR{"error": "record not found"},
+		},
+		Case{
+			Path:   "/stock/7,1",
+			Method: http.MethodPost,
+			Body:   CR{"qty": 3},
+			Result: CR{"response": CR{"updated": 1}},
+		},
+		Case{
+			Path:   "/stock/7,1",
+			Method: http.MethodDelete,
+			Result: CR{"response": CR{"deleted": 1}},
+		},
+	}
+
+	runCases(t, ts, db, cases)
+}
+
+func TestSQLite(t *testing.T) {
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		panic(err)
+	}
+	defer db.Close()
+	// у каждого соединения :memory: своя база
+	db.SetMaxOpenConns(1)
+
+	qs := []string{
+		`CREATE TABLE items (id INTEGER PRIMARY KEY AUTOINCREMENT, title TEXT NOT NULL, updated TEXT NULL);`,
+		`INSERT INTO items (id, title, updated) VALUES (1, 'database/sql', 'rvasily'), (2, 'memcache', NULL);`,
+	}
+	for _, q := range qs {
+		if _, err := db.Exec(q); err != nil {
+			panic(err)
+		}
+	}
+
+	handler, err := NewDbExplorer(db)
+	if err != nil {
+		panic(err)
+	}
+
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	cases := []Case{
+		Case{
+			Path:  "/items",
+			Query: "limit=1&offset=1",
+			Result: CR{
+				"response": CR{
+					"records": []CR{CR{"id": 2, "title": "memcache", "updated": nil}},
+				},
+			},
+		},
+		Case{
+			Path:   "/items/",
+			Method: http.MethodPut,
+			Body:   CR{"id": 42, "title": "sqlite"},
+			Result: CR{"response": CR{"id": 3}},
+		},
+		Case{
+			Path:   "/items/3",
+			Method: http.MethodPost,
+			Body:   CR{"updated": "admin"},
+			Result: CR{"response": CR{"updated": 1}},
+		},
+		Case{
+			Path:   "/items/3",
+			Result: CR{"response": CR{"record": CR{"id": 3, "title": "sqlite", "updated": "admin"}}},
+		},
+		Case{
+			Path:   "/items/3",
+			Method: http.MethodDelete,
+			Result: CR{"response": CR{"deleted": 1}},
+		},
+		Case{
+			Path:   "/items/3",
+			Status: http.StatusNotFound,
+			Result: CR{"error": "record not found"},
+		},
+	}
+
+	runCases(t, ts, db, cases)
+}
+
+func TestDateFilters(t *testing.T) {
+	db, err := sql.Open("mysql", DSN)
+	err = db.Ping()
+	if err != nil {
+		panic(err)
+	}
+
+	qs := []string{
+		`DROP TABLE IF EXISTS probe_dates;`,
+		`CREATE TABLE probe_dates (
+  id int(11) NOT NULL AUTO_INCREMENT,
+  day date NOT NULL,
+  created datetime NOT NULL,
+  PRIMARY KEY (id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;`,
+		`INSERT INTO probe_dates (id, day, created) VALUES
+  (1, '2024-01-01', '2024-05-31 23:59:59'),
+  (2, '2024-06-01', '2024-06-01 00:00:00'),
+  (3, '2024-06-15', '2024-06-15 12:30:00');`,
+	}
+	for _, q := range qs {
+		if _, err := db.Exec(q); err != nil {
+			panic(err)
+		}
+	}
+	defer db.Exec(`DROP TABLE IF EXISTS probe_dates;`)
+
+	handler, err := NewDbExplorer(db)
+	if err != nil {
+		panic(err)
+	}
+
+	ts := httptest.NewServer(handler)
+
+	cases := []Case{
+		Case{
+			Path:   "/probe_dates",
+			Query:  "day=2024-01-01&fields=id",
+			Result: CR{"response": CR{"records": []CR{CR{"id": 1}}}},
+		},
+		Case{
+			Path:   "/probe_dates",
+			Query:  "created=2024-06-15%2012:30:00&fields=id",
+			Result: CR{"response": CR{"records": []CR{CR{"id": 3}}}},
+		},
+		Case{
+			Path:   "/probe_dates",
+			Query:  "created__gte=2024-06-01&created__lt=2024-06-15&fields=id",
+			Result: CR{"response": CR{"records": []CR{CR{"id": 2}}}},
+		},
+		Case{
+			Path:   "/probe_dates",
+			Query:  "day__lt=2024-06-01&fields=id",
+			Result: CR{"response": CR{"records": []CR{CR{"id": 1}}}},
+		},
+		Case{
+			Path:   "/probe_dates",
+			Query:  "day__in=2024-01-01,2024-06-15&fields=id",
+			Result: CR{"response": CR{"records": []CR{CR{"id": 1}, CR{"id": 3}}}},
+		},
+	}
+
+	runCases(t, ts, db, cases)
+}
+
+func TestTableWithoutPrimaryKey(t *testing.T) {
+	db, err := sql.Open("mysql", DSN)
+	err = db.Ping()
+	if err != nil {
+		panic(err)
+	}
+
+	qs := []string{
+		`DROP TABLE IF EXISTS logs;`,
+		`CREATE TABLE logs (
+  message varchar(255) NOT NULL
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;`,
+		`INSERT INTO logs (message) VALUES ('started');`,
+	}
+	for _, q := range qs {
+		if _, err := db.Exec(q); err != nil {
+			panic(err)
+		}
+	}
+	defer db.Exec(`DROP TABLE IF EXISTS logs;`)
+
+	handler, err := NewDbExplorer(db)
+	if err != nil {
+		panic(err)
+	}
+
+	ts := httptest.NewServer(handler)
+
+	cases := []Case{
+		Case{
+			Path: "/logs",
+			Result: CR{
+				"response": CR{
+					"records": []CR{
+						CR{"message": "started"},
+					},
+				},
+			},
+		},
+		Case{
+			Path:   "/logs/1",
+			Status: http.StatusMethodNotAllowed,
+			Result: CR{
+				"error": "table logs has no primary key",
+			},
+		},
+		Case{
+			Path:   "/logs/",
+			Method: http.MethodPut,
+			Status: http.StatusMethodNotAllowed,
+			Body: CR{
+				"message": "new",
+			},
+			Result: CR{
+				"error": "table logs has no primary key",
+			},
+		},
+		Case{
+			Path:   "/logs/1",
+			Method: http.MethodDelete,
+			Status: http.StatusMethodNotAllowed,
+			Result: CR{
+				"error": "table logs has no primary key",
+			},
+		},
+	}
+
+	runCases(t, ts, db, cases)
+}
+
+func TestReservedTableNames(t *testing.T) {
+	db, err := sql.Open("mysql", DSN)
+	err = db.Ping()
+	if err != nil {
+		panic(err)
+	}
+
+	qs := []string{
+		"DROP TABLE IF EXISTS `order`;",
+		"DROP TABLE IF EXISTS `group-2`;",
+		"CREATE TABLE `order` (`id` int(11) NOT NULL AUTO_INCREMENT, `select` varchar(255) NOT NULL, PRIMARY KEY (`id`)) ENGINE=InnoDB DEFAULT CHARSET=utf8;",
+		"CREATE TABLE `group-2` (`id` int(11) NOT NULL AUTO_INCREMENT, `from` int(11) NOT NULL, PRIMARY KEY (`id`)) ENGINE=InnoDB DEFAULT CHARSET=utf8;",
+		"INSERT INTO `order` (`id`, `select`) VALUES (1, 'first');",
+		"INSERT INTO `group-2` (`id`, `from`) VALUES (1, 10);",
+	}
+	for _, q := range qs {
+		if _, err := db.Exec(q); err != nil {
+			panic(err)
+		}
+	}
+	defer db.Exec("DROP TABLE IF EXISTS `order`;")
+	defer db.Exec("DROP TABLE IF EXISTS `group-2`;")
+
+	handler, err := NewDbExplorer(db)
+	if err != nil {
+		panic(err)
+	}
+
+	ts := httptest.NewServer(handler)
+
+	cases := []Case{
+		Case{
+			Path:   "/order",
+			Query:  "select=first",
+			Result: CR{"response": CR{"records": []CR{CR{"id": 1, "select": "first"}}}},
+		},
+		Case{
+			Path:   "/order/",
+			Method: http.MethodPut,
+			Body:   CR{"select": "second"},
+			Result: CR{"response": CR{"id": 2}},
+		},
+		Case{
+			Path:   "/order/2",
+			Method: http.MethodPost,
+			Body:   CR{"select": "updated"},
+			Result: CR{"response": CR{"updated": 1}},
+		},
+		Case{
+			Path:   "/order/2",
+			Result: CR{"response": CR{"record": CR{"id": 2, "select": "updated"}}},
+		},
+		Case{
+			Path:   "/order/2",
+			Method: http.MethodDelete,
+			Result: CR{"response": CR{"deleted": 1}},
+		},
+		Case{
+			Path:   "/group-2/1",
+			Method: http.MethodPatch,
+			Body:   CR{"from": 20},
+			Result: CR{"response": CR{"updated": 1}},
+		},
+		Case{
+			Path:   "/group-2",
+			Query:  "from=20",
+			Result: CR{"response": CR{"records": []CR{CR{"from": 20, "id": 1}}}},
+		},
+	}
+
+	runCases(t, ts, db, cases)
+}
+
+func TestStringPrimaryKey(t *testing.T) {
+	db, err := sql.Open("mysql", DSN)
+	err = db.Ping()
+	if err != nil {
+		panic(err)
+	}
+
+	qs := []string{
+		"DROP TABLE IF EXISTS sessions;",
+		"CREATE TABLE sessions (token char(36) NOT NULL, login varchar(255) NOT NULL, PRIMARY KEY (token)) ENGINE=InnoDB DEFAULT CHARSET=utf8;",
+		"INSERT INTO sessions (token, login) VALUES ('6f1c2a9e-3b4d-4e5f-8a7b-1c2d3e4f5a6b', 'rvasily');",
+	}
+	for _, q := range qs {
+		if _, err := db.Exec(q); err != nil {
+			panic(err)
+		}
+	}
+	defer db.Exec("DROP TABLE IF EXISTS sessions;")
+
+	handler, err := NewDbExplorer(db)
+	if err != nil {
+		panic(err)
+	}
+
+	ts := httptest.NewServer(handler)
+
+	cases := []Case{
+		Case{
+			Path:   "/sessions/6f1c2a9e-3b4d-4e5f-8a7b-1c2d3e4f5a6b",
+			Result: CR{"response": CR{"record": CR{"token": "6f1c2a9e-3b4d-4e5f-8a7b-1c2d3e4f5a6b", "login": "rvasily"}}},
+		},
+		Case{
+			Path:   "/sessions/",
+			Method: http.MethodPut,
+			Body:   CR{"token": "0b8e7d6c-5a4b-4c3d-9e2f-1a0b9c8d7e6f", "login": "admin"},
+			Result: CR{"response": CR{"token": "0b8e7d6c-5a4b-4c3d-9e2f-1a0b9c8d7e6f"}},
+		},
+		Case{
+			Path:   "/sessions/",
+			Method: http.MethodPut,
+			Status: http.StatusUnprocessableEntity,
+			Body:   CR{"login": "guest"},
+			Result: CR{
+				"error": "field token is required",
+				"response": CR{
+					"fields": []CR{CR{"field": "token", "rule": "required", "message": "is required"}},
+				},
+			},
+		},
+		Case{
+			Path:   "/sessions/0b8e7d6c-5a4b-4c3d-9e2f-1a0b9c8d7e6f",
+			Method: http.MethodPost,
+			Body:   CR{"login": "root"},
+			Result: CR{"response": CR{"updated": 1}},
+		},
+		Case{
+			Path:   "/sessions/0b8e7d6c-5a4b-4c3d-9e2f-1a0b9c8d7e6f",
+			Result: CR{"response": CR{"record": CR{"token": "0b8e7d6c-5a4b-4c3d-9e2f-1a0b9c8d7e6f", "login": "root"}}},
+		},
+		Case{
+			Path:   "/sessions/0b8e7d6c-5a4b-4c3d-9e2f-1a0b9c8d7e6f",
+			Method: http.MethodDelete,
+			Result: CR{"response": CR{"deleted": 1}},
+		},
+		Case{
+			Path:   "/sessions/0b8e7d6c-5a4b-4c3d-9e2f-1a0b9c8d7e6f",
+			Status: http.StatusNotFound,
+			Result: CR{"error": "record not found"},
+		},
+	}
+
+	runCases(t, ts, db, cases)
+}
+
+func TestNumericColumns(t *testing.T) {
+	db, err := sql.Open("mysql", DSN)
+	err = db.Ping()
+	if err != nil {
+		panic(err)
+	}
+
+	qs := []string{
+		`DROP TABLE IF EXISTS prices;`,
+		`CREATE TABLE prices (
+  id int(11) NOT NULL AUTO_INCREMENT,
+  amount decimal(10,2) NOT NULL,
+  ratio double DEFAULT NULL,
+  PRIMARY KEY (id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;`,
+		`INSERT INTO prices (id, amount, ratio) VALUES (1, 12.50, 0.25);`,
+	}
+	for _, q := range qs {
+		if _, err := db.Exec(q); err != nil {
+			panic(err)
+		}
+	}
+	defer db.Exec(`DROP TABLE IF EXISTS prices;`)
+
+	handler, err := NewDbExplorer(db)
+	if err != nil {
+		panic(err)
+	}
+
+	ts := httptest.NewServer(handler)
+
+	cases := []Case{
+		Case{
+			Path: "/prices/1",
+			Result: CR{
+				"response": CR{
+					"record": CR{
+						"id":     1,
+						"amount": 12.5,
+						"ratio":  0.25,
+					},
+				},
+			},
+		},
+		Case{
+			Path:   "/prices/",
+			Method: http.MethodPut,
+			Body: CR{
+				"amount": "19.99",
+				"ratio":  1.5,
+			},
+			Result: CR{
+				"response": CR{
+					"id": 2,
+				},
+			},
+		},
+		Case{
+			Path:   "/prices/2",
+			Method: http.MethodPost,
+			Body: CR{
+				"ratio": nil,
+			},
+			Result: CR{
+				"response": CR{
+					"updated": 1,
+				},
+			},
+		},
+		Case{
+			Path: "/prices/2",
+			Result: CR{
+				"response": CR{
+					"record": CR{
+						"id":     2,
+						"amount": 19.99,
+						"ratio":  nil,
+					},
+				},
+			},
+		},
+		Case{
+			Path:   "/prices/2",
+			Method: http.MethodPost,
+			Status: http.StatusBadRequest,
+			Body: CR{
+				"amount": "12,5",
+			},
+			Result: CR{
+				"error": "field amount have invalid type",
+			},
+		},
+	}
+
+	runCases(t, ts, db, cases)
+
+	handler, err = NewDbExplorer(db, WithDecimalsAsStrings(true))
+	if err != nil {
+		panic(err)
+	}
+
+	ts = httptest.NewServer(handler)
+
+	runCases(t, ts, db, []Case{
+		Case{
+			Path: "/prices/1",
+			Result: CR{
+				"response": CR{
+					"record": CR{
+						"id":     1,
+						"amount": "12.50",
+						"ratio":  0.25,
+					},
+				},
+			},
+		},
+	})
+}
+
+func TestBoolColumns(t *testing.T) {
+	db, err := sql.Open("mysql", DSN)
+	err = db.Ping()
+	if err != nil {
+		panic(err)
+	}
+
+	qs := []string{
+		`DROP TABLE IF EXISTS flags;`,
+		`CREATE TABLE flags (
+  id int(11) NOT NULL AUTO_INCREMENT,
+  active tinyint(1) NOT NULL,
+  level tinyint NOT NULL,
+  PRIMARY KEY (id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;`,
+		`INSERT INTO flags (id, active, level) VALUES (1, 1, 3);`,
+	}
+	for _, q := range qs {
+		if _, err := db.Exec(q); err != nil {
+			panic(err)
+		}
+	}
+	defer db.Exec(`DROP TABLE IF EXISTS flags;`)
+
+	handler, err := NewDbExplorer(db)
+	if err != nil {
+		panic(err)
+	}
+
+	ts := httptest.NewServer(handler)
+
+	runCases(t, ts, db, []Case{
+		Case{
+			Path: "/flags/1",
+			Result: CR{
+				"response": CR{
+					"record": CR{
+						"id":     1,
+						"active": true,
+						"level":  3,
+					},
+				},
+			},
+		},
+		Case{
+			Path:   "/flags/1",
+			Method: http.MethodPost,
+			Body: CR{
+				"active": false,
+			},
+			Result: CR{
+				"response": CR{
+					"updated": 1,
+				},
+			},
+		},
+		Case{
+			Path:   "/flags/1",
+			Method: http.MethodPost,
+			Status: http.StatusBadRequest,
+			Body: CR{
+				"active": 1,
+			},
+			Result: CR{
+				"error": "field active have invalid type",
+			},
+		},
+		Case{
+			Path: "/flags",
+			Result: CR{
+				"response": CR{
+					"records": []CR{
+						CR{
+							"id":     1,
+							"active": false,
+							"level":  3,
+						},
+					},
+				},
+			},
+		},
+	})
+
+	handler, err = NewDbExplorer(db, WithTinyintAsBool(false))
+	if err != nil {
+		panic(err)
+	}
+
+	ts = httptest.NewServer(handler)
+
+	runCases(t, ts, db, []Case{
+		Case{
+			Path: "/flags/1",
+			Result: CR{
+				"response": CR{
+					"record": CR{
+						"id":     1,
+						"active": 0,
+						"level":  3,
+					},
+				},
+			},
+		},
+	})
+}
+
+func TestEnumColumns(t *testing.T) {
+	db, err := sql.Open("mysql", DSN)
+	err = db.Ping()
+	if err != nil {
+		panic(err)
+	}
+
+	qs := []string{
+		`DROP TABLE IF EXISTS tasks;`,
+		`CREATE TABLE tasks (
+  id int(11) NOT NULL AUTO_INCREMENT,
+  status enum('new','done') NOT NULL,
+  tags set('red','green','blue') DEFAULT NULL,
+  PRIMARY KEY (id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;`,
+		`INSERT INTO tasks (id, status, tags) VALUES (1, 'new', 'red,blue');`,
+	}
+	for _, q := range qs {
+		if _, err := db.Exec(q); err != nil {
+			panic(err)
+		}
+	}
+	defer db.Exec(`DROP TABLE IF EXISTS tasks;`)
+
+	handler, err := NewDbExplorer(db)
+	if err != nil {
+		panic(err)
+	}
+
+	ts := httptest.NewServer(handler)
+
+	runCases(t, ts, db, []Case{
+		Case{
+			Path: "/tasks/1",
+			Result: CR{
+				"response": CR{
+					"record": CR{
+						"id":     1,
+						"status": "new",
+						"tags":   "red,blue",
+					},
+				},
+			},
+		},
+		Case{
+			Path:   "/tasks/1",
+			Method: http.MethodPost,
+			Body: CR{
+				"status": "done",
+				"tags":   []string{"green"},
+			},
+			Result: CR{
+				"response": CR{
+					"updated": 1,
+				},
+			},
+		},
+		Case{
+			Path:   "/tasks/1",
+			Method: http.MethodPost,
+			Status: http.StatusBadRequest,
+			Body: CR{
+				"status": "archived",
+			},
+			Result: CR{
+				"error": "field status must be one of: new, done",
+			},
+		},
+		Case{
+			Path:   "/tasks/",
+			Method: http.MethodPut,
+			Status: http.StatusBadRequest,
+			Body: CR{
+				"tags": "red,black",
+			},
+			Result: CR{
+				"error": "field tags must be one of: red, green, blue",
+			},
+		},
+		Case{
+			Path: "/tasks/1",
+			Result: CR{
+				"response": CR{
+					"record": CR{
+						"id":     1,
+						"status": "done",
+						"tags":   "green",
+					},
+				},
+			},
+		},
+	})
+}
+
+func TestJSONColumns(t *testing.T) {
+	db, err := sql.Open("mysql", DSN)
+	err = db.Ping()
+	if err != nil {
+		panic(err)
+	}
+
+	qs := []string{
+		`DROP TABLE IF EXISTS documents;`,
+		`CREATE TABLE documents (
+  id int(11) NOT NULL AUTO_INCREMENT,
+  payload json DEFAULT NULL,
+  PRIMARY KEY (id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;`,
+		`INSERT INTO documents (id, payload) VALUES (1, '{"tags": ["a", "b"], "count": 2}');`,
+	}
+	for _, q := range qs {
+		if _, err := db.Exec(q); err != nil {
+			panic(err)
+		}
+	}
+	defer db.Exec(`DROP TABLE IF EXISTS documents;`)
+
+	handler, err := NewDbExplorer(db)
+	if err != nil {
+		panic(err)
+	}
+
+	ts := httptest.NewServer(handler)
+
+	runCases(t, ts, db, []Case{
+		Case{
+			Path: "/documents/1",
+			Result: CR{
+				"response": CR{
+					"record": CR{
+						"id": 1,
+						"payload": CR{
+							"tags":  []string{"a", "b"},
+							"count": 2,
+						},
+					},
+				},
+			},
+		},
+		Case{
+			Path:   "/documents/",
+			Method: http.MethodPut,
+			Body: CR{
+				"payload": []interface{}{1, CR{"nested": true}},
+			},
+			Result: CR{
+				"response": CR{
+					"id": 2,
+				},
+			},
+		},
+		Case{
+			Path: "/documents/2",
+			Result: CR{
+				"response": CR{
+					"record": CR{
+						"id":      2,
+						"payload": []interface{}{1, CR{"nested": true}},
+					},
+				},
+			},
+		},
+
+		// JSON Merge Patch сливает объекты внутри JSON-колонки
+		Case{
+			Path:   "/documents/1",
+			Method: http.MethodPatch,
+			Body: CR{
+				"payload": CR{
+					"count": nil,
+					"owner": CR{"name": "rvasily"},
+				},
+			},
+			Result: CR{
+				"response": CR{
+					"updated": 1,
+				},
+			},
+		},
+		Case{
+			Path: "/documents/1",
+			Result: CR{
+				"response": CR{
+					"record": CR{
+						"id": 1,
+						"payload": CR{
+							"tags":  []string{"a", "b"},
+							"owner": CR{"name": "rvasily"},
+						},
+					},
+				},
+			},
+		},
+		Case{
+			Path:   "/documents/1",
+			Method: http.MethodPatch,
+			Body: CR{
+				"payload": nil,
+			},
+			Result: CR{
+				"response": CR{
+					"updated": 1,
+				},
+			},
+		},
+		Case{
+			Path: "/documents/1",
+			Result: CR{
+				"response": CR{
+					"record": CR{
+						"id":      1,
+						"payload": nil,
+					},
+				},
+			},
+		},
+	})
+}
+
+func TestBlobColumns(t *testing.T) {
+	db, err := sql.Open("mysql", DSN)
+	err = db.Ping()
+	if err != nil {
+		panic(err)
+	}
+
+	qs := []string{
+		`DROP TABLE IF EXISTS files;`,
+		`CREATE TABLE files (
+  id int(11) NOT NULL AUTO_INCREMENT,
+  content blob DEFAULT NULL,
+  PRIMARY KEY (id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;`,
+		`INSERT INTO files (id, content) VALUES (1, X'00FF10');`,
+	}
+	for _, q := range qs {
+		if _, err := db.Exec(q); err != nil {
+			panic(err)
+		}
+	}
+	defer db.Exec(`DROP TABLE IF EXISTS files;`)
+
+	handler, err := NewDbExplorer(db, WithMaxBlobSize(4))
+	if err != nil {
+		panic(err)
+	}
+
+	ts := httptest.NewServer(handler)
+
+	runCases(t, ts, db, []Case{
+		Case{
+			Path: "/files/1",
+			Result: CR{
+				"response": CR{
+					"record": CR{
+						"id":      1,
+						"content": "AP8Q",
+					},
+				},
+			},
+		},
+		Case{
+			Path:   "/files/1",
+			Method: http.MethodPost,
+			Body: CR{
+				"content": "AQID",
+			},
+			Result: CR{
+				"response": CR{
+					"updated": 1,
+				},
+			},
+		},
+		Case{
+			Path:   "/files/1",
+			Method: http.MethodPost,
+			Status: http.StatusBadRequest,
+			Body: CR{
+				"content": "not base64!",
+			},
+			Result: CR{
+				"error": "field content have invalid type",
+			},
+		},
+		Case{
+			Path:   "/files/1",
+			Method: http.MethodPost,
+			Status: http.StatusBadRequest,
+			Body: CR{
+				"content": "AQIDBAU=",
+			},
+			Result: CR{
+				"error": "field content exceeds 4 bytes",
+			},
+		},
+		Case{
+			Path: "/files/1",
+			Result: CR{
+				"response": CR{
+					"record": CR{
+						"id":      1,
+						"content": "AQID",
+					},
+				},
+			},
+		},
+	})
+}
+
+func TestGraphQL(t *testing.T) {
+	db, err := sql.Open("mysql", DSN)
+	err = db.Ping()
+	if err != nil {
+		panic(err)
+	}
+
+	qs := []string{
+		`DROP TABLE IF EXISTS books;`,
+		`CREATE TABLE books (
+  id int(11) NOT NULL AUTO_INCREMENT,
+  title varchar(255) NOT NULL,
+  pages int(11) DEFAULT NULL,
+  PRIMARY KEY (id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;`,
+		`INSERT INTO books (id, title, pages) VALUES (1, 'go', 300), (2, 'sql', 150), (3, 'graphql', NULL);`,
+	}
+	for _, q := range qs {
+		if _, err := db.Exec(q); err != nil {
+			panic(err)
+		}
+	}
+	defer db.Exec(`DROP TABLE IF EXISTS books;`)
+
+	handler, err := NewDbExplorer(db)
+	if err != nil {
+		panic(err)
+	}
+
+	ts := httptest.NewServer(handler)
+
+	runCases(t, ts, db, []Case{
+		Case{
+			Path:   "/graphql",
+			Method: http.MethodPost,
+			Body: CR{
+				"query": `{ books(pages__gte: 150, sort: "-pages") { id name: title } }`,
+			},
+			Result: CR{
+				"data": CR{
+					"books": []CR{
+						CR{"id": 1, "name": "go"},
+						CR{"id": 2, "name": "sql"},
+					},
+				},
+			},
+		},
+		Case{
+			Path:   "/graphql",
+			Method: http.MethodPost,
+			Body: CR{
+				"query":     `query Book($id: Int!) { books_by_pk(id: $id) { ...fields } } fragment fields on Books { __typename title pages }`,
+				"variables": CR{"id": 3},
+			},
+			Result: CR{
+				"data": CR{
+					"books_by_pk": CR{"__typename": "Books", "title": "graphql", "pages": nil},
+				},
+			},
+		},
+		Case{
+			Path:   "/graphql",
+			Method: http.MethodPost,
+			Body: CR{
+				"query": `{ books_by_pk(id: 42) { title } }`,
+			},
+			Result: CR{
+				"data": CR{
+					"books_by_pk": nil,
+				},
+			},
+		},
+		Case{
+			Path:   "/graphql",
+			Method: http.MethodPost,
+			Body: CR{
+				"query": `mutation {
+  created: insert_books(data: {title: "rest", pages: 90}) { id title }
+  update_books(id: 1, data: {pages: 320})
+  delete_books(id: 2)
+}`,
+			},
+			Result: CR{
+				"data": CR{
+					"created":      CR{"id": 4, "title": "rest"},
+					"update_books": 1,
+					"delete_books": 1,
+				},
+			},
+		},
+		Case{
+			Path:   "/graphql",
+			Method: http.MethodPost,
+			Body: CR{
+				"query": `{ books(limit: 10) { id pages } }`,
+			},
+			Result: CR{
+				"data": CR{
+					"books": []CR{
+						CR{"id": 1, "pages": 320},
+						CR{"id": 3, "pages": nil},
+						CR{"id": 4, "pages": 90},
+					},
+				},
+			},
+		},
+		Case{
+			Path:   "/graphql",
+			Method: http.MethodPost,
+			Body: CR{
+				"query": `mutation { update_books(id: 1, data: {pages: "many"}) }`,
+			},
+			Result: CR{
+				"errors": []CR{
+					CR{"message": "field pages have invalid type", "path": []string{"update_books"}},
+				},
+				"data": CR{
+					"update_books": nil,
+				},
+			},
+		},
+		Case{
+			Path:   "/graphql",
+			Method: http.MethodPost,
+			Status: http.StatusBadRequest,
+			Body: CR{
+				"query": `{ books { id }`,
+			},
+			Result: CR{
+				"errors": []CR{
+					CR{"message": "syntax error: unexpected end of document"},
+				},
+			},
+		},
+	})
+}
+
+func TestStreamExport(t *testing.T) {
+	db, err := sql.Open("mysql", DSN)
+	err = db.Ping()
+	if err != nil {
+		panic(err)
+	}
+
+	PrepareTestApis(db)
+	defer CleanupTestApis(db)
+
+	handler, err := NewDbExplorer(db)
+	if err != nil {
+		panic(err)
+	}
+
+	ts := httptest.NewServer(handler)
+
+	cases := []struct {
+		path        string
+		accept      string
+		contentType string
+		status      int
+		body        string
+	}{
+		{
+			path: "/items?format=csv&fields=id,title,updated",
+			body: "id,title,updated\n1,database/sql,rvasily\n2,memcache,\n",
+		},
+		{
+			path:   "/items?fields=id,title&id=2",
+			accept: "text/csv",
+			body:   "id,title\n2,memcache\n",
+		},
+		{
+			path: "/users?format=csv&fields=login,info&limit=1",
+			body: "login,info\nrvasily,none\n",
+		},
+		{
+			path:        "/items?fields=id,updated",
+			accept:      "application/x-ndjson",
+			contentType: "application/x-ndjson",
+			body:        "{\"id\":1,\"updated\":\"rvasily\"}\n{\"id\":2,\"updated\":null}\n",
+		},
+		{
+			path:   "/items?format=xml",
+			status: http.StatusBadRequest,
+			body:   `{"error":"unknown format xml"}`,
+		},
+	}
+
+	for idx, item := range cases {
+		req, _ := http.NewRequest(http.MethodGet, ts.URL+item.path, nil)
+		if item.accept != "" {
+			req.Header.Set("Accept", item.accept)
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("[case %d: %s] request error: %v", idx, item.path, err)
+		}
+		body, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if item.status == 0 {
+			item.status = http.StatusOK
+			if item.contentType == "" {
+				item.contentType = "text/csv; charset=utf-8"
+			}
+			if contentType := resp.Header.Get("Content-Type"); contentType != item.contentType {
+				t.Fatalf("[case %d: %s] unexpected content type %q", idx, item.path, contentType)
+			}
+		}
+		if resp.StatusCode != item.status {
+			t.Fatalf("[case %d: %s] expected http status %v, got %v", idx, item.path, item.status, resp.StatusCode)
+		}
+		if string(body) != item.body {
+			t.Fatalf("[case %d: %s] results not match\nGot : %q\nWant: %q", idx, item.path, body, item.body)
+		}
+	}
+}
+
+// brokenRowsConnector отдаёт на любой запрос rows строк с колонкой id, а затем ошибку соединения:
+// так проверяется обрыв потоковых ответов посреди выборки
+type brokenRowsConnector struct{ rows int }
+
+func (c brokenRowsConnector) Connect(ctx context.Context) (driver.Conn, error) { return c, nil }
+func (c brokenRowsConnector) Driver() driver.Driver                            { return nil }
+func (c brokenRowsConnector) Prepare(query string) (driver.Stmt, error)        { return c, nil }
+func (c brokenRowsConnector) Begin() (driver.Tx, error)                        { return nil, driver.ErrSkip }
+func (c brokenRowsConnector) Close() error                                     { return nil }
+func (c brokenRowsConnector) NumInput() int                                    { return -1 }
+
+func (c brokenRowsConnector) Exec(args []driver.Value) (driver.Result, error) {
+	return nil, driver.ErrSkip
+}
+
+func (c brokenRowsConnector) Query(args []driver.Value) (driver.Rows, error) {
+	return &brokenRows{total: c.rows}, nil
+}
+
+type brokenRows struct{ total, next int }
+
+func (r *brokenRows) Columns() []string { return []string{"id"} }
+func (r *brokenRows) Close() error      { return nil }
+
+func (r *brokenRows) Next(dest []driver.Value) error {
+	if r.next == r.total {
+		return errors.New("connection lost")
+	}
+	r.next++
+	dest[0] = int64(r.next)
+	return nil
+}
+
+// brokenRowsExplorer — explorer над таблицей items(id), выборка из которой обрывается после rows строк
+func brokenRowsExplorer(rows int) DbExplorer {
+	return DbExplorer{
+		db:                 sql.OpenDB(brokenRowsConnector{rows: rows}),
+		dialect:            MySQL,
+		logger:             slog.New(slog.NewTextHandler(io.Discard, nil)),
+		tableKeys:          []string{"items"},
+		columnKeysMap:      map[string][]string{"items": {"id"}},
+		columnsInTablesMap: map[string]map[string]columnParams{"items": {"id": {name: "id", typeName: "int", primary: true}}},
+		tableIdNamesMap:    map[string][]string{"items": {"id"}},
+	}
+}
+
+// checkAborted проверяет, что ответ начался с 200, но оборвался, а не закончился как обычно
+func checkAborted(t *testing.T, url string) {
+	resp, err := client.Get(url)
+	if err != nil {
+		t.Fatalf("[%s] request error: %v", url, err)
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK || err == nil {
+		t.Fatalf("[%s] expected aborted response, got %v, %d bytes, %v", url, resp.StatusCode, len(body), err)
+	}
+}
+
+func TestStreamExportAborted(t *testing.T) {
+	explorer := brokenRowsExplorer(2 * streamFlushRows)
+	ts := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		list, err := explorer.parseListQuery("items", url.Values{})
+		if err != nil {
+			panic(err)
+		}
+		explorer.handlerListStream(rw, r, list, r.URL.Query().Get("format"))
+	}))
+	defer ts.Close()
+
+	checkAborted(t, ts.URL+"/items?format=csv")
+	checkAborted(t, ts.URL+"/items?format=ndjson")
+}
+
+func TestStreamListAborted(t *testing.T) {
+	explorer := brokenRowsExplorer(2 * streamFlushRows)
+	ts := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		list, err := explorer.parseListQuery("items", r.URL.Query())
+		if err != nil {
+			panic(err)
+		}
+		explorer.handlerListJSON(rw, r, list)
+	}))
+	defer ts.Close()
+
+	checkAborted(t, ts.URL+"/items?limit=1000")
+}
+
+func TestStreamList(t *testing.T) {
+	db, err := sql.Open("mysql", DSN)
+	err = db.Ping()
+	if err != nil {
+		panic(err)
+	}
+
+	PrepareTestApis(db)
+	defer CleanupTestApis(db)
+
+	handler, err := NewDbExplorer(db)
+	if err != nil {
+		panic(err)
+	}
+
+	ts := httptest.NewServer(handler)
+
+	cases := []struct {
+		path   string
+		status int
+		body   string
+	}{
+		{
+			path: "/items?fields=id,title",
+			body: `{"response":{"records":[{"id":1,"title":"database/sql"},{"id":2,"title":"memcache"}]}}`,
+		},
+		{
+			path: "/items?fields=id&limit=1&count=true",
+			body: `{"response":{"records":[{"id":1}],"has_more":true,"limit":1,"offset":0,"total":2}}`,
+		},
+		{
+			path: "/items?fields=id&after=&limit=5",
+			body: `{"response":{"records":[{"id":1},{"id":2}],"next_cursor":null}}`,
+		},
+		{
+			path:   "/items?id=100",
+			status: http.StatusNotFound,
+			body:   `{"error":"record not found"}`,
+		},
+	}
+
+	for idx, item := range cases {
+		resp, err := client.Get(ts.URL + item.path)
+		if err != nil {
+			t.Fatalf("[case %d: %s] request error: %v", idx, item.path, err)
+		}
+		body, err := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if item.status == 0 {
+			item.status = http.StatusOK
+		}
+		if resp.StatusCode != item.status {
+			t.Fatalf("[case %d: %s] expected http status %v, got %v", idx, item.path, item.status, resp.StatusCode)
+		}
+		if string(body) != item.body {
+			t.Fatalf("[case %d: %s] results not match\nGot : %s\nWant: %s", idx, item.path, body, item.body)
+		}
+	}
+}
+
+func TestCSVImport(t *testing.T) {
+	db, err := sql.Open("mysql", DSN)
+	err = db.Ping()
+	if err != nil {
+		panic(err)
+	}
+
+	PrepareTestApis(db)
+	defer CleanupTestApis(db)
+
+	handler, err := NewDbExplorer(db)
+	if err != nil {
+		panic(err)
+	}
+
+	ts := httptest.NewServer(handler)
+
+	upload := "title,description,updated\n" +
+		"redis,\"Рассказать про redis, с примером\",\n" +
+		"kafka,брокеры,admin\n" +
+		"broken\n" +
+		"nats,,\n"
+	resp, err := client.Post(ts.URL+"/items/import", "text/csv", strings.NewReader(upload))
+	if err != nil {
+		t.Fatalf("request error: %v", err)
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+
+	var result interface{}
+	if err := json.Unmarshal(body, &result); err != nil {
+		t.Fatalf("cant unpack json: %v", err)
+	}
+	expected := map[string]interface{}{
+		"response": map[string]interface{}{
+			"inserted": float64(3),
+			"ids": []interface{}{
+				map[string]interface{}{"id": float64(3)},
+				map[string]interface{}{"id": float64(4)},
+				map[string]interface{}{"id": float64(5)},
+			},
+			"errors": []interface{}{
+				map[string]interface{}{"row": float64(4), "error": "expected 3 fields, got 1"},
+			},
+		},
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Fatalf("results not match\nGot : %#v\nWant: %#v", result, expected)
+	}
+
+	runCases(t, ts, db, []Case{
+		Case{
+			Path: "/items/3",
+			Result: CR{
+				"response": CR{
+					"record": CR{
+						"id":          3,
+						"title":       "redis",
+						"description": "Рассказать про redis, с примером",
+						"updated":     nil,
+					},
+				},
+			},
+		},
+		Case{
+			Path:   "/items/import",
+			Method: http.MethodPost,
+			Status: http.StatusBadRequest,
+			Result: CR{
+				"error": "unknown field null",
+			},
+		},
+	})
+}
+
+func TestETag(t *testing.T) {
+	db, err := sql.Open("mysql", DSN)
+	err = db.Ping()
+	if err != nil {
+		panic(err)
+	}
+
+	PrepareTestApis(db)
+	defer CleanupTestApis(db)
+
+	handler, err := NewDbExplorer(db)
+	if err != nil {
+		panic(err)
+	}
+
+	ts := httptest.NewServer(handler)
+
+	get := func(etag string) *http.Response {
+		req, _ := http.NewRequest(http.MethodGet, ts.URL+"/items/1", nil)
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("request error: %v", err)
+		}
+		ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		return resp
+	}
+
+	etag := get("").Header.Get("ETag")
+	if etag == "" {
+		t.Fatalf("expected ETag header")
+	}
+
+	if resp := get(etag); resp.StatusCode != http.StatusNotModified {
+		t.Fatalf("expected http status %v, got %v", http.StatusNotModified, resp.StatusCode)
+	}
+	if resp := get(`"other", W/` + etag); resp.StatusCode != http.StatusNotModified {
+		t.Fatalf("expected weak match, got %v", resp.StatusCode)
+	}
+
+	req, _ := http.NewRequest(http.MethodPost, ts.URL+"/items/1", strings.NewReader(`{"title":"changed"}`))
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("request error: %v", err)
+	}
+	resp.Body.Close()
+
+	resp = get(etag)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected http status %v after update, got %v", http.StatusOK, resp.StatusCode)
+	}
+	current := resp.Header.Get("ETag")
+	if current == etag {
+		t.Fatalf("expected ETag to change after update")
+	}
+
+	cases := []struct {
+		method string
+		etag   string
+		status int
+	}{
+		{http.MethodPost, etag, http.StatusPreconditionFailed},
+		{http.MethodDelete, etag, http.StatusPreconditionFailed},
+		{http.MethodPost, "W/" + current, http.StatusPreconditionFailed},
+		{http.MethodPost, current, http.StatusOK},
+		{http.MethodDelete, current, http.StatusPreconditionFailed},
+		{http.MethodDelete, "*", http.StatusOK},
+		{http.MethodDelete, "*", http.StatusPreconditionFailed},
+	}
+	for idx, item := range cases {
+		req, _ := http.NewRequest(item.method, ts.URL+"/items/1", strings.NewReader(`{"title":"again"}`))
+		req.Header.Set("If-Match", item.etag)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("[case %d] request error: %v", idx, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != item.status {
+			t.Fatalf("[case %d: %s %s] expected http status %v, got %v", idx, item.method, item.etag, item.status, resp.StatusCode)
+		}
+	}
+}
+
+func TestIdempotencyKey(t *testing.T) {
+	db, err := sql.Open("mysql", DSN)
+	err = db.Ping()
+	if err != nil {
+		panic(err)
+	}
+
+	PrepareTestApis(db)
+	defer CleanupTestApis(db)
+
+	handler, err := NewDbExplorer(db)
+	if err != nil {
+		panic(err)
+	}
+
+	ts := httptest.NewServer(handler)
+
+	put := func(key, body string) (*http.Response, string) {
+		req, _ := http.NewRequest(http.MethodPut, ts.URL+"/items", strings.NewReader(body))
+		req.Header.Set("Idempotency-Key", key)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("request error: %v", err)
+		}
+		data, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		return resp, string(data)
+	}
+
+	first, firstBody := put("insert-1", `{"title":"idempotent"}`)
+	second, secondBody := put("insert-1", `{"title":"idempotent"}`)
+	if first.StatusCode != http.StatusOK || secondBody != firstBody {
+		t.Fatalf("expected replayed response %q, got %q", firstBody, secondBody)
+	}
+	if second.Header.Get("Idempotent-Replayed") != "true" {
+		t.Fatalf("expected Idempotent-Replayed header on retry")
+	}
+
+	var count int
+	db.QueryRow("SELECT COUNT(*) FROM items WHERE title = 'idempotent'").Scan(&count)
+	if count != 1 {
+		t.Fatalf("expected 1 inserted record, got %d", count)
+	}
+
+	if resp, _ := put("insert-1", `{"title":"other"}`); resp.StatusCode != http.StatusUnprocessableEntity {
+		t.Fatalf("expected http status %v for reused key, got %v", http.StatusUnprocessableEntity, resp.StatusCode)
+	}
+	if _, body := put("insert-2", `{"title":"idempotent"}`); body == firstBody {
+		t.Fatalf("expected a new record for a new key")
+	}
+}
+
+func TestMasking(t *testing.T) {
+	db, err := sql.Open("mysql", DSN)
+	err = db.Ping()
+	if err != nil {
+		panic(err)
+	}
+
+	PrepareTestApis(db)
+	defer CleanupTestApis(db)
+
+	handler, err := NewDbExplorer(db, WithMasking(MaskingConfig{
+		Columns:    map[string]map[string]string{"users": {"password": "", "email": "***"}},
+		Privileged: func(r *http.Request) bool { return r.Header.Get("X-Admin") == "yes" },
+	}))
+	if err != nil {
+		panic(err)
+	}
+
+	ts := httptest.NewServer(handler)
+
+	cases := []struct {
+		method string
+		path   string
+		body   string
+		admin  bool
+		status int
+		result string
+	}{
+		{
+			path:   "/users/1",
+			result: `{"response":{"record":{"email":"***","info":"none","login":"rvasily","updated":null,"user_id":1}}}`,
+		},
+		{
+			path:   "/users/1",
+			admin:  true,
+			result: `{"response":{"record":{"email":"rvasily@example.com","info":"none","login":"rvasily","password":"love","updated":null,"user_id":1}}}`,
+		},
+		{
+			path:   "/users?fields=login,password",
+			status: http.StatusBadRequest,
+			result: `{"error":"unknown field password"}`,
+		},
+		{
+			path:   "/users?email=rvasily@example.com",
+			status: http.StatusBadRequest,
+			result: `{"error":"unknown field email"}`,
+		},
+		{
+			method: http.MethodPost,
+			path:   "/users/1",
+			body:   `{"password":"hacked"}`,
+			status: http.StatusBadRequest,
+			result: `{"error":"field password is not writable"}`,
+		},
+		{
+			method: http.MethodPost,
+			path:   "/users/1",
+			body:   `{"email":"admin@example.com"}`,
+			admin:  true,
+			result: `{"response":{"updated":1}}`,
+		},
+	}
+
+	for idx, item := range cases {
+		if item.method == "" {
+			item.method = http.MethodGet
+		}
+		if item.status == 0 {
+			item.status = http.StatusOK
+		}
+
+		req, _ := http.NewRequest(item.method, ts.URL+item.path, strings.NewReader(item.body))
+		if item.admin {
+			req.Header.Set("X-Admin", "yes")
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("[case %d] request error: %v", idx, err)
+		}
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if resp.StatusCode != item.status {
+			t.Fatalf("[case %d: %s %s] expected http status %v, got %v", idx, item.method, item.path, item.status, resp.StatusCode)
+		}
+		if string(body) != item.result {
+			t.Fatalf("[case %d: %s %s] results not match\nGot : %s\nWant: %s", idx, item.method, item.path, body, item.result)
+		}
+	}
+
+	// если скрыты все колонки, SELECT * не должен отдать их как есть
+	hiddenAll, err := NewDbExplorer(db, WithMasking(MaskingConfig{
+		Columns: map[string]map[string]string{"items": {"id": "", "title": "", "description": "", "updated": ""}},
+	}))
+	if err != nil {
+		panic(err)
+	}
+	for _, path := range []string{"/items", "/items/1"} {
+		rw := httptest.NewRecorder()
+		hiddenAll.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, path, nil))
+		if want := `{"error":"all columns of table items are hidden"}`; rw.Code == http.StatusOK || rw.Body.String() != want {
+			t.Fatalf("[%s] expected hidden table error, got %v: %s", path, rw.Code, rw.Body)
+		}
+	}
+}
+
+func TestMaxBodySize(t *testing.T) {
+	db, err := sql.Open("mysql", DSN)
+	err = db.Ping()
+	if err != nil {
+		panic(err)
+	}
+
+	PrepareTestApis(db)
+	defer CleanupTestApis(db)
+
+	handler, err := NewDbExplorer(db, WithMaxBodySize(64))
+	if err != nil {
+		panic(err)
+	}
+
+	ts := httptest.NewServer(handler)
+
+	large := `{"title":"` + strings.Repeat("a", 100) + `","description":""}`
+	cases := []struct {
+		method string
+		path   string
+		body   string
+		status int
+	}{
+		{http.MethodPut, "/items", `{"title":"small","description":""}`, http.StatusOK},
+		{http.MethodPut, "/items", large, http.StatusRequestEntityTooLarge},
+		{http.MethodPut, "/items", "[" + large + "]", http.StatusRequestEntityTooLarge},
+		{http.MethodPost, "/items/1", large, http.StatusRequestEntityTooLarge},
+		{http.MethodPatch, "/items/1", large, http.StatusRequestEntityTooLarge},
+	}
+
+	for idx, item := range cases {
+		req, _ := http.NewRequest(item.method, ts.URL+item.path, strings.NewReader(item.body))
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("[case %d] request error: %v", idx, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != item.status {
+			t.Fatalf("[case %d: %s %s] expected http status %v, got %v", idx, item.method, item.path, item.status, resp.StatusCode)
+		}
+	}
+}
+
+func TestAudit(t *testing.T) {
+	db, err := sql.Open("mysql", DSN)
+	err = db.Ping()
+	if err != nil {
+		panic(err)
+	}
+
+	PrepareTestApis(db)
+	defer CleanupTestApis(db)
+
+	qs := []string{
+		`DROP TABLE IF EXISTS audit_log;`,
+		`CREATE TABLE audit_log (logged_at varchar(32), actor varchar(255), action varchar(16),
+  table_name varchar(255), record_id text, filter text, before_values text, after_values text);`,
+	}
+	for _, q := range qs {
+		if _, err := db.Exec(q); err != nil {
+			panic(err)
+		}
+	}
+	defer db.Exec(`DROP TABLE IF EXISTS audit_log;`)
+
+	handler, err := NewDbExplorer(db, WithAudit(NewAuditTable(db, "audit_log")))
+	if err != nil {
+		panic(err)
+	}
+	if containsString(handler.tableKeys, "audit_log") {
+		t.Fatalf("audit table must not be exposed")
+	}
+
+	ts := httptest.NewServer(handler)
+
+	requests := []struct {
+		method string
+		path   string
+		body   string
+	}{
+		{http.MethodPut, "/items", `{"title":"audited","description":"new"}`},
+		{http.MethodPost, "/items/3", `{"title":"changed"}`},
+		{http.MethodDelete, "/items/3", ""},
+	}
+	for idx, item := range requests {
+		req, _ := http.NewRequest(item.method, ts.URL+item.path, strings.NewReader(item.body))
+		req.Header.Set("X-API-Key", "client")
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("[case %d] request error: %v", idx, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("[case %d: %s %s] unexpected http status %v", idx, item.method, item.path, resp.StatusCode)
+		}
+	}
+
+	entries, err := handler.audit.Entries(AuditQuery{Table: "items", Limit: 10})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("expected 3 audit entries, got %d", len(entries))
+	}
+
+	deleted, updated, inserted := entries[0], entries[1], entries[2]
+	if deleted.Action != "delete" || deleted.Before["title"] != "changed" || deleted.After != nil {
+		t.Fatalf("unexpected delete entry %+v", deleted)
+	}
+	if updated.Action != "update" || updated.Before["title"] != "audited" || updated.After["title"] != "changed" {
+		t.Fatalf("unexpected update entry %+v", updated)
+	}
+	if inserted.Action != "insert" || inserted.After["description"] != "new" || inserted.Actor == "" || !strings.HasPrefix(inserted.Actor, "key:") {
+		t.Fatalf("unexpected insert entry %+v", inserted)
+	}
+
+	resp, err := client.Get(ts.URL + "/admin/audit?table=items&limit=1")
+	if err != nil {
+		t.Fatalf("request error: %v", err)
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !strings.Contains(string(body), `"action":"delete"`) || strings.Contains(string(body), `"action":"update"`) {
+		t.Fatalf("unexpected audit response %s", body)
+	}
+}
+
+func TestTracing(t *testing.T) {
+	db, err := sql.Open("mysql", DSN)
+	err = db.Ping()
+	if err != nil {
+		panic(err)
+	}
+
+	PrepareTestApis(db)
+	defer CleanupTestApis(db)
+
+	recorder := tracetest.NewSpanRecorder()
+	handler, err := NewDbExplorer(db, WithTracing(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))))
+	if err != nil {
+		panic(err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/items/1", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	handler.ServeHTTP(httptest.NewRecorder(), req)
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("expected 2 spans, got %d", len(spans))
+	}
+	query, request := spans[0], spans[1]
+	if request.Name() != "GET /items/{id}" || query.Name() != "SELECT items" {
+		t.Fatalf("unexpected span names %q, %q", request.Name(), query.Name())
+	}
+	if request.SpanContext().TraceID().String() != "4bf92f3577b34da6a3ce929d0e0e4736" {
+		t.Fatalf("expected trace to continue incoming traceparent, got %s", request.SpanContext().TraceID())
+	}
+	if query.Parent().SpanID() != request.SpanContext().SpanID() {
+		t.Fatalf("expected SQL span to be a child of the request span")
+	}
+
+	attributes := map[string]string{}
+	for _, attribute := range request.Attributes() {
+		attributes[string(attribute.Key)] = attribute.Value.Emit()
+	}
+	if attributes["db.sql.table"] != "items" || attributes["db.rows"] != "1" || attributes["http.response.status_code"] != "200" {
+		t.Fatalf("unexpected request span attributes %v", attributes)
+	}
+}
+
+func TestCanceledRequest(t *testing.T) {
+	db, err := sql.Open("mysql", DSN)
+	err = db.Ping()
+	if err != nil {
+		panic(err)
+	}
+
+	PrepareTestApis(db)
+	defer CleanupTestApis(db)
+
+	handler, err := NewDbExplorer(db)
+	if err != nil {
+		panic(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	for _, method := range []string{http.MethodGet, http.MethodDelete} {
+		rw := httptest.NewRecorder()
+		handler.ServeHTTP(rw, httptest.NewRequest(method, "/items/1", nil).WithContext(ctx))
+		if !strings.Contains(rw.Body.String(), context.Canceled.Error()) {
+			t.Fatalf("[%s] expected query to be cancelled, got %d %s", method, rw.Code, rw.Body.String())
+		}
+	}
+
+	var count int
+	db.QueryRow("SELECT COUNT(*) FROM items WHERE id = 1").Scan(&count)
+	if count != 1 {
+		t.Fatalf("cancelled delete must not remove the record")
+	}
+}
+
+func TestDBStats(t *testing.T) {
+	db, err := sql.Open("mysql", DSN)
+	err = db.Ping()
+	if err != nil {
+		panic(err)
+	}
+
+	handler, err := NewDbExplorer(db, WithMaxOpenConns(7), WithMaxIdleConns(2), WithConnMaxLifetime(time.Minute))
+	if err != nil {
+		panic(err)
+	}
+
+	rw := httptest.NewRecorder()
+	handler.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/admin/db-stats", nil))
+
+	result := struct {
+		Response map[string]interface{} `json:"response"`
+	}{}
+	if err := json.Unmarshal(rw.Body.Bytes(), &result); err != nil {
+		t.Fatalf("unexpected body %s: %v", rw.Body.String(), err)
+	}
+	if result.Response["max_open_connections"] != float64(7) {
+		t.Fatalf("expected max_open_connections 7, got %v", result.Response)
+	}
+	if _, ok := result.Response["open_connections"]; !ok {
+		t.Fatalf("expected open_connections in %v", result.Response)
+	}
+}
+
+func TestCache(t *testing.T) {
+	db, err := sql.Open("mysql", DSN)
+	err = db.Ping()
+	if err != nil {
+		panic(err)
+	}
+
+	PrepareTestApis(db)
+	defer CleanupTestApis(db)
+
+	handler, err := NewDbExplorer(db, WithCache(time.Minute, 100))
+	if err != nil {
+		panic(err)
+	}
+
+	ts := httptest.NewServer(handler)
+
+	get := func(path string) (*http.Response, string) {
+		resp, err := client.Get(ts.URL + path)
+		if err != nil {
+			t.Fatalf("request error: %v", err)
+		}
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		return resp, string(body)
+	}
+
+	resp, first := get("/items/1?fields=id,title")
+	if cache := resp.Header.Get("X-Cache"); cache != "MISS" {
+		t.Fatalf("expected cache miss, got %q", cache)
+	}
+	resp, second := get("/items/1?fields=id,title")
+	if cache := resp.Header.Get("X-Cache"); cache != "HIT" || second != first {
+		t.Fatalf("expected cache hit with %s, got %q %s", first, cache, second)
+	}
+	if resp.Header.Get("ETag") == "" {
+		t.Fatalf("expected ETag on cached response")
+	}
+
+	// запись в таблицу через explorer сбрасывает её ответы
+	db.Exec("UPDATE items SET title = 'changed' WHERE id = 1")
+	if _, body := get("/items/1?fields=id,title"); body != second {
+		t.Fatalf("expected cached body before explorer write, got %s", body)
+	}
+
+	req, _ := http.NewRequest(http.MethodPost, ts.URL+"/items/1", strings.NewReader(`{"title": "updated"}`))
+	req.Header.Set("Content-Type", "application/json")
+	if resp, err := client.Do(req); err != nil {
+		t.Fatalf("request error: %v", err)
+	} else {
+		resp.Body.Close()
+	}
+
+	resp, body := get("/items/1?fields=id,title")
+	if cache := resp.Header.Get("X-Cache"); cache != "MISS" {
+		t.Fatalf("expected cache miss after update, got %q", cache)
+	}
+	if body != `{"response":{"record":{"id":1,"title":"updated"}}}` {
+		t.Fatalf("unexpected body after update: %s", body)
+	}
+}
+
+func TestCacheAborted(t *testing.T) {
+	explorer := brokenRowsExplorer(2 * streamFlushRows)
+	explorer.cache = newResponseCache(time.Minute, 100)
+	ts := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		explorer.cached(rw, r, "items", func(rw http.ResponseWriter, r *http.Request) {
+			list, err := explorer.parseListQuery("items", r.URL.Query())
+			if err != nil {
+				panic(err)
+			}
+			explorer.handlerListJSON(rw, r, list)
+		})
+	}))
+	defer ts.Close()
+
+	// оборванный ответ не должен попасть в кэш и отдаваться следующим клиентам как целый
+	checkAborted(t, ts.URL+"/items?limit=1000")
+	checkAborted(t, ts.URL+"/items?limit=1000")
+}
+
+func TestTransaction(t *testing.T) {
+	db, err := sql.Open("mysql", DSN)
+	err = db.Ping()
+	if err != nil {
+		panic(err)
+	}
+
+	PrepareTestApis(db)
+	defer CleanupTestApis(db)
+
+	handler, err := NewDbExplorer(db)
+	if err != nil {
+		panic(err)
+	}
+
+	ts := httptest.NewServer(handler)
+
+	post := func(body string) (int, string) {
+		req, _ := http.NewRequest(http.MethodPost, ts.URL+"/transaction", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("request error: %v", err)
+		}
+		data, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		return resp.StatusCode, string(data)
+	}
+	countItems := func() int {
+		count := 0
+		db.QueryRow("SELECT COUNT(*) FROM items").Scan(&count)
+		return count
+	}
+
+	cases := []struct {
+		body   string
+		status int
+		result string
+		items  int
+	}{
+		{
+			body: `{"operations": [
+				{"op": "insert", "table": "items", "record": {"title": "tx", "description": "created in tx"}},
+				{"op": "update", "table": "items", "id": 1, "record": {"title": "updated in tx"}},
+				{"op": "delete", "table": "items", "id": "2"}
+			]}`,
+			status: http.StatusOK,
+			result: `{"response":{"results":[{"id":{"id":3},"op":"insert","table":"items"},{"op":"update","table":"items","updated":1},{"deleted":1,"op":"delete","table":"items"}]}}`,
+			items:  2,
+		},
+		{
+			// вторая операция падает уже в транзакции — вставка первой откатывается
+			body: `{"operations": [
+				{"op": "insert", "table": "items", "record": {"title": "rolled back", "description": ""}},
+				{"op": "update", "table": "items", "id": 1, "record": {"id": 5}}
+			]}`,
+			status: http.StatusBadRequest,
+			result: `{"error":"operation 1: field id have invalid type","response":{"failed":1}}`,
+			items:  2,
+		},
+		{
+			body:   `{"operations": [{"op": "delete", "table": "unknown_table", "id": 1}]}`,
+			status: http.StatusNotFound,
+			result: `{"error":"operation 0: unknown table","response":{"failed":0}}`,
+			items:  2,
+		},
+		{
+			body:   `{"operations": [{"op": "upsert", "table": "items", "id": 1}]}`,
+			status: http.StatusBadRequest,
+			result: `{"error":"operation 0: unknown op upsert","response":{"failed":0}}`,
+			items:  2,
+		},
+		{
+			body:   `{"operations": []}`,
+			status: http.StatusBadRequest,
+			result: `{"error":"operations are required"}`,
+			items:  2,
+		},
+	}
+
+	for idx, item := range cases {
+		status, body := post(item.body)
+		if status != item.status {
+			t.Fatalf("[case %d] expected http status %v, got %v: %s", idx, item.status, status, body)
+		}
+		if body != item.result {
+			t.Fatalf("[case %d] results not match\nGot : %s\nWant: %s", idx, body, item.result)
+		}
+		if count := countItems(); count != item.items {
+			t.Fatalf("[case %d] expected %d items, got %d", idx, item.items, count)
+		}
+	}
+}
+
+func TestMutationRollback(t *testing.T) {
+	db, err := sql.Open("mysql", DSN)
+	err = db.Ping()
+	if err != nil {
+		panic(err)
+	}
+
+	PrepareTestApis(db)
+	defer CleanupTestApis(db)
+
+	// ответ, который не удаётся закодировать, откатывает уже выполненную запись
+	RegisterEncoder("application/x-broken", func(envelope map[string]interface{}) ([]byte, error) {
+		return nil, errors.New("broken encoder")
+	})
+
+	handler, err := NewDbExplorer(db)
+	if err != nil {
+		panic(err)
+	}
+
+	ts := httptest.NewServer(handler)
+
+	cases := []struct {
+		method string
+		path   string
+		body   string
+	}{
+		{http.MethodPut, "/items/", `{"title": "not committed", "description": ""}`},
+		{http.MethodPut, "/items/", `[{"title": "not committed", "description": ""}]`},
+		{http.MethodPost, "/items/1", `{"title": "not committed"}`},
+		{http.MethodPost, "/items?id=1", `{"title": "not committed"}`},
+		{http.MethodPatch, "/items/1", `{"title": "not committed"}`},
+		{http.MethodDelete, "/items/1", ``},
+	}
+
+	for idx, item := range cases {
+		req, _ := http.NewRequest(item.method, ts.URL+item.path, strings.NewReader(item.body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("Accept", "application/x-broken")
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("[case %d] request error: %v", idx, err)
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode != http.StatusInternalServerError {
+			t.Fatalf("[case %d: %s %s] expected http status %v, got %v", idx, item.method, item.path, http.StatusInternalServerError, resp.StatusCode)
+		}
+
+		count, title := 0, ""
+		db.QueryRow("SELECT COUNT(*) FROM items").Scan(&count)
+		db.QueryRow("SELECT title FROM items WHERE id = 1").Scan(&title)
+		if count != 2 || title != "database/sql" {
+			t.Fatalf("[case %d: %s %s] expected rollback, got %d items and title %q", idx, item.method, item.path, count, title)
+		}
+	}
+}
+
+func TestSoftDelete(t *testing.T) {
+	db, err := sql.Open("mysql", DSN)
+	err = db.Ping()
+	if err != nil {
+		panic(err)
+	}
+
+	PrepareTestApis(db)
+	defer CleanupTestApis(db)
+
+	if _, err := db.Exec("ALTER TABLE items ADD COLUMN deleted_at datetime DEFAULT NULL"); err != nil {
+		panic(err)
+	}
+
+	if _, err := NewDbExplorer(db, WithSoftDelete(map[string]string{"items": "removed_at"})); err == nil {
+		t.Fatalf("expected error for unknown soft delete column")
+	}
+
+	handler, err := NewDbExplorer(db, WithSoftDelete(map[string]string{"items": "deleted_at"}))
+	if err != nil {
+		panic(err)
+	}
+
+	ts := httptest.NewServer(handler)
+
+	do := func(method, path string) (int, string) {
+		req, _ := http.NewRequest(method, ts.URL+path, nil)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("request error: %v", err)
+		}
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+		return resp.StatusCode, string(body)
+	}
+
+	cases := []struct {
+		method string
+		path   string
+		status int
+		body   string
+	}{
+		{http.MethodDelete, "/items/1", http.StatusOK, `{"response":{"deleted":1}}`},
+		{http.MethodDelete, "/items/1", http.StatusOK, `{"response":{"deleted":0}}`},
+		{http.MethodGet, "/items/1", http.StatusNotFound, `{"error":"record not found"}`},
+		{http.MethodGet, "/items?fields=id", http.StatusOK, `{"response":{"records":[{"id":2}]}}`},
+		{http.MethodGet, "/items?fields=id&include_deleted=true", http.StatusOK, `{"response":{"records":[{"id":1},{"id":2}]}}`},
+		{http.MethodGet, "/items/1?fields=id&include_deleted=true", http.StatusOK, `{"response":{"record":{"id":1}}}`},
+		{http.MethodGet, "/items/trash?fields=id,title", http.StatusOK, `{"response":{"records":[{"id":1,"title":"database/sql"}]}}`},
+		{http.MethodGet, "/users/trash", http.StatusNotFound, `{"error":"soft delete is not enabled for table users"}`},
+		{http.MethodPost, "/items/1/restore", http.StatusOK, `{"response":{"restored":1}}`},
+		{http.MethodPost, "/items/1/restore", http.StatusOK, `{"response":{"restored":0}}`},
+		{http.MethodGet, "/items/1?fields=id", http.StatusOK, `{"response":{"record":{"id":1}}}`},
+		{http.MethodGet, "/items/trash", http.StatusNotFound, `{"error":"record not found"}`},
+		{http.MethodDelete, "/items/1", http.StatusOK, `{"response":{"deleted":1}}`},
+	}
+
+	for idx, item := range cases {
+		status, body := do(item.method, item.path)
+		if status != item.status {
+			t.Fatalf("[case %d: %s %s] expected http status %v, got %v", idx, item.method, item.path, item.status, status)
+		}
+		if body != item.body {
+			t.Fatalf("[case %d: %s %s] results not match\nGot : %s\nWant: %s", idx, item.method, item.path, body, item.body)
+		}
+	}
+
+	count := 0
+	db.QueryRow("SELECT COUNT(*) FROM items WHERE deleted_at IS NOT NULL").Scan(&count)
+	if count != 1 {
+		t.Fatalf("expected soft-deleted row to stay in table, got %d", count)
+	}
+}
+
+func TestVersionColumn(t *testing.T) {
+	db, err := sql.Open("mysql", DSN)
+	err = db.Ping()
+	if err != nil {
+		panic(err)
+	}
+
+	PrepareTestApis(db)
+	defer CleanupTestApis(db)
+
+	if _, err := db.Exec("ALTER TABLE items ADD COLUMN version int NOT NULL DEFAULT 1"); err != nil {
+		panic(err)
+	}
+
+	handler, err := NewDbExplorer(db, WithVersionColumn(map[string]string{"items": "version"}))
+	if err != nil {
+		panic(err)
+	}
+
+	ts := httptest.NewServer(handler)
+
+	cases := []struct {
+		method  string
+		path    string
+		body    string
+		status  int
+		result  string
+		version int
+	}{
+		{http.MethodPost, "/items/1", `{"title": "first", "version": 1}`, http.StatusOK, `{"response":{"updated":1}}`, 2},
+		{http.MethodPost, "/items/1", `{"title": "stale", "version": 1}`, http.StatusConflict, `{"error":"record version conflict"}`, 2},
+		{http.MethodPatch, "/items/1", `{"title": "stale", "version": 1}`, http.StatusConflict, `{"error":"record version conflict"}`, 2},
+		{http.MethodPatch, "/items/1", `{"title": "second", "version": 2}`, http.StatusOK, `{"response":{"updated":1}}`, 3},
+		{http.MethodPost, "/items/1", `{"title": "unchecked"}`, http.StatusOK, `{"response":{"updated":1}}`, 4},
+		{http.MethodPost, "/items/100", `{"title": "missing", "version": 1}`, http.StatusOK, `{"response":{"updated":0}}`, 4},
+	}
+
+	for idx, item := range cases {
+		req, _ := http.NewRequest(item.method, ts.URL+item.path, strings.NewReader(item.body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("[case %d] request error: %v", idx, err)
+		}
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if resp.StatusCode != item.status {
+			t.Fatalf("[case %d: %s %s] expected http status %v, got %v: %s", idx, item.method, item.path, item.status, resp.StatusCode, body)
+		}
+		if string(body) != item.result {
+			t.Fatalf("[case %d: %s %s] results not match\nGot : %s\nWant: %s", idx, item.method, item.path, body, item.result)
+		}
+
+		version := 0
+		db.QueryRow("SELECT version FROM items WHERE id = 1").Scan(&version)
+		if version != item.version {
+			t.Fatalf("[case %d: %s %s] expected version %d, got %d", idx, item.method, item.path, item.version, version)
+		}
+	}
+}
+
+func TestQueryConsole(t *testing.T) {
+	db, err := sql.Open("mysql", DSN)
+	err = db.Ping()
+	if err != nil {
+		panic(err)
+	}
+
+	PrepareTestApis(db)
+	defer CleanupTestApis(db)
+
+	handler, err := NewDbExplorer(db, WithQueryConsole(1, time.Second), WithExcludedTables("users"))
+	if err != nil {
+		panic(err)
+	}
+
+	ts := httptest.NewServer(handler)
+
+	cases := []struct {
+		body   string
+		status int
+		result string
+	}{
+		{
+			body:   `{"query": "SELECT id, title FROM items ORDER BY id"}`,
+			status: http.StatusOK,
+			result: `{"response":{"columns":["id","title"],"records":[{"id":1,"title":"database/sql"}],"truncated":true}}`,
+		},
+		{
+			body:   `{"query": "SELECT id FROM items WHERE id = 2"}`,
+			status: http.StatusOK,
+			result: `{"response":{"columns":["id"],"records":[{"id":2}],"truncated":false}}`,
+		},
+		{
+			body:   `{"query": "SELECT id FROM items WHERE id = 100"}`,
+			status: http.StatusOK,
+			result: `{"response":{"columns":["id"],"records":[],"truncated":false}}`,
+		},
+		{
+			body:   `{"query": "DELETE FROM items"}`,
+			status: http.StatusBadRequest,
+			result: `{"error":"only SELECT statements are allowed"}`,
+		},
+		{
+			body:   `{"query": "SELECT LOAD_FILE('/etc/passwd')"}`,
+			status: http.StatusBadRequest,
+			result: `{"error":"LOAD_FILE is not allowed in a read-only query"}`,
+		},
+		{
+			body:   `{"query": "SELECT i.id FROM items i JOIN users u ON u.user_id = i.id"}`,
+			status: http.StatusForbidden,
+			result: `{"error":"table users is not available"}`,
+		},
+		{
+			body:   `{"query": "SELECT * FROM mysql.user"}`,
+			status: http.StatusForbidden,
+			result: `{"error":"table mysql.user is not available"}`,
+		},
+	}
+
+	for idx, item := range cases {
+		resp, err := client.Post(ts.URL+"/admin/query", "application/json", strings.NewReader(item.body))
+		if err != nil {
+			t.Fatalf("[case %d] request error: %v", idx, err)
+		}
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if resp.StatusCode != item.status {
+			t.Fatalf("[case %d] expected http status %v, got %v: %s", idx, item.status, resp.StatusCode, body)
+		}
+		if string(body) != item.result {
+			t.Fatalf("[case %d] results not match\nGot : %s\nWant: %s", idx, body, item.result)
+		}
+	}
+}
+
+func TestAggregate(t *testing.T) {
+	db, err := sql.Open("mysql", DSN)
+	err = db.Ping()
+	if err != nil {
+		panic(err)
+	}
+
+	PrepareTestApis(db)
+	defer CleanupTestApis(db)
+
+	db.Exec("INSERT INTO items (id, title, description, updated) VALUES (3, 'grpc', '', 'rvasily')")
+
+	handler, err := NewDbExplorer(db)
+	if err != nil {
+		panic(err)
+	}
+
+	ts := httptest.NewServer(handler)
+
+	cases := []struct {
+		path   string
+		status int
+		result string
+	}{
+		{
+			path:   "/items/aggregate?count=*",
+			status: http.StatusOK,
+			result: `{"response":{"groups":[{"count":3}]}}`,
+		},
+		{
+			path:   "/items/aggregate?group_by=updated&count=*&max=id&sum=id",
+			status: http.StatusOK,
+			result: `{"response":{"groups":[{"count":1,"max_id":2,"sum_id":2,"updated":null},{"count":2,"max_id":3,"sum_id":4,"updated":"rvasily"}]}}`,
+		},
+		{
+			path:   "/items/aggregate?group_by=updated&count=id&id__gt=1",
+			status: http.StatusOK,
+			result: `{"response":{"groups":[{"count_id":1,"updated":null},{"count_id":1,"updated":"rvasily"}]}}`,
+		},
+		{
+			path:   "/items/aggregate?sum=title",
+			status: http.StatusBadRequest,
+			result: `{"error":"field title does not support sum"}`,
+		},
+		{
+			path:   "/items/aggregate?group_by=nosuch&count=*",
+			status: http.StatusBadRequest,
+			result: `{"error":"unknown field nosuch"}`,
+		},
+		{
+			path:   "/items/aggregate?group_by=title",
+			status: http.StatusBadRequest,
+			result: `{"error":"at least one of count, sum, avg, min, max is required"}`,
+		},
+	}
+
+	for idx, item := range cases {
+		resp, err := client.Get(ts.URL + item.path)
+		if err != nil {
+			t.Fatalf("[case %d] request error: %v", idx, err)
+		}
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if resp.StatusCode != item.status {
+			t.Fatalf("[case %d: %s] expected http status %v, got %v: %s", idx, item.path, item.status, resp.StatusCode, body)
+		}
+		if string(body) != item.result {
+			t.Fatalf("[case %d: %s] results not match\nGot : %s\nWant: %s", idx, item.path, body, item.result)
+		}
+	}
+}
+
+func TestNestedRoutes(t *testing.T) {
+	db, err := sql.Open("mysql", DSN)
+	err = db.Ping()
+	if err != nil {
+		panic(err)
+	}
+
+	PrepareTestApis(db)
+	defer CleanupTestApis(db)
+
+	qs := []string{
+		`CREATE TABLE orders (
+  id int(11) NOT NULL AUTO_INCREMENT,
+  user_id int(11) NOT NULL,
+  amount int(11) NOT NULL,
+  PRIMARY KEY (id),
+  CONSTRAINT orders_user FOREIGN KEY (user_id) REFERENCES users (user_id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;`,
+		`INSERT INTO users (user_id, login, password, email, info) VALUES (2, 'vasya', 'pass', 'vasya@example.com', '');`,
+		`INSERT INTO orders (id, user_id, amount) VALUES (1, 1, 100), (2, 1, 250), (3, 2, 50);`,
+	}
+	for _, q := range qs {
+		if _, err := db.Exec(q); err != nil {
+			panic(err)
+		}
+	}
+	defer db.Exec(`DROP TABLE IF EXISTS orders;`)
+
+	handler, err := NewDbExplorer(db)
+	if err != nil {
+		panic(err)
+	}
+
+	ts := httptest.NewServer(handler)
+
+	cases := []struct {
+		path   string
+		status int
+		result string
+	}{
+		{
+			path:   "/users/1/orders",
+			status: http.StatusOK,
+			result: `{"response":{"records":[{"amount":100,"id":1,"user_id":1},{"amount":250,"id":2,"user_id":1}]}}`,
+		},
+		{
+			path:   "/users/1/orders?amount__gt=100&fields=id",
+			status: http.StatusOK,
+			result: `{"response":{"records":[{"id":2}]}}`,
+		},
+		{
+			path:   "/users/2/orders?limit=5",
+			status: http.StatusOK,
+			result: `{"response":{"records":[{"amount":50,"id":3,"user_id":2}]}}`,
+		},
+		{
+			path:   "/users/1/orders?amount__gt=1000",
+			status: http.StatusOK,
+			result: `{"response":{"records":[]}}`,
+		},
+		{
+			path:   "/users/42/orders",
+			status: http.StatusNotFound,
+			result: `{"error":"record not found"}`,
+		},
+		{
+			path:   "/users/1/items",
+			status: http.StatusNotFound,
+			result: `{"error":"table items does not reference users"}`,
+		},
+		{
+			path:   "/users/1/nosuch",
+			status: http.StatusNotFound,
+			result: `{"error":"unknown table"}`,
+		},
+	}
+
+	for idx, item := range cases {
+		resp, err := client.Get(ts.URL + item.path)
+		if err != nil {
+			t.Fatalf("[case %d] request error: %v", idx, err)
+		}
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if resp.StatusCode != item.status {
+			t.Fatalf("[case %d: %s] expected http status %v, got %v: %s", idx, item.path, item.status, resp.StatusCode, body)
+		}
+		if string(body) != item.result {
+			t.Fatalf("[case %d: %s] results not match\nGot : %s\nWant: %s", idx, item.path, body, item.result)
+		}
+	}
+
+	req, _ := http.NewRequest(http.MethodOptions, ts.URL+"/users/1/orders", nil)
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatalf("options request error: %v", err)
+	}
+	resp.Body.Close()
+	if allow := resp.Header.Get("Allow"); allow != "GET, HEAD, OPTIONS" {
+		t.Fatalf("unexpected Allow header for nested route: %q", allow)
+	}
+}
+
+func TestConstructorOptions(t *testing.T) {
+	db, err := sql.Open("mysql", DSN)
+	err = db.Ping()
+	if err != nil {
+		panic(err)
+	}
+
+	PrepareTestApis(db)
+	defer CleanupTestApis(db)
+
+	db.Exec("INSERT INTO items (id, title, description) VALUES (3, 'grpc', ''), (4, 'kafka', '')")
+
+	cases := []struct {
+		opts   []Option
+		path   string
+		status int
+		result string
+	}{
+		{
+			opts:   []Option{WithDefaultLimit(1)},
+			path:   "/items?fields=id",
+			status: http.StatusOK,
+			result: `{"response":{"records":[{"id":1}]}}`,
+		},
+		{
+			opts:   []Option{WithDefaultLimit(1)},
+			path:   "/items?fields=id&limit=3",
+			status: http.StatusOK,
+			result: `{"response":{"records":[{"id":1},{"id":2},{"id":3}]}}`,
+		},
+		{
+			opts:   []Option{WithMaxLimit(2)},
+			path:   "/items?fields=id&limit=2",
+			status: http.StatusOK,
+			result: `{"response":{"records":[{"id":1},{"id":2}]}}`,
+		},
+		{
+			opts:   []Option{WithMaxLimit(2)},
+			path:   "/items?fields=id&limit=3",
+			status: http.StatusBadRequest,
+			result: `{"error":"limit must not exceed 2"}`,
+		},
+		{
+			opts:   []Option{WithDefaultLimit(10), WithMaxLimit(2)},
+			path:   "/items?fields=id",
+			status: http.StatusOK,
+			result: `{"response":{"records":[{"id":1},{"id":2}]}}`,
+		},
+		{
+			opts:   []Option{WithMaxLimit(2)},
+			path:   "/items?fields=id&format=csv",
+			status: http.StatusOK,
+			result: "id\n1\n2\n",
+		},
+		{
+			path:   "/items?fields=id&limit=-1",
+			status: http.StatusBadRequest,
+			result: `{"error":"limit and offset must not be negative"}`,
+		},
+		{
+			path:   "/items?fields=id&offset=-2",
+			status: http.StatusBadRequest,
+			result: `{"error":"limit and offset must not be negative"}`,
+		},
+		{
+			opts:   []Option{WithTables("users")},
+			path:   "/",
+			status: http.StatusOK,
+			result: `{"response":{"tables":["users"]}}`,
+		},
+		{
+			opts:   []Option{WithTables("users")},
+			path:   "/items",
+			status: http.StatusNotFound,
+			result: `{"error":"unknown table"}`,
+		},
+		{
+			opts:   []Option{WithExcludedTables("users")},
+			path:   "/",
+			status: http.StatusOK,
+			result: `{"response":{"tables":["items"]}}`,
+		},
+	}
+
+	for idx, item := range cases {
+		handler, err := NewDbExplorer(db, item.opts...)
+		if err != nil {
+			panic(err)
+		}
+
+		rw := httptest.NewRecorder()
+		handler.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, item.path, nil))
+		if rw.Code != item.status {
+			t.Fatalf("[case %d: %s] expected http status %v, got %v: %s", idx, item.path, item.status, rw.Code, rw.Body)
+		}
+		if body := rw.Body.String(); body != item.result {
+			t.Fatalf("[case %d: %s] results not match\nGot : %s\nWant: %s", idx, item.path, body, item.result)
+		}
+	}
+}
+
+func TestInsertDefaults(t *testing.T) {
+	db, err := sql.Open("mysql", DSN)
+	err = db.Ping()
+	if err != nil {
+		panic(err)
+	}
+
+	PrepareTestApis(db)
+	defer CleanupTestApis(db)
+	db.Exec(`DROP TABLE IF EXISTS tickets;`)
+	if _, err := db.Exec(`CREATE TABLE tickets (
+  id int(11) NOT NULL AUTO_INCREMENT,
+  title varchar(50) NOT NULL,
+  status varchar(20) NOT NULL DEFAULT 'new',
+  priority int(11) NOT NULL DEFAULT 3,
+  created_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+  PRIMARY KEY (id)
+);`); err != nil {
+		panic(err)
+	}
+	defer db.Exec(`DROP TABLE IF EXISTS tickets;`)
+
+	handler, err := NewDbExplorer(db)
+	if err != nil {
+		panic(err)
+	}
+
+	rw := httptest.NewRecorder()
+	handler.ServeHTTP(rw, httptest.NewRequest(http.MethodPut, "/tickets/", strings.NewReader(`{"title":"printer"}`)))
+	if body := rw.Body.String(); body != `{"response":{"id":1}}` {
+		t.Fatalf("unexpected insert result: %s", body)
+	}
+
+	rw = httptest.NewRecorder()
+	handler.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/tickets/1?fields=title,status,priority", nil))
+	if body := rw.Body.String(); body != `{"response":{"record":{"priority":3,"status":"new","title":"printer"}}}` {
+		t.Fatalf("defaults are not applied: %s", body)
+	}
+
+	var createdAt sql.NullString
+	if err := db.QueryRow("SELECT created_at FROM tickets WHERE id = 1").Scan(&createdAt); err != nil || !createdAt.Valid || strings.HasPrefix(createdAt.String, "0000") {
+		t.Fatalf("CURRENT_TIMESTAMP default is not applied: %v %v", createdAt, err)
+	}
+}
+
+func TestNullUpdates(t *testing.T) {
+	db, err := sql.Open("mysql", DSN)
+	err = db.Ping()
+	if err != nil {
+		panic(err)
+	}
+
+	qs := []string{
+		`DROP TABLE IF EXISTS stock;`,
+		`CREATE TABLE stock (
+  id int(11) NOT NULL AUTO_INCREMENT,
+  name varchar(255) NOT NULL,
+  quantity int(11) DEFAULT NULL,
+  note varchar(255) DEFAULT NULL,
+  PRIMARY KEY (id)
+) ENGINE=InnoDB DEFAULT CHARSET=utf8;`,
+		`INSERT INTO stock (id, name, quantity, note) VALUES (1, 'bolt', 5, 'box');`,
+	}
+	for _, q := range qs {
+		if _, err := db.Exec(q); err != nil {
+			panic(err)
+		}
+	}
+	defer db.Exec(`DROP TABLE IF EXISTS stock;`)
+
+	handler, err := NewDbExplorer(db)
+	if err != nil {
+		panic(err)
+	}
+
+	ts := httptest.NewServer(handler)
+
+	runCases(t, ts, db, []Case{
+		// null очищает колонку, отсутствующее поле остаётся как было
+		Case{
+			Path:   "/stock/1",
+			Method: http.MethodPost,
+			Body:   CR{"quantity": nil},
+			Result: CR{"response": CR{"updated": 1}},
+		},
+		Case{
+			Path:   "/stock/1",
+			Result: CR{"response": CR{"record": CR{"id": 1, "name": "bolt", "quantity": nil, "note": "box"}}},
+		},
+		Case{
+			Path:   "/stock/1",
+			Method: http.MethodPatch,
+			Body:   CR{"quantity": 7, "note": nil},
+			Result: CR{"response": CR{"updated": 1}},
+		},
+		Case{
+			Path:   "/stock/1",
+			Result: CR{"response": CR{"record": CR{"id": 1, "name": "bolt", "quantity": 7, "note": nil}}},
+		},
+		Case{
+			Path:   "/stock/1",
+			Method: http.MethodPost,
+			Status: http.StatusBadRequest,
+			Body:   CR{"name": nil},
+			Result: CR{"error": "field name have invalid type"},
+		},
+		Case{
+			Path:   "/stock/",
+			Method: http.MethodPut,
+			Body:   CR{"name": "nut", "quantity": nil},
+			Result: CR{"response": CR{"id": 2}},
+		},
+		Case{
+			Path:   "/stock/2",
+			Result: CR{"response": CR{"record": CR{"id": 2, "name": "nut", "quantity": nil, "note": nil}}},
+		},
+	})
+}
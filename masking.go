@@ -0,0 +1,77 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// MaskingConfig скрывает чувствительные колонки от непривилегированных запросов.
+// Columns: таблица → колонка → маска вроде "***"; пустая маска убирает колонку из ответа.
+type MaskingConfig struct {
+	Columns map[string]map[string]string
+	// Privileged отмечает запросы, которые видят значения как есть и могут их записывать;
+	// nil — привилегированных запросов нет
+	Privileged func(r *http.Request) bool
+}
+
+// withMasking возвращает копию explorer'а для запроса r с включённым маскированием, если оно нужно
+func (d DbExplorer) withMasking(r *http.Request) DbExplorer {
+	d.masked = d.masking != nil && (r == nil || d.masking.Privileged == nil || !d.masking.Privileged(r))
+	return d
+}
+
+// sensitiveColumn возвращает маску колонки, если для текущего запроса она чувствительная
+func (d DbExplorer) sensitiveColumn(tableName, columnName string) (string, bool) {
+	if !d.masked {
+		return "", false
+	}
+	mask, ok := d.masking.Columns[tableName][columnName]
+	return mask, ok
+}
+
+// hiddenColumn — чувствительная колонка без маски: её нельзя выбирать, фильтровать и сортировать
+func (d DbExplorer) hiddenColumn(tableName, columnName string) bool {
+	mask, ok := d.sensitiveColumn(tableName, columnName)
+	return ok && mask == ""
+}
+
+// visibleColumns заменяет "*" явным списком колонок, если у таблицы есть скрытые;
+// если скрыты все, читать из таблицы нечего
+func (d DbExplorer) visibleColumns(tableName string) (string, error) {
+	columns := make([]string, 0, len(d.columnKeysMap[tableName]))
+	hidden := false
+	for _, columnName := range d.columnKeysMap[tableName] {
+		if d.hiddenColumn(tableName, columnName) {
+			hidden = true
+			continue
+		}
+		columns = append(columns, d.dialect.quote(columnName))
+	}
+
+	if !hidden {
+		return "*", nil
+	}
+	if len(columns) == 0 {
+		return "", errors.New("all columns of table " + tableName + " are hidden")
+	}
+	return strings.Join(columns, ", "), nil
+}
+
+// maskValue подменяет значение чувствительной колонки маской; NULL остаётся NULL
+func (d DbExplorer) maskValue(tableName, columnName string, value interface{}) interface{} {
+	if mask, ok := d.sensitiveColumn(tableName, columnName); ok && value != nil {
+		return mask
+	}
+	return value
+}
+
+// checkSensitiveWrite запрещает непривилегированным запросам писать в чувствительные колонки
+func (d DbExplorer) checkSensitiveWrite(tableName string, data map[string]interface{}) error {
+	for columnName := range data {
+		if _, ok := d.sensitiveColumn(tableName, columnName); ok {
+			return errors.New("field " + columnName + " is not writable")
+		}
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+)
+
+var errMethodNotAllowed = errors.New("method not allowed")
+
+// allowedMethods возвращает методы, которые поддерживает маршрут: запись возможна только
+// в таблицах с первичным ключом и только если диалект и explorer не read-only
+func (d DbExplorer) allowedMethods(path string) []string {
+	methods := []string{http.MethodGet, http.MethodHead, http.MethodOptions}
+	if path == "/graphql" {
+		return []string{http.MethodGet, http.MethodPost, http.MethodOptions}
+	}
+	if path == "/admin/query" {
+		return []string{http.MethodPost, http.MethodOptions}
+	}
+	if path == "/transaction" || path == "/batch" || path == "/admin/migrate" || path == "/admin/seed" {
+		if !d.dialect.writable() || d.readOnly {
+			return []string{http.MethodOptions}
+		}
+		return []string{http.MethodPost, http.MethodOptions}
+	}
+	if path == "/" || !d.dialect.writable() || d.readOnly {
+		return methods
+	}
+	if path == "/_tables" && d.ddl != nil {
+		return []string{http.MethodPut, http.MethodOptions}
+	}
+
+	tableName, err := getTableName(path, d.tableKeys)
+	pathParts := strings.Split(path, "/")
+	if err == nil && d.ddl != nil && len(pathParts) == 3 && pathParts[2] == "_columns" {
+		return []string{http.MethodPost, http.MethodOptions}
+	}
+	if err != nil || len(d.tableIdNamesMap[tableName]) == 0 {
+		return methods
+	}
+
+	switch {
+	case len(pathParts) == 2 || (len(pathParts) == 3 && pathParts[2] == ""):
+		methods = append(methods, http.MethodPut, http.MethodPost)
+	case len(pathParts) == 3 && pathParts[2] == "import":
+		methods = append(methods, http.MethodPost)
+	case len(pathParts) == 4 && pathParts[2] != "distinct" && pathParts[3] == "restore":
+		methods = append(methods, http.MethodPost)
+	case len(pathParts) == 3 && pathParts[2] != "schema" && pathParts[2] != "trash" && pathParts[2] != "aggregate" && pathParts[2] != "_columns" && pathParts[2] != "dump":
+		methods = append(methods, http.MethodPost, http.MethodDelete, http.MethodPatch)
+	}
+	return methods
+}
+
+func (d DbExplorer) handlerOptions(rw http.ResponseWriter, r *http.Request) {
+	rw.Header().Set("Allow", strings.Join(d.allowedMethods(r.URL.Path), ", "))
+	rw.WriteHeader(http.StatusNoContent)
+}
+
+// handlerMethodNotAllowed отвечает 405 со списком допустимых методов в заголовке Allow
+func (d DbExplorer) handlerMethodNotAllowed(rw http.ResponseWriter, r *http.Request, err error) {
+	rw.Header().Set("Allow", strings.Join(d.allowedMethods(r.URL.Path), ", "))
+	responseResult(rw, err, http.StatusMethodNotAllowed, nil)
+}
+
+// headResponseWriter отдаёт заголовки GET-ответа, отбрасывая тело
+type headResponseWriter struct {
+	http.ResponseWriter
+}
+
+func (w headResponseWriter) Write(data []byte) (int, error) {
+	return len(data), nil
+}
+
+func (w headResponseWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (d DbExplorer) handlerHead(rw http.ResponseWriter, r *http.Request) {
+	d.handlerGet(headResponseWriter{rw}, r)
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestAllowedMethods(t *testing.T) {
+	explorer := DbExplorer{
+		dialect:         MySQL,
+		tableKeys:       []string{"items", "logs"},
+		tableIdNamesMap: map[string][]string{"items": {"id"}},
+	}
+
+	cases := []struct {
+		method string
+		path   string
+		status int
+		allow  string
+	}{
+		{http.MethodOptions, "/", http.StatusNoContent, "GET, HEAD, OPTIONS"},
+		{http.MethodOptions, "/items", http.StatusNoContent, "GET, HEAD, OPTIONS, PUT, POST"},
+		{http.MethodOptions, "/items/1", http.StatusNoContent, "GET, HEAD, OPTIONS, POST, DELETE, PATCH"},
+		{http.MethodOptions, "/items/schema", http.StatusNoContent, "GET, HEAD, OPTIONS"},
+		{http.MethodOptions, "/logs/1", http.StatusNoContent, "GET, HEAD, OPTIONS"},
+		{"TRACE", "/items/1", http.StatusMethodNotAllowed, "GET, HEAD, OPTIONS, POST, DELETE, PATCH"},
+	}
+
+	for _, item := range cases {
+		rw := httptest.NewRecorder()
+		explorer.ServeHTTP(rw, httptest.NewRequest(item.method, item.path, nil))
+		if rw.Code != item.status {
+			t.Fatalf("[%s %s] expected status %d, got %d", item.method, item.path, item.status, rw.Code)
+		}
+		if allow := rw.Header().Get("Allow"); allow != item.allow {
+			t.Fatalf("[%s %s] expected Allow %q, got %q", item.method, item.path, item.allow, allow)
+		}
+	}
+
+	explorer.dialect = ClickHouse
+	rw := httptest.NewRecorder()
+	explorer.ServeHTTP(rw, httptest.NewRequest(http.MethodDelete, "/items/1", nil))
+	if rw.Code != http.StatusMethodNotAllowed || rw.Header().Get("Allow") != "GET, HEAD, OPTIONS" {
+		t.Fatalf("expected read-only 405, got %d with Allow %q", rw.Code, rw.Header().Get("Allow"))
+	}
+}
+
+func TestHeadWithoutBody(t *testing.T) {
+	explorer := DbExplorer{dialect: MySQL, tableKeys: []string{"items"}}
+
+	rw := httptest.NewRecorder()
+	explorer.ServeHTTP(rw, httptest.NewRequest(http.MethodHead, "/", nil))
+	if rw.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rw.Code)
+	}
+	if rw.Body.Len() != 0 {
+		t.Fatalf("expected empty body, got %q", rw.Body.String())
+	}
+}
+
+func TestReadOnly(t *testing.T) {
+	explorer := DbExplorer{
+		dialect:         MySQL,
+		tableKeys:       []string{"items"},
+		tableIdNamesMap: map[string][]string{"items": {"id"}},
+	}
+	WithReadOnly(true)(&explorer)
+
+	for _, method := range []string{http.MethodPut, http.MethodPost, http.MethodDelete, http.MethodPatch} {
+		rw := httptest.NewRecorder()
+		explorer.ServeHTTP(rw, httptest.NewRequest(method, "/items/1", nil))
+		if rw.Code != http.StatusForbidden {
+			t.Fatalf("[%s] expected status %d, got %d", method, http.StatusForbidden, rw.Code)
+		}
+		if body := rw.Body.String(); body != `{"error":"explorer is in read-only mode"}` {
+			t.Fatalf("[%s] unexpected body %s", method, body)
+		}
+	}
+
+	rw := httptest.NewRecorder()
+	explorer.ServeHTTP(rw, httptest.NewRequest(http.MethodOptions, "/items/1", nil))
+	if allow := rw.Header().Get("Allow"); allow != "GET, HEAD, OPTIONS" {
+		t.Fatalf("expected read-only Allow, got %q", allow)
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+)
+
+// middlewareChain общий для всех копий explorer'а, поэтому Use действует и на уже смонтированные маршруты
+type middlewareChain struct {
+	middlewares []func(http.Handler) http.Handler
+	handler     http.Handler
+
+	mu       sync.Mutex
+	snapshot *schemaSnapshot // схема, по которой построен routes
+	routes   *http.ServeMux  // шаблоны Routes для RequestRoute
+}
+
+// RouteInfo — маршрут текущего запроса, который middleware получают через RequestRoute
+type RouteInfo struct {
+	Pattern string // шаблон из Routes, например "/{table}/{id}"; пустой, если маршрут не найден
+	Table   string
+	ID      string
+}
+
+type routeKey struct{}
+
+// RequestRoute возвращает маршрут запроса; false — запрос пришёл не через middleware explorer'а
+func RequestRoute(r *http.Request) (RouteInfo, bool) {
+	route, ok := r.Context().Value(routeKey{}).(RouteInfo)
+	return route, ok
+}
+
+// Use добавляет middleware вокруг обработки запроса: первое добавленное выполняется первым.
+// Цепочка собирается при вызове, поэтому Use нужно вызывать до начала обслуживания запросов.
+func (d *DbExplorer) Use(middlewares ...func(http.Handler) http.Handler) {
+	if d.middleware == nil {
+		d.middleware = &middlewareChain{}
+	}
+	chain := d.middleware
+	chain.middlewares = append(chain.middlewares, middlewares...)
+
+	var handler http.Handler = http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		d.serve(rw, r)
+	})
+	for i := len(chain.middlewares) - 1; i >= 0; i-- {
+		handler = chain.middlewares[i](handler)
+	}
+	chain.handler = handler
+}
+
+// withRoute сопоставляет запрос с шаблонами Routes и кладёт маршрут в контекст запроса
+func (d DbExplorer) withRoute(r *http.Request) *http.Request {
+	_, pattern := d.routeMux().Handler(r)
+	_, pattern, _ = strings.Cut(pattern, " ")
+
+	route := RouteInfo{Pattern: pattern}
+	patternParts := strings.Split(pattern, "/")
+	pathParts := strings.Split(r.URL.Path, "/")
+	for i, part := range patternParts {
+		if i >= len(pathParts) {
+			break
+		}
+		switch part {
+		case "{table}":
+			route.Table = pathParts[i]
+		case "{id}":
+			route.ID = pathParts[i]
+		}
+	}
+	return r.WithContext(context.WithValue(r.Context(), routeKey{}, route))
+}
+
+// routeMux строит ServeMux по Routes заново только после обновления схемы: от неё зависят вложенные маршруты
+func (d DbExplorer) routeMux() *http.ServeMux {
+	var snapshot *schemaSnapshot
+	if d.schema != nil {
+		snapshot = d.schema.load()
+	}
+
+	chain := d.middleware
+	chain.mu.Lock()
+	defer chain.mu.Unlock()
+	if chain.routes == nil || chain.snapshot != snapshot {
+		chain.routes = http.NewServeMux()
+		for _, route := range d.routes() {
+			chain.routes.Handle(route.Method+" "+route.Pattern, route.Handler)
+		}
+		chain.snapshot = snapshot
+	}
+	return chain.routes
+}
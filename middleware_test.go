@@ -0,0 +1,105 @@
+package main
+
+import (
+	"database/sql"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMiddleware(t *testing.T) {
+	db, err := sql.Open("mysql", DSN)
+	err = db.Ping()
+	if err != nil {
+		panic(err)
+	}
+
+	PrepareTestApis(db)
+	defer CleanupTestApis(db)
+
+	handler, err := NewDbExplorer(db)
+	if err != nil {
+		panic(err)
+	}
+
+	// маршруты монтируются до Use: цепочка общая для копий explorer'а
+	mux := http.NewServeMux()
+	handler.Mount(mux, "/api")
+
+	calls := make([]string, 0)
+	handler.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			defer func() {
+				if recover() != nil {
+					http.Error(rw, "recovered", http.StatusInternalServerError)
+				}
+			}()
+			calls = append(calls, "recover")
+			next.ServeHTTP(rw, r)
+		})
+	}, func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+			route, _ := RequestRoute(r)
+			calls = append(calls, route.Pattern+" "+route.Table+" "+route.ID)
+			if r.Header.Get("X-Panic") != "" {
+				panic("boom")
+			}
+			if route.Table == "users" && r.Method != http.MethodGet {
+				http.Error(rw, "users are read-only", http.StatusForbidden)
+				return
+			}
+			next.ServeHTTP(rw, r)
+		})
+	})
+
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	cases := []struct {
+		method string
+		path   string
+		panics bool
+		status int
+		body   string
+		calls  string
+	}{
+		{method: http.MethodGet, path: "/api/items/1?fields=id", status: http.StatusOK,
+			body:  `{"response":{"record":{"id":1}}}`,
+			calls: "recover,/{table}/{id} items 1"},
+		{method: http.MethodGet, path: "/api/", status: http.StatusOK,
+			body:  `{"response":{"tables":["items","users"]}}`,
+			calls: "recover,/{$}  "},
+		{method: http.MethodDelete, path: "/api/users/1", status: http.StatusForbidden,
+			body:  "users are read-only\n",
+			calls: "recover,/{table}/{id} users 1"},
+		{method: http.MethodGet, path: "/api/items/schema", panics: true, status: http.StatusInternalServerError,
+			body:  "recovered\n",
+			calls: "recover,/{table}/schema items "},
+	}
+
+	for idx, item := range cases {
+		calls = calls[:0]
+		req, _ := http.NewRequest(item.method, ts.URL+item.path, nil)
+		if item.panics {
+			req.Header.Set("X-Panic", "1")
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("[%d] request error: %v", idx, err)
+		}
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if resp.StatusCode != item.status {
+			t.Fatalf("[%d] expected http status %v, got %v", idx, item.status, resp.StatusCode)
+		}
+		if string(body) != item.body {
+			t.Fatalf("[%d] results not match\nGot : %s\nWant: %s", idx, body, item.body)
+		}
+		if strings.Join(calls, ",") != item.calls {
+			t.Fatalf("[%d] middleware calls not match\nGot : %s\nWant: %s", idx, strings.Join(calls, ","), item.calls)
+		}
+	}
+}
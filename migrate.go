@@ -0,0 +1,236 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"io/fs"
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// migrationsTable хранит применённые версии; наружу через API не отдаётся
+const migrationsTable = "schema_migrations"
+
+// Migration — версия схемы: SQL-скрипт или функция Go. Каждая миграция выполняется в своей
+// транзакции вместе с записью в schema_migrations; в MySQL DDL фиксируется сразу, поэтому
+// упавшая на середине миграция может остаться применённой частично.
+type Migration struct {
+	Version int64
+	Name    string
+	SQL     string
+	Func    func(ctx context.Context, tx *sql.Tx) error
+}
+
+// migrator — настройки миграций explorer'а, см. WithMigrations
+type migrator struct {
+	mu         sync.Mutex
+	migrations []Migration
+	onStart    bool
+}
+
+// MigrationsFromFS читает миграции из файлов вида 0001_create_orders.sql в корне fsys: номер до
+// первого "_" — версия, остаток имени — описание. Операторы в файле разделяются ";".
+func MigrationsFromFS(fsys fs.FS) ([]Migration, error) {
+	files, err := fs.Glob(fsys, "*.sql")
+	if err != nil {
+		return nil, err
+	}
+
+	migrations := make([]Migration, 0, len(files))
+	for _, file := range files {
+		name := strings.TrimSuffix(path.Base(file), ".sql")
+		rawVersion, description, _ := strings.Cut(name, "_")
+		version, err := strconv.ParseInt(rawVersion, 10, 64)
+		if err != nil {
+			return nil, errors.New("migration " + file + ": file name must start with a version number")
+		}
+
+		data, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return nil, err
+		}
+		migrations = append(migrations, Migration{Version: version, Name: description, SQL: string(data)})
+	}
+	return migrations, nil
+}
+
+func newMigrator(migrations []Migration, onStart bool) *migrator {
+	sorted := append([]Migration{}, migrations...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].Version < sorted[j].Version })
+	return &migrator{migrations: sorted, onStart: onStart}
+}
+
+func (m *migrator) validate() error {
+	for i, migration := range m.migrations {
+		version := strconv.FormatInt(migration.Version, 10)
+		if migration.Version <= 0 {
+			return errors.New("migration " + version + ": version must be positive")
+		}
+		if i > 0 && m.migrations[i-1].Version == migration.Version {
+			return errors.New("migration " + version + " is defined twice")
+		}
+		if (migration.SQL == "") == (migration.Func == nil) {
+			return errors.New("migration " + version + ": exactly one of SQL and Func is required")
+		}
+	}
+	return nil
+}
+
+// appliedMigrations возвращает применённые версии, при первом запуске создавая таблицу учёта
+func (d DbExplorer) appliedMigrations(ctx context.Context) (map[int64]bool, error) {
+	table := d.dialect.quote(migrationsTable)
+	rows, err := d.db.QueryContext(ctx, "SELECT "+d.dialect.quote("version")+" FROM "+table+";")
+	if err != nil {
+		_, createErr := d.db.ExecContext(ctx, "CREATE TABLE "+table+" ("+
+			d.dialect.quote("version")+" BIGINT NOT NULL, "+
+			d.dialect.quote("name")+" VARCHAR(255) NOT NULL, "+
+			d.dialect.quote("applied_at")+" VARCHAR(64) NOT NULL, "+
+			"PRIMARY KEY ("+d.dialect.quote("version")+"));")
+		if createErr != nil {
+			return nil, errors.New("schema_migrations: " + createErr.Error())
+		}
+		return map[int64]bool{}, nil
+	}
+	defer rows.Close()
+
+	applied := make(map[int64]bool)
+	for rows.Next() {
+		var version int64
+		if err := rows.Scan(&version); err != nil {
+			return nil, err
+		}
+		applied[version] = true
+	}
+	return applied, rows.Err()
+}
+
+// migrate применяет ещё не применённые миграции по возрастанию версии и возвращает применённые.
+// На первой ошибке останавливается: следующие миграции могут зависеть от упавшей.
+func (d DbExplorer) migrate(ctx context.Context) ([]Migration, error) {
+	d.migrations.mu.Lock()
+	defer d.migrations.mu.Unlock()
+
+	applied, err := d.appliedMigrations(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	done := make([]Migration, 0)
+	for _, migration := range d.migrations.migrations {
+		if applied[migration.Version] {
+			continue
+		}
+		if err := d.applyMigration(ctx, migration); err != nil {
+			return done, errors.New("migration " + strconv.FormatInt(migration.Version, 10) + ": " + err.Error())
+		}
+		done = append(done, migration)
+	}
+	return done, nil
+}
+
+func (d DbExplorer) applyMigration(ctx context.Context, migration Migration) error {
+	tx, err := d.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	if migration.Func != nil {
+		err = migration.Func(ctx, tx)
+	} else {
+		for _, statement := range splitStatements(migration.SQL) {
+			if _, err = tx.ExecContext(ctx, statement); err != nil {
+				break
+			}
+		}
+	}
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	args := &queryArgs{dialect: d.dialect}
+	query := "INSERT INTO " + d.dialect.quote(migrationsTable) + " (" + d.dialect.quote("version") + ", " +
+		d.dialect.quote("name") + ", " + d.dialect.quote("applied_at") + ") VALUES (" +
+		args.add(migration.Version) + ", " + args.add(migration.Name) + ", " +
+		args.add(time.Now().UTC().Format(time.RFC3339)) + ");"
+	if _, err := tx.ExecContext(ctx, query, args.values...); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+// splitStatements делит скрипт по ";" вне строк, идентификаторов в кавычках и комментариев.
+// DELIMITER и тела процедур не поддерживаются — для них есть миграции-функции.
+func splitStatements(script string) []string {
+	statements := make([]string, 0)
+	start := 0
+	add := func(end int) {
+		if statement := strings.TrimSpace(script[start:end]); statement != "" {
+			statements = append(statements, statement)
+		}
+	}
+
+	for i := 0; i < len(script); i++ {
+		switch c := script[i]; {
+		case c == '\'' || c == '"' || c == '`':
+			for i++; i < len(script) && script[i] != c; i++ {
+				if script[i] == '\\' {
+					i++
+				}
+			}
+		case c == '-' && i+1 < len(script) && script[i+1] == '-':
+			for i < len(script) && script[i] != '\n' {
+				i++
+			}
+		case c == '/' && i+1 < len(script) && script[i+1] == '*':
+			end := strings.Index(script[i+2:], "*/")
+			if end < 0 {
+				i = len(script)
+			} else {
+				i += end + 3
+			}
+		case c == ';':
+			add(i)
+			start = i + 1
+		}
+	}
+	add(len(script))
+	return statements
+}
+
+// handlerMigrate выполняет POST /admin/migrate: применяет новые миграции и обновляет схему explorer'а
+func (d DbExplorer) handlerMigrate(rw http.ResponseWriter, r *http.Request) {
+	if d.migrations == nil {
+		responseResult(rw, errors.New("migrations are not configured"), http.StatusNotFound, nil)
+		return
+	}
+	if err := d.tableAccess(r, "/admin/migrate", http.MethodPost); err != nil {
+		responseResult(rw, err, http.StatusForbidden, nil)
+		return
+	}
+
+	done, err := d.migrate(d.requestContext())
+	// часть миграций могла примениться и до ошибки, схему обновляем в любом случае
+	if len(done) > 0 {
+		if refreshErr := d.refreshSchema(); refreshErr != nil && err == nil {
+			err = refreshErr
+		}
+	}
+	if err != nil {
+		responseResult(rw, err, http.StatusInternalServerError, nil)
+		return
+	}
+
+	applied := make([]map[string]interface{}, 0, len(done))
+	for _, migration := range done {
+		applied = append(applied, map[string]interface{}{"version": migration.Version, "name": migration.Name})
+	}
+	responseResult(rw, nil, http.StatusOK, map[string]interface{}{"applied": applied})
+}
@@ -0,0 +1,116 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestSplitStatements(t *testing.T) {
+	script := `CREATE TABLE t (id int); -- комментарий; не оператор
+INSERT INTO t VALUES (';'), ("a;b"); /* ; */
+;`
+	expected := []string{
+		"CREATE TABLE t (id int)",
+		"-- комментарий; не оператор\nINSERT INTO t VALUES (';'), (\"a;b\")",
+		"/* ; */",
+	}
+	if statements := splitStatements(script); !reflect.DeepEqual(statements, expected) {
+		t.Fatalf("statements not match\nGot : %q\nWant: %q", statements, expected)
+	}
+}
+
+func TestMigrationsFromFS(t *testing.T) {
+	migrations, err := MigrationsFromFS(fstest.MapFS{
+		"0002_add_amount.sql":    {Data: []byte("ALTER TABLE orders ADD COLUMN amount int;")},
+		"0001_create_orders.sql": {Data: []byte("CREATE TABLE orders (id int);")},
+		"README.md":              {Data: []byte("not a migration")},
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(migrations) != 2 || migrations[0].Version != 1 || migrations[0].Name != "create_orders" || migrations[1].Version != 2 {
+		t.Fatalf("unexpected migrations: %+v", migrations)
+	}
+
+	if _, err := MigrationsFromFS(fstest.MapFS{"create.sql": {Data: []byte("SELECT 1")}}); err == nil {
+		t.Fatalf("expected error for file without version")
+	}
+	if err := newMigrator([]Migration{{Version: 1, SQL: "a"}, {Version: 1, SQL: "b"}}, false).validate(); err == nil {
+		t.Fatalf("expected error for duplicate version")
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	db, err := sql.Open("mysql", DSN)
+	err = db.Ping()
+	if err != nil {
+		panic(err)
+	}
+
+	PrepareTestApis(db)
+	defer CleanupTestApis(db)
+	defer db.Exec(`DROP TABLE IF EXISTS schema_migrations;`)
+	defer db.Exec(`DROP TABLE IF EXISTS orders;`)
+
+	migrations, err := MigrationsFromFS(fstest.MapFS{
+		"1_create_orders.sql": {Data: []byte(`CREATE TABLE orders (id int NOT NULL AUTO_INCREMENT, PRIMARY KEY (id));
+INSERT INTO orders (id) VALUES (1);`)},
+	})
+	if err != nil {
+		panic(err)
+	}
+	migrations = append(migrations, Migration{Version: 2, Name: "add_amount", Func: func(ctx context.Context, tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, `ALTER TABLE orders ADD COLUMN amount int NOT NULL DEFAULT 10;`)
+		return err
+	}})
+
+	handler, err := NewDbExplorer(db, WithMigrations(migrations, false), WithCache(time.Minute, 100))
+	if err != nil {
+		panic(err)
+	}
+
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	cases := []struct {
+		method string
+		path   string
+		status int
+		result string
+	}{
+		{http.MethodGet, "/orders", http.StatusNotFound, `{"error":"unknown table"}`},
+		{http.MethodPost, "/admin/migrate", http.StatusOK, `{"response":{"applied":[{"name":"create_orders","version":1},{"name":"add_amount","version":2}]}}`},
+		{http.MethodGet, "/", http.StatusOK, `{"response":{"tables":["items","orders","users"]}}`},
+		{http.MethodGet, "/orders/1", http.StatusOK, `{"response":{"record":{"amount":10,"id":1}}}`},
+		{http.MethodPost, "/admin/migrate", http.StatusOK, `{"response":{"applied":[]}}`},
+	}
+
+	for idx, item := range cases {
+		req, _ := http.NewRequest(item.method, ts.URL+item.path, nil)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("[case %d] request error: %v", idx, err)
+		}
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if resp.StatusCode != item.status {
+			t.Fatalf("[case %d: %s %s] expected http status %v, got %v: %s", idx, item.method, item.path, item.status, resp.StatusCode, body)
+		}
+		if string(body) != item.result {
+			t.Fatalf("[case %d: %s %s] results not match\nGot : %s\nWant: %s", idx, item.method, item.path, body, item.result)
+		}
+	}
+
+	// при старте применённые версии не повторяются
+	if _, err := NewDbExplorer(db, WithMigrations(migrations, true)); err != nil {
+		t.Fatalf("unexpected error on restart: %v", err)
+	}
+}
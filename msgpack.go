@@ -0,0 +1,125 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"math"
+	"sort"
+	"strconv"
+)
+
+// encodeMsgpack кодирует результат в MessagePack. Значения сначала проходят через JSON,
+// поэтому ответ совпадает с JSON-ответом по структуре и типам.
+func encodeMsgpack(envelope map[string]interface{}) ([]byte, error) {
+	generic, err := normalizeEnvelope(envelope)
+	if err != nil {
+		return nil, err
+	}
+
+	buffer := &bytes.Buffer{}
+	if err := writeMsgpack(buffer, generic); err != nil {
+		return nil, err
+	}
+	return buffer.Bytes(), nil
+}
+
+func writeMsgpack(buffer *bytes.Buffer, value interface{}) error {
+	switch typed := value.(type) {
+	case nil:
+		buffer.WriteByte(0xc0)
+	case bool:
+		if typed {
+			buffer.WriteByte(0xc3)
+		} else {
+			buffer.WriteByte(0xc2)
+		}
+	case json.Number:
+		if intValue, err := strconv.ParseInt(typed.String(), 10, 64); err == nil {
+			writeMsgpackInt(buffer, intValue)
+			return nil
+		}
+		floatValue, err := typed.Float64()
+		if err != nil {
+			return err
+		}
+		buffer.WriteByte(0xcb)
+		binary.Write(buffer, binary.BigEndian, math.Float64bits(floatValue))
+	case string:
+		writeMsgpackHeader(buffer, len(typed), 0xa0, 31, 0xd9, 0xda, 0xdb)
+		buffer.WriteString(typed)
+	case []interface{}:
+		writeMsgpackHeader(buffer, len(typed), 0x90, 15, 0, 0xdc, 0xdd)
+		for _, item := range typed {
+			if err := writeMsgpack(buffer, item); err != nil {
+				return err
+			}
+		}
+	case map[string]interface{}:
+		keys := make([]string, 0, len(typed))
+		for key := range typed {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		writeMsgpackHeader(buffer, len(typed), 0x80, 15, 0, 0xde, 0xdf)
+		for _, key := range keys {
+			writeMsgpack(buffer, key)
+			if err := writeMsgpack(buffer, typed[key]); err != nil {
+				return err
+			}
+		}
+	default:
+		return errors.New("msgpack: unsupported value")
+	}
+	return nil
+}
+
+func writeMsgpackInt(buffer *bytes.Buffer, value int64) {
+	switch {
+	case value >= 0 && value <= 0x7f:
+		buffer.WriteByte(byte(value))
+	case value < 0 && value >= -32:
+		buffer.WriteByte(byte(int8(value)))
+	case value > 0 && value <= math.MaxUint8:
+		buffer.WriteByte(0xcc)
+		buffer.WriteByte(byte(value))
+	case value > 0 && value <= math.MaxUint16:
+		buffer.WriteByte(0xcd)
+		binary.Write(buffer, binary.BigEndian, uint16(value))
+	case value > 0 && value <= math.MaxUint32:
+		buffer.WriteByte(0xce)
+		binary.Write(buffer, binary.BigEndian, uint32(value))
+	case value >= math.MinInt8 && value <= math.MaxInt8:
+		buffer.WriteByte(0xd0)
+		buffer.WriteByte(byte(int8(value)))
+	case value >= math.MinInt16 && value <= math.MaxInt16:
+		buffer.WriteByte(0xd1)
+		binary.Write(buffer, binary.BigEndian, int16(value))
+	case value >= math.MinInt32 && value <= math.MaxInt32:
+		buffer.WriteByte(0xd2)
+		binary.Write(buffer, binary.BigEndian, int32(value))
+	default:
+		buffer.WriteByte(0xd3)
+		binary.Write(buffer, binary.BigEndian, value)
+	}
+}
+
+// writeMsgpackHeader пишет заголовок строки, массива или map: fix-формат для коротких,
+// дальше 8-, 16- или 32-битная длина (code8 == 0, если 8-битного варианта нет)
+func writeMsgpackHeader(buffer *bytes.Buffer, length int, fixPrefix byte, fixMax int, code8, code16, code32 byte) {
+	switch {
+	case length <= fixMax:
+		buffer.WriteByte(fixPrefix | byte(length))
+	case code8 != 0 && length <= math.MaxUint8:
+		buffer.WriteByte(code8)
+		buffer.WriteByte(byte(length))
+	case length <= math.MaxUint16:
+		buffer.WriteByte(code16)
+		binary.Write(buffer, binary.BigEndian, uint16(length))
+	default:
+		buffer.WriteByte(code32)
+		binary.Write(buffer, binary.BigEndian, uint32(length))
+	}
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestEncodeMsgpack(t *testing.T) {
+	cases := []struct {
+		value    interface{}
+		expected []byte
+	}{
+		{nil, []byte{0xc0}},
+		{true, []byte{0xc3}},
+		{5, []byte{0x05}},
+		{-3, []byte{0xfd}},
+		{200, []byte{0xcc, 0xc8}},
+		{-200, []byte{0xd1, 0xff, 0x38}},
+		{70000, []byte{0xce, 0x00, 0x01, 0x11, 0x70}},
+		{-70000, []byte{0xd2, 0xff, 0xfe, 0xee, 0x90}},
+		{1.5, []byte{0xcb, 0x3f, 0xf8, 0, 0, 0, 0, 0, 0}},
+		{"db", []byte{0xa2, 'd', 'b'}},
+		{json.RawMessage(`[1,{"a":null}]`), []byte{0x92, 0x01, 0x81, 0xa1, 'a', 0xc0}},
+		{strings.Repeat("x", 40), append([]byte{0xd9, 40}, strings.Repeat("x", 40)...)},
+	}
+
+	for _, item := range cases {
+		value, err := normalizeEnvelope(item.value)
+		if err != nil {
+			t.Fatalf("[%v] unexpected error: %v", item.value, err)
+		}
+		buffer := &bytes.Buffer{}
+		if err := writeMsgpack(buffer, value); err != nil {
+			t.Fatalf("[%v] unexpected error: %v", item.value, err)
+		}
+		if result := buffer.Bytes(); !bytes.Equal(result, item.expected) {
+			t.Fatalf("[%v] results not match\nGot : % x\nWant: % x", item.value, result, item.expected)
+		}
+	}
+}
@@ -0,0 +1,170 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"net/http"
+	"sort"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// SchemaHeader выбирает схему запроса у explorer'а из NewSchemaExplorer
+const SchemaHeader = "X-Db-Schema"
+
+// MultiDbExplorer отдаёт несколько баз под префиксами пути: /{alias}/{table}/...
+// У каждой базы свой DbExplorer со своей схемой; опции применяются к каждому отдельно,
+// так что кеш ответов и лимиты запросов у баз тоже свои.
+type MultiDbExplorer struct {
+	aliases   []string
+	explorers map[string]*DbExplorer
+	// header и defaultAlias задаются NewSchemaExplorer: база выбирается заголовком, а не путём
+	header       string
+	defaultAlias string
+	owned        []*sql.DB
+	basePath     string // префикс из WithBasePath, общий для всех баз
+}
+
+func NewMultiDbExplorer(dbs map[string]*sql.DB, opts ...Option) (*MultiDbExplorer, error) {
+	multi := &MultiDbExplorer{explorers: make(map[string]*DbExplorer, len(dbs))}
+	for alias, db := range dbs {
+		if alias == "" || strings.Contains(alias, "/") {
+			return nil, errors.New("invalid database alias " + alias)
+		}
+
+		explorer, err := NewDbExplorer(db, opts...)
+		if err != nil {
+			return nil, errors.New("database " + alias + ": " + err.Error())
+		}
+		// перед префиксом базы стоит общий префикс, его срезает сам MultiDbExplorer
+		multi.basePath, explorer.basePath = explorer.basePath, ""
+		multi.explorers[alias] = explorer
+		multi.aliases = append(multi.aliases, alias)
+	}
+	sort.Strings(multi.aliases)
+	return multi, nil
+}
+
+// NewSchemaExplorer отдаёт несколько схем одного сервера MySQL: схема выбирается заголовком
+// X-Db-Schema из списка schemas, без заголовка — схема из DSN (или первая из списка).
+// Для каждой схемы открывается свой пул соединений с тем же DSN, ведь USE в общем пуле
+// переключил бы базу и для чужих запросов.
+func NewSchemaExplorer(dsn string, schemas []string, opts ...Option) (*MultiDbExplorer, error) {
+	config, err := mysql.ParseDSN(dsn)
+	if err != nil {
+		return nil, err
+	}
+	if len(schemas) == 0 {
+		return nil, errors.New("no schemas to serve")
+	}
+	defaultAlias := config.DBName
+	if defaultAlias == "" {
+		defaultAlias = schemas[0]
+	}
+	if !containsString(schemas, defaultAlias) {
+		return nil, errors.New("default schema " + defaultAlias + " is not in the schema list")
+	}
+
+	dbs := make(map[string]*sql.DB, len(schemas))
+	owned := make([]*sql.DB, 0, len(schemas))
+	for _, schema := range schemas {
+		schemaConfig := config.Clone()
+		schemaConfig.DBName = schema
+		connector, err := mysql.NewConnector(schemaConfig)
+		if err != nil {
+			closeAll(owned)
+			return nil, err
+		}
+		db := sql.OpenDB(connector)
+		dbs[schema] = db
+		owned = append(owned, db)
+	}
+
+	multi, err := NewMultiDbExplorer(dbs, opts...)
+	if err != nil {
+		closeAll(owned)
+		return nil, err
+	}
+	multi.header = SchemaHeader
+	multi.defaultAlias = defaultAlias
+	multi.owned = owned
+	return multi, nil
+}
+
+// Close закрывает пулы соединений, открытые NewSchemaExplorer; переданные в NewMultiDbExplorer базы не трогает
+func (m *MultiDbExplorer) Close() error {
+	return closeAll(m.owned)
+}
+
+// Shutdown останавливает explorer'ы всех баз, см. DbExplorer.Shutdown
+func (m *MultiDbExplorer) Shutdown(ctx context.Context) error {
+	var firstErr error
+	for _, alias := range m.aliases {
+		if err := m.explorers[alias].Shutdown(ctx); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+func closeAll(dbs []*sql.DB) error {
+	var firstErr error
+	for _, db := range dbs {
+		if err := db.Close(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// Explorer возвращает explorer базы, например чтобы зарегистрировать для неё gRPC-фасад
+func (m *MultiDbExplorer) Explorer(alias string) (*DbExplorer, bool) {
+	explorer, ok := m.explorers[alias]
+	return explorer, ok
+}
+
+func (m *MultiDbExplorer) ServeHTTP(rw http.ResponseWriter, r *http.Request) {
+	if m.basePath != "" {
+		if !hasPathPrefix(r.URL.Path, m.basePath) {
+			responseResult(rw, errors.New("not found"), http.StatusNotFound, nil)
+			return
+		}
+		r = stripPathPrefix(r, m.basePath)
+	}
+	if m.header != "" {
+		m.serveSchema(rw, r)
+		return
+	}
+	if r.URL.Path == "/" {
+		responseResult(rw, nil, http.StatusOK, map[string]interface{}{"databases": m.aliases})
+		return
+	}
+
+	alias := strings.SplitN(strings.TrimPrefix(r.URL.Path, "/"), "/", 2)[0]
+	explorer, ok := m.explorers[alias]
+	if !ok {
+		responseResult(rw, errors.New("unknown database"), http.StatusNotFound, nil)
+		return
+	}
+
+	// explorer разбирает путь от корня, поэтому префикс базы срезаем, как http.StripPrefix
+	explorer.ServeHTTP(rw, stripPathPrefix(r, "/"+alias))
+}
+
+func (m *MultiDbExplorer) serveSchema(rw http.ResponseWriter, r *http.Request) {
+	// ответы разных схем по одному URL различаются, об этом нужно знать промежуточным кешам
+	rw.Header().Add("Vary", m.header)
+
+	schema := r.Header.Get(m.header)
+	if schema == "" {
+		schema = m.defaultAlias
+	}
+	explorer, ok := m.explorers[schema]
+	if !ok {
+		responseResult(rw, errors.New("schema "+schema+" is not allowed"), http.StatusBadRequest, nil)
+		return
+	}
+	explorer.ServeHTTP(rw, r)
+}
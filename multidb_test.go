@@ -0,0 +1,152 @@
+package main
+
+import (
+	"database/sql"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestMultiDbExplorer(t *testing.T) {
+	db, err := sql.Open("mysql", DSN)
+	err = db.Ping()
+	if err != nil {
+		panic(err)
+	}
+
+	PrepareTestApis(db)
+	defer CleanupTestApis(db)
+
+	if _, err := db.Exec(`CREATE DATABASE IF NOT EXISTS golang_second;`); err != nil {
+		panic(err)
+	}
+	defer db.Exec(`DROP DATABASE IF EXISTS golang_second;`)
+
+	second, err := sql.Open("mysql", strings.Replace(DSN, "/golang?", "/golang_second?", 1))
+	if err != nil {
+		panic(err)
+	}
+	defer second.Close()
+	qs := []string{
+		`CREATE TABLE notes (id int(11) NOT NULL AUTO_INCREMENT, text varchar(255) NOT NULL, PRIMARY KEY (id));`,
+		`INSERT INTO notes (id, text) VALUES (1, 'hello');`,
+	}
+	for _, q := range qs {
+		if _, err := second.Exec(q); err != nil {
+			panic(err)
+		}
+	}
+
+	handler, err := NewMultiDbExplorer(map[string]*sql.DB{"main": db, "second": second})
+	if err != nil {
+		panic(err)
+	}
+
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	cases := []struct {
+		path   string
+		status int
+		result string
+	}{
+		{"/", http.StatusOK, `{"response":{"databases":["main","second"]}}`},
+		{"/main", http.StatusOK, `{"response":{"tables":["items","users"]}}`},
+		{"/second/", http.StatusOK, `{"response":{"tables":["notes"]}}`},
+		{"/second/notes/1", http.StatusOK, `{"response":{"record":{"id":1,"text":"hello"}}}`},
+		{"/main/users/1?fields=login", http.StatusOK, `{"response":{"record":{"login":"rvasily"}}}`},
+		{"/main/notes", http.StatusNotFound, `{"error":"unknown table"}`},
+		{"/third/items", http.StatusNotFound, `{"error":"unknown database"}`},
+	}
+
+	for idx, item := range cases {
+		resp, err := client.Get(ts.URL + item.path)
+		if err != nil {
+			t.Fatalf("[case %d] request error: %v", idx, err)
+		}
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if resp.StatusCode != item.status {
+			t.Fatalf("[case %d: %s] expected http status %v, got %v: %s", idx, item.path, item.status, resp.StatusCode, body)
+		}
+		if string(body) != item.result {
+			t.Fatalf("[case %d: %s] results not match\nGot : %s\nWant: %s", idx, item.path, body, item.result)
+		}
+	}
+
+	if _, err := NewMultiDbExplorer(map[string]*sql.DB{"a/b": db}); err == nil {
+		t.Fatalf("expected error for alias with slash")
+	}
+}
+
+func TestSchemaExplorer(t *testing.T) {
+	db, err := sql.Open("mysql", DSN)
+	err = db.Ping()
+	if err != nil {
+		panic(err)
+	}
+
+	PrepareTestApis(db)
+	defer CleanupTestApis(db)
+
+	qs := []string{
+		`CREATE DATABASE IF NOT EXISTS golang_second;`,
+		`CREATE TABLE golang_second.notes (id int(11) NOT NULL AUTO_INCREMENT, text varchar(255) NOT NULL, PRIMARY KEY (id));`,
+	}
+	for _, q := range qs {
+		if _, err := db.Exec(q); err != nil {
+			panic(err)
+		}
+	}
+	defer db.Exec(`DROP DATABASE IF EXISTS golang_second;`)
+
+	handler, err := NewSchemaExplorer(DSN, []string{"golang", "golang_second"})
+	if err != nil {
+		panic(err)
+	}
+	defer handler.Close()
+
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	cases := []struct {
+		schema string
+		status int
+		result string
+	}{
+		{"", http.StatusOK, `{"response":{"tables":["items","users"]}}`},
+		{"golang", http.StatusOK, `{"response":{"tables":["items","users"]}}`},
+		{"golang_second", http.StatusOK, `{"response":{"tables":["notes"]}}`},
+		{"mysql", http.StatusBadRequest, `{"error":"schema mysql is not allowed"}`},
+	}
+
+	for idx, item := range cases {
+		req, _ := http.NewRequest(http.MethodGet, ts.URL+"/", nil)
+		if item.schema != "" {
+			req.Header.Set(SchemaHeader, item.schema)
+		}
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("[case %d] request error: %v", idx, err)
+		}
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if resp.StatusCode != item.status {
+			t.Fatalf("[case %d: %s] expected http status %v, got %v: %s", idx, item.schema, item.status, resp.StatusCode, body)
+		}
+		if string(body) != item.result {
+			t.Fatalf("[case %d: %s] results not match\nGot : %s\nWant: %s", idx, item.schema, body, item.result)
+		}
+		if vary := resp.Header.Get("Vary"); !strings.Contains(vary, SchemaHeader) {
+			t.Fatalf("[case %d] expected Vary to mention %s, got %q", idx, SchemaHeader, vary)
+		}
+	}
+
+	if _, err := NewSchemaExplorer(DSN, []string{"golang_second"}); err == nil {
+		t.Fatalf("expected error for default schema outside the list")
+	}
+}
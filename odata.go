@@ -0,0 +1,352 @@
+package main
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+	"unicode"
+)
+
+// odataOptions — параметры OData v4, которые переводятся в обычные параметры списка;
+// $filter разбирается отдельно, см. odataCondition
+var odataOptions = map[string]string{
+	"$top":     "limit",
+	"$skip":    "offset",
+	"$orderby": "sort",
+	"$select":  "fields",
+	"$count":   "count",
+}
+
+// odataComparisons — операторы сравнения $filter
+var odataComparisons = map[string]string{
+	"eq": "=",
+	"ne": "<>",
+	"gt": ">",
+	"ge": ">=",
+	"lt": "<",
+	"le": "<=",
+}
+
+// odataParams переводит $top, $skip, $orderby, $select и $count в limit, offset, sort, fields и count
+// и возвращает $filter отдельно. Остальные параметры OData ($expand, $search...) не поддерживаются.
+func odataParams(params url.Values) (url.Values, string, error) {
+	translated := url.Values{}
+	filter := ""
+	for key, values := range params {
+		if !strings.HasPrefix(key, "$") {
+			translated[key] = values
+			continue
+		}
+		if key == "$filter" {
+			filter = values[0]
+			if len(values) > 1 {
+				filter = "(" + strings.Join(values, ") and (") + ")"
+			}
+			continue
+		}
+		name, ok := odataOptions[key]
+		if !ok {
+			return nil, "", errors.New("unsupported query option " + key)
+		}
+		if _, ok := params[name]; ok {
+			return nil, "", errors.New("use either " + key + " or " + name)
+		}
+
+		value := strings.Join(values, ",")
+		if key == "$orderby" {
+			value = odataOrder(value)
+		}
+		translated.Set(name, strings.ReplaceAll(value, " ", ""))
+	}
+	return translated, filter, nil
+}
+
+// odataOrder переводит "name desc, id asc" в формат ?sort=: "-name,id"
+func odataOrder(orderBy string) string {
+	fields := make([]string, 0)
+	for _, part := range strings.Split(orderBy, ",") {
+		words := strings.Fields(part)
+		switch {
+		case len(words) == 0:
+			continue
+		case len(words) == 2 && strings.EqualFold(words[1], "desc"):
+			fields = append(fields, "-"+words[0])
+		case len(words) == 2 && strings.EqualFold(words[1], "asc"):
+			fields = append(fields, words[0])
+		default:
+			// неизвестное направление дойдёт до orderClause и получит ошибку как неизвестное поле
+			fields = append(fields, strings.Join(words, ""))
+		}
+	}
+	return strings.Join(fields, ",")
+}
+
+// odataToken — лексема $filter: имя, строка в кавычках, число или знак
+type odataToken struct {
+	text   string
+	quoted bool
+}
+
+func tokenizeODataFilter(filter string) ([]odataToken, error) {
+	tokens := make([]odataToken, 0)
+	runes := []rune(filter)
+	for i := 0; i < len(runes); {
+		switch r := runes[i]; {
+		case unicode.IsSpace(r):
+			i++
+		case r == '(' || r == ')' || r == ',':
+			tokens = append(tokens, odataToken{text: string(r)})
+			i++
+		case r == '\'':
+			// кавычка внутри строки удваивается: 'O''Brien'
+			value := strings.Builder{}
+			i++
+			for {
+				if i >= len(runes) {
+					return nil, errors.New("invalid $filter: unterminated string")
+				}
+				if runes[i] == '\'' {
+					if i+1 < len(runes) && runes[i+1] == '\'' {
+						value.WriteRune('\'')
+						i += 2
+						continue
+					}
+					i++
+					break
+				}
+				value.WriteRune(runes[i])
+				i++
+			}
+			tokens = append(tokens, odataToken{text: value.String(), quoted: true})
+		default:
+			start := i
+			for i < len(runes) && !unicode.IsSpace(runes[i]) && !strings.ContainsRune("(),'", runes[i]) {
+				i++
+			}
+			tokens = append(tokens, odataToken{text: string(runes[start:i])})
+		}
+	}
+	return tokens, nil
+}
+
+// odataParser строит параметризованное условие WHERE из $filter рекурсивным спуском:
+// or < and < not < сравнение, скобки и функции contains, startswith, endswith
+type odataParser struct {
+	d         DbExplorer
+	tableName string
+	tokens    []odataToken
+	pos       int
+	args      *queryArgs
+}
+
+// odataCondition строит условие из $filter вида "age ge 18 and (name eq 'Ivan' or contains(email,'@mail'))"
+func (d DbExplorer) odataCondition(tableName, filter string, args *queryArgs) (string, error) {
+	tokens, err := tokenizeODataFilter(filter)
+	if err != nil {
+		return "", err
+	}
+	parser := &odataParser{d: d, tableName: tableName, tokens: tokens, args: args}
+	condition, err := parser.parseOr()
+	if err != nil {
+		return "", err
+	}
+	if parser.pos < len(tokens) {
+		return "", errors.New("invalid $filter: unexpected " + tokens[parser.pos].text)
+	}
+	return "(" + condition + ")", nil
+}
+
+func (p *odataParser) peek() (odataToken, bool) {
+	if p.pos >= len(p.tokens) {
+		return odataToken{}, false
+	}
+	return p.tokens[p.pos], true
+}
+
+func (p *odataParser) next() (odataToken, error) {
+	token, ok := p.peek()
+	if !ok {
+		return token, errors.New("invalid $filter: unexpected end")
+	}
+	p.pos++
+	return token, nil
+}
+
+// keyword проверяет и пропускает слово вроде and/or/not
+func (p *odataParser) keyword(word string) bool {
+	token, ok := p.peek()
+	if ok && !token.quoted && strings.EqualFold(token.text, word) {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func (p *odataParser) expect(text string) error {
+	token, err := p.next()
+	if err != nil {
+		return err
+	}
+	if token.quoted || token.text != text {
+		return errors.New("invalid $filter: expected " + text + ", got " + token.text)
+	}
+	return nil
+}
+
+func (p *odataParser) parseOr() (string, error) {
+	condition, err := p.parseAnd()
+	if err != nil {
+		return "", err
+	}
+	for p.keyword("or") {
+		other, err := p.parseAnd()
+		if err != nil {
+			return "", err
+		}
+		condition = condition + " OR " + other
+	}
+	return condition, nil
+}
+
+func (p *odataParser) parseAnd() (string, error) {
+	condition, err := p.parseNot()
+	if err != nil {
+		return "", err
+	}
+	for p.keyword("and") {
+		other, err := p.parseNot()
+		if err != nil {
+			return "", err
+		}
+		condition = condition + " AND " + other
+	}
+	return condition, nil
+}
+
+func (p *odataParser) parseNot() (string, error) {
+	if p.keyword("not") {
+		condition, err := p.parseNot()
+		if err != nil {
+			return "", err
+		}
+		return "NOT " + condition, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *odataParser) parsePrimary() (string, error) {
+	token, err := p.next()
+	if err != nil {
+		return "", err
+	}
+	if token.text == "(" && !token.quoted {
+		condition, err := p.parseOr()
+		if err != nil {
+			return "", err
+		}
+		if err := p.expect(")"); err != nil {
+			return "", err
+		}
+		return "(" + condition + ")", nil
+	}
+	if token.quoted {
+		return "", errors.New("invalid $filter: expected field, got '" + token.text + "'")
+	}
+	if token.text == ")" || token.text == "," {
+		return "", errors.New("invalid $filter: unexpected " + token.text)
+	}
+
+	if next, ok := p.peek(); ok && next.text == "(" && !next.quoted {
+		return p.parseFunction(strings.ToLower(token.text))
+	}
+
+	column, err := p.column(token.text)
+	if err != nil {
+		return "", err
+	}
+	operatorToken, err := p.next()
+	if err != nil {
+		return "", err
+	}
+	operator, ok := odataComparisons[strings.ToLower(operatorToken.text)]
+	if !ok || operatorToken.quoted {
+		return "", errors.New("invalid $filter: unknown operator " + operatorToken.text)
+	}
+	literal, err := p.next()
+	if err != nil {
+		return "", err
+	}
+
+	quotedColumn := p.d.dialect.quote(column.name)
+	if !literal.quoted && literal.text == "null" {
+		switch operator {
+		case "=":
+			return quotedColumn + " IS NULL", nil
+		case "<>":
+			return quotedColumn + " IS NOT NULL", nil
+		}
+		return "", errors.New("invalid $filter: null can only be compared with eq or ne")
+	}
+	value, err := p.d.filterValue(column, literal.text)
+	if err != nil {
+		return "", err
+	}
+	return quotedColumn + " " + operator + " " + p.args.add(value), nil
+}
+
+// parseFunction разбирает contains(field,'text'), startswith и endswith в LIKE с экранированием шаблона
+func (p *odataParser) parseFunction(name string) (string, error) {
+	if name != "contains" && name != "startswith" && name != "endswith" {
+		return "", errors.New("invalid $filter: unsupported function " + name)
+	}
+	if err := p.expect("("); err != nil {
+		return "", err
+	}
+	fieldToken, err := p.next()
+	if err != nil {
+		return "", err
+	}
+	column, err := p.column(fieldToken.text)
+	if err != nil {
+		return "", err
+	}
+	if column.typeName != "string" && column.typeName != "enum" && column.typeName != "set" {
+		return "", errors.New("field " + column.name + " does not support " + name)
+	}
+	if err := p.expect(","); err != nil {
+		return "", err
+	}
+	literal, err := p.next()
+	if err != nil {
+		return "", err
+	}
+	if !literal.quoted {
+		return "", errors.New("invalid $filter: " + name + " expects a string")
+	}
+	if err := p.expect(")"); err != nil {
+		return "", err
+	}
+
+	pattern := strings.NewReplacer("!", "!!", "%", "!%", "_", "!_").Replace(literal.text)
+	switch name {
+	case "contains":
+		pattern = "%" + pattern + "%"
+	case "startswith":
+		pattern = pattern + "%"
+	case "endswith":
+		pattern = "%" + pattern
+	}
+	return p.d.dialect.quote(column.name) + " LIKE " + p.args.add(pattern) + " ESCAPE '!'", nil
+}
+
+// column находит колонку поля с теми же ограничениями, что у обычных фильтров
+func (p *odataParser) column(field string) (columnParams, error) {
+	column, ok := p.d.columnsInTablesMap[p.tableName][p.d.columnName(p.tableName, field)]
+	if !ok {
+		return columnParams{}, errors.New("unknown field " + field)
+	}
+	if _, sensitive := p.d.sensitiveColumn(p.tableName, column.name); sensitive {
+		return columnParams{}, errors.New("unknown field " + field)
+	}
+	return column, nil
+}
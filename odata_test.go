@@ -0,0 +1,104 @@
+package main
+
+import (
+	"database/sql"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+)
+
+func TestODataQuery(t *testing.T) {
+	db, err := sql.Open("mysql", DSN)
+	err = db.Ping()
+	if err != nil {
+		panic(err)
+	}
+
+	PrepareTestApis(db)
+	defer CleanupTestApis(db)
+
+	db.Exec("INSERT INTO items (id, title, description) VALUES (3, 'grpc', ''), (4, 'O''Reilly 100%', '')")
+
+	handler, err := NewDbExplorer(db)
+	if err != nil {
+		panic(err)
+	}
+
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	cases := []struct {
+		query  url.Values
+		status int
+		result string
+	}{
+		{
+			query:  url.Values{"$filter": {"id gt 1 and (title eq 'grpc' or updated eq null)"}, "$select": {"id"}},
+			status: http.StatusOK,
+			result: `{"response":{"records":[{"id":2},{"id":3},{"id":4}]}}`,
+		},
+		{
+			query:  url.Values{"$filter": {"not (id le 2)"}, "$orderby": {"id desc"}, "$select": {"id, title"}},
+			status: http.StatusOK,
+			result: `{"response":{"records":[{"id":4,"title":"O'Reilly 100%"},{"id":3,"title":"grpc"}]}}`,
+		},
+		{
+			query:  url.Values{"$filter": {"contains(title,'100%') or startswith(title,'mem')"}, "$select": {"id"}},
+			status: http.StatusOK,
+			result: `{"response":{"records":[{"id":2},{"id":4}]}}`,
+		},
+		{
+			query:  url.Values{"$filter": {"title eq 'O''Reilly 100%'"}, "$select": {"id"}},
+			status: http.StatusOK,
+			result: `{"response":{"records":[{"id":4}]}}`,
+		},
+		{
+			query:  url.Values{"$top": {"2"}, "$skip": {"1"}, "$select": {"id"}, "$count": {"true"}},
+			status: http.StatusOK,
+			result: `{"response":{"records":[{"id":2},{"id":3}],"has_more":true,"limit":2,"offset":1,"total":4}}`,
+		},
+		{
+			query:  url.Values{"$filter": {"id ge 'abc'"}},
+			status: http.StatusBadRequest,
+			result: `{"error":"field id have invalid type"}`,
+		},
+		{
+			query:  url.Values{"$filter": {"password eq 'love'"}},
+			status: http.StatusBadRequest,
+			result: `{"error":"unknown field password"}`,
+		},
+		{
+			query:  url.Values{"$filter": {"id gt 1 and"}},
+			status: http.StatusBadRequest,
+			result: `{"error":"invalid $filter: unexpected end"}`,
+		},
+		{
+			query:  url.Values{"$expand": {"users"}},
+			status: http.StatusBadRequest,
+			result: `{"error":"unsupported query option $expand"}`,
+		},
+		{
+			query:  url.Values{"$top": {"2"}, "limit": {"1"}},
+			status: http.StatusBadRequest,
+			result: `{"error":"use either $top or limit"}`,
+		},
+	}
+
+	for idx, item := range cases {
+		resp, err := client.Get(ts.URL + "/items?" + item.query.Encode())
+		if err != nil {
+			t.Fatalf("[case %d] request error: %v", idx, err)
+		}
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if resp.StatusCode != item.status {
+			t.Fatalf("[case %d: %v] expected http status %v, got %v: %s", idx, item.query, item.status, resp.StatusCode, body)
+		}
+		if string(body) != item.result {
+			t.Fatalf("[case %d: %v] results not match\nGot : %s\nWant: %s", idx, item.query, body, item.result)
+		}
+	}
+}
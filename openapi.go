@@ -0,0 +1,337 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+)
+
+type jsonObject = map[string]interface{}
+
+// handlerOpenAPI отдаёт OpenAPI-документ без обёртки response: его читают генераторы клиентов
+func (d DbExplorer) handlerOpenAPI(rw http.ResponseWriter, r *http.Request) {
+	rw.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(rw).Encode(d.openAPISpec()); err != nil {
+		responseResult(rw, err, http.StatusInternalServerError, nil)
+	}
+}
+
+// openAPISpec строит OpenAPI 3 по схеме, прочитанной при старте: на каждую таблицу
+// схема записи, схема тела запроса и пути списка/записи/схемы
+func (d DbExplorer) openAPISpec() jsonObject {
+	schemas := jsonObject{
+		"Error": jsonObject{
+			"type":       "object",
+			"properties": jsonObject{"error": jsonObject{"type": "string"}},
+		},
+	}
+	paths := jsonObject{
+		"/": jsonObject{
+			"get": jsonObject{
+				"summary": "List tables",
+				"responses": jsonObject{
+					"200": openAPIResponse(jsonObject{
+						"type": "object",
+						"properties": jsonObject{
+							"tables": jsonObject{"type": "array", "items": jsonObject{"type": "string"}},
+						},
+					}),
+				},
+			},
+		},
+	}
+
+	for _, tableName := range d.tableKeys {
+		name := openAPIName(tableName)
+		schemas[name] = d.openAPIRecordSchema(tableName, false)
+		recordRef := jsonObject{"$ref": "#/components/schemas/" + name}
+
+		list := jsonObject{
+			"get": jsonObject{
+				"summary":    "List " + tableName,
+				"parameters": d.openAPIListParameters(tableName),
+				"responses": jsonObject{
+					"200": openAPIResponse(jsonObject{
+						"type": "object",
+						"properties": jsonObject{
+							"records":     jsonObject{"type": "array", "items": recordRef},
+							"next_cursor": jsonObject{"type": "string", "nullable": true},
+							"total":       jsonObject{"type": "integer"},
+							"limit":       jsonObject{"type": "integer"},
+							"offset":      jsonObject{"type": "integer"},
+							"has_more":    jsonObject{"type": "boolean"},
+						},
+					}),
+					"400": openAPIErrorResponse(),
+					"404": openAPIErrorResponse(),
+				},
+			},
+		}
+		paths["/"+tableName+"/schema"] = jsonObject{
+			"get": jsonObject{
+				"summary":   "Describe " + tableName + " columns",
+				"responses": jsonObject{"200": openAPIResponse(jsonObject{"type": "object"})},
+			},
+		}
+
+		primaryKeys := d.tableIdNamesMap[tableName]
+		if len(primaryKeys) > 0 {
+			idDescription := "Primary key value"
+			if len(primaryKeys) > 1 {
+				idDescription = "Comma-separated values of " + strings.Join(primaryKeys, ", ")
+			}
+			record := jsonObject{
+				"parameters": []interface{}{
+					jsonObject{
+						"name":        "id",
+						"in":          "path",
+						"required":    true,
+						"description": idDescription,
+						"schema":      jsonObject{"type": "string"},
+					},
+				},
+				"get": jsonObject{
+					"summary": "Get " + tableName + " record",
+					"parameters": []interface{}{
+						openAPIQueryParameter("fields", "Comma-separated list of columns", jsonObject{"type": "string"}),
+					},
+					"responses": jsonObject{
+						"200": openAPIResponse(jsonObject{
+							"type":       "object",
+							"properties": jsonObject{"record": recordRef},
+						}),
+						"404": openAPIErrorResponse(),
+					},
+				},
+			}
+
+			if d.dialect.writable() {
+				inputName := name + "Input"
+				schemas[inputName] = d.openAPIRecordSchema(tableName, true)
+				inputRef := jsonObject{"$ref": "#/components/schemas/" + inputName}
+
+				list["put"] = jsonObject{
+					"summary": "Insert " + tableName + " records",
+					"requestBody": openAPIRequestBody("application/json", jsonObject{
+						"oneOf": []interface{}{inputRef, jsonObject{"type": "array", "items": inputRef}},
+					}),
+					"responses": jsonObject{
+						"200": openAPIResponse(jsonObject{"type": "object"}),
+						"400": openAPIErrorResponse(),
+					},
+				}
+				list["post"] = jsonObject{
+					"summary":     "Update " + tableName + " records matching a filter",
+					"parameters":  d.openAPIFilterParameters(tableName),
+					"requestBody": openAPIRequestBody("application/json", inputRef),
+					"responses": jsonObject{
+						"200": openAPICountResponse("updated"),
+						"400": openAPIErrorResponse(),
+					},
+				}
+				record["post"] = jsonObject{
+					"summary":     "Update " + tableName + " record",
+					"requestBody": openAPIRequestBody("application/json", inputRef),
+					"responses": jsonObject{
+						"200": openAPICountResponse("updated"),
+						"400": openAPIErrorResponse(),
+					},
+				}
+				record["patch"] = jsonObject{
+					"summary": "Patch " + tableName + " record",
+					"requestBody": jsonObject{
+						"required": true,
+						"content": jsonObject{
+							"application/merge-patch+json": jsonObject{"schema": inputRef},
+							"application/json-patch+json": jsonObject{"schema": jsonObject{
+								"type": "array",
+								"items": jsonObject{
+									"type":     "object",
+									"required": []string{"op", "path"},
+									"properties": jsonObject{
+										"op":    jsonObject{"type": "string", "enum": []string{"add", "replace", "remove"}},
+										"path":  jsonObject{"type": "string"},
+										"value": jsonObject{},
+									},
+								},
+							}},
+						},
+					},
+					"responses": jsonObject{
+						"200": openAPICountResponse("updated"),
+						"400": openAPIErrorResponse(),
+						"404": openAPIErrorResponse(),
+					},
+				}
+				record["delete"] = jsonObject{
+					"summary":   "Delete " + tableName + " record",
+					"responses": jsonObject{"200": openAPICountResponse("deleted")},
+				}
+			}
+
+			paths["/"+tableName+"/{id}"] = record
+		}
+
+		paths["/"+tableName] = list
+	}
+
+	return jsonObject{
+		"openapi": "3.0.3",
+		"info": jsonObject{
+			"title":   "DbExplorer API",
+			"version": "1.0.0",
+		},
+		"paths":      paths,
+		"components": jsonObject{"schemas": schemas},
+	}
+}
+
+// openAPIRecordSchema описывает запись таблицы; для тела запроса первичный ключ
+// исключается (кроме составного) и ничего не обязательно.
+func (d DbExplorer) openAPIRecordSchema(tableName string, input bool) jsonObject {
+	properties := jsonObject{}
+	required := make([]string, 0)
+	for _, columnName := range d.columnKeysMap[tableName] {
+		column := d.columnsInTablesMap[tableName][columnName]
+		if input && (!column.writable() || (column.primary && len(d.tableIdNamesMap[tableName]) == 1)) {
+			continue
+		}
+
+		properties[d.fieldName(tableName, columnName)] = d.openAPIColumnSchema(column)
+		if !input && !column.isNull {
+			required = append(required, d.fieldName(tableName, columnName))
+		}
+	}
+
+	schema := jsonObject{"type": "object", "properties": properties}
+	if len(required) > 0 {
+		schema["required"] = required
+	}
+	return schema
+}
+
+func (d DbExplorer) openAPIColumnSchema(column columnParams) jsonObject {
+	schema := jsonObject{}
+	switch column.typeName {
+	case "int":
+		schema["type"] = "integer"
+	case "float":
+		schema["type"] = "number"
+	case "decimal":
+		schema["type"] = "number"
+		if d.decimalsAsStrings {
+			schema["type"] = "string"
+			schema["format"] = "decimal"
+		}
+	case "bool":
+		schema["type"] = "boolean"
+	case "json":
+		// произвольное JSON-значение
+	case "binary":
+		schema["type"] = "string"
+		schema["format"] = "byte"
+	case "enum":
+		schema["type"] = "string"
+		schema["enum"] = column.enumValues
+	case "set":
+		schema["type"] = "string"
+		schema["description"] = "Comma-separated subset of: " + strings.Join(column.enumValues, ", ")
+	default:
+		schema["type"] = "string"
+	}
+
+	if column.isNull {
+		schema["nullable"] = true
+	}
+	if column.comment != "" {
+		schema["description"] = column.comment
+	}
+	return schema
+}
+
+func (d DbExplorer) openAPIListParameters(tableName string) []interface{} {
+	limit := jsonObject{"type": "integer", "default": d.listLimit(), "minimum": 0}
+	if d.maxLimit > 0 {
+		limit["maximum"] = d.maxLimit
+	}
+	parameters := []interface{}{
+		openAPIQueryParameter("limit", "Page size", limit),
+		openAPIQueryParameter("offset", "Number of records to skip", jsonObject{"type": "integer", "default": 0, "minimum": 0}),
+		openAPIQueryParameter("sort", "Comma-separated columns, prefix with - for descending order", jsonObject{"type": "string"}),
+		openAPIQueryParameter("fields", "Comma-separated list of columns", jsonObject{"type": "string"}),
+		openAPIQueryParameter("count", "Include total count and pagination metadata", jsonObject{"type": "boolean"}),
+		openAPIQueryParameter("after", "Cursor from next_cursor of the previous page", jsonObject{"type": "string"}),
+		openAPIQueryParameter("format", "Response format; csv and ndjson stream all matching rows", jsonObject{"type": "string", "enum": []string{"json", "csv", "ndjson"}}),
+	}
+	if _, ok := d.softDelete[tableName]; ok {
+		parameters = append(parameters, openAPIQueryParameter("include_deleted", "Include soft-deleted records", jsonObject{"type": "boolean"}))
+	}
+	return append(parameters, d.openAPIFilterParameters(tableName)...)
+}
+
+func (d DbExplorer) openAPIFilterParameters(tableName string) []interface{} {
+	parameters := make([]interface{}, 0)
+	for _, columnName := range d.columnKeysMap[tableName] {
+		column := d.columnsInTablesMap[tableName][columnName]
+		if column.typeName == "json" {
+			continue
+		}
+		field := d.fieldName(tableName, columnName)
+		parameters = append(parameters, openAPIQueryParameter(
+			field,
+			"Filter by "+field+"; use "+field+"__<op> for eq, ne, gt, gte, lt, lte, like, in",
+			jsonObject{"type": "string"},
+		))
+	}
+	return parameters
+}
+
+func openAPIName(tableName string) string {
+	parts := strings.FieldsFunc(tableName, func(r rune) bool {
+		return r == '_' || r == '-' || r == ' ' || r == '.'
+	})
+	for i, part := range parts {
+		parts[i] = strings.ToUpper(part[:1]) + part[1:]
+	}
+	return strings.Join(parts, "")
+}
+
+func openAPIQueryParameter(name, description string, schema jsonObject) jsonObject {
+	return jsonObject{"name": name, "in": "query", "description": description, "schema": schema}
+}
+
+func openAPIRequestBody(contentType string, schema jsonObject) jsonObject {
+	return jsonObject{
+		"required": true,
+		"content":  jsonObject{contentType: jsonObject{"schema": schema}},
+	}
+}
+
+// openAPIResponse оборачивает схему в конверт {"response": ...}, как это делает responseResult
+func openAPIResponse(schema jsonObject) jsonObject {
+	return jsonObject{
+		"description": "OK",
+		"content": jsonObject{
+			"application/json": jsonObject{"schema": jsonObject{
+				"type":       "object",
+				"properties": jsonObject{"response": schema},
+			}},
+		},
+	}
+}
+
+func openAPICountResponse(field string) jsonObject {
+	return openAPIResponse(jsonObject{
+		"type":       "object",
+		"properties": jsonObject{field: jsonObject{"type": "integer"}},
+	})
+}
+
+func openAPIErrorResponse() jsonObject {
+	return jsonObject{
+		"description": "Error",
+		"content": jsonObject{
+			"application/json": jsonObject{"schema": jsonObject{"$ref": "#/components/schemas/Error"}},
+		},
+	}
+}
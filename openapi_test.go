@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestOpenAPISpec(t *testing.T) {
+	explorer := DbExplorer{
+		dialect:   MySQL,
+		tableKeys: []string{"order_items", "logs"},
+		columnKeysMap: map[string][]string{
+			"order_items": {"id", "status", "price", "note"},
+			"logs":        {"message"},
+		},
+		columnsInTablesMap: map[string]map[string]columnParams{
+			"order_items": {
+				"id":     {name: "id", typeName: "int", primary: true},
+				"status": {name: "status", typeName: "enum", enumValues: []string{"new", "done"}},
+				"price":  {name: "price", typeName: "decimal"},
+				"note":   {name: "note", typeName: "string", isNull: true, comment: "free text"},
+			},
+			"logs": {
+				"message": {name: "message", typeName: "string"},
+			},
+		},
+		tableIdNamesMap: map[string][]string{"order_items": {"id"}},
+	}
+
+	rw := httptest.NewRecorder()
+	explorer.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	if rw.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rw.Code)
+	}
+
+	var spec map[string]interface{}
+	if err := json.Unmarshal(rw.Body.Bytes(), &spec); err != nil {
+		t.Fatalf("cant unpack json: %v", err)
+	}
+
+	schemas := spec["components"].(map[string]interface{})["schemas"].(map[string]interface{})
+	expected := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"status": map[string]interface{}{"type": "string", "enum": []interface{}{"new", "done"}},
+			"price":  map[string]interface{}{"type": "number"},
+			"note":   map[string]interface{}{"type": "string", "nullable": true, "description": "free text"},
+		},
+	}
+	if !reflect.DeepEqual(schemas["OrderItemsInput"], expected) {
+		t.Fatalf("input schema not match\nGot : %#v\nWant: %#v", schemas["OrderItemsInput"], expected)
+	}
+	if required := schemas["OrderItems"].(map[string]interface{})["required"]; !reflect.DeepEqual(required, []interface{}{"id", "status", "price"}) {
+		t.Fatalf("unexpected required columns: %#v", required)
+	}
+
+	paths := spec["paths"].(map[string]interface{})
+	record := paths["/order_items/{id}"].(map[string]interface{})
+	for _, method := range []string{"get", "post", "patch", "delete"} {
+		if _, ok := record[method]; !ok {
+			t.Fatalf("expected %s operation for record path", method)
+		}
+	}
+	if _, ok := paths["/logs/{id}"]; ok {
+		t.Fatalf("table without primary key must not have record path")
+	}
+	if _, ok := paths["/logs"].(map[string]interface{})["put"]; ok {
+		t.Fatalf("table without primary key must not have insert operation")
+	}
+}
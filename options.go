@@ -0,0 +1,428 @@
+package main
+
+import (
+	"io/fs"
+	"log/slog"
+	"strings"
+	"time"
+
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Option настраивает DbExplorer при создании
+type Option func(*DbExplorer)
+
+// WithDialect явно задаёт диалект SQL вместо определения по драйверу
+func WithDialect(dialect Dialect) Option {
+	return func(d *DbExplorer) {
+		d.dialect = dialect
+	}
+}
+
+// WithDecimalsAsStrings отдаёт DECIMAL-колонки строками вместо чисел,
+// чтобы клиенты не теряли точность при разборе в float64
+func WithDecimalsAsStrings(enabled bool) Option {
+	return func(d *DbExplorer) {
+		d.decimalsAsStrings = enabled
+	}
+}
+
+// WithTinyintAsBool включает или отключает отображение tinyint(1) в JSON boolean.
+// По умолчанию включено; отключать стоит, если tinyint(1) хранит обычные числа.
+func WithTinyintAsBool(enabled bool) Option {
+	return func(d *DbExplorer) {
+		d.tinyintAsBool = enabled
+	}
+}
+
+// WithMaxBlobSize ограничивает размер бинарных значений (после декодирования base64)
+// в теле запроса; 0 снимает ограничение
+func WithMaxBlobSize(size int) Option {
+	return func(d *DbExplorer) {
+		d.maxBlobSize = size
+	}
+}
+
+// WithCORS включает CORS-заголовки и ответы на preflight-запросы,
+// чтобы браузерные админки ходили в API без прокси
+func WithCORS(config CORSConfig) Option {
+	return func(d *DbExplorer) {
+		d.cors = &config
+	}
+}
+
+// WithCompression задаёт минимальный размер ответа в байтах, начиная с которого он сжимается
+// gzip/deflate по Accept-Encoding; по умолчанию 1024, отрицательное значение отключает сжатие
+func WithCompression(minSize int) Option {
+	return func(d *DbExplorer) {
+		d.compressionMinSize = minSize
+	}
+}
+
+// WithAPIKeys требует от каждого запроса один из ключей в X-API-Key или Authorization: Bearer
+func WithAPIKeys(keys ...string) Option {
+	return func(d *DbExplorer) {
+		d.apiKeys = staticAPIKeys(keys)
+	}
+}
+
+// WithAPIKeyLookup включает аутентификацию с проверкой ключа внешней функцией,
+// например по таблице ключей или внешнему сервису
+func WithAPIKeyLookup(lookup APIKeyLookup) Option {
+	return func(d *DbExplorer) {
+		d.apiKeys = lookup
+	}
+}
+
+// WithJWT принимает Bearer-токены JWT с подписью HS256. Claim tables задаёт права
+// на таблицы, например {"users": "ro", "orders": "rw"}; остальные таблицы недоступны.
+func WithJWT(secret []byte) Option {
+	return func(d *DbExplorer) {
+		d.jwtSecret = secret
+	}
+}
+
+// WithAuthorizer проверяет каждый запрос к таблице через authorizer, например ACL по ролям
+func WithAuthorizer(authorizer Authorizer) Option {
+	return func(d *DbExplorer) {
+		d.authorizer = authorizer
+	}
+}
+
+// WithMasking скрывает или маскирует чувствительные колонки (password_hash, ssn) во всех ответах
+// и запрещает писать в них всем, кроме привилегированных запросов
+func WithMasking(config MaskingConfig) Option {
+	return func(d *DbExplorer) {
+		d.masking = &config
+	}
+}
+
+// WithReadOnly запрещает изменение данных во всех таблицах: PUT, POST, PATCH и DELETE
+// получают 403, мутации GraphQL и gRPC — ошибку
+func WithReadOnly(enabled bool) Option {
+	return func(d *DbExplorer) {
+		d.readOnly = enabled
+	}
+}
+
+// WithDefaultLimit задаёт размер страницы списка, если ?limit= не указан; по умолчанию 5
+func WithDefaultLimit(limit int) Option {
+	return func(d *DbExplorer) {
+		d.defaultLimit = limit
+	}
+}
+
+// WithMaxLimit ограничивает ?limit= списков: запрос большей страницы получает 400, а выгрузка в CSV
+// и NDJSON без limit обрезается до этого числа строк; 0 снимает ограничение
+func WithMaxLimit(limit int) Option {
+	return func(d *DbExplorer) {
+		d.maxLimit = limit
+	}
+}
+
+// WithTables отдаёт через API только перечисленные таблицы; остальные недоступны ни для чтения, ни для записи
+func WithTables(tables ...string) Option {
+	return func(d *DbExplorer) {
+		d.tableFilter = func(tableName string) bool {
+			return containsString(tables, tableName)
+		}
+	}
+}
+
+// WithExcludedTables скрывает перечисленные таблицы, например служебные
+func WithExcludedTables(tables ...string) Option {
+	return func(d *DbExplorer) {
+		d.tableFilter = func(tableName string) bool {
+			return !containsString(tables, tableName)
+		}
+	}
+}
+
+// WithRateLimit включает ограничение частоты запросов: сверх лимита клиент получает 429 и Retry-After
+func WithRateLimit(config RateLimitConfig) Option {
+	return func(d *DbExplorer) {
+		d.limiter = newRateLimiter(config)
+	}
+}
+
+// WithMaxBodySize ограничивает размер тела запроса в байтах, сверх лимита запрос получает 413;
+// по умолчанию 10 МиБ, 0 снимает ограничение
+func WithMaxBodySize(size int64) Option {
+	return func(d *DbExplorer) {
+		d.maxBodySize = size
+	}
+}
+
+// WithAudit записывает каждую вставку, изменение и удаление в sink и открывает журнал на GET /admin/audit
+func WithAudit(sink AuditSink) Option {
+	return func(d *DbExplorer) {
+		d.audit = sink
+	}
+}
+
+// WithLogger пишет в logger строку на каждый запрос (method, path, table, status, duration, rows, error)
+// и внутренние ошибки; уровни: 5xx — Error, 4xx — Warn, остальные — Info. Без него ошибки идут в slog.Default.
+func WithLogger(logger *slog.Logger) Option {
+	return func(d *DbExplorer) {
+		d.logger = logger
+	}
+}
+
+// WithTracing создаёт OpenTelemetry span на каждый запрос и дочерний span на каждый SQL-запрос,
+// продолжая трассу из входящего заголовка traceparent
+func WithTracing(provider trace.TracerProvider) Option {
+	return func(d *DbExplorer) {
+		d.tracer = provider.Tracer(tracerName)
+	}
+}
+
+// WithCache кэширует ответы GET списков и записей на ttl, храня не больше maxEntries ответов.
+// Запись в таблицу через explorer сбрасывает её ответы; изменения в обход explorer'а видны по истечении ttl.
+func WithCache(ttl time.Duration, maxEntries int) Option {
+	return func(d *DbExplorer) {
+		d.cache = newResponseCache(ttl, maxEntries)
+	}
+}
+
+// WithSoftDelete включает мягкое удаление: columns задаёт для таблицы nullable-колонку времени удаления,
+// например {"users": "deleted_at"}. DELETE проставляет в неё текущее время вместо удаления строки,
+// а чтение пропускает такие записи, если не передан ?include_deleted=true.
+func WithSoftDelete(columns map[string]string) Option {
+	return func(d *DbExplorer) {
+		d.softDelete = columns
+	}
+}
+
+// WithVersionColumn включает оптимистическую блокировку: columns задаёт для таблицы колонку версии,
+// например {"items": "version"} или {"orders": "updated_at"}. Каждое обновление продвигает версию
+// (целое число +1, иначе текущее время), а если клиент прислал версию в теле и она устарела, отвечает 409.
+func WithVersionColumn(columns map[string]string) Option {
+	return func(d *DbExplorer) {
+		d.versionColumns = columns
+	}
+}
+
+// WithPublishers отправляет каждое событие insert/update/delete во внешние системы,
+// например NewKafkaPublisher или NewNATSPublisher. Доставка асинхронная: ошибки только логируются.
+func WithPublishers(publishers ...Publisher) Option {
+	return func(d *DbExplorer) {
+		d.publishers = append(d.publishers, publishers...)
+	}
+}
+
+// WithQueryConsole включает POST /admin/query для произвольных SELECT: не больше maxRows строк
+// и не дольше timeout (0 — 1000 строк и 5 секунд). Запрос читает только таблицы API с правами
+// запроса на чтение, но сам путь "/admin/query" всё равно стоит закрыть через WithAuthorizer или JWT.
+func WithQueryConsole(maxRows int, timeout time.Duration) Option {
+	return func(d *DbExplorer) {
+		if maxRows <= 0 {
+			maxRows = consoleDefaultMaxRows
+		}
+		if timeout <= 0 {
+			timeout = consoleDefaultTimeout
+		}
+		d.console = &queryConsole{maxRows: maxRows, timeout: timeout}
+	}
+}
+
+// WithMaxOpenConns ограничивает число открытых соединений пула *sql.DB; 0 — без ограничения
+func WithMaxOpenConns(n int) Option {
+	return func(d *DbExplorer) {
+		d.db.SetMaxOpenConns(n)
+	}
+}
+
+// WithMaxIdleConns задаёт, сколько простаивающих соединений пул держит открытыми
+func WithMaxIdleConns(n int) Option {
+	return func(d *DbExplorer) {
+		d.db.SetMaxIdleConns(n)
+	}
+}
+
+// WithConnMaxLifetime закрывает соединения старше lifetime, например до таймаута на стороне MySQL
+func WithConnMaxLifetime(lifetime time.Duration) Option {
+	return func(d *DbExplorer) {
+		d.db.SetConnMaxLifetime(lifetime)
+	}
+}
+
+// WithMigrations подключает миграции схемы: с onStart они применяются в NewDbExplorer,
+// а POST /admin/migrate применяет новые без перезапуска
+func WithMigrations(migrations []Migration, onStart bool) Option {
+	return func(d *DbExplorer) {
+		d.migrations = newMigrator(migrations, onStart)
+	}
+}
+
+// WithDDL включает PUT /_tables и POST /{table}/_columns для изменения схемы через API.
+// Предназначено для dev-окружений: права проверяются как на запись, отдельной роли нет.
+func WithDDL(enabled bool) Option {
+	return func(d *DbExplorer) {
+		d.ddl = nil
+		if enabled {
+			d.ddl = &ddlState{}
+		}
+	}
+}
+
+// WithFixtures включает POST /admin/seed, который перезаливает таблицы фикстурами из fsys, см. LoadFixtures
+func WithFixtures(fsys fs.FS) Option {
+	return func(d *DbExplorer) {
+		d.fixtures = fsys
+	}
+}
+
+// WithHook регистрирует хук изменения записей; хуки одного события вызываются в порядке регистрации
+func WithHook(event HookEvent, hook Hook) Option {
+	return func(d *DbExplorer) {
+		if d.hooks == nil {
+			d.hooks = make(map[HookEvent][]Hook)
+		}
+		d.hooks[event] = append(d.hooks[event], hook)
+	}
+}
+
+// WithValidation задаёт правила колонок таблицы, например {"title": {Required: true, MaxLength: 100}};
+// нарушения всех полей перечисляются в ответе 422
+func WithValidation(tableName string, rules map[string]ValidationRule) Option {
+	return func(d *DbExplorer) {
+		if d.validation == nil {
+			d.validation = make(map[string]map[string]ValidationRule)
+		}
+		d.validation[tableName] = rules
+	}
+}
+
+// WithColumnAliases переименовывает колонки таблицы в JSON, например {"user_name": "userName"}.
+// Поле используется в ответах, теле запроса, фильтрах, сортировке и ?fields= в REST, GraphQL и gRPC;
+// исходное имя колонки больше не принимается. Дампы и фикстуры работают с именами колонок.
+func WithColumnAliases(tableName string, aliases map[string]string) Option {
+	return func(d *DbExplorer) {
+		if d.columnAliases == nil {
+			d.columnAliases = make(map[string]map[string]string)
+			d.aliasColumns = make(map[string]map[string]string)
+		}
+		d.columnAliases[tableName] = aliases
+		d.aliasColumns[tableName] = make(map[string]string, len(aliases))
+		for columnName, field := range aliases {
+			d.aliasColumns[tableName][field] = columnName
+		}
+	}
+}
+
+// WithStructuredErrors отдаёт ошибки объектом {"error": {"code", "message", "field", "details"}}
+// вместо строки {"error": "..."}; code не зависит от текста ошибки, его можно разбирать клиентом
+func WithStructuredErrors(enabled bool) Option {
+	return func(d *DbExplorer) {
+		d.structuredErrors = enabled
+	}
+}
+
+// WithUnknownFields задаёт, что делать с полями тела запроса, которых нет в таблице: по умолчанию
+// они отбрасываются молча, RejectUnknownFields отвечает 400 со списком полей, WarnUnknownFields
+// отбрасывает их и перечисляет в "warnings" ответа
+func WithUnknownFields(mode UnknownFields) Option {
+	return func(d *DbExplorer) {
+		d.unknownFields = mode
+	}
+}
+
+// WithJSONAPI отвечает документами JSON:API (jsonapi.org) на запросы с Accept: application/vnd.api+json:
+// записи и списки — объектами ресурсов с relationships по внешним ключам и included для ?include=,
+// ошибки — массивом errors. Тело записи и ответы на запись остаются в обычном формате.
+func WithJSONAPI(enabled bool) Option {
+	return func(d *DbExplorer) {
+		d.jsonAPI = enabled
+	}
+}
+
+// WithPostgRESTFilters включает грамматику запросов PostgREST для списков: ?age=gte.18&name=ilike.*ivan*,
+// in.(1,2), is.null, not.eq.5, а также select= и order=age.desc. Фильтры вида ?age__gte=18 при этом
+// не работают: значение параметра колонки всегда разбирается как operator.value.
+func WithPostgRESTFilters(enabled bool) Option {
+	return func(d *DbExplorer) {
+		d.postgrestFilters = enabled
+	}
+}
+
+// WithHypermedia добавляет в записи и списки ссылки _links в стиле HAL: self, next и prev для страниц,
+// записи по внешним ключам и дочерние таблицы. Списки с ними собираются в памяти, а не потоком.
+func WithHypermedia(enabled bool) Option {
+	return func(d *DbExplorer) {
+		d.hypermedia = enabled
+	}
+}
+
+// WithLinkHeader добавляет к ответам списков заголовок Link (RFC 5988) со ссылками next и prev,
+// как у GitHub API. Как и с WithHypermedia, такие списки собираются в памяти, а не потоком.
+func WithLinkHeader(enabled bool) Option {
+	return func(d *DbExplorer) {
+		d.linkHeader = enabled
+	}
+}
+
+// WithSlowQueryLog пишет в журнал (WithLogger или slog.Default) запросы дольше threshold и хранит
+// последние size из них для GET /admin/slow-queries (0 — 100). Строковые параметры запросов маскируются.
+func WithSlowQueryLog(threshold time.Duration, size int) Option {
+	return func(d *DbExplorer) {
+		if size <= 0 {
+			size = slowQueryDefaultSize
+		}
+		d.slowQueries = &slowQueryLog{threshold: threshold, entries: make([]SlowQuery, size)}
+	}
+}
+
+// WithQueryStats считает в памяти SQL-запросы по таблицам — чтения, записи, ошибки и среднее время —
+// и отдаёт их на GET /admin/stats для простого мониторинга без Prometheus
+func WithQueryStats(enabled bool) Option {
+	return func(d *DbExplorer) {
+		d.queryStats = nil
+		if enabled {
+			d.queryStats = &queryStats{since: time.Now(), tables: map[string]*tableStats{}}
+		}
+	}
+}
+
+// WithRetry повторяет запросы вне транзакций при взаимоблокировках, таймаутах блокировок и, для чтения,
+// обрывах соединения — с паузой config.Backoff, удваивающейся с каждой попыткой. Переподключение
+// к упавшему серверу при этом делает сам пул *sql.DB.
+func WithRetry(config RetryConfig) Option {
+	return func(d *DbExplorer) {
+		d.retry = &config
+	}
+}
+
+// WithCircuitBreaker размыкает цепь после config.Threshold ошибок соединения с базой подряд: запросы
+// сразу получают 503 и Retry-After, а не ждут мёртвый сервер. Через config.Cooldown один запрос
+// проверяет базу Ping'ом и, если она ответила, цепь замыкается.
+func WithCircuitBreaker(config BreakerConfig) Option {
+	return func(d *DbExplorer) {
+		if config.Threshold <= 0 {
+			config.Threshold = breakerDefaultThreshold
+		}
+		if config.Cooldown <= 0 {
+			config.Cooldown = breakerDefaultCooldown
+		}
+		d.breaker = &circuitBreaker{config: config}
+	}
+}
+
+// WithBasePath обслуживает API под префиксом вида "/api/v1": он срезается с пути до разбора таблиц,
+// запросы вне префикса получают 404, шаблоны Routes начинаются с него
+func WithBasePath(prefix string) Option {
+	return func(d *DbExplorer) {
+		d.basePath = strings.TrimSuffix("/"+strings.Trim(prefix, "/"), "/")
+	}
+}
+
+// WithUUIDKeys генерирует UUID версии version (UUIDv4 или UUIDv7) для вставок без первичного ключа
+// в таблицах, где он один и хранит UUID (char(36), uuid, uniqueidentifier); ключ приходит в ответе,
+// как id автоинкремента. Переданный клиентом ключ не заменяется.
+func WithUUIDKeys(version UUIDVersion) Option {
+	return func(d *DbExplorer) {
+		if version != UUIDv7 {
+			version = UUIDv4
+		}
+		d.uuidKeys = version
+	}
+}
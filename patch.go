@@ -0,0 +1,173 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// handlerPatch обновляет запись по RFC 7386 (JSON Merge Patch): отсутствующие поля не трогаются,
+// null очищает колонку, а объекты в JSON-колонках сливаются с текущим значением рекурсивно.
+// С Content-Type: application/json-patch+json тело разбирается как RFC 6902 (JSON Patch).
+func (d DbExplorer) handlerPatch(rw http.ResponseWriter, r *http.Request) {
+	pathParts := strings.Split(r.URL.Path, "/")
+	if len(pathParts) != 3 {
+		responseResult(rw, ErrUnknownTable, http.StatusNotFound, nil)
+		return
+	}
+
+	tableName, err := getTableName(r.URL.Path, d.tableKeys)
+	if err != nil {
+		responseResult(rw, ErrUnknownTable, http.StatusNotFound, nil)
+		return
+	}
+
+	if !d.checkPrimaryKey(rw, tableName) {
+		return
+	}
+
+	var patch map[string]interface{}
+	if strings.HasPrefix(r.Header.Get("Content-Type"), "application/json-patch+json") {
+		patch, err = d.jsonPatchData(tableName, pathParts[2], r.Body)
+	} else {
+		patch, err = d.mergePatchData(tableName, pathParts[2], r.Body)
+	}
+	if err == ErrRecordNotFound {
+		responseResult(rw, err, http.StatusNotFound, nil)
+		return
+	}
+	if err != nil {
+		responseResult(rw, err, bodyErrorStatus(err), nil)
+		return
+	}
+
+	requestData, err := d.validateRecord(tableName, patch)
+	if err != nil {
+		responseResult(rw, err, bodyErrorStatus(err), nil)
+		return
+	}
+
+	tx, err := d.db.BeginTx(d.requestContext(), nil)
+	if err != nil {
+		responseResult(rw, err, http.StatusInternalServerError, nil)
+		return
+	}
+
+	affectedCount, err := d.updateRecord(tx, requestData, tableName, pathParts[2])
+	if err != nil {
+		tx.Rollback()
+		responseResult(rw, err, updateErrorStatus(err), nil)
+		return
+	}
+	d.commitResult(rw, tx, map[string]int{"updated": affectedCount}, func() {
+		if affectedCount > 0 {
+			d.emitUpdate(tableName, pathParts[2], requestData)
+		}
+	})
+}
+
+func (d DbExplorer) mergePatchData(tableName, rawId string, body io.Reader) (map[string]interface{}, error) {
+	patch := make(map[string]interface{})
+	decoder := json.NewDecoder(body)
+	decoder.UseNumber()
+	if err := decoder.Decode(&patch); err != nil {
+		if bodyErrorStatus(err) == http.StatusRequestEntityTooLarge {
+			return nil, err
+		}
+		return nil, errors.New("merge patch must be a JSON object")
+	}
+
+	patch, err := d.toColumns(tableName, patch)
+	if err != nil {
+		return nil, err
+	}
+	if err := d.mergeJSONColumns(tableName, rawId, patch); err != nil {
+		return nil, err
+	}
+	return patch, nil
+}
+
+// mergeJSONColumns подставляет в patch результат слияния для JSON-колонок, в которых пришёл объект
+func (d DbExplorer) mergeJSONColumns(tableName, rawId string, patch map[string]interface{}) error {
+	columns := make([]string, 0)
+	for key, value := range patch {
+		if _, ok := value.(map[string]interface{}); ok && d.columnsInTablesMap[tableName][key].typeName == "json" {
+			columns = append(columns, key)
+		}
+	}
+	if len(columns) == 0 {
+		return nil
+	}
+
+	args := &queryArgs{dialect: d.dialect}
+	condition, err := d.primaryKeyCondition(tableName, rawId, args)
+	if err != nil {
+		return ErrRecordNotFound
+	}
+
+	current, err := d.currentJSONValues(tableName, condition, args, columns)
+	if err != nil {
+		return err
+	}
+
+	for _, column := range columns {
+		patch[column] = mergePatch(current[column], patch[column])
+	}
+	return nil
+}
+
+// currentJSONValues читает текущие значения JSON-колонок записи уже разобранными в interface{}
+func (d DbExplorer) currentJSONValues(tableName, condition string, args *queryArgs, columns []string) (map[string]interface{}, error) {
+	quoted := make([]string, 0, len(columns))
+	for _, column := range columns {
+		quoted = append(quoted, d.dialect.quote(column))
+	}
+
+	query := "SELECT " + strings.Join(quoted, ", ") + " FROM " + d.dialect.quote(tableName) + " WHERE " + condition + ";"
+	queryResult, err := d.traced(d.db, tableName).QueryContext(d.requestContext(), query, args.values...)
+	if err != nil {
+		return nil, err
+	}
+
+	records, err := d.parsingSqlQueryResult(queryResult, tableName)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]interface{}, len(columns))
+	for _, column := range columns {
+		var value interface{}
+		if raw, ok := records[0][column].(json.RawMessage); ok {
+			decoder := json.NewDecoder(bytes.NewReader(raw))
+			decoder.UseNumber()
+			decoder.Decode(&value)
+		}
+		result[column] = value
+	}
+	return result, nil
+}
+
+// mergePatch применяет patch к target по алгоритму из RFC 7386
+func mergePatch(target, patch interface{}) interface{} {
+	patchObject, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+
+	targetObject, ok := target.(map[string]interface{})
+	if !ok {
+		targetObject = make(map[string]interface{})
+	}
+
+	for key, value := range patchObject {
+		if value == nil {
+			delete(targetObject, key)
+			continue
+		}
+		targetObject[key] = mergePatch(targetObject[key], value)
+	}
+	return targetObject
+}
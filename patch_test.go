@@ -0,0 +1,82 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMergePatch(t *testing.T) {
+	target := map[string]interface{}{
+		"title": "Goodbye!",
+		"author": map[string]interface{}{
+			"givenName":  "John",
+			"familyName": "Doe",
+		},
+		"tags": []interface{}{"example", "sample"},
+	}
+	patch := map[string]interface{}{
+		"title": "Hello!",
+		"author": map[string]interface{}{
+			"familyName": nil,
+		},
+		"tags": []interface{}{"example"},
+	}
+
+	expected := map[string]interface{}{
+		"title": "Hello!",
+		"author": map[string]interface{}{
+			"givenName": "John",
+		},
+		"tags": []interface{}{"example"},
+	}
+
+	if result := mergePatch(target, patch); !reflect.DeepEqual(result, expected) {
+		t.Fatalf("results not match\nGot : %#v\nWant: %#v", result, expected)
+	}
+}
+
+func TestApplyJSONPointer(t *testing.T) {
+	document := map[string]interface{}{
+		"tags":  []interface{}{"a", "c"},
+		"owner": map[string]interface{}{"name": "rvasily"},
+	}
+
+	operations := []jsonPatchOperation{
+		{Op: "add", Path: "/payload/tags/1", Value: "b"},
+		{Op: "add", Path: "/payload/tags/-", Value: "d"},
+		{Op: "replace", Path: "/payload/owner/name", Value: "admin"},
+		{Op: "remove", Path: "/payload/owner/name"},
+		{Op: "add", Path: "/payload/a~1b", Value: true},
+	}
+
+	var result interface{} = document
+	for _, operation := range operations {
+		pointer, err := parseJSONPointer(operation.Path)
+		if err != nil {
+			t.Fatalf("[%s] unexpected error: %v", operation.Path, err)
+		}
+		if result, err = applyJSONPointer(result, pointer[1:], operation); err != nil {
+			t.Fatalf("[%s %s] unexpected error: %v", operation.Op, operation.Path, err)
+		}
+	}
+
+	expected := map[string]interface{}{
+		"tags":  []interface{}{"a", "b", "c", "d"},
+		"owner": map[string]interface{}{},
+		"a/b":   true,
+	}
+	if !reflect.DeepEqual(result, expected) {
+		t.Fatalf("results not match\nGot : %#v\nWant: %#v", result, expected)
+	}
+
+	_, err := applyJSONPointer(expected, []string{"missing"}, jsonPatchOperation{Op: "replace", Path: "/payload/missing"})
+	if err == nil || err.Error() != "json patch path /payload/missing not found" {
+		t.Fatalf("expected not found error, got %v", err)
+	}
+
+	// индекс за концом массива допустим только последним сегментом add
+	_, err = applyJSONPointer(expected, []string{"tags", "4", "x"}, jsonPatchOperation{Op: "add", Path: "/payload/tags/4/x", Value: 1})
+	if err == nil || err.Error() != "json patch path /payload/tags/4/x not found" {
+		t.Fatalf("expected not found error, got %v", err)
+	}
+}
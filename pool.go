@@ -0,0 +1,26 @@
+package main
+
+import (
+	"net/http"
+)
+
+// handlerDBStats отдаёт состояние пула соединений: GET /admin/db-stats
+func (d DbExplorer) handlerDBStats(rw http.ResponseWriter, r *http.Request) {
+	if err := d.tableAccess(r, "/admin/db-stats", http.MethodGet); err != nil {
+		responseResult(rw, err, http.StatusForbidden, nil)
+		return
+	}
+
+	stats := d.db.Stats()
+	responseResult(rw, nil, http.StatusOK, map[string]interface{}{
+		"max_open_connections": stats.MaxOpenConnections,
+		"open_connections":     stats.OpenConnections,
+		"in_use":               stats.InUse,
+		"idle":                 stats.Idle,
+		"wait_count":           stats.WaitCount,
+		"wait_duration":        stats.WaitDuration.String(),
+		"max_idle_closed":      stats.MaxIdleClosed,
+		"max_idle_time_closed": stats.MaxIdleTimeClosed,
+		"max_lifetime_closed":  stats.MaxLifetimeClosed,
+	})
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"errors"
+	"net/url"
+	"sort"
+	"strings"
+)
+
+// postgrestOperators — операторы фильтров PostgREST вида ?age=gte.18, кроме like, ilike, in и is
+var postgrestOperators = map[string]string{
+	"eq":  "=",
+	"neq": "<>",
+	"gt":  ">",
+	"gte": ">=",
+	"lt":  "<",
+	"lte": "<=",
+}
+
+// postgrestParams разбирает параметры в грамматике PostgREST, см. WithPostgRESTFilters: select= и order=
+// переводятся в fields и sort, фильтры по колонкам — в условие WHERE. Служебные параметры списка
+// вроде limit и offset остаются как есть.
+func (d DbExplorer) postgrestParams(tableName string, params url.Values, args *queryArgs) (url.Values, string, error) {
+	translated := url.Values{}
+	keys := make([]string, 0, len(params))
+	for key, values := range params {
+		switch {
+		case key == "select":
+			translated.Set("fields", strings.Join(values, ","))
+		case key == "order":
+			order, err := postgrestOrder(strings.Join(values, ","))
+			if err != nil {
+				return nil, "", err
+			}
+			translated.Set("sort", order)
+		case listParams[key] || strings.HasPrefix(key, "$"):
+			translated[key] = values
+		default:
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	conditions := make([]string, 0, len(keys))
+	for _, field := range keys {
+		column, ok := d.columnsInTablesMap[tableName][d.columnName(tableName, field)]
+		if _, sensitive := d.sensitiveColumn(tableName, column.name); !ok || sensitive {
+			return nil, "", errors.New("unknown field " + field)
+		}
+		for _, rawValue := range params[field] {
+			condition, err := d.postgrestCondition(column, field, rawValue, args)
+			if err != nil {
+				return nil, "", err
+			}
+			conditions = append(conditions, condition)
+		}
+	}
+	return translated, strings.Join(conditions, " AND "), nil
+}
+
+// postgrestOrder переводит "age.desc,name.asc" в формат ?sort=: "-age,name"
+func postgrestOrder(order string) (string, error) {
+	fields := make([]string, 0)
+	for _, part := range strings.Split(order, ",") {
+		words := strings.Split(strings.TrimSpace(part), ".")
+		if words[0] == "" {
+			continue
+		}
+		field := words[0]
+		for _, modifier := range words[1:] {
+			switch modifier {
+			case "asc":
+			case "desc":
+				field = "-" + words[0]
+			default:
+				return "", errors.New("unsupported order modifier " + modifier)
+			}
+		}
+		fields = append(fields, field)
+	}
+	return strings.Join(fields, ","), nil
+}
+
+// postgrestCondition строит условие из значения вида "gte.18", "ilike.*ivan*", "in.(1,2)", "is.null"
+// или с отрицанием "not.eq.5"
+func (d DbExplorer) postgrestCondition(column columnParams, field, rawValue string, args *queryArgs) (string, error) {
+	negate := false
+	if strings.HasPrefix(rawValue, "not.") {
+		negate, rawValue = true, strings.TrimPrefix(rawValue, "not.")
+	}
+	operator, value, ok := strings.Cut(rawValue, ".")
+	if !ok {
+		return "", errors.New("invalid filter " + field + "=" + rawValue + ": expected operator.value")
+	}
+
+	quotedColumn := d.dialect.quote(column.name)
+	condition := ""
+	switch operator {
+	case "like", "ilike":
+		if column.typeName != "string" && column.typeName != "enum" && column.typeName != "set" {
+			return "", errors.New("field " + column.name + " does not support " + operator)
+		}
+		pattern := args.add(strings.ReplaceAll(value, "*", "%"))
+		if operator == "ilike" {
+			condition = "LOWER(" + quotedColumn + ") LIKE LOWER(" + pattern + ")"
+		} else {
+			condition = quotedColumn + " LIKE " + pattern
+		}
+	case "in":
+		if !strings.HasPrefix(value, "(") || !strings.HasSuffix(value, ")") {
+			return "", errors.New("invalid filter " + field + "=" + rawValue + ": expected in.(a,b)")
+		}
+		placeholders := make([]string, 0)
+		for _, item := range strings.Split(value[1:len(value)-1], ",") {
+			typed, err := d.filterValue(column, strings.Trim(strings.TrimSpace(item), `"`))
+			if err != nil {
+				return "", err
+			}
+			placeholders = append(placeholders, args.add(typed))
+		}
+		condition = quotedColumn + " IN (" + strings.Join(placeholders, ", ") + ")"
+	case "is":
+		switch value {
+		case "null":
+			condition = quotedColumn + " IS NULL"
+		case "true", "false":
+			typed, err := d.filterValue(column, value)
+			if err != nil {
+				return "", err
+			}
+			condition = quotedColumn + " = " + args.add(typed)
+		default:
+			return "", errors.New("invalid filter " + field + "=" + rawValue + ": is expects null, true or false")
+		}
+	default:
+		sqlOperator, ok := postgrestOperators[operator]
+		if !ok {
+			return "", errors.New("unknown operator " + operator + " for field " + field)
+		}
+		typed, err := d.filterValue(column, value)
+		if err != nil {
+			return "", err
+		}
+		condition = quotedColumn + " " + sqlOperator + " " + args.add(typed)
+	}
+
+	if negate {
+		return "NOT (" + condition + ")", nil
+	}
+	return condition, nil
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"database/sql"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPostgRESTFilters(t *testing.T) {
+	db, err := sql.Open("mysql", DSN)
+	err = db.Ping()
+	if err != nil {
+		panic(err)
+	}
+
+	PrepareTestApis(db)
+	defer CleanupTestApis(db)
+
+	db.Exec("INSERT INTO items (id, title, description) VALUES (3, 'grpc', ''), (4, 'Kafka', '')")
+
+	handler, err := NewDbExplorer(db, WithPostgRESTFilters(true))
+	if err != nil {
+		panic(err)
+	}
+
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	cases := []struct {
+		query  string
+		status int
+		result string
+	}{
+		{
+			query:  "id=gte.2&id=lt.4&select=id",
+			status: http.StatusOK,
+			result: `{"response":{"records":[{"id":2},{"id":3}]}}`,
+		},
+		{
+			query:  "title=ilike.*KAF*&select=id,title",
+			status: http.StatusOK,
+			result: `{"response":{"records":[{"id":4,"title":"Kafka"}]}}`,
+		},
+		{
+			query:  "id=in.(1,3,4)&order=id.desc&select=id&limit=2",
+			status: http.StatusOK,
+			result: `{"response":{"records":[{"id":4},{"id":3}]}}`,
+		},
+		{
+			query:  "updated=is.null&id=not.eq.2&select=id",
+			status: http.StatusOK,
+			result: `{"response":{"records":[{"id":3},{"id":4}]}}`,
+		},
+		{
+			query:  "updated=not.is.null&select=id,updated",
+			status: http.StatusOK,
+			result: `{"response":{"records":[{"id":1,"updated":"rvasily"}]}}`,
+		},
+		{
+			query:  "id=5",
+			status: http.StatusBadRequest,
+			result: `{"error":"invalid filter id=5: expected operator.value"}`,
+		},
+		{
+			query:  "id=between.1",
+			status: http.StatusBadRequest,
+			result: `{"error":"unknown operator between for field id"}`,
+		},
+		{
+			query:  "order=id.nullsfirst",
+			status: http.StatusBadRequest,
+			result: `{"error":"unsupported order modifier nullsfirst"}`,
+		},
+		{
+			query:  "author=eq.rvasily",
+			status: http.StatusBadRequest,
+			result: `{"error":"unknown field author"}`,
+		},
+	}
+
+	for idx, item := range cases {
+		resp, err := client.Get(ts.URL + "/items?" + item.query)
+		if err != nil {
+			t.Fatalf("[case %d] request error: %v", idx, err)
+		}
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if resp.StatusCode != item.status {
+			t.Fatalf("[case %d: %s] expected http status %v, got %v: %s", idx, item.query, item.status, resp.StatusCode, body)
+		}
+		if string(body) != item.result {
+			t.Fatalf("[case %d: %s] results not match\nGot : %s\nWant: %s", idx, item.query, body, item.result)
+		}
+	}
+}
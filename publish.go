@@ -0,0 +1,169 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/segmentio/kafka-go"
+)
+
+const (
+	publisherQueueSize = 1024
+	publishTimeout     = 10 * time.Second
+)
+
+// Publisher доставляет события изменений во внешнюю систему (очередь, шину данных).
+// Publish вызывается из отдельной горутины по одному событию в порядке изменений.
+type Publisher interface {
+	Publish(ctx context.Context, event MutationEvent) error
+}
+
+// publisherQueue отвязывает запись от доставки: переполненная очередь теряет события, но не тормозит API
+type publisherQueue struct {
+	publisher Publisher
+	events    chan MutationEvent
+	logError  func(message string, err error)
+	mu        sync.RWMutex
+	closed    bool
+	stopped   chan struct{}
+}
+
+func newPublisherQueue(publisher Publisher, logError func(message string, err error)) *publisherQueue {
+	queue := &publisherQueue{publisher: publisher, events: make(chan MutationEvent, publisherQueueSize), logError: logError,
+		stopped: make(chan struct{})}
+	go queue.run()
+	return queue
+}
+
+func (q *publisherQueue) run() {
+	defer close(q.stopped)
+	for event := range q.events {
+		ctx, cancel := context.WithTimeout(context.Background(), publishTimeout)
+		err := q.publisher.Publish(ctx, event)
+		cancel()
+		if err != nil {
+			q.logError("event publish failed", err)
+		}
+	}
+}
+
+func (q *publisherQueue) enqueue(event MutationEvent) {
+	q.mu.RLock()
+	defer q.mu.RUnlock()
+	if q.closed {
+		q.logError("event publish failed", errShuttingDown)
+		return
+	}
+	select {
+	case q.events <- event:
+	default:
+		q.logError("event publish failed", errors.New("publisher queue is full, event dropped"))
+	}
+}
+
+// close дожидается отправки накопленных событий; новые после этого отбрасываются
+func (q *publisherQueue) close() {
+	q.mu.Lock()
+	if !q.closed {
+		q.closed = true
+		close(q.events)
+	}
+	q.mu.Unlock()
+	<-q.stopped
+}
+
+// eventKey — ключ события для партиционирования: таблица и значения первичного ключа,
+// чтобы изменения одной записи попадали в одну партицию по порядку
+func eventKey(event MutationEvent) string {
+	keys := make([]string, 0, len(event.ID))
+	for key := range event.ID {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	values := make([]string, 0, len(keys))
+	for _, key := range keys {
+		values = append(values, fmt.Sprintf("%v", event.ID[key]))
+	}
+	return event.Table + "/" + strings.Join(values, ",")
+}
+
+type channelPublisher struct {
+	events chan<- MutationEvent
+}
+
+// NewChannelPublisher отдаёт события в канал, например для обработки в том же процессе
+func NewChannelPublisher(events chan<- MutationEvent) Publisher {
+	return channelPublisher{events: events}
+}
+
+func (p channelPublisher) Publish(ctx context.Context, event MutationEvent) error {
+	select {
+	case p.events <- event:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+type kafkaPublisher struct {
+	writer *kafka.Writer
+}
+
+// NewKafkaPublisher пишет события JSON-сообщениями в топик writer'а; ключ сообщения — таблица и id записи.
+// Закрывать writer после остановки explorer'а должен вызывающий.
+func NewKafkaPublisher(writer *kafka.Writer) Publisher {
+	return kafkaPublisher{writer: writer}
+}
+
+func kafkaMessage(event MutationEvent) (kafka.Message, error) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return kafka.Message{}, err
+	}
+	return kafka.Message{
+		Key:   []byte(eventKey(event)),
+		Value: data,
+		Headers: []kafka.Header{
+			{Key: "type", Value: []byte(event.Type)},
+			{Key: "table", Value: []byte(event.Table)},
+		},
+	}, nil
+}
+
+func (p kafkaPublisher) Publish(ctx context.Context, event MutationEvent) error {
+	message, err := kafkaMessage(event)
+	if err != nil {
+		return err
+	}
+	return p.writer.WriteMessages(ctx, message)
+}
+
+type natsPublisher struct {
+	conn   *nats.Conn
+	prefix string
+}
+
+// NewNATSPublisher публикует события JSON в subject вида <prefix>.<table>.<type>, например dbexplorer.users.update
+func NewNATSPublisher(conn *nats.Conn, prefix string) Publisher {
+	return natsPublisher{conn: conn, prefix: prefix}
+}
+
+func natsSubject(prefix string, event MutationEvent) string {
+	return prefix + "." + event.Table + "." + event.Type
+}
+
+func (p natsPublisher) Publish(ctx context.Context, event MutationEvent) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	return p.conn.Publish(natsSubject(p.prefix, event), data)
+}
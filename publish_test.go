@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestChannelPublisher(t *testing.T) {
+	events := make(chan MutationEvent, 1)
+	explorer := DbExplorer{
+		tableKeys:       []string{"items"},
+		tableIdNamesMap: map[string][]string{"items": {"id"}},
+		events:          newMutationBroker(),
+	}
+	explorer.events.publishers = append(explorer.events.publishers, newPublisherQueue(NewChannelPublisher(events), func(message string, err error) {
+		t.Errorf("%s: %v", message, err)
+	}))
+
+	explorer.emitDelete("items", "7")
+	select {
+	case event := <-events:
+		expected := MutationEvent{Type: "delete", Table: "items", ID: map[string]interface{}{"id": 7}}
+		if !reflect.DeepEqual(event, expected) {
+			t.Fatalf("unexpected event %#v", event)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("timeout waiting for event")
+	}
+}
+
+type failingPublisher struct{}
+
+func (failingPublisher) Publish(ctx context.Context, event MutationEvent) error {
+	return errors.New("broker is down")
+}
+
+func TestPublisherErrors(t *testing.T) {
+	failures := make(chan error, 1)
+	queue := newPublisherQueue(failingPublisher{}, func(message string, err error) {
+		failures <- err
+	})
+
+	queue.enqueue(MutationEvent{Type: "insert", Table: "items"})
+	select {
+	case err := <-failures:
+		if err.Error() != "broker is down" {
+			t.Fatalf("unexpected error %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatalf("timeout waiting for publish error")
+	}
+}
+
+func TestPublisherMessages(t *testing.T) {
+	event := MutationEvent{Type: "update", Table: "orders", ID: map[string]interface{}{"shop_id": 2, "order_id": 10}, Fields: []string{"status"}}
+
+	message, err := kafkaMessage(event)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(message.Key) != "orders/10,2" {
+		t.Fatalf("unexpected key %q", message.Key)
+	}
+	if string(message.Value) != `{"type":"update","table":"orders","id":{"order_id":10,"shop_id":2},"fields":["status"]}` {
+		t.Fatalf("unexpected value %s", message.Value)
+	}
+	if len(message.Headers) != 2 || string(message.Headers[0].Value) != "update" || string(message.Headers[1].Value) != "orders" {
+		t.Fatalf("unexpected headers %v", message.Headers)
+	}
+
+	if subject := natsSubject("dbexplorer", event); subject != "dbexplorer.orders.update" {
+		t.Fatalf("unexpected subject %q", subject)
+	}
+}
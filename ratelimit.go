@@ -0,0 +1,128 @@
+package main
+
+import (
+	"errors"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+var errTooManyRequests = errors.New("too many requests")
+
+// rateLimitClients — сколько клиентских корзин держим, прежде чем выбросить простаивающие
+const rateLimitClients = 10000
+
+// RateLimit — корзина токенов: Rate запросов в секунду с запасом Burst; нулевой Rate отключает лимит
+type RateLimit struct {
+	Rate  float64
+	Burst int
+}
+
+// RateLimitConfig ограничивает частоту запросов ко всему explorer'у и к каждому клиенту отдельно
+type RateLimitConfig struct {
+	Global    RateLimit
+	PerClient RateLimit
+	// ClientKey определяет клиента; по умолчанию — IP из RemoteAddr
+	ClientKey func(r *http.Request) string
+}
+
+func (l RateLimit) burst() float64 {
+	return math.Max(1, float64(l.Burst))
+}
+
+type tokenBucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// take списывает токен; если его нет, возвращает время до появления следующего
+func (b *tokenBucket) take(limit RateLimit, now time.Time) (bool, time.Duration) {
+	burst := limit.burst()
+	if b.last.IsZero() {
+		b.tokens = burst
+	} else {
+		b.tokens = math.Min(burst, b.tokens+now.Sub(b.last).Seconds()*limit.Rate)
+	}
+	b.last = now
+
+	if b.tokens >= 1 {
+		b.tokens--
+		return true, 0
+	}
+	return false, time.Duration((1 - b.tokens) / limit.Rate * float64(time.Second))
+}
+
+// full — корзина успела полностью восстановиться, клиента можно забыть
+func (b *tokenBucket) full(limit RateLimit, now time.Time) bool {
+	return b.tokens+now.Sub(b.last).Seconds()*limit.Rate >= limit.burst()
+}
+
+type rateLimiter struct {
+	config  RateLimitConfig
+	mu      sync.Mutex
+	global  tokenBucket
+	clients map[string]*tokenBucket
+}
+
+func newRateLimiter(config RateLimitConfig) *rateLimiter {
+	return &rateLimiter{config: config, clients: make(map[string]*tokenBucket)}
+}
+
+func (l *rateLimiter) allow(r *http.Request, now time.Time) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.config.PerClient.Rate > 0 {
+		key := l.clientKey(r)
+		bucket, ok := l.clients[key]
+		if !ok {
+			if len(l.clients) >= rateLimitClients {
+				for client, idle := range l.clients {
+					if idle.full(l.config.PerClient, now) {
+						delete(l.clients, client)
+					}
+				}
+			}
+			bucket = &tokenBucket{}
+			l.clients[key] = bucket
+		}
+		if ok, wait := bucket.take(l.config.PerClient, now); !ok {
+			return false, wait
+		}
+	}
+
+	if l.config.Global.Rate > 0 {
+		return l.global.take(l.config.Global, now)
+	}
+	return true, 0
+}
+
+func (l *rateLimiter) clientKey(r *http.Request) string {
+	if l.config.ClientKey != nil {
+		return l.config.ClientKey(r)
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// rateLimit отвечает 429 с Retry-After, если лимит исчерпан; false — ответ уже отправлен
+func (d DbExplorer) rateLimit(rw http.ResponseWriter, r *http.Request) bool {
+	if d.limiter == nil {
+		return true
+	}
+
+	ok, wait := d.limiter.allow(r, time.Now())
+	if ok {
+		return true
+	}
+
+	rw.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+	responseResult(rw, errTooManyRequests, http.StatusTooManyRequests, nil)
+	return false
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestTokenBucket(t *testing.T) {
+	limit := RateLimit{Rate: 2, Burst: 2}
+	bucket := &tokenBucket{}
+	now := time.Unix(1000, 0)
+
+	for i := 0; i < 2; i++ {
+		if ok, _ := bucket.take(limit, now); !ok {
+			t.Fatalf("expected burst request %d to pass", i)
+		}
+	}
+	ok, wait := bucket.take(limit, now)
+	if ok || wait != 500*time.Millisecond {
+		t.Fatalf("expected to wait 500ms, got %v %v", ok, wait)
+	}
+	if ok, _ := bucket.take(limit, now.Add(500*time.Millisecond)); !ok {
+		t.Fatalf("expected token to be refilled")
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	explorer := DbExplorer{dialect: MySQL}
+	WithRateLimit(RateLimitConfig{PerClient: RateLimit{Rate: 0.5, Burst: 1}})(&explorer)
+
+	request := func(remoteAddr string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = remoteAddr
+		rw := httptest.NewRecorder()
+		explorer.ServeHTTP(rw, req)
+		return rw
+	}
+
+	if rw := request("10.0.0.1:1000"); rw.Code != http.StatusOK {
+		t.Fatalf("expected first request to pass, got %d", rw.Code)
+	}
+	rw := request("10.0.0.1:2000")
+	if rw.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected status %d, got %d", http.StatusTooManyRequests, rw.Code)
+	}
+	if retryAfter := rw.Header().Get("Retry-After"); retryAfter != "2" {
+		t.Fatalf("expected Retry-After 2, got %q", retryAfter)
+	}
+	if rw := request("10.0.0.2:1000"); rw.Code != http.StatusOK {
+		t.Fatalf("expected another client to pass, got %d", rw.Code)
+	}
+}
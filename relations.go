@@ -0,0 +1,152 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+)
+
+// foreignKey — внешний ключ из одной колонки: table.column ссылается на refTable.refColumn
+type foreignKey struct {
+	table     string
+	column    string
+	refTable  string
+	refColumn string
+}
+
+// loadForeignKeys читает внешние ключи между отдаваемыми таблицами. Составные ключи пропускаются:
+// вложенный маршрут /{table}/{id}/{child} сопоставляет родителю одну колонку.
+func (d DbExplorer) loadForeignKeys() ([]foreignKey, error) {
+	foreignKeys := make([]foreignKey, 0)
+	query := d.dialect.foreignKeysQuery()
+	if query == "" {
+		return foreignKeys, nil
+	}
+
+	rows, err := d.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	constraints := make([]string, 0)
+	columns := make(map[string][]foreignKey)
+	for rows.Next() {
+		var constraint, table, column, refTable string
+		var refColumn *string
+		if err := rows.Scan(&constraint, &table, &column, &refTable, &refColumn); err != nil {
+			return nil, err
+		}
+		if !containsString(d.tableKeys, table) || !containsString(d.tableKeys, refTable) {
+			continue
+		}
+
+		key := foreignKey{table: table, column: column, refTable: refTable}
+		if refColumn != nil && *refColumn != "" {
+			key.refColumn = *refColumn
+		} else if primaryKeys := d.tableIdNamesMap[refTable]; len(primaryKeys) == 1 {
+			key.refColumn = primaryKeys[0]
+		} else {
+			continue
+		}
+
+		id := table + "\x00" + constraint
+		if _, ok := columns[id]; !ok {
+			constraints = append(constraints, id)
+		}
+		columns[id] = append(columns[id], key)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, id := range constraints {
+		if len(columns[id]) == 1 {
+			foreignKeys = append(foreignKeys, columns[id][0])
+		}
+	}
+	return foreignKeys, nil
+}
+
+// readableRelations отдаёт внешние ключи между таблицами, которые запросу разрешено читать;
+// с непустым tableName — только ключи, в которых таблица ссылается или на которую ссылаются
+func (d DbExplorer) readableRelations(r *http.Request, tableName string) []map[string]interface{} {
+	relations := make([]map[string]interface{}, 0)
+	for _, key := range d.foreignKeys {
+		if tableName != "" && key.table != tableName && key.refTable != tableName {
+			continue
+		}
+		if d.tableAccess(r, key.table, http.MethodGet) != nil || d.tableAccess(r, key.refTable, http.MethodGet) != nil {
+			continue
+		}
+		relations = append(relations, map[string]interface{}{
+			"table":             key.table,
+			"column":            d.fieldName(key.table, key.column),
+			"referenced_table":  key.refTable,
+			"referenced_column": d.fieldName(key.refTable, key.refColumn),
+		})
+	}
+	return relations
+}
+
+// childRelation ищет внешний ключ дочерней таблицы на родительскую; при нескольких берётся первый по имени ограничения
+func (d DbExplorer) childRelation(tableName, childName string) (foreignKey, bool) {
+	for _, key := range d.foreignKeys {
+		if key.table == childName && key.refTable == tableName {
+			return key, true
+		}
+	}
+	return foreignKey{}, false
+}
+
+// handlerNested отдаёт GET /{table}/{id}/{child}: записи дочерней таблицы, ссылающиеся на родителя,
+// с теми же параметрами, что у списка. У существующего родителя без дочерних записей список пуст.
+func (d DbExplorer) handlerNested(rw http.ResponseWriter, r *http.Request, tableName, rawId, childName string) {
+	if !containsString(d.tableKeys, childName) {
+		responseResult(rw, ErrUnknownTable, http.StatusNotFound, nil)
+		return
+	}
+	if err := d.tableAccess(r, childName, http.MethodGet); err != nil {
+		responseResult(rw, err, http.StatusForbidden, nil)
+		return
+	}
+	relation, ok := d.childRelation(tableName, childName)
+	if !ok {
+		responseResult(rw, fmt.Errorf("table %v does not reference %v", childName, tableName), http.StatusNotFound, nil)
+		return
+	}
+	if !d.checkPrimaryKey(rw, tableName) {
+		return
+	}
+
+	if _, err := d.queryRecord(d.db, tableName, rawId, d.dialect.quote(relation.refColumn)); err != nil {
+		responseResult(rw, err, http.StatusNotFound, nil)
+		return
+	}
+
+	list, err := d.parseListQuery(childName, r.URL.Query())
+	if err != nil {
+		responseResult(rw, err, http.StatusBadRequest, nil)
+		return
+	}
+	parentCondition, err := d.primaryKeyCondition(tableName, rawId, list.args)
+	if err != nil {
+		responseResult(rw, ErrRecordNotFound, http.StatusNotFound, nil)
+		return
+	}
+	list.condition = andCondition(list.condition, d.dialect.quote(relation.column)+" IN (SELECT "+
+		d.dialect.quote(relation.refColumn)+" FROM "+d.dialect.quote(tableName)+" WHERE "+parentCondition+")")
+
+	records, err := d.queryList(list)
+	if err == ErrRecordNotFound {
+		records, err = []map[string]interface{}{}, nil
+	}
+	if err != nil {
+		responseResult(rw, err, http.StatusInternalServerError, nil)
+		return
+	}
+	if jsonAPIResponse(rw) {
+		d.responseJSONAPI(rw, r, childName, records, nil)
+		return
+	}
+	responseResult(rw, nil, http.StatusOK, map[string]interface{}{"records": d.toFieldsList(childName, records)})
+}
@@ -0,0 +1,108 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+const (
+	retryDefaultAttempts = 3
+	retryDefaultBackoff  = 50 * time.Millisecond
+)
+
+// RetryConfig — повтор запросов при временных ошибках БД, см. WithRetry
+type RetryConfig struct {
+	// Attempts — сколько всего раз выполнить запрос, включая первый; 0 — 3
+	Attempts int
+	// Backoff — пауза перед первым повтором, дальше она удваивается; 0 — 50ms
+	Backoff time.Duration
+}
+
+// transientError — ошибка, после которой запрос можно выполнить ещё раз. Взаимоблокировку (1213)
+// и таймаут блокировки (1205) сервер откатывает сам, поэтому повторять можно любой запрос.
+// Обрыв соединения повторяется только для чтения: запись могла успеть выполниться.
+func transientError(err error, read bool) bool {
+	var mysqlErr *mysql.MySQLError
+	if errors.As(err, &mysqlErr) {
+		return mysqlErr.Number == 1213 || mysqlErr.Number == 1205
+	}
+	return read && (errors.Is(err, driver.ErrBadConn) || errors.Is(err, mysql.ErrInvalidConn) ||
+		errors.Is(err, syscall.ECONNRESET) || errors.Is(err, syscall.EPIPE) || errors.Is(err, io.ErrUnexpectedEOF))
+}
+
+func readQuery(query string) bool {
+	switch strings.ToUpper(strings.SplitN(strings.TrimSpace(query), " ", 2)[0]) {
+	case "SELECT", "WITH", "EXPLAIN", "SHOW":
+		return true
+	}
+	return false
+}
+
+// retryExecutor повторяет запросы к пулу соединений; внутри транзакции повтор невозможен:
+// после взаимоблокировки сервер откатывает её целиком, поэтому такие запросы сюда не попадают
+type retryExecutor struct {
+	queryExecutor
+	config RetryConfig
+}
+
+// do выполняет attempt, пока он возвращает временную ошибку и остаются попытки; пауза прерывается отменой ctx
+func (e retryExecutor) do(ctx context.Context, query string, attempt func() error) error {
+	attempts, backoff := e.config.Attempts, e.config.Backoff
+	if attempts <= 0 {
+		attempts = retryDefaultAttempts
+	}
+	if backoff <= 0 {
+		backoff = retryDefaultBackoff
+	}
+
+	read := readQuery(query)
+	err := attempt()
+	for i := 1; i < attempts && err != nil && transientError(err, read); i++ {
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+		backoff *= 2
+		err = attempt()
+	}
+	return err
+}
+
+func (e retryExecutor) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	var result sql.Result
+	err := e.do(ctx, query, func() (err error) {
+		result, err = e.queryExecutor.ExecContext(ctx, query, args...)
+		return err
+	})
+	return result, err
+}
+
+func (e retryExecutor) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	var rows *sql.Rows
+	err := e.do(ctx, query, func() (err error) {
+		rows, err = e.queryExecutor.QueryContext(ctx, query, args...)
+		return err
+	})
+	return rows, err
+}
+
+// QueryRow: повторяется, только если ошибка видна сразу; ошибки чтения строки выясняются уже при Scan
+func (e retryExecutor) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	var row *sql.Row
+	e.do(ctx, query, func() error {
+		row = e.queryExecutor.QueryRowContext(ctx, query, args...)
+		return row.Err()
+	})
+	return row
+}
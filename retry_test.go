@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// flakyExecutor возвращает заданные ошибки по очереди, затем отвечает успешно
+type flakyExecutor struct {
+	queryExecutor
+	errs  []error
+	calls *int
+}
+
+func (e flakyExecutor) next() error {
+	*e.calls++
+	if *e.calls <= len(e.errs) {
+		return e.errs[*e.calls-1]
+	}
+	return nil
+}
+
+func (e flakyExecutor) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	if err := e.next(); err != nil {
+		return nil, err
+	}
+	return driver.RowsAffected(1), nil
+}
+
+func (e flakyExecutor) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return nil, e.next()
+}
+
+func TestRetry(t *testing.T) {
+	deadlock := &mysql.MySQLError{Number: 1213, Message: "Deadlock found when trying to get lock"}
+	lockWait := &mysql.MySQLError{Number: 1205, Message: "Lock wait timeout exceeded"}
+	duplicate := &mysql.MySQLError{Number: 1062, Message: "Duplicate entry"}
+	config := RetryConfig{Attempts: 3, Backoff: time.Millisecond}
+
+	cases := []struct {
+		name  string
+		query string
+		errs  []error
+		calls int
+		fails bool
+	}{
+		{"deadlock on update", "UPDATE items SET title = ?", []error{deadlock, lockWait}, 3, false},
+		{"attempts exhausted", "SELECT * FROM items", []error{deadlock, deadlock, deadlock}, 3, true},
+		{"lost connection on read", "SELECT * FROM items", []error{mysql.ErrInvalidConn}, 2, false},
+		{"lost connection on write", "INSERT INTO items (title) VALUES (?)", []error{mysql.ErrInvalidConn}, 1, true},
+		{"not transient", "INSERT INTO items (title) VALUES (?)", []error{duplicate}, 1, true},
+	}
+
+	for _, item := range cases {
+		calls := 0
+		executor := retryExecutor{queryExecutor: flakyExecutor{errs: item.errs, calls: &calls}, config: config}
+		var err error
+		if readQuery(item.query) {
+			_, err = executor.QueryContext(context.Background(), item.query)
+		} else {
+			_, err = executor.ExecContext(context.Background(), item.query)
+		}
+		if calls != item.calls || (err != nil) != item.fails {
+			t.Fatalf("[%s] expected %d calls and failure %v, got %d calls and %v", item.name, item.calls, item.fails, calls, err)
+		}
+	}
+
+	// отмена запроса прерывает паузу перед повтором
+	calls := 0
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	executor := retryExecutor{queryExecutor: flakyExecutor{errs: []error{deadlock}, calls: &calls}, config: RetryConfig{Backoff: time.Hour}}
+	if _, err := executor.ExecContext(ctx, "DELETE FROM items"); err != deadlock || calls != 1 {
+		t.Fatalf("canceled request must not be retried, got %d calls and %v", calls, err)
+	}
+}
+
+func TestRetryOutsideTransactions(t *testing.T) {
+	db, err := sql.Open("mysql", DSN)
+	err = db.Ping()
+	if err != nil {
+		panic(err)
+	}
+
+	PrepareTestApis(db)
+	defer CleanupTestApis(db)
+
+	explorer, err := NewDbExplorer(db, WithRetry(RetryConfig{}))
+	if err != nil {
+		panic(err)
+	}
+
+	if _, ok := explorer.traced(db, "items").(retryExecutor); !ok {
+		t.Fatalf("queries to the pool must be retried")
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatalf("begin: %v", err)
+	}
+	defer tx.Rollback()
+	if _, ok := explorer.traced(tx, "items").(retryExecutor); ok {
+		t.Fatalf("queries inside a transaction must not be retried")
+	}
+}
@@ -0,0 +1,118 @@
+package main
+
+import (
+	"io/fs"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// Route — маршрут explorer'а в синтаксисе шаблонов http.ServeMux из Go 1.22; параметры {table}
+// и {id} понимают и chi, и gorilla/mux, а {$} и {path...} для них заменяются на "/" и "/*".
+// Handler — сам explorer: разбор пути остаётся за ним, поэтому роутер должен передавать путь
+// без префикса монтирования (но с префиксом WithBasePath).
+type Route struct {
+	Method  string
+	Pattern string
+	Handler http.Handler
+}
+
+// Routes возвращает маршруты с учётом настроек explorer'а: запись, DDL, миграции и фикстуры
+// попадают в список, только если включены. HEAD обслуживается шаблонами GET, OPTIONS — любым путём.
+// Шаблоны начинаются с префикса WithBasePath, если он задан.
+func (d DbExplorer) Routes() []Route {
+	routes := d.routes()
+	for i := range routes {
+		routes[i].Pattern = d.basePath + routes[i].Pattern
+	}
+	return routes
+}
+
+// routes — маршруты без префикса WithBasePath, в том виде, в каком explorer разбирает путь
+func (d DbExplorer) routes() []Route {
+	routes := make([]Route, 0)
+	add := func(method string, patterns ...string) {
+		for _, pattern := range patterns {
+			routes = append(routes, Route{Method: method, Pattern: pattern, Handler: d})
+		}
+	}
+
+	add(http.MethodGet, "/{$}", "/openapi.json", "/_events", "/admin/db-stats", "/admin/dump",
+		"/ui", "/ui/{$}", "/graphql",
+		"/{table}", "/{table}/schema", "/{table}/aggregate", "/{table}/distinct/{column}", "/{table}/dump", "/{table}/{id}")
+	// файлы админки перечисляются явно: шаблон /ui/{file} пересекался бы с /{table}/schema
+	files, _ := fs.ReadDir(uiFiles, "ui")
+	for _, file := range files {
+		add(http.MethodGet, "/ui/"+file.Name())
+	}
+	if d.audit != nil {
+		add(http.MethodGet, "/admin/audit")
+	}
+	if d.slowQueries != nil {
+		add(http.MethodGet, "/admin/slow-queries")
+	}
+	if d.queryStats != nil {
+		add(http.MethodGet, "/admin/stats")
+	}
+	if len(d.softDelete) > 0 {
+		add(http.MethodGet, "/{table}/trash")
+	}
+	if len(d.foreignKeys) > 0 {
+		add(http.MethodGet, "/{table}/{id}/{child}")
+	}
+	add(http.MethodPost, "/graphql")
+	if d.console != nil {
+		add(http.MethodPost, "/admin/query")
+	}
+
+	if d.dialect.writable() && !d.readOnly {
+		add(http.MethodPut, "/{table}")
+		add(http.MethodPost, "/transaction", "/batch", "/{table}", "/{table}/import", "/{table}/{id}")
+		add(http.MethodPatch, "/{table}/{id}")
+		add(http.MethodDelete, "/{table}/{id}")
+		if len(d.softDelete) > 0 {
+			add(http.MethodPost, "/{table}/{id}/restore")
+		}
+		if d.migrations != nil {
+			add(http.MethodPost, "/admin/migrate")
+		}
+		if d.fixtures != nil {
+			add(http.MethodPost, "/admin/seed")
+		}
+		if d.ddl != nil {
+			add(http.MethodPut, "/_tables")
+			add(http.MethodPost, "/{table}/_columns")
+		}
+	}
+
+	add(http.MethodOptions, "/{path...}")
+	return routes
+}
+
+// hasPathPrefix — путь равен prefix или продолжается после него новым сегментом
+func hasPathPrefix(path, prefix string) bool {
+	return path == prefix || strings.HasPrefix(path, prefix+"/")
+}
+
+// stripPathPrefix возвращает копию запроса без prefix в начале пути, как http.StripPrefix
+func stripPathPrefix(r *http.Request, prefix string) *http.Request {
+	stripped := new(http.Request)
+	*stripped = *r
+	stripped.URL = new(url.URL)
+	*stripped.URL = *r.URL
+	stripped.URL.Path = strings.TrimPrefix(r.URL.Path, prefix)
+	stripped.URL.RawPath = strings.TrimPrefix(r.URL.RawPath, prefix)
+	if stripped.URL.Path == "" {
+		stripped.URL.Path = "/"
+	}
+	return stripped
+}
+
+// Mount регистрирует маршруты explorer'а в mux под префиксом вида "/api"; запросы к остальным
+// методам mux отклоняет сам с 405 и заголовком Allow
+func (d DbExplorer) Mount(mux *http.ServeMux, prefix string) {
+	prefix = strings.TrimSuffix(prefix, "/")
+	for _, route := range d.Routes() {
+		mux.Handle(route.Method+" "+prefix+route.Pattern, http.StripPrefix(prefix, route.Handler))
+	}
+}
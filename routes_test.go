@@ -0,0 +1,148 @@
+package main
+
+import (
+	"database/sql"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestRoutes(t *testing.T) {
+	full := DbExplorer{
+		dialect:     MySQL,
+		audit:       NewAuditWriter(ioutil.Discard),
+		softDelete:  map[string]string{"items": "deleted_at"},
+		foreignKeys: []foreignKey{{table: "order_items", column: "item_id", refTable: "items", refColumn: "id"}},
+		console:     &queryConsole{},
+		migrations:  &migrator{},
+		ddl:         &ddlState{},
+		fixtures:    fstest.MapFS{},
+	}
+	// ServeMux паникует на пересекающихся шаблонах, так что регистрация всех маршрутов — уже проверка
+	full.Mount(http.NewServeMux(), "/api/")
+
+	readOnly := full
+	readOnly.readOnly = true
+	for _, route := range readOnly.Routes() {
+		if route.Method != http.MethodGet && route.Method != http.MethodOptions && route.Pattern != "/graphql" && route.Pattern != "/admin/query" {
+			t.Fatalf("read-only explorer exposes %s %s", route.Method, route.Pattern)
+		}
+	}
+
+	db, err := sql.Open("mysql", DSN)
+	err = db.Ping()
+	if err != nil {
+		panic(err)
+	}
+
+	PrepareTestApis(db)
+	defer CleanupTestApis(db)
+
+	handler, err := NewDbExplorer(db)
+	if err != nil {
+		panic(err)
+	}
+
+	mux := http.NewServeMux()
+	handler.Mount(mux, "/api")
+	ts := httptest.NewServer(mux)
+	defer ts.Close()
+
+	cases := []struct {
+		method string
+		path   string
+		status int
+		body   string
+	}{
+		{http.MethodGet, "/api/", http.StatusOK, `{"response":{"tables":["items","users"]}}`},
+		{http.MethodGet, "/api/items/1", http.StatusOK, `{"response":{"record":{"description":"Рассказать про базы данных","id":1,"title":"database/sql","updated":"rvasily"}}}`},
+		{http.MethodGet, "/api/unknown_table", http.StatusNotFound, `{"error":"unknown table"}`},
+		{http.MethodPost, "/api/items/1", http.StatusOK, `{"response":{"updated":1}}`},
+		{http.MethodOptions, "/api/items", http.StatusNoContent, ``},
+		{http.MethodDelete, "/api/items", http.StatusMethodNotAllowed, "Method Not Allowed\n"},
+		{http.MethodGet, "/items", http.StatusNotFound, "404 page not found\n"},
+	}
+
+	for idx, item := range cases {
+		req, _ := http.NewRequest(item.method, ts.URL+item.path, strings.NewReader(`{"title":"net/http"}`))
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("[%d] request error: %v", idx, err)
+		}
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if resp.StatusCode != item.status {
+			t.Fatalf("[%d] expected http status %v, got %v", idx, item.status, resp.StatusCode)
+		}
+		if string(body) != item.body {
+			t.Fatalf("[%d] results not match\nGot : %s\nWant: %s", idx, body, item.body)
+		}
+	}
+}
+
+func TestBasePath(t *testing.T) {
+	db, err := sql.Open("mysql", DSN)
+	err = db.Ping()
+	if err != nil {
+		panic(err)
+	}
+
+	PrepareTestApis(db)
+	defer CleanupTestApis(db)
+
+	handler, err := NewDbExplorer(db, WithBasePath("/api/v1/"), WithHypermedia(true))
+	if err != nil {
+		panic(err)
+	}
+	for _, route := range handler.Routes() {
+		if !strings.HasPrefix(route.Pattern, "/api/v1/") {
+			t.Fatalf("route %s %s must start with base path", route.Method, route.Pattern)
+		}
+	}
+
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	cases := []struct {
+		path   string
+		status int
+		body   string
+	}{
+		{"/api/v1", http.StatusOK, `{"response":{"tables":["items","users"]}}`},
+		{"/api/v1/items/1?fields=id", http.StatusOK, `{"response":{"record":{"_links":{"self":{"href":"/api/v1/items/1"}},"id":1}}}`},
+		{"/api/v1/unknown_table", http.StatusNotFound, `{"error":"unknown table"}`},
+		{"/items/1", http.StatusNotFound, `{"error":"not found"}`},
+		{"/api/v10/items", http.StatusNotFound, `{"error":"not found"}`},
+	}
+
+	for idx, item := range cases {
+		resp, err := client.Get(ts.URL + item.path)
+		if err != nil {
+			t.Fatalf("[%d] request error: %v", idx, err)
+		}
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if resp.StatusCode != item.status {
+			t.Fatalf("[%d] expected http status %v, got %v", idx, item.status, resp.StatusCode)
+		}
+		if string(body) != item.body {
+			t.Fatalf("[%d] results not match\nGot : %s\nWant: %s", idx, body, item.body)
+		}
+	}
+
+	// у нескольких баз общий префикс стоит перед именем базы
+	multi, err := NewMultiDbExplorer(map[string]*sql.DB{"main": db}, WithBasePath("/api"))
+	if err != nil {
+		panic(err)
+	}
+	rw := httptest.NewRecorder()
+	multi.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, "/api/main/items/1?fields=id", nil))
+	if want := `{"response":{"record":{"id":1}}}`; rw.Code != http.StatusOK || rw.Body.String() != want {
+		t.Fatalf("multi explorer must serve under base path, got %v: %s", rw.Code, rw.Body.String())
+	}
+}
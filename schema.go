@@ -0,0 +1,105 @@
+package main
+
+import "sync"
+
+// schemaSnapshot — результат интроспекции базы, см. loadSchema
+type schemaSnapshot struct {
+	tableKeys          []string
+	columnsInTablesMap map[string]map[string]columnParams
+	columnKeysMap      map[string][]string
+	tableIdNamesMap    map[string][]string
+	foreignKeys        []foreignKey
+}
+
+// schemaState общий для всех копий explorer'а: после миграций схема подменяется целиком
+type schemaState struct {
+	mu       sync.RWMutex
+	snapshot *schemaSnapshot
+}
+
+func (s *schemaState) load() *schemaSnapshot {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.snapshot
+}
+
+func (s *schemaState) store(snapshot *schemaSnapshot) {
+	s.mu.Lock()
+	s.snapshot = snapshot
+	s.mu.Unlock()
+}
+
+// withSchema переносит в копию explorer'а актуальную схему; запрос работает с ней до конца,
+// даже если схема обновится посередине
+func (d DbExplorer) withSchema() DbExplorer {
+	if d.schema == nil {
+		return d
+	}
+	snapshot := d.schema.load()
+	d.tableKeys = snapshot.tableKeys
+	d.columnsInTablesMap = snapshot.columnsInTablesMap
+	d.columnKeysMap = snapshot.columnKeysMap
+	d.tableIdNamesMap = snapshot.tableIdNamesMap
+	d.foreignKeys = snapshot.foreignKeys
+	return d
+}
+
+// refreshSchema перечитывает схему базы после изменения DDL и сбрасывает кеш ответов её таблиц
+func (d DbExplorer) refreshSchema() error {
+	if d.schema == nil {
+		return nil
+	}
+	snapshot, err := d.loadSchema()
+	if err != nil {
+		return err
+	}
+	previous := d.schema.load()
+	d.schema.store(snapshot)
+
+	if d.cache != nil {
+		for _, tableName := range append(append([]string{}, previous.tableKeys...), snapshot.tableKeys...) {
+			d.cache.invalidate(tableName)
+		}
+	}
+	return nil
+}
+
+// tableSchema отдаёт закешированные при старте метаданные колонок таблицы в порядке их объявления
+func (d DbExplorer) tableSchema(tableName string) map[string]interface{} {
+	columns := make([]map[string]interface{}, 0, len(d.columnKeysMap[tableName]))
+	for _, columnName := range d.columnKeysMap[tableName] {
+		column := d.columnsInTablesMap[tableName][columnName]
+
+		columnSchema := map[string]interface{}{
+			"name":     d.fieldName(tableName, column.name),
+			"type":     column.typeName,
+			"db_type":  column.dbType,
+			"nullable": column.isNull,
+			"primary":  column.primary,
+			"default":  column.dbDefault,
+			"comment":  column.comment,
+		}
+		if len(column.enumValues) > 0 {
+			columnSchema["values"] = column.enumValues
+		}
+		if column.computed {
+			columnSchema["read_only"] = true
+		}
+		if field := d.fieldName(tableName, column.name); field != column.name {
+			columnSchema["column"] = column.name
+		}
+
+		columns = append(columns, columnSchema)
+	}
+
+	primaryKeys := make([]string, 0, len(d.tableIdNamesMap[tableName]))
+	for _, columnName := range d.tableIdNamesMap[tableName] {
+		primaryKeys = append(primaryKeys, d.fieldName(tableName, columnName))
+	}
+
+	return map[string]interface{}{
+		"table":       tableName,
+		"primary_key": primaryKeys,
+		"columns":     columns,
+	}
+}
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func TestTableSchema(t *testing.T) {
+	d := DbExplorer{
+		dialect:   MySQL,
+		tableKeys: []string{"items"},
+		columnKeysMap: map[string][]string{
+			"items": {"id", "title"},
+		},
+		tableIdNamesMap: map[string][]string{
+			"items": {"id"},
+		},
+		columnsInTablesMap: map[string]map[string]columnParams{
+			"items": {
+				"id":    {name: "id", typeName: "int", dbType: "int(11)", primary: true},
+				"title": {name: "title", typeName: "string", dbType: "varchar(255)", isNull: true, dbDefault: "none", comment: "заголовок"},
+			},
+		},
+	}
+
+	rw := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/items/schema", nil)
+	d.ServeHTTP(rw, req)
+
+	var result interface{}
+	if err := json.Unmarshal(rw.Body.Bytes(), &result); err != nil {
+		t.Fatalf("cant unpack json: %v", err)
+	}
+
+	var expected interface{}
+	data, _ := json.Marshal(CR{
+		"response": CR{
+			"table":       "items",
+			"primary_key": []string{"id"},
+			"columns": []CR{
+				CR{"name": "id", "type": "int", "db_type": "int(11)", "nullable": false, "primary": true, "default": nil, "comment": ""},
+				CR{"name": "title", "type": "string", "db_type": "varchar(255)", "nullable": true, "primary": false, "default": "none", "comment": "заголовок"},
+			},
+		},
+	})
+	json.Unmarshal(data, &expected)
+
+	if !reflect.DeepEqual(result, expected) {
+		t.Fatalf("results not match\nGot : %#v\nWant: %#v", result, expected)
+	}
+}
+
+func TestRelations(t *testing.T) {
+	d := DbExplorer{
+		dialect:   MySQL,
+		tableKeys: []string{"items", "orders", "users"},
+		columnKeysMap: map[string][]string{
+			"orders": {"id", "user_id"},
+			"users":  {"id"},
+		},
+		tableIdNamesMap: map[string][]string{
+			"items":  {"id"},
+			"orders": {"id"},
+			"users":  {"id"},
+		},
+		columnsInTablesMap: map[string]map[string]columnParams{
+			"orders": {
+				"id":      {name: "id", typeName: "int", dbType: "int(11)", primary: true},
+				"user_id": {name: "user_id", typeName: "int", dbType: "int(11)"},
+			},
+			"users": {
+				"id": {name: "id", typeName: "int", dbType: "int(11)", primary: true},
+			},
+		},
+		foreignKeys: []foreignKey{{table: "orders", column: "user_id", refTable: "users", refColumn: "id"}},
+	}
+	relations := `[{"column":"user_id","referenced_column":"id","referenced_table":"users","table":"orders"}]`
+
+	cases := []struct {
+		path   string
+		result string
+	}{
+		{"/", `{"response":{"relations":` + relations + `,"tables":["items","orders","users"]}}`},
+		{"/users/schema", `{"response":{"columns":[{"comment":"","db_type":"int(11)","default":null,"name":"id","nullable":false,"primary":true,"type":"int"}],"primary_key":["id"],"relations":` + relations + `,"table":"users"}}`},
+		{"/items/schema", `{"response":{"columns":[],"primary_key":["id"],"table":"items"}}`},
+	}
+
+	for _, item := range cases {
+		rw := httptest.NewRecorder()
+		d.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, item.path, nil))
+		if body := rw.Body.String(); body != item.result {
+			t.Fatalf("[%s] results not match\nGot : %s\nWant: %s", item.path, body, item.result)
+		}
+	}
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"net/http"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// fixture — записи одной таблицы из файла <table>.json, <table>.yaml или <table>.yml
+type fixture struct {
+	tableName string
+	records   []map[string]interface{}
+}
+
+// readFixtures читает файлы фикстур из корня fsys; каждый файл — массив записей таблицы
+func (d DbExplorer) readFixtures(fsys fs.FS) (map[string]fixture, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	fixtures := make(map[string]fixture)
+	for _, entry := range entries {
+		extension := path.Ext(entry.Name())
+		if entry.IsDir() || extension != ".json" && extension != ".yaml" && extension != ".yml" {
+			continue
+		}
+		tableName := strings.TrimSuffix(entry.Name(), extension)
+		if !containsString(d.tableKeys, tableName) {
+			return nil, errors.New("fixture " + entry.Name() + ": unknown table")
+		}
+		if _, ok := fixtures[tableName]; ok {
+			return nil, errors.New("fixture " + entry.Name() + ": table " + tableName + " has several fixture files")
+		}
+
+		data, err := fs.ReadFile(fsys, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		if extension != ".json" {
+			// YAML переводится в JSON, чтобы числа разбирались в json.Number, как в теле запроса
+			var document interface{}
+			if err := yaml.Unmarshal(data, &document); err != nil {
+				return nil, errors.New("fixture " + entry.Name() + ": " + err.Error())
+			}
+			if data, err = json.Marshal(document); err != nil {
+				return nil, errors.New("fixture " + entry.Name() + ": " + err.Error())
+			}
+		}
+
+		records := make([]map[string]interface{}, 0)
+		decoder := json.NewDecoder(bytes.NewReader(data))
+		decoder.UseNumber()
+		if err := decoder.Decode(&records); err != nil {
+			return nil, errors.New("fixture " + entry.Name() + ": " + err.Error())
+		}
+		fixtures[tableName] = fixture{tableName: tableName, records: records}
+	}
+	return fixtures, nil
+}
+
+// tableOrder упорядочивает таблицы так, чтобы таблица, на которую ссылаются, шла раньше ссылающейся.
+// Ссылки таблицы на себя не учитываются: порядок таких записей задаёт сам файл или дамп.
+func (d DbExplorer) tableOrder(tables []string) ([]string, error) {
+	parents := make(map[string]map[string]bool, len(tables))
+	for _, tableName := range tables {
+		parents[tableName] = make(map[string]bool)
+	}
+	for _, key := range d.foreignKeys {
+		_, child := parents[key.table]
+		_, parent := parents[key.refTable]
+		if child && parent && key.table != key.refTable {
+			parents[key.table][key.refTable] = true
+		}
+	}
+
+	order := make([]string, 0, len(tables))
+	for len(parents) > 0 {
+		ready := make([]string, 0)
+		for tableName, tableParents := range parents {
+			if len(tableParents) == 0 {
+				ready = append(ready, tableName)
+			}
+		}
+		if len(ready) == 0 {
+			cycle := make([]string, 0, len(parents))
+			for tableName := range parents {
+				cycle = append(cycle, tableName)
+			}
+			sort.Strings(cycle)
+			return nil, errors.New("tables have a foreign key cycle: " + strings.Join(cycle, ", "))
+		}
+
+		sort.Strings(ready)
+		for _, tableName := range ready {
+			delete(parents, tableName)
+			for _, tableParents := range parents {
+				delete(tableParents, tableName)
+			}
+		}
+		order = append(order, ready...)
+	}
+	return order, nil
+}
+
+// LoadFixtures заменяет содержимое таблиц, для которых в корне fsys есть файл фикстур: записи
+// удаляются от дочерних таблиц к родительским и вставляются в обратном порядке, в одной транзакции.
+// Первичные ключи берутся из файлов, чтобы ссылки между фикстурами совпадали.
+func (d DbExplorer) LoadFixtures(ctx context.Context, fsys fs.FS) error {
+	d = d.withSchema()
+	d.ctx = ctx
+	_, err := d.loadFixtures(fsys)
+	return err
+}
+
+func (d DbExplorer) loadFixtures(fsys fs.FS) (map[string]int, error) {
+	fixtures, err := d.readFixtures(fsys)
+	if err != nil {
+		return nil, err
+	}
+	tables := make([]string, 0, len(fixtures))
+	for tableName := range fixtures {
+		tables = append(tables, tableName)
+	}
+	order, err := d.tableOrder(tables)
+	if err != nil {
+		return nil, err
+	}
+
+	// записи проверяются до транзакции, чтобы опечатка в файле не стоила удаления данных
+	for _, tableName := range order {
+		for i, record := range fixtures[tableName].records {
+			if err := d.validateFixture(tableName, record); err != nil {
+				return nil, errors.New("fixture " + tableName + " record " + strconv.Itoa(i) + ": " + err.Error())
+			}
+		}
+	}
+
+	tx, err := d.db.BeginTx(d.requestContext(), nil)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := len(order) - 1; i >= 0; i-- {
+		if _, err := d.traced(tx, order[i]).ExecContext(d.requestContext(), "DELETE FROM "+d.dialect.quote(order[i])+";"); err != nil {
+			tx.Rollback()
+			return nil, errors.New("fixture " + order[i] + ": " + err.Error())
+		}
+	}
+
+	inserted := make(map[string]int, len(order))
+	for _, tableName := range order {
+		for i, record := range fixtures[tableName].records {
+			if err := d.insertFixture(tx, tableName, record); err != nil {
+				tx.Rollback()
+				return nil, errors.New("fixture " + tableName + " record " + strconv.Itoa(i) + ": " + err.Error())
+			}
+		}
+		inserted[tableName] = len(fixtures[tableName].records)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, err
+	}
+	if d.cache != nil {
+		for _, tableName := range order {
+			d.cache.invalidate(tableName)
+		}
+	}
+	return inserted, nil
+}
+
+// validateFixture проверяет колонки записи и приводит значения к аргументам SQL на месте
+func (d DbExplorer) validateFixture(tableName string, record map[string]interface{}) error {
+	for columnName := range record {
+		if _, ok := d.columnsInTablesMap[tableName][columnName]; !ok {
+			return errors.New("unknown field " + columnName)
+		}
+	}
+	_, err := d.validateRecord(tableName, record)
+	return err
+}
+
+// insertFixture вставляет проверенную запись с теми колонками, что указаны в файле, включая первичный ключ
+func (d DbExplorer) insertFixture(db queryExecutor, tableName string, data map[string]interface{}) error {
+	columns := sortedKeys(data)
+	args := &queryArgs{dialect: d.dialect}
+	quoted := make([]string, 0, len(columns))
+	placeholders := make([]string, 0, len(columns))
+	for _, columnName := range columns {
+		quoted = append(quoted, d.dialect.quote(columnName))
+		placeholders = append(placeholders, args.add(data[columnName]))
+	}
+
+	query := "INSERT INTO " + d.dialect.quote(tableName) + " (" + strings.Join(quoted, ", ") + ") VALUES (" + strings.Join(placeholders, ", ") + ");"
+	_, err := d.traced(db, tableName).ExecContext(d.requestContext(), query, args.values...)
+	return err
+}
+
+// handlerSeed выполняет POST /admin/seed: перезаливает таблицы фикстурами из WithFixtures
+func (d DbExplorer) handlerSeed(rw http.ResponseWriter, r *http.Request) {
+	if d.fixtures == nil {
+		responseResult(rw, errors.New("fixtures are not configured"), http.StatusNotFound, nil)
+		return
+	}
+	if err := d.tableAccess(r, "/admin/seed", http.MethodPost); err != nil {
+		responseResult(rw, err, http.StatusForbidden, nil)
+		return
+	}
+
+	inserted, err := d.loadFixtures(d.fixtures)
+	if err != nil {
+		responseResult(rw, err, http.StatusInternalServerError, nil)
+		return
+	}
+	responseResult(rw, nil, http.StatusOK, map[string]interface{}{"inserted": inserted})
+}
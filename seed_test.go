@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"testing/fstest"
+)
+
+func TestTableOrder(t *testing.T) {
+	d := DbExplorer{foreignKeys: []foreignKey{
+		{table: "orders", column: "user_id", refTable: "users", refColumn: "id"},
+		{table: "items", column: "order_id", refTable: "orders", refColumn: "id"},
+		{table: "users", column: "invited_by", refTable: "users", refColumn: "id"},
+	}}
+	tables := []string{"items", "orders", "users", "tags"}
+
+	order, err := d.tableOrder(tables)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if expected := []string{"tags", "users", "orders", "items"}; !reflect.DeepEqual(order, expected) {
+		t.Fatalf("order not match\nGot : %v\nWant: %v", order, expected)
+	}
+
+	d.foreignKeys = append(d.foreignKeys, foreignKey{table: "users", column: "last_order_id", refTable: "orders", refColumn: "id"})
+	if _, err := d.tableOrder(tables); err == nil || err.Error() != "tables have a foreign key cycle: items, orders, users" {
+		t.Fatalf("expected cycle error, got %v", err)
+	}
+}
+
+func TestLoadFixtures(t *testing.T) {
+	db, err := sql.Open("mysql", DSN)
+	err = db.Ping()
+	if err != nil {
+		panic(err)
+	}
+
+	PrepareTestApis(db)
+	defer CleanupTestApis(db)
+
+	if _, err := db.Exec(`CREATE TABLE orders (
+  id int(11) NOT NULL AUTO_INCREMENT,
+  user_id int(11) NOT NULL,
+  amount int(11) NOT NULL,
+  PRIMARY KEY (id),
+  CONSTRAINT orders_user FOREIGN KEY (user_id) REFERENCES users (user_id)
+);`); err != nil {
+		panic(err)
+	}
+	defer db.Exec(`DROP TABLE IF EXISTS orders;`)
+
+	fixtures := fstest.MapFS{
+		"orders.yaml": {Data: []byte("- id: 7\n  user_id: 5\n  amount: 100\n- {id: 8, user_id: 5, amount: 250}\n")},
+		"users.json":  {Data: []byte(`[{"user_id": 5, "login": "demo", "password": "demo", "email": "demo@example.com", "info": ""}]`)},
+		"README.md":   {Data: []byte("not a fixture")},
+	}
+
+	handler, err := NewDbExplorer(db, WithFixtures(fixtures))
+	if err != nil {
+		panic(err)
+	}
+
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	cases := []struct {
+		method string
+		path   string
+		status int
+		result string
+	}{
+		{http.MethodPost, "/admin/seed", http.StatusOK, `{"response":{"inserted":{"orders":2,"users":1}}}`},
+		// повторная заливка заменяет записи, а не добавляет к ним
+		{http.MethodPost, "/admin/seed", http.StatusOK, `{"response":{"inserted":{"orders":2,"users":1}}}`},
+		{http.MethodGet, "/users?fields=user_id,login", http.StatusOK, `{"response":{"records":[{"login":"demo","user_id":5}]}}`},
+		{http.MethodGet, "/users/5/orders?fields=id,amount", http.StatusOK, `{"response":{"records":[{"amount":100,"id":7},{"amount":250,"id":8}]}}`},
+	}
+
+	for idx, item := range cases {
+		req, _ := http.NewRequest(item.method, ts.URL+item.path, nil)
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("[case %d] request error: %v", idx, err)
+		}
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if resp.StatusCode != item.status {
+			t.Fatalf("[case %d: %s %s] expected http status %v, got %v: %s", idx, item.method, item.path, item.status, resp.StatusCode, body)
+		}
+		if string(body) != item.result {
+			t.Fatalf("[case %d: %s %s] results not match\nGot : %s\nWant: %s", idx, item.method, item.path, body, item.result)
+		}
+	}
+
+	broken := fstest.MapFS{"users.json": {Data: []byte(`[{"user_id": 9, "nickname": "x"}]`)}}
+	if err := handler.LoadFixtures(context.Background(), broken); err == nil || err.Error() != "fixture users record 0: unknown field nickname" {
+		t.Fatalf("expected unknown field error, got %v", err)
+	}
+	// неудачная заливка откатывается целиком
+	var count int
+	db.QueryRow(`SELECT COUNT(*) FROM users;`).Scan(&count)
+	if count != 1 {
+		t.Fatalf("expected users to stay after failed load, got %d", count)
+	}
+}
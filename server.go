@@ -0,0 +1,35 @@
+package main
+
+import (
+	"crypto/tls"
+	"net/http"
+	"time"
+)
+
+// WriteTimeout не задаём: SSE и потоковая выгрузка держат ответ открытым сколько нужно
+const (
+	serverReadHeaderTimeout = 10 * time.Second
+	serverIdleTimeout       = 2 * time.Minute
+)
+
+// Server возвращает http.Server с explorer'ом в качестве обработчика и безопасными таймаутами
+func (d DbExplorer) Server(addr string) *http.Server {
+	return &http.Server{
+		Addr:              addr,
+		Handler:           d,
+		ReadHeaderTimeout: serverReadHeaderTimeout,
+		IdleTimeout:       serverIdleTimeout,
+	}
+}
+
+// Serve слушает addr по HTTP
+func (d DbExplorer) Serve(addr string) error {
+	return d.Server(addr).ListenAndServe()
+}
+
+// ServeTLS слушает addr по HTTPS с сертификатом и ключом из файлов, не ниже TLS 1.2
+func (d DbExplorer) ServeTLS(addr, certFile, keyFile string) error {
+	server := d.Server(addr)
+	server.TLSConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+	return server.ListenAndServeTLS(certFile, keyFile)
+}
@@ -0,0 +1,15 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestServer(t *testing.T) {
+	server := DbExplorer{}.Server(":8082")
+	if server.Addr != ":8082" || server.Handler == nil {
+		t.Fatalf("unexpected server %+v", server)
+	}
+	if server.ReadHeaderTimeout == 0 || server.IdleTimeout == 0 {
+		t.Fatalf("expected server timeouts to be set")
+	}
+}
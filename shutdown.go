@@ -0,0 +1,114 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+var errShuttingDown = errors.New("server is shutting down")
+
+// lifecycle отслеживает выполняющиеся запросы и фоновые обработчики для Shutdown
+type lifecycle struct {
+	mu       sync.Mutex
+	closing  bool
+	done     chan struct{} // закрывается в начале Shutdown: будит потоки SSE и слушатель binlog
+	drained  chan struct{} // закрывается, когда всё завершилось и очереди публикации дописаны
+	requests sync.WaitGroup
+	workers  sync.WaitGroup
+}
+
+func newLifecycle() *lifecycle {
+	return &lifecycle{done: make(chan struct{}), drained: make(chan struct{})}
+}
+
+// begin учитывает запрос; false — explorer уже останавливается и запрос надо отклонить
+func (l *lifecycle) begin() bool {
+	if l == nil {
+		return true
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closing {
+		return false
+	}
+	l.requests.Add(1)
+	return true
+}
+
+func (l *lifecycle) end() {
+	if l != nil {
+		l.requests.Done()
+	}
+}
+
+// stopping — канал, который закрывается с началом остановки; без lifecycle не закрывается никогда
+func (l *lifecycle) stopping() <-chan struct{} {
+	if l == nil {
+		return nil
+	}
+	return l.done
+}
+
+// startWorker регистрирует фоновый обработчик: его ctx отменяется при остановке, stop вызывается по выходу
+func (l *lifecycle) startWorker(ctx context.Context) (context.Context, func(), error) {
+	if l == nil {
+		return ctx, func() {}, nil
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.closing {
+		return nil, nil, errShuttingDown
+	}
+	l.workers.Add(1)
+
+	ctx, cancel := context.WithCancel(ctx)
+	go func() {
+		select {
+		case <-l.done:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+	return ctx, func() {
+		cancel()
+		l.workers.Done()
+	}, nil
+}
+
+// Shutdown останавливает explorer для плавного перезапуска: новые запросы получают 503, потоки
+// /_events закрываются, ListenBinlog возвращается. Когда выполняющиеся запросы завершатся,
+// очереди WithPublishers отправляют накопленные события и останавливаются. Если ctx истёк раньше,
+// возвращается его ошибка, а остановка продолжается в фоне. Вызывать до http.Server.Shutdown:
+// тот ждёт простоя соединений, а поток SSE сам не завершается. Подготовленных выражений explorer
+// не держит, а пул *sql.DB закрывает его владелец.
+func (d DbExplorer) Shutdown(ctx context.Context) error {
+	l := d.lifecycle
+	if l == nil {
+		return nil
+	}
+
+	l.mu.Lock()
+	if !l.closing {
+		l.closing = true
+		close(l.done)
+		go func() {
+			l.requests.Wait()
+			l.workers.Wait()
+			if d.events != nil {
+				for _, publisher := range d.events.publishers {
+					publisher.close()
+				}
+			}
+			close(l.drained)
+		}()
+	}
+	l.mu.Unlock()
+
+	select {
+	case <-l.drained:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestShutdown(t *testing.T) {
+	db, err := sql.Open("mysql", DSN)
+	err = db.Ping()
+	if err != nil {
+		panic(err)
+	}
+
+	PrepareTestApis(db)
+	defer CleanupTestApis(db)
+
+	started, release := make(chan struct{}), make(chan struct{})
+	events := make(chan MutationEvent)
+	handler, err := NewDbExplorer(db,
+		WithPublishers(NewChannelPublisher(events)),
+		WithHook(BeforeInsert, func(ctx context.Context, table string, id, record map[string]interface{}) error {
+			close(started)
+			<-release
+			return nil
+		}))
+	if err != nil {
+		panic(err)
+	}
+
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	stream, err := client.Get(ts.URL + "/_events")
+	if err != nil {
+		t.Fatalf("events request error: %v", err)
+	}
+	defer stream.Body.Close()
+	reader := bufio.NewReader(stream.Body)
+	if line, _ := reader.ReadString('\n'); line != ": connected\n" {
+		t.Fatalf("unexpected events stream start %q", line)
+	}
+
+	inserted := make(chan int)
+	go func() {
+		req, _ := http.NewRequest(http.MethodPut, ts.URL+"/items", strings.NewReader(`{"title": "grpc", "description": ""}`))
+		resp, err := client.Do(req)
+		if err != nil {
+			inserted <- 0
+			return
+		}
+		resp.Body.Close()
+		inserted <- resp.StatusCode
+	}()
+	<-started
+
+	stopped := make(chan error)
+	go func() {
+		stopped <- handler.Shutdown(context.Background())
+	}()
+
+	select {
+	case err := <-stopped:
+		t.Fatalf("shutdown must wait for in-flight requests, returned %v", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	resp, err := client.Get(ts.URL + "/items/1")
+	if err != nil {
+		t.Fatalf("request error: %v", err)
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if want := `{"error":"server is shutting down"}`; resp.StatusCode != http.StatusServiceUnavailable || string(body) != want {
+		t.Fatalf("new requests must be rejected during shutdown, got %v: %s", resp.StatusCode, body)
+	}
+	// поток событий закрывается сам, не дожидаясь клиента
+	if _, err := ioutil.ReadAll(reader); err != nil {
+		t.Fatalf("events stream must end cleanly: %v", err)
+	}
+
+	close(release)
+	if status := <-inserted; status != http.StatusOK {
+		t.Fatalf("in-flight insert must complete, got status %v", status)
+	}
+	// накопленное событие доставляется до завершения Shutdown
+	if event := <-events; event.Type != "insert" || event.Table != "items" {
+		t.Fatalf("unexpected event %+v", event)
+	}
+	if err := <-stopped; err != nil {
+		t.Fatalf("shutdown error: %v", err)
+	}
+
+	// повторный вызов возвращается сразу: остановка уже завершена
+	if err := handler.Shutdown(context.Background()); err != nil {
+		t.Fatalf("repeated shutdown must succeed: %v", err)
+	}
+}
@@ -0,0 +1,141 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const slowQueryDefaultSize = 100
+
+// SlowQuery — запись журнала медленных запросов, см. WithSlowQueryLog
+type SlowQuery struct {
+	Time     time.Time     `json:"time"`
+	Table    string        `json:"table,omitempty"`
+	Query    string        `json:"query"`
+	Args     []interface{} `json:"args"`
+	Duration string        `json:"duration"`
+	Error    string        `json:"error,omitempty"`
+}
+
+// slowQueryLog — кольцевой буфер последних медленных запросов
+type slowQueryLog struct {
+	mu        sync.Mutex
+	threshold time.Duration
+	entries   []SlowQuery
+	next      int
+	full      bool
+}
+
+func (l *slowQueryLog) add(query SlowQuery) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.entries[l.next] = query
+	l.next = (l.next + 1) % len(l.entries)
+	if l.next == 0 {
+		l.full = true
+	}
+}
+
+// recent возвращает записи от новых к старым
+func (l *slowQueryLog) recent() []SlowQuery {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	count := l.next
+	if l.full {
+		count = len(l.entries)
+	}
+	result := make([]SlowQuery, 0, count)
+	for i := 1; i <= count; i++ {
+		result = append(result, l.entries[(l.next-i+len(l.entries))%len(l.entries)])
+	}
+	return result
+}
+
+// redactArgs оставляет в журнале числа, bool и NULL, а строки и двоичные значения заменяет маской:
+// в них бывают пароли, токены и персональные данные
+func redactArgs(args []interface{}) []interface{} {
+	redacted := make([]interface{}, len(args))
+	for i, arg := range args {
+		switch arg.(type) {
+		case nil, bool, int, int32, int64, uint, uint32, uint64, float32, float64:
+			redacted[i] = arg
+		default:
+			redacted[i] = "***"
+		}
+	}
+	return redacted
+}
+
+// timedExecutor замеряет время каждого запроса для статистики и журнала медленных запросов.
+// Для QueryContext время — до получения первых строк, чтение результата в него не входит.
+type timedExecutor struct {
+	queryExecutor
+	explorer  DbExplorer
+	tableName string
+}
+
+func (e timedExecutor) observe(start time.Time, query string, args []interface{}, err error) {
+	duration := time.Since(start)
+	if e.explorer.queryStats != nil {
+		e.explorer.queryStats.add(e.tableName, query, duration, err)
+	}
+	log := e.explorer.slowQueries
+	if log == nil || duration < log.threshold {
+		return
+	}
+
+	entry := SlowQuery{Time: start, Table: e.tableName, Query: query, Args: redactArgs(args), Duration: duration.String()}
+	if err != nil {
+		entry.Error = err.Error()
+	}
+	log.add(entry)
+
+	logger := e.explorer.logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	logger.Warn("slow query", "table", e.tableName, "duration", duration, "query", query, "args", entry.Args)
+}
+
+func (e timedExecutor) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	start := time.Now()
+	result, err := e.queryExecutor.ExecContext(ctx, query, args...)
+	e.observe(start, query, args, err)
+	return result, err
+}
+
+func (e timedExecutor) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	start := time.Now()
+	rows, err := e.queryExecutor.QueryContext(ctx, query, args...)
+	e.observe(start, query, args, err)
+	return rows, err
+}
+
+func (e timedExecutor) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	start := time.Now()
+	row := e.queryExecutor.QueryRowContext(ctx, query, args...)
+	e.observe(start, query, args, row.Err())
+	return row
+}
+
+// handlerSlowQueries отдаёт последние медленные запросы: GET /admin/slow-queries
+func (d DbExplorer) handlerSlowQueries(rw http.ResponseWriter, r *http.Request) {
+	if d.slowQueries == nil {
+		responseResult(rw, errors.New("slow query log is disabled"), http.StatusNotFound, nil)
+		return
+	}
+	if err := d.tableAccess(r, "/admin/slow-queries", http.MethodGet); err != nil {
+		responseResult(rw, err, http.StatusForbidden, nil)
+		return
+	}
+
+	responseResult(rw, nil, http.StatusOK, map[string]interface{}{
+		"threshold": d.slowQueries.threshold.String(),
+		"queries":   d.slowQueries.recent(),
+	})
+}
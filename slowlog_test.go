@@ -0,0 +1,85 @@
+package main
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"io/ioutil"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestSlowQueryLog(t *testing.T) {
+	db, err := sql.Open("mysql", DSN)
+	err = db.Ping()
+	if err != nil {
+		panic(err)
+	}
+
+	PrepareTestApis(db)
+	defer CleanupTestApis(db)
+
+	out := &bytes.Buffer{}
+	// нулевой порог считает медленным каждый запрос
+	handler, err := NewDbExplorer(db, WithSlowQueryLog(0, 2), WithLogger(slog.New(slog.NewJSONHandler(out, nil))))
+	if err != nil {
+		panic(err)
+	}
+
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	for _, path := range []string{"/items/1", "/items?title=memcache&fields=id", "/users?user_id=1&fields=login"} {
+		resp, err := client.Get(ts.URL + path)
+		if err != nil {
+			t.Fatalf("request error: %v", err)
+		}
+		resp.Body.Close()
+	}
+
+	resp, err := client.Get(ts.URL + "/admin/slow-queries")
+	if err != nil {
+		t.Fatalf("request error: %v", err)
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected http status 200, got %v: %s", resp.StatusCode, body)
+	}
+
+	result := struct {
+		Response struct {
+			Threshold string      `json:"threshold"`
+			Queries   []SlowQuery `json:"queries"`
+		} `json:"response"`
+	}{}
+	if err := json.Unmarshal(body, &result); err != nil {
+		t.Fatalf("bad json: %v: %s", err, body)
+	}
+	queries := result.Response.Queries
+	if result.Response.Threshold != "0s" || len(queries) != 2 {
+		t.Fatalf("expected 2 latest queries with threshold 0s: %s", body)
+	}
+	if queries[0].Table != "users" || queries[1].Table != "items" {
+		t.Fatalf("queries must be newest first: %s", body)
+	}
+	if !strings.Contains(queries[1].Query, "`title` = ?") || queries[1].Args[0] != "***" || queries[1].Args[1] != float64(5) {
+		t.Fatalf("string args must be redacted, numbers kept: %s", body)
+	}
+	if !strings.Contains(out.String(), `"msg":"slow query"`) || strings.Contains(out.String(), "memcache") {
+		t.Fatalf("slow queries must be logged without string args: %s", out.String())
+	}
+
+	disabled, err := NewDbExplorer(db)
+	if err != nil {
+		panic(err)
+	}
+	recorder := httptest.NewRecorder()
+	disabled.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/admin/slow-queries", nil))
+	if want := `{"error":"slow query log is disabled"}`; recorder.Code != http.StatusNotFound || recorder.Body.String() != want {
+		t.Fatalf("expected 404 without WithSlowQueryLog, got %v: %s", recorder.Code, recorder.Body.String())
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+)
+
+// notDeletedCondition возвращает условие, отсекающее мягко удалённые записи таблицы,
+// или пустую строку, если для таблицы не настроен WithSoftDelete
+func (d DbExplorer) notDeletedCondition(tableName string) string {
+	column, ok := d.softDelete[tableName]
+	if !ok {
+		return ""
+	}
+	return d.dialect.quote(column) + " IS NULL"
+}
+
+// visibleCondition — условие для чтения: с ?include_deleted=true мягко удалённые записи тоже видны
+func (d DbExplorer) visibleCondition(tableName string) string {
+	if d.includeDeleted {
+		return ""
+	}
+	return d.notDeletedCondition(tableName)
+}
+
+// withIncludeDeleted возвращает копию explorer'а, которая по ?include_deleted=true читает и удалённые записи
+func (d DbExplorer) withIncludeDeleted(r *http.Request) DbExplorer {
+	d.includeDeleted, _ = strconv.ParseBool(r.URL.Query().Get("include_deleted"))
+	return d
+}
+
+func andCondition(condition, other string) string {
+	switch {
+	case condition == "":
+		return other
+	case other == "":
+		return condition
+	}
+	return "(" + condition + ") AND " + other
+}
+
+// handlerTrash отдаёт GET /{table}/trash: мягко удалённые записи с теми же параметрами, что у списка
+func (d DbExplorer) handlerTrash(rw http.ResponseWriter, r *http.Request, tableName string) {
+	column, ok := d.softDelete[tableName]
+	if !ok {
+		responseResult(rw, errors.New("soft delete is not enabled for table "+tableName), http.StatusNotFound, nil)
+		return
+	}
+
+	d.includeDeleted = true
+	list, err := d.parseListQuery(tableName, r.URL.Query())
+	if err != nil {
+		responseResult(rw, err, http.StatusBadRequest, nil)
+		return
+	}
+	list.condition = andCondition(list.condition, d.dialect.quote(column)+" IS NOT NULL")
+
+	records, err := d.queryList(list)
+	if err != nil {
+		responseResult(rw, err, http.StatusNotFound, nil)
+		return
+	}
+	responseResult(rw, nil, http.StatusOK, map[string]interface{}{"records": d.toFieldsList(tableName, records)})
+}
+
+// handlerRestore выполняет POST /{table}/{id}/restore: снимает отметку мягкого удаления
+func (d DbExplorer) handlerRestore(rw http.ResponseWriter, r *http.Request, tableName, rawId string) {
+	if _, ok := d.softDelete[tableName]; !ok {
+		responseResult(rw, errors.New("soft delete is not enabled for table "+tableName), http.StatusNotFound, nil)
+		return
+	}
+
+	tx, err := d.db.BeginTx(d.requestContext(), nil)
+	if err != nil {
+		responseResult(rw, err, http.StatusInternalServerError, nil)
+		return
+	}
+
+	restored, err := d.restoreRecord(tx, tableName, rawId)
+	if err != nil {
+		tx.Rollback()
+		responseResult(rw, err, http.StatusBadRequest, nil)
+		return
+	}
+	d.commitResult(rw, tx, map[string]int{"restored": restored}, func() {
+		if restored > 0 {
+			d.emit(MutationEvent{Type: "update", Table: tableName, ID: d.primaryKeyValues(tableName, rawId), Fields: []string{d.softDelete[tableName]}})
+		}
+	})
+}
+
+func (d DbExplorer) restoreRecord(db queryExecutor, tableName, id string) (int, error) {
+	args := &queryArgs{dialect: d.dialect}
+	condition, err := d.primaryKeyCondition(tableName, id, args)
+	if err != nil {
+		return 0, err
+	}
+
+	column := d.dialect.quote(d.softDelete[tableName])
+	affectedCount, err := d.execUpdate(db, tableName, column+" = NULL", andCondition(condition, column+" IS NOT NULL"), args)
+	if err == nil && affectedCount > 0 {
+		d.auditSnapshot(db, tableName, id, true)
+	}
+	return affectedCount, err
+}
+
+// checkSoftDelete проверяет, что колонки мягкого удаления есть в таблицах и допускают NULL
+func (d DbExplorer) checkSoftDelete() error {
+	for tableName, columnName := range d.softDelete {
+		column, ok := d.columnsInTablesMap[tableName][columnName]
+		if !ok {
+			return errors.New("soft delete column " + tableName + "." + columnName + " not found")
+		}
+		if !column.isNull {
+			return errors.New("soft delete column " + tableName + "." + columnName + " must be nullable")
+		}
+	}
+	return nil
+}
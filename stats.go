@@ -0,0 +1,83 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// tableStats — счётчики SQL-запросов к одной таблице
+type tableStats struct {
+	reads   int64
+	writes  int64
+	errors  int64
+	latency time.Duration
+}
+
+// queryStats копит счётчики запросов по таблицам с момента запуска, см. WithQueryStats
+type queryStats struct {
+	mu     sync.Mutex
+	since  time.Time
+	tables map[string]*tableStats
+}
+
+// add учитывает запрос: SELECT, WITH и EXPLAIN считаются чтением, остальное — записью.
+// Запросы без таблицы, например из консоли, не учитываются.
+func (s *queryStats) add(tableName, query string, duration time.Duration, err error) {
+	if tableName == "" {
+		return
+	}
+	operation := strings.ToUpper(strings.SplitN(strings.TrimSpace(query), " ", 2)[0])
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stats, ok := s.tables[tableName]
+	if !ok {
+		stats = &tableStats{}
+		s.tables[tableName] = stats
+	}
+	switch operation {
+	case "SELECT", "WITH", "EXPLAIN":
+		stats.reads++
+	default:
+		stats.writes++
+	}
+	if err != nil {
+		stats.errors++
+	}
+	stats.latency += duration
+}
+
+// snapshot возвращает счётчики в виде ответа GET /admin/stats
+func (s *queryStats) snapshot() map[string]interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tables := make(map[string]interface{}, len(s.tables))
+	for tableName, stats := range s.tables {
+		total := stats.reads + stats.writes
+		tables[tableName] = map[string]interface{}{
+			"reads":          stats.reads,
+			"writes":         stats.writes,
+			"errors":         stats.errors,
+			"error_rate":     float64(stats.errors) / float64(total),
+			"avg_latency_ms": float64(stats.latency) / float64(total) / float64(time.Millisecond),
+		}
+	}
+	return map[string]interface{}{"since": s.since.UTC().Format(time.RFC3339), "tables": tables}
+}
+
+// handlerStats отдаёт счётчики запросов по таблицам: GET /admin/stats
+func (d DbExplorer) handlerStats(rw http.ResponseWriter, r *http.Request) {
+	if d.queryStats == nil {
+		responseResult(rw, errors.New("query statistics are disabled"), http.StatusNotFound, nil)
+		return
+	}
+	if err := d.tableAccess(r, "/admin/stats", http.MethodGet); err != nil {
+		responseResult(rw, err, http.StatusForbidden, nil)
+		return
+	}
+
+	responseResult(rw, nil, http.StatusOK, d.queryStats.snapshot())
+}
@@ -0,0 +1,78 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestQueryStats(t *testing.T) {
+	db, err := sql.Open("mysql", DSN)
+	err = db.Ping()
+	if err != nil {
+		panic(err)
+	}
+
+	PrepareTestApis(db)
+	defer CleanupTestApis(db)
+
+	handler, err := NewDbExplorer(db, WithQueryStats(true))
+	if err != nil {
+		panic(err)
+	}
+
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	requests := []struct {
+		method, path, body string
+	}{
+		{http.MethodGet, "/items/1", ""},
+		{http.MethodGet, "/items?count=true", ""},
+		{http.MethodPut, "/items", `{"title": "grpc", "description": ""}`},
+		{http.MethodGet, "/users/1", ""},
+	}
+	for _, item := range requests {
+		req, _ := http.NewRequest(item.method, ts.URL+item.path, strings.NewReader(item.body))
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("request error: %v", err)
+		}
+		resp.Body.Close()
+	}
+
+	resp, err := client.Get(ts.URL + "/admin/stats")
+	if err != nil {
+		t.Fatalf("request error: %v", err)
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected http status 200, got %v: %s", resp.StatusCode, body)
+	}
+
+	result := struct {
+		Response struct {
+			Since  string                        `json:"since"`
+			Tables map[string]map[string]float64 `json:"tables"`
+		} `json:"response"`
+	}{}
+	if err := json.Unmarshal(body, &result); err != nil {
+		t.Fatalf("bad json: %v: %s", err, body)
+	}
+	items, users := result.Response.Tables["items"], result.Response.Tables["users"]
+	if result.Response.Since == "" || len(result.Response.Tables) != 2 {
+		t.Fatalf("expected stats for items and users: %s", body)
+	}
+	// список с count=true — два SELECT: COUNT(*) и страница
+	if items["reads"] != 3 || items["writes"] != 1 || items["errors"] != 0 || items["error_rate"] != 0 {
+		t.Fatalf("bad items stats: %s", body)
+	}
+	if users["reads"] != 1 || users["writes"] != 0 || users["avg_latency_ms"] <= 0 {
+		t.Fatalf("bad users stats: %s", body)
+	}
+}
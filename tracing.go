@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"strings"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const tracerName = "dbexplorer"
+
+// withTracing открывает span запроса, продолжая трассу из заголовка traceparent
+func (d DbExplorer) withTracing(r *http.Request) (*http.Request, DbExplorer) {
+	if d.tracer == nil {
+		return r, d
+	}
+
+	ctx := propagation.TraceContext{}.Extract(r.Context(), propagation.HeaderCarrier(r.Header))
+	attributes := []attribute.KeyValue{
+		attribute.String("http.request.method", r.Method),
+		attribute.String("url.path", r.URL.Path),
+	}
+	route := r.URL.Path
+	if tableName, err := getTableName(r.URL.Path, d.tableKeys); err == nil {
+		attributes = append(attributes, attribute.String("db.sql.table", tableName))
+		// id записи в имя span'а не попадает, иначе имён будет столько же, сколько записей
+		if pathParts := strings.Split(r.URL.Path, "/"); len(pathParts) == 3 && pathParts[2] != "schema" && pathParts[2] != "import" {
+			route = "/" + tableName + "/{id}"
+		}
+	}
+
+	ctx, _ = d.tracer.Start(ctx, r.Method+" "+route, trace.WithSpanKind(trace.SpanKindServer), trace.WithAttributes(attributes...))
+	d.ctx = ctx
+	return r.WithContext(ctx), d
+}
+
+// endRequestSpan дописывает в span запроса статус ответа и число строк и закрывает его
+func (d DbExplorer) endRequestSpan() {
+	if d.tracer == nil || d.requestLog == nil {
+		return
+	}
+
+	span := trace.SpanFromContext(d.ctx)
+	d.requestLog.mu.Lock()
+	status, rows, err := d.requestLog.status, d.requestLog.rows, d.requestLog.err
+	d.requestLog.mu.Unlock()
+	if status == 0 {
+		status = http.StatusOK
+	}
+
+	span.SetAttributes(attribute.Int("http.response.status_code", status), attribute.Int("db.rows", rows))
+	if status >= http.StatusInternalServerError {
+		message := http.StatusText(status)
+		if err != nil {
+			message = err.Error()
+		}
+		span.SetStatus(codes.Error, message)
+	}
+	span.End()
+}
+
+// requestContext — контекст текущего запроса: отмена клиентом прерывает SQL; вне запроса — фоновый
+func (d DbExplorer) requestContext() context.Context {
+	if d.ctx != nil {
+		return d.ctx
+	}
+	return context.Background()
+}
+
+// traced оборачивает db так, что каждый SQL-запрос к таблице становится дочерним span'ом запроса
+// и попадает в статистику, журнал медленных запросов и автомат отключения, если они включены.
+// Запросы вне транзакций повторяются при временных ошибках, см. WithRetry; каждая попытка — отдельный span.
+func (d DbExplorer) traced(db queryExecutor, tableName string) queryExecutor {
+	_, pooled := db.(*sql.DB)
+	if d.breaker != nil {
+		db = breakerExecutor{queryExecutor: db, breaker: d.breaker}
+	}
+	if d.slowQueries != nil || d.queryStats != nil {
+		db = timedExecutor{queryExecutor: db, explorer: d, tableName: tableName}
+	}
+	if d.tracer != nil {
+		db = tracedExecutor{queryExecutor: db, explorer: d, tableName: tableName}
+	}
+	if d.retry != nil && pooled {
+		db = retryExecutor{queryExecutor: db, config: *d.retry}
+	}
+	return db
+}
+
+type tracedExecutor struct {
+	queryExecutor
+	explorer  DbExplorer
+	tableName string
+}
+
+func (e tracedExecutor) start(ctx context.Context, query string) (context.Context, trace.Span) {
+	operation := strings.ToUpper(strings.SplitN(strings.TrimSpace(query), " ", 2)[0])
+	return e.explorer.tracer.Start(ctx, operation+" "+e.tableName,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(
+			attribute.String("db.system", e.explorer.dialect.name()),
+			attribute.String("db.operation", operation),
+			attribute.String("db.sql.table", e.tableName),
+			attribute.String("db.statement", query),
+		))
+}
+
+func finishSQLSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+func (e tracedExecutor) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	ctx, span := e.start(ctx, query)
+	result, err := e.queryExecutor.ExecContext(ctx, query, args...)
+	if err == nil {
+		if affected, affectedErr := result.RowsAffected(); affectedErr == nil {
+			span.SetAttributes(attribute.Int64("db.rows", affected))
+		}
+	}
+	finishSQLSpan(span, err)
+	return result, err
+}
+
+func (e tracedExecutor) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	ctx, span := e.start(ctx, query)
+	rows, err := e.queryExecutor.QueryContext(ctx, query, args...)
+	finishSQLSpan(span, err)
+	return rows, err
+}
+
+// QueryRow: ошибка *sql.Row видна только при Scan, поэтому span фиксирует лишь отправку запроса
+func (e tracedExecutor) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	ctx, span := e.start(ctx, query)
+	row := e.queryExecutor.QueryRowContext(ctx, query, args...)
+	finishSQLSpan(span, row.Err())
+	return row
+}
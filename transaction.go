@@ -0,0 +1,197 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+)
+
+const transactionMaxOperations = 1000
+
+// transactionOperation — шаг POST /transaction: insert записи record в table,
+// update или delete записи id (в формате пути: "42" или "123,456" для составного ключа)
+type transactionOperation struct {
+	Op     string                 `json:"op"`
+	Table  string                 `json:"table"`
+	ID     interface{}            `json:"id"`
+	Record map[string]interface{} `json:"record"`
+}
+
+// operationError — ошибка шага транзакции; status — код ответа, с которым откатывается вся транзакция
+type operationError struct {
+	index  int
+	status int
+	err    error
+}
+
+func (e operationError) Error() string {
+	return "operation " + strconv.Itoa(e.index) + ": " + e.err.Error()
+}
+
+// handlerTransaction выполняет POST /transaction: операции из тела {"operations": [...]}
+// по порядку в одной транзакции. Ответ содержит результат каждой операции в том же порядке;
+// при ошибке любой из них транзакция откатывается, а в ответе указан номер упавшей операции.
+func (d DbExplorer) handlerTransaction(rw http.ResponseWriter, r *http.Request) {
+	operations, err := decodeTransaction(r.Body)
+	if err != nil {
+		responseResult(rw, err, bodyErrorStatus(err), nil)
+		return
+	}
+
+	tx, results, events, err := d.execTransaction(r, operations)
+	if err != nil {
+		transactionError(rw, err)
+		return
+	}
+	d.commitResult(rw, tx, map[string]interface{}{"results": results}, func() {
+		for _, event := range events {
+			d.emit(event)
+		}
+	})
+}
+
+// transactionError отвечает на ошибку execTransaction; для упавшей операции в ответе её номер
+func transactionError(rw http.ResponseWriter, err error) {
+	if opErr, ok := err.(operationError); ok {
+		responseResult(rw, opErr, opErr.status, map[string]interface{}{"failed": opErr.index})
+		return
+	}
+	responseResult(rw, err, http.StatusInternalServerError, nil)
+}
+
+// execTransaction выполняет операции в одной транзакции и возвращает её незакоммиченной вместе
+// с результатами и событиями; при ошибке транзакция уже откачена, ошибка операции — operationError
+func (d DbExplorer) execTransaction(r *http.Request, operations []transactionOperation) (*sql.Tx, []map[string]interface{}, []MutationEvent, error) {
+	// права и формат проверяем до начала транзакции, чтобы не держать её открытой зря
+	records := make([]map[string]interface{}, len(operations))
+	for i, operation := range operations {
+		record, err := d.prepareOperation(r, operation)
+		if err != nil {
+			opErr := err.(operationError)
+			opErr.index = i
+			return nil, nil, nil, opErr
+		}
+		records[i] = record
+	}
+
+	tx, err := d.db.BeginTx(d.requestContext(), nil)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	results := make([]map[string]interface{}, 0, len(operations))
+	events := make([]MutationEvent, 0, len(operations))
+	for i, operation := range operations {
+		rawId := operationId(operation.ID)
+		result := map[string]interface{}{"op": operation.Op, "table": operation.Table}
+
+		switch operation.Op {
+		case "insert":
+			id, err := d.insertRecord(tx, records[i], operation.Table)
+			if err != nil {
+				tx.Rollback()
+				return nil, nil, nil, operationError{index: i, status: http.StatusBadRequest, err: err}
+			}
+			result["id"] = id
+			events = append(events, MutationEvent{Type: "insert", Table: operation.Table, ID: id, Fields: sortedKeys(records[i])})
+		case "update":
+			updated, err := d.updateRecord(tx, records[i], operation.Table, rawId)
+			if err != nil {
+				tx.Rollback()
+				return nil, nil, nil, operationError{index: i, status: updateErrorStatus(err), err: err}
+			}
+			result["updated"] = updated
+			if updated > 0 {
+				events = append(events, MutationEvent{Type: "update", Table: operation.Table, ID: d.primaryKeyValues(operation.Table, rawId), Fields: sortedKeys(records[i])})
+			}
+		case "delete":
+			deleted, err := d.deleteRecord(tx, operation.Table, rawId)
+			if err != nil {
+				tx.Rollback()
+				return nil, nil, nil, operationError{index: i, status: http.StatusBadRequest, err: err}
+			}
+			result["deleted"] = deleted
+			if deleted > 0 {
+				events = append(events, MutationEvent{Type: "delete", Table: operation.Table, ID: d.primaryKeyValues(operation.Table, rawId)})
+			}
+		}
+		results = append(results, result)
+	}
+	return tx, results, events, nil
+}
+
+func decodeTransaction(body io.Reader) ([]transactionOperation, error) {
+	request := struct {
+		Operations []transactionOperation `json:"operations"`
+	}{}
+	decoder := json.NewDecoder(body)
+	decoder.UseNumber()
+	if err := decoder.Decode(&request); err != nil {
+		return nil, err
+	}
+
+	if len(request.Operations) == 0 {
+		return nil, errors.New("operations are required")
+	}
+	if len(request.Operations) > transactionMaxOperations {
+		return nil, errors.New("too many operations, max " + strconv.Itoa(transactionMaxOperations))
+	}
+	return request.Operations, nil
+}
+
+// prepareOperation проверяет таблицу, права и тип операции и приводит record к аргументам SQL
+func (d DbExplorer) prepareOperation(r *http.Request, operation transactionOperation) (map[string]interface{}, error) {
+	method := ""
+	switch operation.Op {
+	case "insert":
+		method = http.MethodPut
+	case "update":
+		method = http.MethodPost
+	case "delete":
+		method = http.MethodDelete
+	default:
+		return nil, operationError{status: http.StatusBadRequest, err: errors.New("unknown op " + operation.Op)}
+	}
+
+	tableName := operation.Table
+	if !containsString(d.tableKeys, tableName) {
+		return nil, operationError{status: http.StatusNotFound, err: ErrUnknownTable}
+	}
+	if err := d.tableAccess(r, tableName, method); err != nil {
+		return nil, operationError{status: http.StatusForbidden, err: err}
+	}
+	if len(d.tableIdNamesMap[tableName]) == 0 {
+		return nil, operationError{status: http.StatusMethodNotAllowed, err: errors.New("table " + tableName + " has no primary key")}
+	}
+
+	if operation.Op != "insert" && operationId(operation.ID) == "" {
+		return nil, operationError{status: http.StatusBadRequest, err: errors.New("id is required")}
+	}
+	if operation.Op == "delete" {
+		return nil, nil
+	}
+	if operation.Record == nil {
+		return nil, operationError{status: http.StatusBadRequest, err: errors.New("record is required")}
+	}
+
+	record, err := d.toColumns(tableName, operation.Record)
+	if err == nil {
+		record, err = d.validateRecord(tableName, record)
+	}
+	if err != nil {
+		return nil, operationError{status: http.StatusBadRequest, err: err}
+	}
+	return record, nil
+}
+
+// operationId принимает id числом ("id": 1) или строкой в формате пути ("id": "1,2")
+func operationId(id interface{}) string {
+	if id == nil {
+		return ""
+	}
+	return fmt.Sprintf("%v", id)
+}
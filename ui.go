@@ -0,0 +1,40 @@
+package main
+
+import (
+	"embed"
+	"io/fs"
+	"net/http"
+	"strings"
+)
+
+//go:embed ui
+var uiFiles embed.FS
+
+// uiHandler раздаёт встроенную админку; данные она берёт из того же API от имени пользователя
+var uiHandler = func() http.Handler {
+	files, err := fs.Sub(uiFiles, "ui")
+	if err != nil {
+		panic(err)
+	}
+	return http.StripPrefix("/ui", http.FileServer(http.FS(files)))
+}()
+
+func isUIPath(path string) bool {
+	return path == "/ui" || strings.HasPrefix(path, "/ui/")
+}
+
+// handlerUI отдаёт статику /ui без аутентификации: ключ API вводится в самой админке
+func (d DbExplorer) handlerUI(rw http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		rw.Header().Set("Allow", "GET, HEAD")
+		responseResult(rw, errMethodNotAllowed, http.StatusMethodNotAllowed, nil)
+		return
+	}
+	if r.URL.Path == "/ui" {
+		http.Redirect(rw, r, "/ui/", http.StatusMovedPermanently)
+		return
+	}
+
+	rw.Header().Set("Content-Security-Policy", "default-src 'self'")
+	uiHandler.ServeHTTP(rw, r)
+}
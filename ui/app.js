@@ -0,0 +1,184 @@
+'use strict';
+
+// Клиент админки: все данные берутся из того же REST API, что и у остальных клиентов
+const api = {
+  base: location.pathname.replace(/\/ui(\/.*)?$/, ''),
+
+  async request(method, path, body) {
+    const headers = { 'Accept': 'application/json' };
+    const key = localStorage.getItem('dbexplorer.apiKey');
+    if (key) headers['X-API-Key'] = key;
+    if (body !== undefined) headers['Content-Type'] = 'application/json';
+
+    const resp = await fetch(this.base + path, { method, headers, body: body === undefined ? undefined : JSON.stringify(body) });
+    const data = resp.status === 204 ? {} : await resp.json();
+    if (data.error) throw new Error(data.error);
+    return data.response;
+  },
+};
+
+const state = { table: null, schema: null, offset: 0, limit: 20, filters: {} };
+
+function el(tag, attrs = {}, ...children) {
+  const node = document.createElement(tag);
+  for (const [key, value] of Object.entries(attrs)) {
+    if (key.startsWith('on')) node.addEventListener(key.slice(2), value);
+    else if (value !== undefined && value !== null && value !== false) node.setAttribute(key, value);
+  }
+  for (const child of children) node.append(child);
+  return node;
+}
+
+function showError(err) {
+  const box = document.getElementById('error');
+  box.textContent = err.message;
+  box.hidden = false;
+  setTimeout(() => { box.hidden = true; }, 5000);
+}
+
+function recordId(record) {
+  return state.schema.primary_key.map((key) => record[key]).join(',');
+}
+
+function formatValue(value) {
+  if (value === null) return 'null';
+  if (typeof value === 'object') return JSON.stringify(value);
+  return String(value);
+}
+
+async function loadTables() {
+  const { tables } = await api.request('GET', '/');
+  const list = document.getElementById('tables');
+  list.replaceChildren(...tables.map((name) => el('li', {}, el('a', {
+    href: '#' + name,
+    class: name === state.table ? 'active' : null,
+  }, name))));
+}
+
+async function openTable(name) {
+  state.table = name;
+  state.offset = 0;
+  state.filters = {};
+  state.schema = await api.request('GET', '/' + encodeURIComponent(name) + '/schema');
+  await loadTables();
+  await renderRows();
+}
+
+async function renderRows() {
+  const params = new URLSearchParams({ limit: state.limit, offset: state.offset, count: 'true' });
+  for (const [key, value] of Object.entries(state.filters)) {
+    if (value !== '') params.set(key, value);
+  }
+
+  let page;
+  try {
+    page = await api.request('GET', '/' + encodeURIComponent(state.table) + '?' + params);
+  } catch (err) {
+    if (err.message !== 'record not found') throw err;
+    page = { records: [], total: 0 };
+  }
+
+  const columns = state.schema.columns.map((column) => column.name);
+  const writable = state.schema.primary_key.length > 0;
+
+  const filters = el('form', { class: 'filters', onsubmit: (event) => {
+    event.preventDefault();
+    state.filters = Object.fromEntries(new FormData(event.target));
+    state.offset = 0;
+    renderRows().catch(showError);
+  } }, ...columns.map((name) => el('input', { name, placeholder: name, value: state.filters[name] || '' })), el('button', {}, 'Filter'));
+
+  const header = el('tr', {}, ...columns.map((name) => el('th', {}, name)), el('th'));
+  const rows = page.records.map((record) => el('tr', {},
+    ...columns.map((name) => el('td', { class: record[name] === null ? 'null' : null, title: formatValue(record[name]) }, formatValue(record[name]))),
+    el('td', {}, writable ? el('button', { onclick: () => renderForm(record) }, 'Edit') : '',
+      writable ? el('button', { class: 'danger', onclick: () => deleteRecord(record) }, 'Delete') : '')));
+
+  const end = Math.min(state.offset + page.records.length, page.total);
+  const pager = el('div', { class: 'pager' },
+    el('button', { disabled: state.offset === 0 ? 'disabled' : null, onclick: () => { state.offset = Math.max(0, state.offset - state.limit); renderRows().catch(showError); } }, 'Prev'),
+    `${page.records.length ? state.offset + 1 : 0}–${end} of ${page.total}`,
+    el('button', { disabled: end >= page.total ? 'disabled' : null, onclick: () => { state.offset += state.limit; renderRows().catch(showError); } }, 'Next'));
+
+  document.getElementById('content').replaceChildren(
+    el('div', { class: 'toolbar' }, el('h2', {}, state.table), writable ? el('button', { onclick: () => renderForm(null) }, 'New record') : ''),
+    filters,
+    el('table', {}, el('thead', {}, header), el('tbody', {}, ...rows)),
+    pager);
+}
+
+function inputFor(column, value) {
+  const attrs = { name: column.name };
+  if (column.values) {
+    return el('select', attrs, ...(column.nullable ? [el('option', { value: '' }, '')] : []),
+      ...column.values.map((option) => el('option', { value: option, selected: option === value ? 'selected' : null }, option)));
+  }
+  if (column.type === 'bool') {
+    return el('input', { ...attrs, type: 'checkbox', checked: value ? 'checked' : null });
+  }
+  if (column.type === 'json' || column.db_type.includes('text')) {
+    const area = el('textarea', attrs);
+    area.value = value === undefined || value === null ? '' : (column.type === 'json' ? JSON.stringify(value, null, 2) : value);
+    return area;
+  }
+  const type = column.type === 'int' || column.type === 'float' ? 'number' : 'text';
+  return el('input', { ...attrs, type, step: column.type === 'float' ? 'any' : null, value: value === undefined || value === null ? '' : value });
+}
+
+function formValue(column, input) {
+  if (column.type === 'bool') return input.checked;
+  if (input.value === '' && column.nullable) return null;
+  if (column.type === 'int' || column.type === 'float') return Number(input.value);
+  if (column.type === 'json') return JSON.parse(input.value || 'null');
+  return input.value;
+}
+
+function renderForm(record) {
+  // первичный ключ существующей записи не редактируется, а у новой заполняется базой
+  const columns = state.schema.columns.filter((column) => !column.primary || (record === null && state.schema.primary_key.length > 1));
+  const form = el('form', { class: 'record', onsubmit: (event) => {
+    event.preventDefault();
+    const data = {};
+    for (const column of columns) data[column.name] = formValue(column, event.target.elements[column.name]);
+    saveRecord(record, data).catch(showError);
+  } });
+  for (const column of columns) {
+    form.append(el('label', {}, column.name + (column.nullable ? '' : ' *')), inputFor(column, record ? record[column.name] : undefined));
+  }
+  form.append(el('span'), el('div', {}, el('button', { type: 'submit' }, 'Save'), ' ', el('button', { type: 'button', onclick: () => renderRows().catch(showError) }, 'Cancel')));
+
+  document.getElementById('content').replaceChildren(
+    el('h2', {}, record ? `${state.table} / ${recordId(record)}` : `New ${state.table}`), form);
+}
+
+async function saveRecord(record, data) {
+  const path = '/' + encodeURIComponent(state.table) + '/';
+  if (record) await api.request('POST', path + recordId(record), data);
+  else await api.request('PUT', path, data);
+  await renderRows();
+}
+
+async function deleteRecord(record) {
+  if (!confirm(`Delete ${state.table} / ${recordId(record)}?`)) return;
+  try {
+    await api.request('DELETE', '/' + encodeURIComponent(state.table) + '/' + recordId(record));
+    await renderRows();
+  } catch (err) {
+    showError(err);
+  }
+}
+
+function route() {
+  const name = decodeURIComponent(location.hash.slice(1));
+  if (name) openTable(name).catch(showError);
+  else loadTables().catch(showError);
+}
+
+const keyInput = document.getElementById('api-key');
+keyInput.value = localStorage.getItem('dbexplorer.apiKey') || '';
+keyInput.addEventListener('change', () => {
+  localStorage.setItem('dbexplorer.apiKey', keyInput.value);
+  route();
+});
+window.addEventListener('hashchange', route);
+route();
@@ -0,0 +1,40 @@
+package main
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestUI(t *testing.T) {
+	explorer := DbExplorer{dialect: MySQL, apiKeys: staticAPIKeys([]string{"secret"})}
+
+	cases := []struct {
+		path        string
+		status      int
+		contentType string
+	}{
+		{"/ui", http.StatusMovedPermanently, ""},
+		{"/ui/", http.StatusOK, "text/html; charset=utf-8"},
+		{"/ui/app.js", http.StatusOK, "text/javascript; charset=utf-8"},
+		{"/ui/style.css", http.StatusOK, "text/css; charset=utf-8"},
+		{"/ui/missing.js", http.StatusNotFound, ""},
+	}
+
+	for idx, item := range cases {
+		rw := httptest.NewRecorder()
+		explorer.ServeHTTP(rw, httptest.NewRequest(http.MethodGet, item.path, nil))
+		if rw.Code != item.status {
+			t.Fatalf("[case %d: %s] expected http status %v, got %v", idx, item.path, item.status, rw.Code)
+		}
+		if item.contentType != "" && rw.Header().Get("Content-Type") != item.contentType {
+			t.Fatalf("[case %d: %s] unexpected content type %q", idx, item.path, rw.Header().Get("Content-Type"))
+		}
+	}
+
+	rw := httptest.NewRecorder()
+	explorer.ServeHTTP(rw, httptest.NewRequest(http.MethodPost, "/ui/", nil))
+	if rw.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("expected http status %v, got %v", http.StatusMethodNotAllowed, rw.Code)
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// UnknownFields — что делать с полями тела запроса, которых нет в таблице, см. WithUnknownFields
+type UnknownFields int
+
+const (
+	IgnoreUnknownFields UnknownFields = iota // поля молча отбрасываются, как раньше
+	RejectUnknownFields                      // запрос получает 400 со списком полей
+	WarnUnknownFields                        // поля отбрасываются, а список приходит в "warnings" ответа
+)
+
+// requestWarnings копит предупреждения текущего запроса, см. withWarnings
+type requestWarnings struct {
+	mu       sync.Mutex
+	messages []string
+}
+
+func (w *requestWarnings) add(message string) {
+	if w == nil {
+		return
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !containsString(w.messages, message) {
+		w.messages = append(w.messages, message)
+	}
+}
+
+// warningsWriter отдаёт предупреждения запроса в responseResult
+type warningsWriter struct {
+	http.ResponseWriter
+	warnings *requestWarnings
+}
+
+func (w warningsWriter) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
+
+func (w warningsWriter) Flush() {
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
+func (d DbExplorer) withWarnings(rw http.ResponseWriter) (http.ResponseWriter, DbExplorer) {
+	if d.unknownFields != WarnUnknownFields {
+		return rw, d
+	}
+	d.warnings = &requestWarnings{}
+	return warningsWriter{ResponseWriter: rw, warnings: d.warnings}, d
+}
+
+// responseWarnings находит предупреждения запроса среди обёрток ответа
+func responseWarnings(rw http.ResponseWriter) []string {
+	for {
+		switch typed := rw.(type) {
+		case warningsWriter:
+			typed.warnings.mu.Lock()
+			defer typed.warnings.mu.Unlock()
+			return append([]string{}, typed.warnings.messages...)
+		case interface{ Unwrap() http.ResponseWriter }:
+			rw = typed.Unwrap()
+		default:
+			return nil
+		}
+	}
+}
+
+// checkUnknownFields применяет WithUnknownFields к полям тела запроса: исходное имя колонки
+// с алиасом тоже считается неизвестным полем
+func (d DbExplorer) checkUnknownFields(tableName string, record map[string]interface{}) error {
+	if d.unknownFields == IgnoreUnknownFields {
+		return nil
+	}
+
+	unknown := make([]string, 0)
+	for field := range record {
+		if _, ok := d.columnsInTablesMap[tableName][d.columnName(tableName, field)]; !ok {
+			unknown = append(unknown, field)
+		}
+	}
+	if len(unknown) == 0 {
+		return nil
+	}
+	sort.Strings(unknown)
+
+	if d.unknownFields == RejectUnknownFields {
+		return &Error{Status: http.StatusBadRequest, Code: "unknown_fields",
+			Message: "unknown fields: " + strings.Join(unknown, ", "), Details: unknown}
+	}
+	for _, field := range unknown {
+		delete(record, field)
+		d.warnings.add("unknown field " + field + " ignored")
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"database/sql"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestUnknownFields(t *testing.T) {
+	db, err := sql.Open("mysql", DSN)
+	err = db.Ping()
+	if err != nil {
+		panic(err)
+	}
+
+	PrepareTestApis(db)
+	defer CleanupTestApis(db)
+
+	cases := []struct {
+		mode   UnknownFields
+		method string
+		path   string
+		body   string
+		status int
+		result string
+	}{
+		{
+			mode:   RejectUnknownFields,
+			method: http.MethodPut,
+			path:   "/items",
+			body:   `{"title":"kafka","description":"","priority":1,"author":"rvasily"}`,
+			status: http.StatusBadRequest,
+			result: `{"error":"unknown fields: author, priority"}`,
+		},
+		{
+			mode:   RejectUnknownFields,
+			method: http.MethodPatch,
+			path:   "/items/1",
+			body:   `{"author":"rvasily"}`,
+			status: http.StatusBadRequest,
+			result: `{"error":"unknown fields: author"}`,
+		},
+		{
+			mode:   RejectUnknownFields,
+			method: http.MethodPost,
+			path:   "/items/1",
+			body:   `{"title":"net/http"}`,
+			status: http.StatusOK,
+			result: `{"response":{"updated":1}}`,
+		},
+		{
+			mode:   WarnUnknownFields,
+			method: http.MethodPut,
+			path:   "/items",
+			body:   `{"title":"kafka","description":"","author":"rvasily"}`,
+			status: http.StatusOK,
+			result: `{"response":{"id":3},"warnings":["unknown field author ignored"]}`,
+		},
+		{
+			mode:   WarnUnknownFields,
+			method: http.MethodPut,
+			path:   "/items",
+			body:   `[{"title":"grpc","description":"","tag":1},{"title":"nats","description":"","tag":2}]`,
+			status: http.StatusOK,
+			result: `{"response":{"ids":[{"id":4},{"id":5}]},"warnings":["unknown field tag ignored"]}`,
+		},
+		{
+			mode:   IgnoreUnknownFields,
+			method: http.MethodPut,
+			path:   "/items",
+			body:   `{"title":"redis","description":"","author":"rvasily"}`,
+			status: http.StatusOK,
+			result: `{"response":{"id":6}}`,
+		},
+	}
+
+	for idx, item := range cases {
+		handler, err := NewDbExplorer(db, WithUnknownFields(item.mode))
+		if err != nil {
+			panic(err)
+		}
+
+		rw := httptest.NewRecorder()
+		req := httptest.NewRequest(item.method, item.path, strings.NewReader(item.body))
+		req.Header.Set("Content-Type", "application/json")
+		handler.ServeHTTP(rw, req)
+		body, _ := ioutil.ReadAll(rw.Body)
+
+		if rw.Code != item.status {
+			t.Fatalf("[case %d: %s %s] expected http status %v, got %v: %s", idx, item.method, item.path, item.status, rw.Code, body)
+		}
+		if string(body) != item.result {
+			t.Fatalf("[case %d: %s %s] results not match\nGot : %s\nWant: %s", idx, item.method, item.path, body, item.result)
+		}
+	}
+}
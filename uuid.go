@@ -0,0 +1,75 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/binary"
+	"encoding/hex"
+	"strings"
+	"time"
+)
+
+// UUIDVersion — версия UUID, которые explorer генерирует для ключей, см. WithUUIDKeys
+type UUIDVersion int
+
+const (
+	UUIDv4 UUIDVersion = 4 // случайный
+	UUIDv7 UUIDVersion = 7 // начинается с времени в миллисекундах: новые ключи идут по порядку и не дробят индекс
+)
+
+// newUUID возвращает UUID в каноническом виде xxxxxxxx-xxxx-xxxx-xxxx-xxxxxxxxxxxx (RFC 9562)
+func newUUID(version UUIDVersion) (string, error) {
+	var uuid [16]byte
+	if _, err := rand.Read(uuid[:]); err != nil {
+		return "", err
+	}
+	if version == UUIDv7 {
+		var millis [8]byte
+		binary.BigEndian.PutUint64(millis[:], uint64(time.Now().UnixMilli()))
+		copy(uuid[:6], millis[2:])
+	}
+	uuid[6] = uuid[6]&0x0f | byte(version)<<4
+	uuid[8] = uuid[8]&0x3f | 0x80
+
+	encoded := hex.EncodeToString(uuid[:])
+	return encoded[:8] + "-" + encoded[8:12] + "-" + encoded[12:16] + "-" + encoded[16:20] + "-" + encoded[20:], nil
+}
+
+// uuidKeyColumn возвращает первичный ключ таблицы, если он один и хранит UUID: char(36), uuid или uniqueidentifier
+func (d DbExplorer) uuidKeyColumn(tableName string) (string, bool) {
+	primaryKeys := d.tableIdNamesMap[tableName]
+	if len(primaryKeys) != 1 {
+		return "", false
+	}
+	// тип может идти с кодировкой: char(36) CHARACTER SET utf8mb4
+	dbType := strings.ToLower(strings.TrimSpace(d.columnsInTablesMap[tableName][primaryKeys[0]].dbType))
+	if i := strings.IndexByte(dbType, ' '); i != -1 {
+		dbType = dbType[:i]
+	}
+	switch dbType {
+	case "char(36)", "uuid", "uniqueidentifier":
+		return primaryKeys[0], true
+	}
+	return "", false
+}
+
+// generateKey подставляет новый UUID в ключ, который клиент не передал, см. WithUUIDKeys.
+// Колонки uuid и uniqueidentifier считаются строковыми, поэтому ключ клиента проверка тела не отбрасывает.
+func (d DbExplorer) generateKey(tableName string, dataMap map[string]interface{}) error {
+	if d.uuidKeys == 0 {
+		return nil
+	}
+	key, ok := d.uuidKeyColumn(tableName)
+	if !ok {
+		return nil
+	}
+	if value, ok := dataMap[key]; ok && value != nil {
+		return nil
+	}
+
+	uuid, err := newUUID(d.uuidKeys)
+	if err != nil {
+		return err
+	}
+	dataMap[key] = uuid
+	return nil
+}
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"testing"
+)
+
+var uuidPattern = regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-([47])[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+
+func TestNewUUID(t *testing.T) {
+	for _, version := range []UUIDVersion{UUIDv4, UUIDv7} {
+		uuid, err := newUUID(version)
+		if err != nil {
+			t.Fatalf("[v%d] unexpected error: %v", version, err)
+		}
+		match := uuidPattern.FindStringSubmatch(uuid)
+		if match == nil || match[1] != string(rune('0'+version)) {
+			t.Fatalf("[v%d] bad uuid %s", version, uuid)
+		}
+	}
+
+	// v7 начинается с времени, поэтому ключи, созданные позже, больше
+	first, _ := newUUID(UUIDv7)
+	second, _ := newUUID(UUIDv7)
+	if first[:8] > second[:8] {
+		t.Fatalf("uuid v7 not ordered: %s > %s", first, second)
+	}
+}
+
+func TestUUIDKeys(t *testing.T) {
+	db, err := sql.Open("mysql", DSN)
+	err = db.Ping()
+	if err != nil {
+		panic(err)
+	}
+
+	qs := []string{
+		"DROP TABLE IF EXISTS sessions;",
+		"CREATE TABLE sessions (token char(36) NOT NULL, login varchar(255) NOT NULL, PRIMARY KEY (token)) ENGINE=InnoDB DEFAULT CHARSET=utf8;",
+	}
+	for _, q := range qs {
+		if _, err := db.Exec(q); err != nil {
+			panic(err)
+		}
+	}
+	defer db.Exec("DROP TABLE IF EXISTS sessions;")
+
+	handler, err := NewDbExplorer(db, WithUUIDKeys(UUIDv7))
+	if err != nil {
+		panic(err)
+	}
+
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	insert := func(body string) (int, map[string]interface{}) {
+		req, _ := http.NewRequest(http.MethodPut, ts.URL+"/sessions/", bytes.NewBufferString(body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("request error: %v", err)
+		}
+		defer resp.Body.Close()
+		result := map[string]interface{}{}
+		json.NewDecoder(resp.Body).Decode(&result)
+		return resp.StatusCode, result
+	}
+
+	status, result := insert(`{"login":"rvasily"}`)
+	response, _ := result["response"].(map[string]interface{})
+	token, _ := response["token"].(string)
+	if status != http.StatusOK || !uuidPattern.MatchString(token) || token[14] != '7' {
+		t.Fatalf("expected generated uuid v7 key, got %v %v", status, result)
+	}
+
+	resp, err := client.Get(ts.URL + "/sessions/" + token)
+	if err != nil {
+		t.Fatalf("request error: %v", err)
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if expected := `{"response":{"record":{"login":"rvasily","token":"` + token + `"}}}`; string(body) != expected {
+		t.Fatalf("results not match\nGot : %s\nWant: %s", body, expected)
+	}
+
+	status, result = insert(`{"token":"6f1c2a9e-3b4d-4e5f-8a7b-1c2d3e4f5a6b","login":"admin"}`)
+	response, _ = result["response"].(map[string]interface{})
+	if status != http.StatusOK || response["token"] != "6f1c2a9e-3b4d-4e5f-8a7b-1c2d3e4f5a6b" {
+		t.Fatalf("expected client key to be kept, got %v %v", status, result)
+	}
+}
+
+func TestSQLiteStringKeys(t *testing.T) {
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		panic(err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	qs := []string{
+		`CREATE TABLE sessions (token uuid NOT NULL PRIMARY KEY, login TEXT NOT NULL);`,
+		`CREATE TABLE tags (name TEXT NOT NULL PRIMARY KEY);`,
+		`CREATE TABLE pairs (a TEXT NOT NULL, b TEXT NOT NULL, PRIMARY KEY (a, b));`,
+	}
+	for _, q := range qs {
+		if _, err := db.Exec(q); err != nil {
+			panic(err)
+		}
+	}
+
+	handler, err := NewDbExplorer(db)
+	if err != nil {
+		panic(err)
+	}
+
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	// ключ типа uuid — строковый: клиент передаёт его сам, а запись адресуется по нему
+	runCases(t, ts, db, []Case{
+		Case{
+			Path:   "/sessions/",
+			Method: http.MethodPut,
+			Body:   CR{"token": "6f1c2a9e-3b4d-4e5f-8a7b-1c2d3e4f5a6b", "login": "rvasily"},
+			Result: CR{
+				"response": CR{"token": "6f1c2a9e-3b4d-4e5f-8a7b-1c2d3e4f5a6b"},
+			},
+		},
+		Case{
+			Path: "/sessions/6f1c2a9e-3b4d-4e5f-8a7b-1c2d3e4f5a6b",
+			Result: CR{
+				"response": CR{
+					"record": CR{"token": "6f1c2a9e-3b4d-4e5f-8a7b-1c2d3e4f5a6b", "login": "rvasily"},
+				},
+			},
+		},
+		Case{
+			Path:   "/sessions/6f1c2a9e-3b4d-4e5f-8a7b-1c2d3e4f5a6b",
+			Method: http.MethodPost,
+			Body:   CR{"login": "admin"},
+			Result: CR{
+				"response": CR{"updated": 1},
+			},
+		},
+		Case{
+			Path:   "/sessions/6f1c2a9e-3b4d-4e5f-8a7b-1c2d3e4f5a6b",
+			Method: http.MethodDelete,
+			Result: CR{
+				"response": CR{"deleted": 1},
+			},
+		},
+		// запятая в обычном ключе — часть значения
+		Case{
+			Path:   "/tags/",
+			Method: http.MethodPut,
+			Body:   CR{"name": "go,sql"},
+			Result: CR{
+				"response": CR{"name": "go,sql"},
+			},
+		},
+		Case{
+			Path: "/tags/go,sql",
+			Result: CR{
+				"response": CR{
+					"record": CR{"name": "go,sql"},
+				},
+			},
+		},
+		// а в составном её нельзя было бы отличить от разделителя
+		Case{
+			Path:   "/pairs/",
+			Method: http.MethodPut,
+			Status: http.StatusUnprocessableEntity,
+			Body:   CR{"a": "x,y", "b": "z"},
+			Result: CR{
+				"error": "field a must not contain a comma",
+				"response": CR{
+					"fields": []CR{CR{"field": "a", "rule": "key", "message": "must not contain a comma"}},
+				},
+			},
+		},
+	})
+}
+
+func TestSQLiteUUIDKeys(t *testing.T) {
+	db, err := sql.Open("sqlite", ":memory:")
+	if err != nil {
+		panic(err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(1)
+
+	if _, err := db.Exec(`CREATE TABLE sessions (token uuid NOT NULL PRIMARY KEY, login TEXT NOT NULL);`); err != nil {
+		panic(err)
+	}
+
+	handler, err := NewDbExplorer(db, WithUUIDKeys(UUIDv4))
+	if err != nil {
+		panic(err)
+	}
+
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	for _, item := range []struct {
+		body  string
+		token string // пустой — ключ генерируется
+	}{
+		{body: `{"login":"rvasily"}`},
+		{body: `{"token":"6f1c2a9e-3b4d-4e5f-8a7b-1c2d3e4f5a6b","login":"admin"}`, token: "6f1c2a9e-3b4d-4e5f-8a7b-1c2d3e4f5a6b"},
+	} {
+		req, _ := http.NewRequest(http.MethodPut, ts.URL+"/sessions/", bytes.NewBufferString(item.body))
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("request error: %v", err)
+		}
+		result := map[string]interface{}{}
+		json.NewDecoder(resp.Body).Decode(&result)
+		resp.Body.Close()
+
+		response, _ := result["response"].(map[string]interface{})
+		token, _ := response["token"].(string)
+		if resp.StatusCode != http.StatusOK || item.token != "" && token != item.token || item.token == "" && (!uuidPattern.MatchString(token) || token[14] != '4') {
+			t.Fatalf("[%s] unexpected key: %v %v", item.body, resp.StatusCode, result)
+		}
+
+		var login string
+		if err := db.QueryRow("SELECT login FROM sessions WHERE token = ?", token).Scan(&login); err != nil {
+			t.Fatalf("[%s] record not stored under key %s: %v", item.body, token, err)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"regexp"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// ValidationRule — правила значения колонки, см. WithValidation. Required на вставке требует
+// поле в теле, на обновлении запрещает null и пустую строку. MaxLength по умолчанию берётся
+// из varchar(N) и проверяется для всех строковых колонок, даже без правил.
+type ValidationRule struct {
+	Required  bool
+	Min       *float64
+	Max       *float64
+	MinLength int
+	MaxLength int
+	Pattern   *regexp.Regexp
+}
+
+// fieldError — нарушенное правило одного поля
+type fieldError struct {
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// validationError перечисляет все нарушенные правила записи; клиент получает 422
+type validationError struct {
+	fields []fieldError
+}
+
+func (e validationError) Error() string {
+	messages := make([]string, 0, len(e.fields))
+	for _, field := range e.fields {
+		messages = append(messages, "field "+field.Field+" "+field.Message)
+	}
+	return strings.Join(messages, "; ")
+}
+
+// validationDetails отдаёт список полей для ответа с ошибкой проверки; nil для остальных ошибок
+func validationDetails(err error) interface{} {
+	var invalid validationError
+	if errors.As(err, &invalid) {
+		return map[string]interface{}{"fields": invalid.fields}
+	}
+	return nil
+}
+
+// columnLength возвращает N из varchar(N) и char(N); 0 — длина не ограничена
+func columnLength(dbType string) int {
+	typeName := strings.ToLower(dbType)
+	open := strings.Index(typeName, "(")
+	if open < 0 || !strings.Contains(typeName[:open], "char") {
+		return 0
+	}
+	end := strings.Index(typeName[open:], ")")
+	if end < 0 {
+		return 0
+	}
+	length, _ := strconv.Atoi(typeName[open+1 : open+end])
+	return length
+}
+
+// checkValidation проверяет, что правила WithValidation относятся к существующим колонкам
+func (d DbExplorer) checkValidation() error {
+	for tableName, rules := range d.validation {
+		for columnName := range rules {
+			if _, ok := d.columnsInTablesMap[tableName][columnName]; !ok {
+				return errors.New("validation rule for " + tableName + "." + columnName + ": column not found")
+			}
+		}
+	}
+	return nil
+}
+
+// ruleErrors проверяет присланные значения записи по правилам колонок в порядке их объявления
+func (d DbExplorer) ruleErrors(tableName string, record map[string]interface{}) []fieldError {
+	fields := make([]fieldError, 0)
+	for _, columnName := range d.columnKeysMap[tableName] {
+		value, ok := record[columnName]
+		if !ok {
+			continue
+		}
+		column := d.columnsInTablesMap[tableName][columnName]
+		rule := d.validation[tableName][columnName]
+		if rule.MaxLength == 0 {
+			rule.MaxLength = column.maxLength
+		}
+
+		switch value := value.(type) {
+		case nil:
+			if rule.Required {
+				fields = append(fields, fieldError{Field: d.fieldName(tableName, columnName), Rule: "required", Message: "is required"})
+			}
+		case string:
+			length := utf8.RuneCountInString(value)
+			switch {
+			case rule.Required && value == "":
+				fields = append(fields, fieldError{Field: d.fieldName(tableName, columnName), Rule: "required", Message: "is required"})
+			case rule.MaxLength > 0 && length > rule.MaxLength:
+				fields = append(fields, fieldError{Field: d.fieldName(tableName, columnName), Rule: "max_length", Message: "must be at most " + strconv.Itoa(rule.MaxLength) + " characters"})
+			case length < rule.MinLength:
+				fields = append(fields, fieldError{Field: d.fieldName(tableName, columnName), Rule: "min_length", Message: "must be at least " + strconv.Itoa(rule.MinLength) + " characters"})
+			case rule.Pattern != nil && !rule.Pattern.MatchString(value):
+				fields = append(fields, fieldError{Field: d.fieldName(tableName, columnName), Rule: "pattern", Message: "must match " + rule.Pattern.String()})
+			}
+		case json.Number:
+			number, err := value.Float64()
+			switch {
+			case err != nil:
+			case rule.Min != nil && number < *rule.Min:
+				fields = append(fields, fieldError{Field: d.fieldName(tableName, columnName), Rule: "min", Message: "must be at least " + strconv.FormatFloat(*rule.Min, 'f', -1, 64)})
+			case rule.Max != nil && number > *rule.Max:
+				fields = append(fields, fieldError{Field: d.fieldName(tableName, columnName), Rule: "max", Message: "must be at most " + strconv.FormatFloat(*rule.Max, 'f', -1, 64)})
+			}
+		}
+	}
+	return fields
+}
+
+// checkRequired при вставке проверяет, что есть все поля с Required
+func (d DbExplorer) checkRequired(tableName string, record map[string]interface{}) error {
+	fields := make([]fieldError, 0)
+	for _, columnName := range d.columnKeysMap[tableName] {
+		if _, ok := record[columnName]; !ok && d.validation[tableName][columnName].Required {
+			fields = append(fields, fieldError{Field: d.fieldName(tableName, columnName), Rule: "required", Message: "is required"})
+		}
+	}
+	if len(fields) > 0 {
+		return validationError{fields}
+	}
+	return nil
+}
+
+// checkKeyValues отклоняет запятые в строковых колонках составного ключа: в пути /table/a,b
+// такую запись нельзя было бы адресовать
+func (d DbExplorer) checkKeyValues(tableName string, record map[string]interface{}) error {
+	primaryKeys := d.tableIdNamesMap[tableName]
+	if len(primaryKeys) < 2 {
+		return nil
+	}
+	fields := make([]fieldError, 0)
+	for _, key := range primaryKeys {
+		if value, ok := record[key].(string); ok && strings.Contains(value, ",") {
+			fields = append(fields, fieldError{Field: d.fieldName(tableName, key), Rule: "key", Message: "must not contain a comma"})
+		}
+	}
+	if len(fields) > 0 {
+		return validationError{fields}
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"database/sql"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+func TestColumnLength(t *testing.T) {
+	cases := map[string]int{
+		"varchar(255)":                255,
+		"VARCHAR(10)":                 10,
+		"char(2)":                     2,
+		"character varying(64)":       64,
+		"nvarchar(max)":               0,
+		"text":                        0,
+		"decimal(10,2)":               0,
+		"varchar(32) CHARACTER SET x": 32,
+	}
+	for dbType, length := range cases {
+		if got := columnLength(dbType); got != length {
+			t.Fatalf("[%s] expected length %d, got %d", dbType, length, got)
+		}
+	}
+}
+
+func TestValidation(t *testing.T) {
+	db, err := sql.Open("mysql", DSN)
+	err = db.Ping()
+	if err != nil {
+		panic(err)
+	}
+
+	PrepareTestApis(db)
+	defer CleanupTestApis(db)
+	db.Exec(`DROP TABLE IF EXISTS products;`)
+	if _, err := db.Exec(`CREATE TABLE products (
+  id int(11) NOT NULL AUTO_INCREMENT,
+  title varchar(10) NOT NULL,
+  price int(11) NOT NULL,
+  code varchar(20) DEFAULT NULL,
+  PRIMARY KEY (id)
+);`); err != nil {
+		panic(err)
+	}
+	defer db.Exec(`DROP TABLE IF EXISTS products;`)
+
+	if _, err := NewDbExplorer(db, WithValidation("products", map[string]ValidationRule{"nosuch": {Required: true}})); err == nil {
+		t.Fatalf("expected error for rule of unknown column")
+	}
+
+	minPrice, maxPrice := 0.0, 1000.0
+	handler, err := NewDbExplorer(db, WithValidation("products", map[string]ValidationRule{
+		"title": {Required: true, MinLength: 2},
+		"price": {Min: &minPrice, Max: &maxPrice},
+		"code":  {Pattern: regexp.MustCompile(`^[A-Z]+$`)},
+	}))
+	if err != nil {
+		panic(err)
+	}
+
+	ts := httptest.NewServer(handler)
+	defer ts.Close()
+
+	cases := []struct {
+		method string
+		path   string
+		body   string
+		status int
+		result string
+	}{
+		{http.MethodPut, "/products/", `{"price":1}`, http.StatusUnprocessableEntity,
+			`{"error":"field title is required","response":{"fields":[{"field":"title","rule":"required","message":"is required"}]}}`},
+		{http.MethodPut, "/products/", `{"title":"x","price":5000,"code":"abc"}`, http.StatusUnprocessableEntity,
+			`{"error":"field title must be at least 2 characters; field price must be at most 1000; field code must match ^[A-Z]+$","response":{"fields":[` +
+				`{"field":"title","rule":"min_length","message":"must be at least 2 characters"},` +
+				`{"field":"price","rule":"max","message":"must be at most 1000"},` +
+				`{"field":"code","rule":"pattern","message":"must match ^[A-Z]+$"}]}}`},
+		{http.MethodPut, "/products/", `{"title":"much too long","price":1}`, http.StatusUnprocessableEntity,
+			`{"error":"field title must be at most 10 characters","response":{"fields":[{"field":"title","rule":"max_length","message":"must be at most 10 characters"}]}}`},
+		{http.MethodPut, "/products/", `{"title":"чайник","price":10,"code":"AB"}`, http.StatusOK,
+			`{"response":{"id":1}}`},
+		{http.MethodPost, "/products/1", `{"title":""}`, http.StatusUnprocessableEntity,
+			`{"error":"field title is required","response":{"fields":[{"field":"title","rule":"required","message":"is required"}]}}`},
+		{http.MethodPost, "/products/1", `{"price":"cheap","code":"x"}`, http.StatusBadRequest,
+			`{"error":"field price have invalid type"}`},
+		{http.MethodPost, "/products/1", `{"price":-1}`, http.StatusUnprocessableEntity,
+			`{"error":"field price must be at least 0","response":{"fields":[{"field":"price","rule":"min","message":"must be at least 0"}]}}`},
+		{http.MethodPost, "/products/1", `{"price":999}`, http.StatusOK,
+			`{"response":{"updated":1}}`},
+		{http.MethodPut, "/items/", `{"title":"` + strings.Repeat("a", 256) + `","description":""}`, http.StatusUnprocessableEntity,
+			`{"error":"field title must be at most 255 characters","response":{"fields":[{"field":"title","rule":"max_length","message":"must be at most 255 characters"}]}}`},
+	}
+
+	for idx, item := range cases {
+		req, _ := http.NewRequest(item.method, ts.URL+item.path, strings.NewReader(item.body))
+		resp, err := client.Do(req)
+		if err != nil {
+			t.Fatalf("[%d] request error: %v", idx, err)
+		}
+		body, _ := ioutil.ReadAll(resp.Body)
+		resp.Body.Close()
+
+		if resp.StatusCode != item.status {
+			t.Fatalf("[%d] expected http status %v, got %v: %s", idx, item.status, resp.StatusCode, body)
+		}
+		if string(body) != item.result {
+			t.Fatalf("[%d] results not match\nGot : %s\nWant: %s", idx, body, item.result)
+		}
+	}
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+)
+
+var errVersionConflict = errors.New("record version conflict")
+
+// versionUpdate убирает колонку версии из данных изменения и возвращает SET, который её продвигает:
+// целочисленная колонка увеличивается на 1, остальные (updated_at) получают текущее время.
+// expected — значение версии, которое прислал клиент; supplied == false, если он её не прислал.
+func (d DbExplorer) versionUpdate(tableName string, data map[string]interface{}) (rest map[string]interface{}, set string, expected interface{}, supplied bool) {
+	columnName, ok := d.versionColumns[tableName]
+	if !ok {
+		return data, "", nil, false
+	}
+
+	rest = make(map[string]interface{}, len(data))
+	for key, value := range data {
+		if key == columnName {
+			expected, supplied = value, true
+			continue
+		}
+		rest[key] = value
+	}
+
+	column := d.dialect.quote(columnName)
+	if d.columnsInTablesMap[tableName][columnName].typeName == "int" {
+		return rest, column + " = " + column + " + 1", expected, supplied
+	}
+	return rest, column + " = CURRENT_TIMESTAMP", expected, supplied
+}
+
+// versionCondition добавляет к WHERE сверку версии с присланной клиентом
+func (d DbExplorer) versionCondition(tableName string, expected interface{}, args *queryArgs) string {
+	if expected == nil {
+		return d.dialect.quote(d.versionColumns[tableName]) + " IS NULL"
+	}
+	return d.dialect.quote(d.versionColumns[tableName]) + " = " + args.add(expected)
+}
+
+// checkVersionColumns проверяет, что колонки версий есть в таблицах и не входят в первичный ключ
+func (d DbExplorer) checkVersionColumns() error {
+	for tableName, columnName := range d.versionColumns {
+		column, ok := d.columnsInTablesMap[tableName][columnName]
+		if !ok {
+			return errors.New("version column " + tableName + "." + columnName + " not found")
+		}
+		if column.primary {
+			return errors.New("version column " + tableName + "." + columnName + " must not be a primary key")
+		}
+	}
+	return nil
+}
+
+// updateErrorStatus выбирает код ответа для ошибки обновления: 409 при конфликте версий
+func updateErrorStatus(err error) int {
+	if err == errVersionConflict {
+		return http.StatusConflict
+	}
+	return mutationErrorStatus(err, http.StatusBadRequest)
+}
+
+// joinSet дописывает к SET дополнительные присваивания
+func joinSet(set ...string) string {
+	parts := make([]string, 0, len(set))
+	for _, part := range set {
+		if part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, ", ")
+}